@@ -0,0 +1,186 @@
+package entitlements
+
+import "time"
+
+// DenyRecord is the evidence WithDenyAuditTrail emits for a denial: an
+// analysis of every branch of the denied requirement and which came closest
+// to passing.
+type DenyRecord struct {
+	// Time is when the denial was made, on the checker's Clock.
+	Time time.Time `json:"time"`
+	// Branches analyses each branch of the requirement, in order.
+	Branches []BranchAnalysis `json:"branches"`
+	// Closest indexes the branch in Branches that came closest to passing:
+	// the fewest missing schemes and unmet tokens, the first on ties.
+	Closest int `json:"closest"`
+	// Cached is set when the denial was answered from the denial cache (see
+	// WithDenialCache); the analysis is recomputed all the same.
+	Cached bool `json:"cached,omitempty"`
+}
+
+// BranchAnalysis is why one branch of a denied requirement did not pass.
+type BranchAnalysis struct {
+	// MissingSchemes lists, sorted, the schemes the branch requires that the
+	// caller did not present, with no base or anonymous entitlements to stand
+	// in for them.
+	MissingSchemes []string `json:"missingSchemes,omitempty"`
+	// Unmet lists the branch's unsatisfied tokens, in scheme order, then
+	// token order.
+	Unmet []UnmetRequirement `json:"unmet,omitempty"`
+}
+
+// UnmetRequirement is one requirement token a caller did not satisfy.
+type UnmetRequirement struct {
+	Scheme      string `json:"scheme"`
+	Requirement string `json:"requirement"`
+	// NearMiss is the caller's entitlement under the scheme that came
+	// closest to satisfying the token: one on the same resource type,
+	// agreeing on the most of resourceName and verb, the first on ties.
+	// Empty when none names the resource type, including for opaque tokens.
+	NearMiss string `json:"nearMiss,omitempty"`
+}
+
+// Unmet returns the unmet tokens of the closest branch: the least the caller
+// would have needed to pass.
+func (r DenyRecord) Unmet() []UnmetRequirement {
+	if r.Closest < 0 || r.Closest >= len(r.Branches) {
+		return nil
+	}
+	return r.Branches[r.Closest].Unmet
+}
+
+// WithDenyAuditTrail calls record with a DenyRecord on every denial, for
+// compliance evidence that each denial was intended. It never fires on an
+// allow. The verify methods, PreparedPolicy.Check and denials answered from
+// the denial cache all report; analysis methods such as Explain, which decide
+// nothing, do not, and neither does VerifyWithForbidden for a caller denied
+// because they satisfy its forbidden requirements, since nothing is unmet.
+//
+// record runs synchronously on the verifying goroutine, after the decision,
+// and must be safe for concurrent use. Building the record re-evaluates every
+// branch, so it costs about as much as a verification with no short-circuit;
+// allows cost nothing extra. nil disables the trail, the default.
+//
+// Intended for use during checker construction; not safe for concurrent
+// mutation with verify calls in flight.
+func (ec *EntitlementsChecker) WithDenyAuditTrail(record func(DenyRecord)) *EntitlementsChecker {
+	ec.denyAudit = record
+	return ec
+}
+
+// auditDenial emits the DenyRecord for a denial of branches, if the trail is
+// enabled.
+func (ec *EntitlementsChecker) auditDenial(held map[string][]entitlementPattern, branches []map[string][]entitlementPattern, fb fallback, cached bool) {
+	if ec.denyAudit == nil {
+		return
+	}
+	analysis, closest := ec.analyseBranches(held, branches, fb)
+	ec.denyAudit(DenyRecord{
+		Time:     ec.clock.Now(),
+		Branches: analysis,
+		Closest:  closest,
+		Cached:   cached,
+	})
+}
+
+// analyseBranches analyses every branch and returns the index of the one
+// closest to passing: the fewest missing schemes and unmet tokens, the first
+// on ties, or -1 when there are no branches.
+func (ec *EntitlementsChecker) analyseBranches(held map[string][]entitlementPattern, branches []map[string][]entitlementPattern, fb fallback) ([]BranchAnalysis, int) {
+	analysis := make([]BranchAnalysis, len(branches))
+	closest, best := -1, 0
+	for i, branch := range branches {
+		a := ec.analyseBranch(held, branch, fb)
+		analysis[i] = a
+		if gap := len(a.MissingSchemes) + len(a.Unmet); closest < 0 || gap < best {
+			closest, best = i, gap
+		}
+	}
+	return analysis, closest
+}
+
+// analyseBranch mirrors satisfiesAndRequirements, recording every failure
+// instead of stopping at the first.
+func (ec *EntitlementsChecker) analyseBranch(held map[string][]entitlementPattern, branch map[string][]entitlementPattern, fb fallback) BranchAnalysis {
+	var a BranchAnalysis
+	for _, listed := range sortedKeys(branch) {
+		list := branch[listed]
+		scheme := ec.judgedScheme(held, listed)
+		_, ok := held[scheme]
+		hasFallback := scheme == ec.defaultScheme &&
+			((fb.base && len(ec.basePatterns) > 0) || (fb.anonymous && len(ec.anonymousPatterns) > 0))
+		if !ok && ec.searchesAllSchemes(list) {
+			for _, p := range list {
+				if !ec.satisfiesUnderAnyScheme(held, p, fb) {
+					a.Unmet = append(a.Unmet, UnmetRequirement{
+						Scheme:      listed,
+						Requirement: p.raw,
+						NearMiss:    ec.nearMissUnderAnyScheme(held, p),
+					})
+				}
+			}
+			continue
+		}
+		if !ok && !hasFallback && !schemeOptional(list) {
+			a.MissingSchemes = append(a.MissingSchemes, listed)
+		}
+		m := ec.matcherFor(scheme)
+		for _, p := range list {
+			if !ec.satisfiesRequirement(held, scheme, []entitlementPattern{p}, fb) {
+				a.Unmet = append(a.Unmet, UnmetRequirement{
+					Scheme:      listed,
+					Requirement: p.raw,
+					NearMiss:    m.nearMiss(held[scheme], p),
+				})
+			}
+		}
+	}
+	return a
+}
+
+// nearMissUnderAnyScheme is nearMiss under the first of the wildcard schemes
+// (see WithSchemeWildcard) holding a near miss.
+func (ec *EntitlementsChecker) nearMissUnderAnyScheme(held map[string][]entitlementPattern, requirement entitlementPattern) string {
+	for _, scheme := range ec.wildcardSchemes(held) {
+		if near := ec.matcherFor(scheme).nearMiss(held[scheme], requirement); near != "" {
+			return near
+		}
+	}
+	return ""
+}
+
+// nearMiss returns the raw held entitlement in list closest to satisfying
+// requirement (see UnmetRequirement.NearMiss), or "".
+func (m matcher) nearMiss(list []entitlementPattern, requirement entitlementPattern) string {
+	if !requirement.isPattern {
+		return ""
+	}
+	if m.foldCase {
+		requirement = foldCase(requirement)
+	}
+	nearest, best := "", -1
+	for _, ep := range list {
+		ep = m.expandBare(ep)
+		if ep.except || !ep.isPattern {
+			continue
+		}
+		if m.foldCase {
+			ep = foldCase(ep)
+		}
+		if ep.resource != requirement.resource &&
+			!(m.versions && (ep.resource == ResourceTypeWildcard || resourceCovers(ep.resource, requirement.resource))) {
+			continue
+		}
+		score := 0
+		if isWildcardName(m.canonicalName(ep.resourceName)) || ep.resourceName == requirement.resourceName {
+			score++
+		}
+		if m.verbMatches(ep.verb, requirement.verb) {
+			score++
+		}
+		if score > best {
+			nearest, best = ep.raw, score
+		}
+	}
+	return nearest
+}
@@ -0,0 +1,43 @@
+package entitlements
+
+// BatchCheck is one resource check of VerifyEntitlementsBatch, with the
+// arguments of VerifyResourceEntitlements.
+type BatchCheck struct {
+	Resource     string
+	ResourceName string
+	// Verb is the verb of the identity requirement; "read" when empty.
+	Verb         string
+	Requirements Requirements
+}
+
+// VerifyEntitlementsBatch verifies entitlements against every check, as
+// VerifyResourceEntitlements would, for a gateway deciding many resources for
+// one caller. The entitlements are parsed once, into a copy the batch owns,
+// and shared by every check rather than parsed again for each.
+//
+// Results are in checks order, one per check. Checks are evaluated
+// sequentially in that order, every one of them — a denial does not stop the
+// batch — so audit records (see WithDenyAuditTrail) follow it too. A check
+// with an empty Resource or ResourceName, which VerifyResourceEntitlements
+// rejects with an error, is false. The break-glass check (see WithBreakGlass)
+// is consulted once per batch: when it grants access, every valid check is
+// true and a single grant is recorded.
+func (ec *EntitlementsChecker) VerifyEntitlementsBatch(entitlements Entitlements, checks []BatchCheck) []bool {
+	results := make([]bool, len(checks))
+	if len(checks) == 0 {
+		return results
+	}
+	if ec.brokeGlass(entitlements) {
+		for i, check := range checks {
+			results[i] = check.Resource != "" && check.ResourceName != ""
+		}
+		return results
+	}
+
+	parsed := ec.ParseEntitlements(entitlements)
+	for i, check := range checks {
+		results[i], _ = ec.VerifyResourceParsedEntitlements(check.Resource, check.ResourceName,
+			parsed, ec.ParseRequirements(check.Requirements), check.Verb)
+	}
+	return results
+}
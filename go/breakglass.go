@@ -0,0 +1,87 @@
+package entitlements
+
+import (
+	"slices"
+	"time"
+)
+
+// BreakGlassRecord is the audit evidence WithBreakGlass emits for every
+// emergency grant.
+type BreakGlassRecord struct {
+	// Time is when access was granted, on the checker's Clock.
+	Time time.Time `json:"time"`
+	// Reason is the reason the break-glass check gave for the grant.
+	Reason string `json:"reason"`
+}
+
+// WithBreakGlass enables emergency access. Before evaluating requirements,
+// the verify methods taking raw Entitlements (VerifyEntitlements and the
+// methods built on it, VerifyWithForbidden, VerifyEntitlementsUsingSchemes,
+// VerifyResourceEntitlements, VerifyWithContext, VerifyEntitlementsBatch) and
+// PreparedPolicy.Check call check with the caller's entitlements; when it
+// returns true, access is granted regardless of the requirements — forbidden
+// ones, denies and the denial cache included — and record is called with a
+// BreakGlassRecord carrying check's reason.
+//
+// Unlike a broad grant such as "*:*:all", a break-glass grant cannot go
+// unrecorded: record runs synchronously, before the grant is returned, on
+// every break-glass decision, and break-glass is off unless both check and
+// record are set. Both must be safe for concurrent use. The parsed-form
+// methods (VerifyParsedEntitlements, VerifyResourceParsedEntitlements) do not
+// hold the raw entitlements and do not consult check. Explain reports a
+// break-glass grant in its Decision without recording it, since it decides
+// nothing.
+//
+// Requirements with no branches still admit every caller without consulting
+// check. Intended for use during checker construction; not safe for
+// concurrent mutation with verify calls in flight.
+func (ec *EntitlementsChecker) WithBreakGlass(check func(entitlements Entitlements) (bool, string), record func(BreakGlassRecord)) *EntitlementsChecker {
+	ec.breakGlass = check
+	ec.breakGlassRecord = record
+	return ec
+}
+
+// breakGlassReason consults the break-glass check, returning its reason and
+// whether it grants access. It records nothing.
+func (ec *EntitlementsChecker) breakGlassReason(entitlements Entitlements) (string, bool) {
+	if ec.breakGlass == nil || ec.breakGlassRecord == nil {
+		return "", false
+	}
+	ok, reason := ec.breakGlass(entitlements)
+	return reason, ok
+}
+
+// brokeGlass reports whether the break-glass check grants entitlements
+// access, recording the grant when it does.
+func (ec *EntitlementsChecker) brokeGlass(entitlements Entitlements) bool {
+	reason, ok := ec.breakGlassReason(entitlements)
+	if ok {
+		ec.recordBreakGlass(reason)
+	}
+	return ok
+}
+
+// recordBreakGlass logs, records and counts a break-glass grant given for
+// reason.
+func (ec *EntitlementsChecker) recordBreakGlass(reason string) {
+	if ec.log != nil {
+		ec.log.Info("Granted break-glass access", "reason", reason)
+	}
+	ec.breakGlassRecord(BreakGlassRecord{Time: ec.clock.Now(), Reason: reason})
+	ec.countDecision(true, nil, nil)
+}
+
+// onlySchemes returns entitlements restricted to schemes.
+func (ec *EntitlementsChecker) onlySchemes(entitlements Entitlements, schemes []string) Entitlements {
+	keys := make([]string, len(schemes))
+	for i, scheme := range schemes {
+		keys[i] = ec.schemeKey(scheme)
+	}
+	restricted := make(Entitlements, len(entitlements))
+	for scheme, list := range entitlements {
+		if slices.Contains(keys, ec.schemeKey(scheme)) {
+			restricted[scheme] = list
+		}
+	}
+	return restricted
+}
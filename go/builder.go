@@ -0,0 +1,89 @@
+package entitlements
+
+import (
+	"errors"
+	"fmt"
+)
+
+// RequirementsBuilder assembles Requirements one scheme at a time, so the
+// OR-of-AND nesting need not be written by hand:
+//
+//	NewRequirementsBuilder().
+//		Or().Scheme("bearer", "pages:read").And().Scheme("oauth2", "files:write").
+//		Or().Scheme("apikey", "admin").
+//		Build()
+//
+// yields {{"bearer": {"pages:read"}, "oauth2": {"files:write"}}, {"apikey":
+// {"admin"}}}. Or starts a new branch and And continues the current one; a
+// Scheme call with no branch started starts one. Or adds the branch only once
+// a Scheme call fills it, so a stray Or never adds the empty branch that would
+// admit every caller.
+//
+// Each token is checked for syntax as it is added, as
+// ValidateRequirements does without a checker's options; Build reports every
+// problem found. A RequirementsBuilder is not safe for concurrent use.
+type RequirementsBuilder struct {
+	requirements Requirements
+	// open is set when the next Scheme call continues the last branch.
+	open bool
+	errs []error
+}
+
+// NewRequirementsBuilder returns an empty RequirementsBuilder.
+func NewRequirementsBuilder() *RequirementsBuilder {
+	return &RequirementsBuilder{}
+}
+
+// Or starts a new branch, an alternative to those before it.
+func (b *RequirementsBuilder) Or() *RequirementsBuilder {
+	b.open = false
+	return b
+}
+
+// And continues the current branch, whose schemes must all be satisfied. It
+// only reads as the connective; Scheme calls join the current branch with or
+// without it.
+func (b *RequirementsBuilder) And() *RequirementsBuilder {
+	return b
+}
+
+// Scheme adds tokens under scheme to the current branch, after any already
+// added there. With no tokens, the branch requires only that the caller
+// present scheme.
+func (b *RequirementsBuilder) Scheme(scheme string, tokens ...string) *RequirementsBuilder {
+	if !b.open {
+		b.requirements = append(b.requirements, map[string][]string{})
+		b.open = true
+	}
+	i := len(b.requirements) - 1
+	if scheme == "" {
+		b.errs = append(b.errs, fmt.Errorf("%w: branch %d: empty scheme name", ErrInvalidRequirement, i))
+		return b
+	}
+	branch := b.requirements[i]
+	if branch[scheme] == nil {
+		branch[scheme] = []string{}
+	}
+	for _, s := range tokens {
+		if problem := (*EntitlementsChecker)(nil).requirementProblem(s); problem != "" {
+			b.errs = append(b.errs, fmt.Errorf("%w: branch %d, scheme %q, token %d: %q %s",
+				ErrInvalidRequirement, i, scheme, len(branch[scheme]), s, problem))
+		}
+		branch[scheme] = append(branch[scheme], s)
+	}
+	return b
+}
+
+// Build returns the requirements built so far, which share nothing with the
+// builder, or nil and the joined problems (each wrapping
+// ErrInvalidRequirement) of every invalid token added. A builder with no
+// Scheme calls builds empty Requirements, which admit every caller.
+func (b *RequirementsBuilder) Build() (Requirements, error) {
+	if len(b.errs) > 0 {
+		return nil, errors.Join(b.errs...)
+	}
+	if b.requirements == nil {
+		return Requirements{}, nil
+	}
+	return cloneRequirements(b.requirements), nil
+}
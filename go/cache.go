@@ -0,0 +1,184 @@
+package entitlements
+
+import (
+	"container/list"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CacheStats reports the effectiveness of one cache. Counters are cumulative
+// over the checker's lifetime; Size and Capacity describe the cache when the
+// snapshot was taken.
+type CacheStats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Size      int    `json:"size"`
+	Capacity  int    `json:"capacity"`
+}
+
+// CacheStatsSnapshot reports every cache a checker has configured.
+type CacheStatsSnapshot struct {
+	// Parse is the pattern interning cache. It never evicts: once full it
+	// stops interning, so a Size at Capacity with rising Misses means the
+	// working set of patterns outgrew it.
+	Parse CacheStats `json:"parse"`
+	// Denial is the denial cache, nil unless WithDenialCache enabled it.
+	// Evictions counts entries dropped to make room; expired entries are
+	// dropped on lookup and count as Misses.
+	Denial *CacheStats `json:"denial,omitempty"`
+}
+
+// CacheStats returns a snapshot of the checker's cache statistics, for tuning
+// cache sizes. It is safe to call concurrently with verify calls, and cheap:
+// counters are atomic, and each cache's lock is held only to read its size.
+// Counters read while verify calls are in flight may be mutually a little
+// out of step.
+func (ec *EntitlementsChecker) CacheStats() CacheStatsSnapshot {
+	ec.mu.RLock()
+	size := len(ec.cache)
+	ec.mu.RUnlock()
+
+	snapshot := CacheStatsSnapshot{Parse: CacheStats{
+		Hits:     ec.parseHits.Load(),
+		Misses:   ec.parseMisses.Load(),
+		Size:     size,
+		Capacity: maxCacheSize,
+	}}
+	if ec.denials != nil {
+		stats := ec.denials.stats()
+		snapshot.Denial = &stats
+	}
+	return snapshot
+}
+
+// denialCache remembers (entitlements, requirements) fingerprints that were
+// recently denied. It never records an allow: a cached denial can only ever
+// short-circuit to the answer verification would have produced anyway, so a
+// stale entry fails closed.
+//
+// Entries expire after ttl. When the cache is full the oldest entry is evicted
+// first, so a flood of distinct denials cannot grow it without bound.
+type denialCache struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+	size    int
+	ttl     time.Duration
+
+	hits, misses, evictions atomic.Uint64
+}
+
+type denialEntry struct {
+	key     string
+	expires time.Time
+}
+
+func newDenialCache(size int, ttl time.Duration) *denialCache {
+	return &denialCache{
+		entries: make(map[string]*list.Element, size),
+		order:   list.New(),
+		size:    size,
+		ttl:     ttl,
+	}
+}
+
+// denied reports whether key holds an unexpired denial at now. Expired
+// entries are dropped on lookup.
+func (c *denialCache) denied(key string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		c.misses.Add(1)
+		return false
+	}
+	if !now.Before(el.Value.(*denialEntry).expires) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		c.misses.Add(1)
+		return false
+	}
+	c.hits.Add(1)
+	return true
+}
+
+// add records a denial of key at now, evicting the oldest entry if full.
+func (c *denialCache) add(key string, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		el.Value.(*denialEntry).expires = now.Add(c.ttl)
+		c.order.MoveToBack(el)
+		return
+	}
+	for c.order.Len() >= c.size {
+		oldest := c.order.Front()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*denialEntry).key)
+		c.evictions.Add(1)
+	}
+	c.entries[key] = c.order.PushBack(&denialEntry{key: key, expires: now.Add(c.ttl)})
+}
+
+// clear drops every entry. Called whenever configuration that feeds a
+// decision changes, so no denial outlives the policy that produced it.
+func (c *denialCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+	c.order.Init()
+}
+
+func (c *denialCache) stats() CacheStats {
+	c.mu.Lock()
+	size := c.order.Len()
+	c.mu.Unlock()
+	return CacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Size:      size,
+		Capacity:  c.size,
+	}
+}
+
+// fingerprint returns a canonical key for an (entitlements, requirements)
+// pair. Scheme keys and the tokens under each scheme are sorted, so two
+// spellings of the same maps share a key; the order of OR branches is kept
+// because it is part of the requirement as written. Every string is
+// length-prefixed, so no token content can forge a separator.
+func fingerprint(entitlements Entitlements, requirements Requirements) string {
+	var b strings.Builder
+	writeSchemes(&b, entitlements)
+	b.WriteByte('|')
+	for _, req := range requirements {
+		b.WriteByte('(')
+		writeSchemes(&b, req)
+		b.WriteByte(')')
+	}
+	return b.String()
+}
+
+func writeSchemes(b *strings.Builder, m map[string][]string) {
+	for _, scheme := range sortedKeys(m) {
+		writeToken(b, scheme)
+		b.WriteByte('[')
+		tokens := slices.Clone(m[scheme])
+		slices.Sort(tokens)
+		for _, t := range tokens {
+			writeToken(b, t)
+		}
+		b.WriteByte(']')
+	}
+}
+
+func writeToken(b *strings.Builder, s string) {
+	b.WriteString(strconv.Itoa(len(s)))
+	b.WriteByte(':')
+	b.WriteString(s)
+}
@@ -0,0 +1,78 @@
+package entitlements
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrAnonymousCeiling is returned by WithAnonymousCeiling when an entitlement
+// granted to anonymous callers exceeds the ceiling.
+var ErrAnonymousCeiling = errors.New("entitlements: anonymous entitlement exceeds ceiling")
+
+// WithAnonymousCeiling is a construction-time guardrail against a
+// misconfigured anonymous list making sensitive resources public. ceiling is
+// the most access anonymous callers may ever have: its tokens under the
+// default scheme, from every branch, are the broadest grants allowed. Every
+// anonymous entitlement (see NewEntitlementsChecker), and every base
+// entitlement (see WithBaseEntitlements), which anonymous callers receive
+// too, must be dominated by one of them (see Dominates); the first that is
+// not returns an error wrapping ErrAnonymousCeiling that names it. Held
+// denies grant nothing and are not checked.
+//
+// The ceiling is kept on the checker: a later WithBaseEntitlements is checked
+// against it too and, if it exceeds it, is not applied (see
+// AnonymousCeilingErr). Checking costs nothing per request. Dominates fails
+// closed on forms it does not understand, so an anonymous entitlement the
+// ceiling does not literally cover, such as an opaque one or a resource type
+// wildcard, must appear in the ceiling verbatim.
+//
+// Replaces any previously set ceiling. Intended for use during checker
+// construction; not safe for concurrent mutation with verify calls in flight.
+func (ec *EntitlementsChecker) WithAnonymousCeiling(ceiling Requirements) (*EntitlementsChecker, error) {
+	// Non-nil even when empty: a nil ceiling means none is set.
+	allowed := []string{}
+	for _, set := range ceiling {
+		for scheme, list := range set {
+			if ec.schemeKey(scheme) == ec.defaultScheme {
+				allowed = append(allowed, list...)
+			}
+		}
+	}
+	ec.anonymousCeiling = allowed
+	ec.anonymousCeilingErr = nil
+
+	for _, patterns := range [][]entitlementPattern{ec.anonymousPatterns, ec.basePatterns} {
+		if err := ec.exceedsAnonymousCeiling(patterns); err != nil {
+			return ec, err
+		}
+	}
+	return ec, nil
+}
+
+// AnonymousCeilingErr returns the error, wrapping ErrAnonymousCeiling, for
+// the last WithBaseEntitlements call that exceeded the anonymous ceiling (see
+// WithAnonymousCeiling), or nil. Such a call leaves the base entitlements
+// unchanged, so a caller that builds the checker fluently should check it
+// before serving.
+func (ec *EntitlementsChecker) AnonymousCeilingErr() error {
+	return ec.anonymousCeilingErr
+}
+
+// exceedsAnonymousCeiling returns an error naming the first grant in patterns
+// the anonymous ceiling does not dominate, or nil, including when no ceiling
+// is set.
+func (ec *EntitlementsChecker) exceedsAnonymousCeiling(patterns []entitlementPattern) error {
+	if ec.anonymousCeiling == nil {
+		return nil
+	}
+	var granted []string
+	for _, p := range patterns {
+		if !p.except {
+			granted = append(granted, p.raw)
+		}
+	}
+	if offender, ok := VerifyAttenuation(ec.anonymousCeiling, granted); !ok {
+		return fmt.Errorf("%w: %q", ErrAnonymousCeiling, offender)
+	}
+	return nil
+}
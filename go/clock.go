@@ -0,0 +1,38 @@
+package entitlements
+
+import "time"
+
+// Clock supplies the current time to the checker's time-dependent features,
+// such as the denial cache TTL (see WithDenialCache) and entitlement expiry
+// when no instant is given (see VerifyEntitlementsWithExpiry). Tests
+// substitute a controllable clock, e.g. entitlementstest.FakeClock, through
+// WithClock.
+type Clock interface {
+	Now() time.Time
+}
+
+// realClock is the default Clock, reading the system time.
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+// WithClock sets the clock the checker reads the current time from. A nil
+// clock restores the system clock, the default.
+//
+// Intended for use during checker construction; not safe for concurrent
+// mutation with verify calls in flight.
+func (ec *EntitlementsChecker) WithClock(c Clock) *EntitlementsChecker {
+	if c == nil {
+		c = realClock{}
+	}
+	ec.clock = c
+	return ec
+}
+
+// at returns now, or the clock's current time when now is zero.
+func (ec *EntitlementsChecker) at(now time.Time) time.Time {
+	if now.IsZero() {
+		return ec.clock.Now()
+	}
+	return now
+}
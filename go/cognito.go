@@ -0,0 +1,56 @@
+package entitlements
+
+import (
+	"errors"
+	"fmt"
+)
+
+// CognitoGroupsClaim is the claim AWS Cognito carries a user's group names in.
+const CognitoGroupsClaim = "cognito:groups"
+
+// ErrInvalidClaim is returned when a token claim does not have the expected
+// shape.
+var ErrInvalidClaim = errors.New("entitlements: invalid claim")
+
+// EntitlementsFromCognito maps the groups in a Cognito token's
+// "cognito:groups" claim to entitlements under scheme ("bearer" when empty):
+// the union, in group order and without duplicates, of groupEntitlements for
+// each group. Groups with no entry are ignored.
+//
+// The scheme is always present in the result, with an empty list when no
+// group maps to anything — the caller did present a token, though with no
+// entitlements they are still treated as anonymous. A missing claim means no
+// groups. A claim that is not an array of strings returns an error wrapping
+// ErrInvalidClaim, since silently reading it as "no groups" could hide a
+// misconfigured user pool.
+func EntitlementsFromCognito(claims map[string]any, groupEntitlements map[string][]string, scheme string) (Entitlements, error) {
+	if scheme == "" {
+		scheme = "bearer"
+	}
+
+	var groups []string
+	switch v := claims[CognitoGroupsClaim].(type) {
+	case nil:
+	case []string:
+		groups = v
+	case []any:
+		groups = make([]string, len(v))
+		for i, g := range v {
+			s, ok := g.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: %q element %d is %T, want string", ErrInvalidClaim, CognitoGroupsClaim, i, g)
+			}
+			groups[i] = s
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q is %T, want an array of strings", ErrInvalidClaim, CognitoGroupsClaim, v)
+	}
+
+	list := []string{}
+	for _, g := range groups {
+		for _, e := range groupEntitlements[g] {
+			list = appendUnique(list, e)
+		}
+	}
+	return Entitlements{scheme: list}, nil
+}
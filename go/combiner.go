@@ -0,0 +1,94 @@
+package entitlements
+
+import (
+	"context"
+	"slices"
+)
+
+// WithBranchCombiner replaces how the OR'd branches of a requirement combine
+// into a decision. combine receives one result per branch, in order — true
+// when the caller satisfies that branch — and returns the decision, so
+// AllBranches makes the branches AND'd and AtLeastBranches(2) accepts any two.
+// nil restores the default, OR (any one branch).
+//
+// The combiner applies to the top level of every requirement a checker
+// verifies, including the single-branch requirements built by helpers such as
+// VerifyCoversAll, so it must handle any number of branches. It does not
+// apply to the branches of a named requirement reached through an "@name"
+// reference, which stay OR'd, and empty Requirements still admit every caller
+// without consulting it. With a combiner every branch is evaluated, so there
+// is no short-circuit on the first satisfied branch.
+//
+// Clears the denial cache, whose entries the old combination produced.
+// Intended for use during checker construction; not safe for concurrent
+// mutation with verify calls in flight.
+func (ec *EntitlementsChecker) WithBranchCombiner(combine func(results []bool) bool) *EntitlementsChecker {
+	ec.branchCombiner = combine
+	if ec.denials != nil {
+		ec.denials.clear()
+	}
+	return ec
+}
+
+// AllBranches is a branch combiner (see WithBranchCombiner) requiring every
+// branch to be satisfied.
+func AllBranches(results []bool) bool {
+	for _, ok := range results {
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// AtLeastBranches returns a branch combiner (see WithBranchCombiner) requiring
+// at least n branches to be satisfied. A requirement with fewer than n
+// branches is never satisfied; n <= 1 is the default OR.
+func AtLeastBranches(n int) func(results []bool) bool {
+	return func(results []bool) bool {
+		satisfied := 0
+		for _, ok := range results {
+			if ok {
+				satisfied++
+			}
+		}
+		return satisfied >= max(n, 1)
+	}
+}
+
+// satisfiesBranches reports whether held satisfies the top-level branches of
+// a non-empty requirement, combined by the checker's branch combiner.
+func (ec *EntitlementsChecker) satisfiesBranches(held map[string][]entitlementPattern, branches []map[string][]entitlementPattern, fb fallback) bool {
+	ok, _, _ := ec.satisfiesBranchesCtx(context.Background(), held, branches, fb)
+	return ok
+}
+
+// satisfiesBranchesCtx is satisfiesBranches, also returning the index of the
+// first satisfied branch (-1 when denied), and returning ctx's error as soon
+// as it is found done before a branch.
+func (ec *EntitlementsChecker) satisfiesBranchesCtx(ctx context.Context, held map[string][]entitlementPattern, branches []map[string][]entitlementPattern, fb fallback) (bool, int, error) {
+	if ec.branchCombiner == nil {
+		for i, branch := range branches {
+			if err := ctx.Err(); err != nil {
+				return false, -1, err
+			}
+			if ec.satisfiesAndRequirements(held, branch, fb) {
+				return true, i, nil
+			}
+		}
+		return false, -1, nil
+	}
+	results := make([]bool, len(branches))
+	for i, branch := range branches {
+		if err := ctx.Err(); err != nil {
+			return false, -1, err
+		}
+		results[i] = ec.satisfiesAndRequirements(held, branch, fb)
+	}
+	if !ec.branchCombiner(results) {
+		return false, -1, nil
+	}
+	// An allow need not rest on any satisfied branch, e.g. under a combiner
+	// admitting when none is.
+	return true, slices.Index(results, true), nil
+}
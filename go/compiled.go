@@ -0,0 +1,60 @@
+package entitlements
+
+import "slices"
+
+// CompiledRequirements is a requirement validated and compiled once by
+// CompileRequirements, for checking a high volume of callers against it with
+// VerifyCompiled. It is immutable and safe for concurrent use by multiple
+// goroutines.
+type CompiledRequirements struct {
+	requirements Requirements
+	policy       *PreparedPolicy
+}
+
+// CompileRequirements validates requirements (see ValidateRequirements),
+// returning the joined problems if any, and compiles them: every token is
+// parsed once and indexed as PrepareFor does, so VerifyCompiled neither
+// re-parses the requirements nor scans every token against every
+// entitlement. requirements is copied; later changes to it do not affect the
+// result.
+func (ec *EntitlementsChecker) CompileRequirements(requirements Requirements) (*CompiledRequirements, error) {
+	if err := ec.ValidateRequirements(requirements); err != nil {
+		return nil, err
+	}
+	requirements = cloneRequirements(requirements)
+	return &CompiledRequirements{requirements: requirements, policy: ec.PrepareFor(requirements)}, nil
+}
+
+// VerifyCompiled reports whether entitlements satisfy compiled, deciding
+// exactly as VerifyEntitlements would, without its denial cache or decision
+// hook (see WithDenialCache and WithDecisionHook). compiled should come from
+// this checker's CompileRequirements; one compiled by another checker is
+// verified correctly under this one, without the speed-up. A nil compiled is
+// denied.
+func (ec *EntitlementsChecker) VerifyCompiled(entitlements Entitlements, compiled *CompiledRequirements) bool {
+	if compiled == nil {
+		return false
+	}
+	if compiled.policy.ec != ec {
+		if len(compiled.requirements) > 0 && ec.brokeGlass(entitlements) {
+			return true
+		}
+		return ec.VerifyParsedEntitlements(ec.ParseEntitlements(entitlements), ec.ParseRequirements(compiled.requirements))
+	}
+	return compiled.policy.Check(entitlements)
+}
+
+// cloneRequirements deep-copies requirements.
+func cloneRequirements(requirements Requirements) Requirements {
+	if requirements == nil {
+		return nil
+	}
+	clone := make(Requirements, len(requirements))
+	for i, set := range requirements {
+		clone[i] = make(map[string][]string, len(set))
+		for scheme, list := range set {
+			clone[i][scheme] = slices.Clone(list)
+		}
+	}
+	return clone
+}
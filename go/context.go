@@ -0,0 +1,108 @@
+package entitlements
+
+import "time"
+
+// Placeholders VerifyWithContext binds from a RequestContext, e.g. in the
+// requirement "users:{self}:read".
+const (
+	SelfPlaceholder   = "self"
+	TenantPlaceholder = "tenant"
+)
+
+// RequestContext is what a handler knows about a request beyond the caller's
+// entitlements, for VerifyWithContext. Every field is optional.
+type RequestContext struct {
+	// Subject is the caller's identity, bound to the {self} placeholder.
+	Subject string
+	// Tenant is the tenant addressed, bound to the {tenant} placeholder.
+	Tenant string
+	// Region is the data-residency region the request is served for.
+	// Region-tagged entitlements are matched in it rather than in the
+	// checker's region (see WithRegion), which, when set, it must equal.
+	Region string
+	// Attributes binds the remaining placeholders, as BindRequirements does.
+	// Subject and Tenant take precedence over entries for "self" and
+	// "tenant".
+	Attributes Binding
+	// Expiries maps an entitlement string to the instant it expires, as in
+	// VerifyEntitlementsWithExpiry.
+	Expiries map[string]time.Time
+	// Clock supplies the time Expiries are judged at; nil uses the checker's
+	// clock (see WithClock).
+	Clock Clock
+}
+
+// binding returns the placeholder values rc supplies.
+func (rc RequestContext) binding() Binding {
+	b := make(Binding, len(rc.Attributes)+2)
+	for key, value := range rc.Attributes {
+		b[key] = value
+	}
+	if rc.Subject != "" {
+		b[SelfPlaceholder] = rc.Subject
+	}
+	if rc.Tenant != "" {
+		b[TenantPlaceholder] = rc.Tenant
+	}
+	return b
+}
+
+// VerifyWithContext verifies entitlements against requirements in the light
+// of rc, reporting the outcome as Explain does, so a handler can gate on
+// subject, tenant, attributes, residency and expiry in one call:
+//
+//   - under a checker with a region, a Region other than it is denied
+//     outright, residency failing closed; otherwise region-tagged
+//     entitlements match only in Region, or in the checker's region when
+//     Region is empty;
+//   - entitlements expired at rc's clock, per Expiries, are ignored, which may
+//     leave the caller anonymous;
+//   - the break-glass check (see WithBreakGlass) then applies, and its grants
+//     are recorded;
+//   - requirement placeholders are bound from Subject, Tenant and Attributes.
+//     A placeholder left unbound, or bound to an invalid value, denies rather
+//     than erring — see BindRequirements for both rules, and the strict
+//     requirement checks it also applies.
+//
+// Requirements with no branches admit every caller in the region. The denial
+// cache is not consulted.
+func (ec *EntitlementsChecker) VerifyWithContext(rc RequestContext, entitlements Entitlements, requirements Requirements) Decision {
+	if rc.Region != "" && ec.region != "" && rc.Region != ec.region {
+		if ec.log != nil {
+			ec.log.V(1).Info("Denied request for another region", "region", rc.Region)
+		}
+		ec.countDecision(false, nil, nil)
+		return Decision{Branch: -1}
+	}
+
+	if len(rc.Expiries) > 0 {
+		clock := rc.Clock
+		if clock == nil {
+			clock = ec.clock
+		}
+		entitlements = unexpired(entitlements, rc.Expiries, clock.Now())
+	}
+
+	parsed := ec.ParseRequirements(requirements)
+	if len(parsed.patterns) > 0 {
+		if reason, ok := ec.breakGlassReason(entitlements); ok {
+			ec.recordBreakGlass(reason)
+			return Decision{Allowed: true, Branch: -1, BreakGlass: true, BreakGlassReason: reason}
+		}
+	}
+
+	bound, err := ec.BindRequirements(parsed, rc.binding())
+	if err != nil {
+		if ec.log != nil {
+			ec.log.V(1).Info("Denied unbindable requirements", "error", err.Error())
+		}
+		ec.countDecision(false, nil, nil)
+		return Decision{Branch: -1}
+	}
+	d := ec.explain(entitlements, bound.patterns, rc.Region)
+	if ec.metrics != nil {
+		held := ec.enabledSchemes(ec.ParseEntitlements(entitlements).patterns)
+		ec.countDecision(d.Allowed, held, branchAt(bound.patterns, d.Branch))
+	}
+	return d
+}
@@ -0,0 +1,152 @@
+package entitlements
+
+// Decision is the outcome of Explain: the verdict and, when allowed, which
+// entitlements satisfied the requirement and where each came from.
+type Decision struct {
+	Allowed bool `json:"allowed"`
+	// Branch is the index of the OR branch that was satisfied, or -1 when
+	// denied or when there were no requirements to satisfy. Under
+	// WithBranchCombiner it is the first satisfied branch, if any.
+	Branch int `json:"branch"`
+	// Grants attributes the tokens of the satisfied branch, in scheme order,
+	// then token order; under WithBranchCombiner, of every satisfied branch,
+	// in branch order. Tokens satisfied without a grant (except conditions)
+	// are omitted.
+	Grants []Grant `json:"grants,omitempty"`
+	// BreakGlass is set when access was granted by the break-glass check (see
+	// WithBreakGlass) rather than by the requirements; Branch is then -1,
+	// Grants is empty and BreakGlassReason carries the check's reason.
+	BreakGlass       bool   `json:"breakGlass,omitempty"`
+	BreakGlassReason string `json:"breakGlassReason,omitempty"`
+}
+
+// Grant is one requirement token of a satisfied branch and the entitlement
+// that satisfied it.
+type Grant struct {
+	// Scheme is the scheme the grant was found under.
+	Scheme string `json:"scheme"`
+	// Requirement is the requirement token satisfied. For an "@name"
+	// reference it is the token of the named requirement that was satisfied.
+	Requirement string `json:"requirement"`
+	// Entitlement is the satisfying entitlement.
+	Entitlement string `json:"entitlement"`
+	// Source tells the caller's own entitlements apart from the base and
+	// anonymous entitlements the checker merges in.
+	Source GrantSource `json:"source"`
+}
+
+// AnonymousOnly reports whether d was allowed solely on the strength of
+// anonymous entitlements (see NewEntitlementsChecker): it has grants, and all
+// of them are anonymous.
+func (d Decision) AnonymousOnly() bool {
+	if !d.Allowed || len(d.Grants) == 0 {
+		return false
+	}
+	for _, g := range d.Grants {
+		if g.Source != GrantSourceAnonymous {
+			return false
+		}
+	}
+	return true
+}
+
+// Explain verifies entitlements against requirements like VerifyEntitlements
+// and reports how the verdict was reached: the first satisfied branch and the
+// grant behind each of its tokens, tagged with its source so a decision that
+// rests on the anonymous or base entitlements is told apart from one resting
+// on the caller's own. The denial cache is not consulted. A break-glass grant
+// (see WithBreakGlass) is reported with BreakGlass set, and not recorded.
+func (ec *EntitlementsChecker) Explain(entitlements Entitlements, requirements Requirements) Decision {
+	branches := ec.ParseRequirements(requirements).patterns
+	if len(branches) > 0 {
+		if reason, ok := ec.breakGlassReason(entitlements); ok {
+			return Decision{Allowed: true, Branch: -1, BreakGlass: true, BreakGlassReason: reason}
+		}
+	}
+	return ec.explain(entitlements, branches, "")
+}
+
+// explain is Explain for parsed requirements, without the break-glass check.
+// A non-empty region replaces the checker's in matching.
+func (ec *EntitlementsChecker) explain(entitlements Entitlements, branches []map[string][]entitlementPattern, region string) Decision {
+	held := ec.enabledSchemes(ec.ParseEntitlements(entitlements).patterns)
+	fb := callerFallback(held)
+	fb.region = region
+	if ec.branchCombiner == nil || len(branches) == 0 {
+		branch, grants, ok := ec.satisfyingGrants(held, branches, fb)
+		if !ok {
+			return Decision{Branch: -1}
+		}
+		return Decision{Allowed: true, Branch: branch, Grants: grants}
+	}
+
+	results := make([]bool, len(branches))
+	for i, branch := range branches {
+		results[i] = ec.satisfiesAndRequirements(held, branch, fb)
+	}
+	d := Decision{Branch: -1}
+	if !ec.branchCombiner(results) {
+		return d
+	}
+	d.Allowed = true
+	for i, ok := range results {
+		if !ok {
+			continue
+		}
+		if d.Branch < 0 {
+			d.Branch = i
+		}
+		d.Grants = ec.branchGrants(held, branches[i], fb, d.Grants)
+	}
+	return d
+}
+
+// satisfyingGrants attributes the first satisfied branch of requirements,
+// returning its index (-1 for empty requirements) and grants, and false when
+// no branch is satisfied.
+func (ec *EntitlementsChecker) satisfyingGrants(held map[string][]entitlementPattern, requirements []map[string][]entitlementPattern, fb fallback) (int, []Grant, bool) {
+	if len(requirements) == 0 {
+		return -1, nil, true
+	}
+	for i, branch := range requirements {
+		if ec.satisfiesAndRequirements(held, branch, fb) {
+			return i, ec.branchGrants(held, branch, fb, nil), true
+		}
+	}
+	return -1, nil, false
+}
+
+// branchGrants appends to grants the attribution of a branch already known
+// to be satisfied.
+func (ec *EntitlementsChecker) branchGrants(held map[string][]entitlementPattern, branch map[string][]entitlementPattern, fb fallback, grants []Grant) []Grant {
+	for _, listed := range sortedKeys(branch) {
+		scheme := ec.judgedScheme(held, listed)
+		for _, requirement := range branch[listed] {
+			switch {
+			case requirement.except:
+			case requirement.distinct > 0:
+				for _, s := range sortedKeys(held) {
+					if grant, ok := ec.ownGrant(held[s], s, *requirement.cond, fb); ok {
+						grants = append(grants, Grant{s, requirement.raw, grant.raw, GrantSourceDirect})
+					}
+				}
+			case requirement.ref != "":
+				if _, refGrants, ok := ec.satisfyingGrants(held, ec.namedRequirements[requirement.ref], fb); ok {
+					grants = append(grants, refGrants...)
+				}
+			default:
+				searched := []string{scheme}
+				if _, ok := held[scheme]; !ok && ec.searchesAllSchemes(branch[listed]) {
+					searched = ec.wildcardSchemes(held)
+				}
+				for _, s := range searched {
+					if grant, source, ok := ec.findGrant(held[s], s, requirement, fb); ok {
+						grants = append(grants, Grant{s, requirement.raw, grant.raw, source})
+						break
+					}
+				}
+			}
+		}
+	}
+	return grants
+}
@@ -0,0 +1,68 @@
+package entitlements
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrResourceNameTooDeep is returned by BindRequirements under
+// WithStrictRequirements when a requirement's resourceName is deeper than
+// WithMaxResourceNameDepth allows.
+var ErrResourceNameTooDeep = errors.New("entitlements: resourceName exceeds the maximum depth")
+
+// WithMaxResourceNameDepth bounds resourceNames to depth '/'-separated
+// segments ("/a/b/c" has three; empty segments are not counted), guarding
+// segment-aware glob matching against crafted, deeply nested names. An
+// entitlement or requirement whose resourceName is deeper than depth never
+// matches, not even an identical token. Under WithStrictRequirements,
+// BindRequirements also rejects a too-deep requirement with
+// ErrResourceNameTooDeep, and verification treats it as unsatisfiable.
+//
+// Wildcard and opaque tokens have no depth and are unaffected. The bound
+// holds under WithLegacySemantics too, since it decides which tokens are
+// considered rather than how two tokens match.
+//
+// depth <= 0 (the default) disables the bound. Intended for use during checker
+// construction; not safe for concurrent mutation with verify calls in flight.
+func (ec *EntitlementsChecker) WithMaxResourceNameDepth(depth int) *EntitlementsChecker {
+	ec.maxResourceNameDepth = max(depth, 0)
+	return ec
+}
+
+// tooDeep reports whether p's resourceName has more than limit segments. A
+// limit of 0 is unbounded.
+func tooDeep(p entitlementPattern, limit int) bool {
+	return p.isPattern && nameTooDeep(p.resourceName, limit)
+}
+
+// nameTooDeep reports whether name has more than limit segments.
+func nameTooDeep(name string, limit int) bool {
+	if limit == 0 {
+		return false
+	}
+	depth := 0
+	for i := 0; i < len(name); i++ {
+		if name[i] != '/' && (i == 0 || name[i-1] == '/') {
+			depth++
+			if depth > limit {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkDepth returns ErrResourceNameTooDeep for the first requirement in reqs
+// deeper than the checker allows.
+func (ec *EntitlementsChecker) checkDepth(reqs ParsedRequirements) error {
+	for _, set := range reqs.patterns {
+		for _, list := range set {
+			for _, p := range list {
+				if tooDeep(p, ec.maxResourceNameDepth) || (p.cond != nil && tooDeep(*p.cond, ec.maxResourceNameDepth)) {
+					return fmt.Errorf("%w (%d): %q", ErrResourceNameTooDeep, ec.maxResourceNameDepth, p.raw)
+				}
+			}
+		}
+	}
+	return nil
+}
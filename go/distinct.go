@@ -0,0 +1,74 @@
+package entitlements
+
+import (
+	"strconv"
+	"strings"
+)
+
+// DistinctSchemesPrefix begins a distinct-schemes token, "#<n>:<entitlement>",
+// satisfied only when at least n distinct schemes the caller presents each
+// grant entitlement — e.g. "#2:payments:approve" for a payment approved with
+// both a password session and an MFA assertion. Build one with
+// RequireDistinctSchemes.
+//
+// Like an "@name" reference, the token is not scoped by the scheme it is
+// listed under: every scheme the caller presents is counted. Only the
+// caller's own entitlements count, since base and anonymous entitlements
+// prove nothing about how the caller authenticated; strict requirements,
+// disabled schemes and resource indicators apply as usual. A token whose
+// entitlement is a reference, an except condition, or an unbound placeholder
+// is unsatisfiable. On the held side the token is literal text.
+const DistinctSchemesPrefix = "#"
+
+// RequireDistinctSchemes returns a single-branch requirement demanding that
+// at least n distinct schemes each grant entitlement (see
+// DistinctSchemesPrefix), multi-factor authorization at the entitlement
+// layer. An n below 1 is treated as 1. The token is listed under "bearer";
+// it can be copied into any branch of a larger requirement.
+func RequireDistinctSchemes(n int, entitlement string) Requirements {
+	n = max(n, 1)
+	return Requirements{{"bearer": {DistinctSchemesPrefix + strconv.Itoa(n) + ":" + entitlement}}}
+}
+
+// distinctSchemesToken splits a "#<n>:<entitlement>" token. n must be a
+// positive decimal and the entitlement non-empty.
+func distinctSchemesToken(s string) (n int, cond string, ok bool) {
+	rest, ok := strings.CutPrefix(s, DistinctSchemesPrefix)
+	if !ok {
+		return 0, "", false
+	}
+	count, cond, ok := strings.Cut(rest, ":")
+	if !ok || cond == "" || count == "" || count[0] < '0' || count[0] > '9' {
+		return 0, "", false
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil || n < 1 {
+		return 0, "", false
+	}
+	return n, cond, true
+}
+
+// satisfiesDistinctSchemes reports whether at least n of the caller's schemes
+// grant cond, matching in fb's region.
+func (ec *EntitlementsChecker) satisfiesDistinctSchemes(entitlements map[string][]entitlementPattern, n int, cond entitlementPattern, fb fallback) bool {
+	if cond.ref != "" || cond.cond != nil || cond.placeholder != "" {
+		return false
+	}
+	count := 0
+	for scheme, list := range entitlements {
+		if _, ok := ec.ownGrant(list, scheme, cond, fb); ok {
+			count++
+			if count >= n {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ownGrant finds the caller's own entitlement under scheme granting
+// requirement, ignoring base and anonymous entitlements, in fb's region.
+func (ec *EntitlementsChecker) ownGrant(list []entitlementPattern, scheme string, requirement entitlementPattern, fb fallback) (entitlementPattern, bool) {
+	grant, _, ok := ec.findGrant(list, scheme, requirement, fallback{region: fb.region})
+	return grant, ok
+}
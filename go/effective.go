@@ -0,0 +1,39 @@
+package entitlements
+
+import "time"
+
+// HasAnyEffectiveEntitlement reports whether entitlements carries at least one
+// entitlement that can grant anything under this checker's configuration.
+// Entitlements that cannot are not counted: those under a disabled scheme (see
+// WithDisabledSchemes), those under a scheme whose token was issued for
+// another audience (see WithResourceIndicator), resource indicators
+// themselves, denies (see ExceptPrefix), entitlements tagged for another
+// region (see WithRegion), and resourceNames past WithMaxResourceNameDepth.
+// Base and anonymous entitlements are not the
+// caller's and are not counted either.
+//
+// Middleware can treat a caller for whom it returns false as anonymous. Note
+// that verification itself does not: only a caller presenting nothing at all
+// receives the anonymous entitlements.
+func (ec *EntitlementsChecker) HasAnyEffectiveEntitlement(entitlements Entitlements) bool {
+	held := ec.enabledSchemes(ec.ParseEntitlements(entitlements).patterns)
+	for _, list := range held {
+		if ec.resourceIndicator != "" && !allowsResourceIndicator(list, ec.resourceIndicator) {
+			continue
+		}
+		for _, ep := range list {
+			if !ep.except && ep.indicator == "" && (ep.region == "" || ep.region == ec.region) &&
+				!tooDeep(ep, ec.maxResourceNameDepth) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HasAnyEffectiveEntitlementWithExpiry is HasAnyEffectiveEntitlement that
+// also ignores entitlements expired at now, with expiries and a zero now as
+// for VerifyEntitlementsWithExpiry.
+func (ec *EntitlementsChecker) HasAnyEffectiveEntitlementWithExpiry(entitlements Entitlements, expiries map[string]time.Time, now time.Time) bool {
+	return ec.HasAnyEffectiveEntitlement(unexpired(entitlements, expiries, ec.at(now)))
+}
@@ -0,0 +1,142 @@
+package entitlements
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Entitlement is one entitlement string, parsed. A structured entitlement
+// names a Resource, ResourceName and Verb; ResourceName is "" for the short
+// (<resource>:<verb>) and medium (<resource>::<verb>) forms, which grant
+// every name. An opaque entitlement is a single free-form string, held in
+// Resource with Opaque set. Region is the RegionSuffix tag, if any.
+type Entitlement struct {
+	Resource     string `json:"resource"`
+	ResourceName string `json:"resourceName,omitempty"`
+	Verb         string `json:"verb,omitempty"`
+	Opaque       bool   `json:"opaque,omitempty"`
+	Region       string `json:"region,omitempty"`
+}
+
+// ParseEntitlement parses s in any of the long, medium, short and opaque
+// forms, so callers need not split entitlement strings themselves. Malformed
+// input returns an error wrapping ErrInvalidEntitlement: the problems
+// ValidateEntitlements reports, and also a token with more than two colons
+// (other than a resource indicator, see ResourceIndicatorPrefix), which
+// verification would silently treat as opaque.
+//
+// The result describes the string as written: a "!" deny or "@name" token
+// parses as opaque, and a "{placeholder}" name as the literal name it is.
+// What a token grants depends on the checker's options.
+func ParseEntitlement(s string) (Entitlement, error) {
+	if problem := entitlementProblem(s); problem != "" {
+		return Entitlement{}, fmt.Errorf("%w: %q %s", ErrInvalidEntitlement, s, problem)
+	}
+	body, region := cutRegion(s)
+	p := parseForm(body)
+	if !p.isPattern {
+		if p.indicator == "" && strings.Count(body, ":") > 2 {
+			return Entitlement{}, fmt.Errorf("%w: %q has too many colons", ErrInvalidEntitlement, s)
+		}
+		return Entitlement{Resource: body, Opaque: true, Region: region}, nil
+	}
+	return Entitlement{Resource: p.resource, ResourceName: p.resourceName, Verb: p.verb, Region: region}, nil
+}
+
+// String returns e in canonical form: the short form when ResourceName is
+// empty, else the long form, followed by any region tag. ParseEntitlement of
+// the result returns e, so a medium-form string round-trips to its short
+// form.
+func (e Entitlement) String() string {
+	var s string
+	switch {
+	case e.Opaque:
+		s = e.Resource
+	case e.ResourceName == "":
+		s = e.Resource + ":" + e.Verb
+	default:
+		s = e.Resource + ":" + e.ResourceName + ":" + e.Verb
+	}
+	if e.Region != "" {
+		s += RegionSuffix + e.Region
+	}
+	return s
+}
+
+// CanonicalizeEntitlement rewrites s in its canonical long form, so that
+// equivalent spellings compare equal: the short ("pages:read"), medium
+// ("pages::read") and wildcard ("pages:*:read") forms all become
+// "pages:*:read". A named grant is already long and a region tag is kept, so
+// only the wildcard spellings change. Opaque tokens, including "!" deny and
+// "@name" tokens, are returned unchanged. Malformed input returns an error
+// as ParseEntitlement does.
+//
+// The canonical form differs from Entitlement.String, which prefers the
+// short form for display; both parse to the same grant. A checker's extra
+// wildcard spelling (see WithResourceNameWildcardChar) is not recognised,
+// since it is the checker's option, not the grammar's.
+func CanonicalizeEntitlement(s string) (string, error) {
+	e, err := ParseEntitlement(s)
+	if err != nil {
+		return "", err
+	}
+	if e.Opaque {
+		return s, nil
+	}
+	if e.ResourceName == "" {
+		e.ResourceName = "*"
+	}
+	return e.String(), nil
+}
+
+// CanonicalizeEntitlements applies CanonicalizeEntitlement to every
+// entitlement and returns the result as a new map, dropping the duplicates
+// canonicalization reveals within a scheme and keeping first-seen order. A
+// malformed entitlement cannot match anything and is kept verbatim, so
+// canonicalization never changes what validation reports. Schemes with an
+// empty list are kept, and nil returns nil.
+func CanonicalizeEntitlements(entitlements Entitlements) Entitlements {
+	if entitlements == nil {
+		return nil
+	}
+	out := make(Entitlements, len(entitlements))
+	for scheme, list := range entitlements {
+		canonical := make([]string, 0, len(list))
+		for _, s := range list {
+			if c, err := CanonicalizeEntitlement(s); err == nil {
+				s = c
+			}
+			canonical = appendUnique(canonical, s)
+		}
+		out[scheme] = canonical
+	}
+	return out
+}
+
+// ExpandEntitlement lists, sorted, the canonical long forms (see
+// CanonicalizeEntitlement) of what e grants, for tooling that shows what a
+// wildcard grant actually permits: one per verb of WithKnownVerbs that e's
+// verb grants under the default scheme — every known verb for the wildcard
+// verb, a group's members under WithVerbGroups, implied verbs and the like —
+// and e's own verb besides. Without known verbs there is nothing to
+// enumerate, and e's verb is listed alone. A wildcard resourceName is listed
+// as "*" rather than enumerated, and a region tag is kept. An opaque e is
+// listed as itself.
+func (ec *EntitlementsChecker) ExpandEntitlement(e Entitlement) []string {
+	if e.Opaque {
+		return []string{e.String()}
+	}
+	if e.ResourceName == "" {
+		e.ResourceName = "*"
+	}
+	m := ec.matcherFor(ec.defaultScheme)
+	expanded := []string{e.String()}
+	for _, verb := range ec.knownVerbs {
+		if m.verbMatches(e.Verb, verb) {
+			expanded = appendUnique(expanded, Entitlement{Resource: e.Resource, ResourceName: e.ResourceName, Verb: verb, Region: e.Region}.String())
+		}
+	}
+	slices.Sort(expanded)
+	return expanded
+}
@@ -1,11 +1,15 @@
 package entitlements
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"maps"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/go-logr/logr"
 )
@@ -42,6 +46,20 @@ type Entitlements map[string][]string
 // (e.g. url.PathEscape) at the caller's boundary on both the input side and
 // the verification side.
 //
+// Concurrency:
+// Once configured, a checker is safe for concurrent use by multiple
+// goroutines: every Verify* method, Explain, AllMissing,
+// GrantedResourceNames, PreparedPolicy.Check and the other read paths may run
+// in parallel. What they share is either read-only after construction or
+// guarded — the parse interning cache and regex cache by mutexes, the denial
+// cache by its own lock, the verb implication table by an atomic swap (so
+// SetVerbImplications alone may be called while checks are in flight) — and
+// base and anonymous entitlements are consulted in place, never appended to.
+// The With* options are not safe to call concurrently with checks; configure
+// the checker before sharing it. Callbacks the checker invokes (the deny audit
+// trail, break-glass, hierarchy resolver, branch combiner, clock) run on the
+// calling goroutine and must themselves be safe for concurrent use.
+//
 // Examples:
 //   - pages:/foo:read - read access to page "foo" (explicit resource name)
 //   - pages:*:read -    read access to all pages (explicit wildcard)
@@ -53,14 +71,58 @@ type Entitlements map[string][]string
 //   - pages:all -       all access to all pages (short form)
 //   - email -           exact match only (opaque form)
 type EntitlementsChecker struct {
-	anonymousPatterns   []entitlementPattern
-	basePatterns        []entitlementPattern
-	cache               map[string]entitlementPattern
-	defaultScheme       string
-	grantReadyByDefault bool
-	log                 *logr.Logger
-	mu                  sync.RWMutex
-	strictRequirements  bool
+	allCoversOpaque             bool
+	anonymousCeiling            []string
+	anonymousCeilingErr         error
+	anonymousPatterns           []entitlementPattern
+	bareTokenVerbs              map[string]string
+	basePatterns                []entitlementPattern
+	branchCombiner              func(results []bool) bool
+	breakGlass                  func(entitlements Entitlements) (bool, string)
+	breakGlassRecord            func(BreakGlassRecord)
+	cache                       map[string]entitlementPattern
+	caseInsensitive             bool
+	caseInsensitiveSchemes      bool
+	clock                       Clock
+	decisionHook                func(DecisionEvent)
+	defaultScheme               string
+	defaultSchemeFallback       bool
+	denials                     *denialCache
+	denyAudit                   func(DenyRecord)
+	disabledSchemes             map[string]struct{}
+	grantReadyByDefault         bool
+	grantReadyRequiresAuth      bool
+	hierarchy                   HierarchyResolver
+	httpVerbAliases             bool
+	implications                atomic.Pointer[verbImplications]
+	knownVerbs                  []string
+	legacySemantics             bool
+	log                         *logr.Logger
+	maxPredicateDepth           int
+	maxResourceNameDepth        int
+	metrics                     MetricsSink
+	mu                          sync.RWMutex
+	namedRequirements           map[string][]map[string][]entitlementPattern
+	parseHits                   atomic.Uint64
+	parseMisses                 atomic.Uint64
+	referenceDepths             map[string]referenceDepth
+	region                      string
+	requirementAllMeansAny      bool
+	requirementVerbSeparator    string
+	requirementVerbSetSeparator string
+	resourceIndicator           string
+	resourceNameGlob            bool
+	resourceNamePrefixes        bool
+	resourceNameRegexes         *regexCache
+	resourceNameSeparator       string
+	resourceNameWildcard        string
+	schemeAliases               map[string]string
+	schemeWildcard              bool
+	strictRequirements          bool
+	strictValidation            bool
+	verbGroups                  map[string]map[string]struct{}
+	wildcardVerbs               map[string]string
+	wildcardVerbSet             []string
 }
 
 // NewEntitlementsChecker creates a new entitlements checker with the specified settings.
@@ -83,6 +145,7 @@ func NewEntitlementsChecker(
 		cache:               make(map[string]entitlementPattern),
 		defaultScheme:       defaultScheme,
 		grantReadyByDefault: grantReadyByDefault,
+		clock:               realClock{},
 	}
 
 	if len(anonymousEntitlements) > 0 {
@@ -113,6 +176,10 @@ type ParsedRequirements struct {
 // Requirements is a slice of maps representing alternative security requirement sets.
 // Each map in the slice represents an alternative set of requirements (OR'd).
 // Within each map, all schemes and their associated scopes must be satisfied (AND'd).
+// A map with no schemes requires nothing, so it admits every caller, as an
+// empty security requirement does in OpenAPI. A nil map is such a map: like
+// {}, a stray nil element admits every caller on every verification path, so
+// Requirements{nil} grants and Requirements{nil, x} grants regardless of x.
 type Requirements []map[string][]string
 
 // CalculateResourceRequirements calculates the requirements for a resource instance.
@@ -156,6 +223,32 @@ func (ec *EntitlementsChecker) CalculateResourceRequirements(
 	return newRequirements, nil
 }
 
+// RequireWorkflow returns a single-branch requirement demanding every one of
+// verbs on the same resource instance, e.g. the "submit" then "approve" gate of
+// a workflow: RequireWorkflow("bearer", "orders", "o-1", "submit", "approve")
+// yields {"bearer": {"orders:o-1:submit", "orders:o-1:approve"}}. Missing any
+// step denies. Only the capabilities are checked; whether the steps were
+// actually performed, or in which order, is workflow state and out of scope.
+//
+// scheme defaults to "bearer" when empty, and an empty resourceName produces
+// the medium (class-wide) form. Duplicate verbs are emitted once. With no
+// verbs there is nothing to require and nil is returned — which, like any
+// empty Requirements, admits every caller.
+func RequireWorkflow(scheme, resource, resourceName string, verbs ...string) Requirements {
+	if len(verbs) == 0 {
+		return nil
+	}
+	if scheme == "" {
+		scheme = "bearer"
+	}
+
+	tokens := make([]string, 0, len(verbs))
+	for _, verb := range verbs {
+		tokens = appendUnique(tokens, resource+":"+resourceName+":"+verb)
+	}
+	return Requirements{{scheme: tokens}}
+}
+
 // ParseEntitlements converts a raw Entitlements map into ParsedEntitlements for
 // efficient reuse in multiple verification calls.
 func (ec *EntitlementsChecker) ParseEntitlements(entitlements Entitlements) ParsedEntitlements {
@@ -167,6 +260,9 @@ func (ec *EntitlementsChecker) ParseEntitlements(entitlements Entitlements) Pars
 		}
 		parsed[scheme] = patterns
 	}
+	if ec.rekeysSchemes() {
+		parsed = ec.foldSchemes(parsed)
+	}
 	return ParsedEntitlements{patterns: parsed}
 }
 
@@ -181,12 +277,15 @@ func (ec *EntitlementsChecker) ParseRequirements(requirements Requirements) Pars
 			patterns := make([]entitlementPattern, len(list))
 			for j, s := range list {
 				patterns[j] = ec.parsePattern(s)
-				if patterns[j].placeholder != "" {
+				if patterns[j].placeholder != "" || (patterns[j].cond != nil && patterns[j].cond.placeholder != "") {
 					hasPlaceholder = true
 				}
 			}
 			newReq[scheme] = patterns
 		}
+		if ec.rekeysSchemes() {
+			newReq = ec.foldSchemes(newReq)
+		}
 		parsed[i] = newReq
 	}
 	return ParsedRequirements{patterns: parsed, hasPlaceholder: hasPlaceholder}
@@ -222,19 +321,30 @@ type Binding map[string]string
 // requirement in reqs — placeholder or not — has a wildcard resourceName (""
 // or "*", including the short/medium syntaxes). This check runs before the
 // placeholder no-op above, so a wildcard-only requirement set (no placeholder
-// at all) is still rejected rather than passed through unchanged.
+// at all) is still rejected rather than passed through unchanged. Likewise it
+// returns ErrResourceNameTooDeep for a requirement deeper than
+// WithMaxResourceNameDepth allows.
+//
+// Returns ErrPredicateTooDeep if reqs reference a named requirement nested
+// too deeply for WithMaxPredicateDepth.
 func (ec *EntitlementsChecker) BindRequirements(reqs ParsedRequirements, b Binding) (ParsedRequirements, error) {
+	if err := ec.checkPredicateDepthOf(reqs); err != nil {
+		return ParsedRequirements{}, err
+	}
 	if ec.strictRequirements {
 		for _, set := range reqs.patterns {
 			for _, list := range set {
 				for _, p := range list {
-					if p.isPattern && p.placeholder == "" && isWildcardName(p.resourceName) {
+					if p.isPattern && p.placeholder == "" && isWildcardName(ec.canonicalName(p.resourceName)) {
 						return ParsedRequirements{}, fmt.Errorf("%w: %q",
 							ErrWildcardRequirement, p.raw)
 					}
 				}
 			}
 		}
+		if err := ec.checkDepth(reqs); err != nil {
+			return ParsedRequirements{}, err
+		}
 	}
 
 	if !reqs.hasPlaceholder {
@@ -247,29 +357,27 @@ func (ec *EntitlementsChecker) BindRequirements(reqs ParsedRequirements, b Bindi
 		for scheme, list := range set {
 			newList := make([]entitlementPattern, len(list))
 			for j, p := range list {
+				if p.cond != nil && p.cond.placeholder != "" {
+					cond, err := ec.bindPattern(*p.cond, b)
+					if err != nil {
+						return ParsedRequirements{}, err
+					}
+					// Keep the token's prefix, rebinding only its condition.
+					bp := p
+					bp.raw = p.raw[:len(p.raw)-len(p.cond.raw)] + cond.raw
+					bp.cond = &cond
+					newList[j] = bp
+					continue
+				}
 				if p.placeholder == "" {
 					newList[j] = p
 					continue
 				}
-				v, ok := b[p.placeholder]
-				if !ok {
-					return ParsedRequirements{}, fmt.Errorf("%w: %q in requirement %q",
-						ErrUnboundPlaceholder, p.placeholder, p.raw)
-				}
-				if isWildcardName(v) || strings.Contains(v, ":") {
-					return ParsedRequirements{}, fmt.Errorf("%w: %q bound to %q in requirement %q",
-						ErrInvalidBoundValue, p.placeholder, v, p.raw)
-				}
-				// Construct directly rather than re-parsing: a bound value
-				// containing ':' would otherwise be re-split into the wrong
-				// shape. Callers encode such values at their boundary.
-				newList[j] = entitlementPattern{
-					raw:          p.resource + ":" + v + ":" + p.verb,
-					resource:     p.resource,
-					resourceName: v,
-					verb:         p.verb,
-					isPattern:    true,
+				bp, err := ec.bindPattern(p, b)
+				if err != nil {
+					return ParsedRequirements{}, err
 				}
+				newList[j] = bp
 			}
 			newSet[scheme] = newList
 		}
@@ -278,6 +386,29 @@ func (ec *EntitlementsChecker) BindRequirements(reqs ParsedRequirements, b Bindi
 	return ParsedRequirements{patterns: bound, hasPlaceholder: false}, nil
 }
 
+// bindPattern substitutes the placeholder of p with its value from b.
+func (ec *EntitlementsChecker) bindPattern(p entitlementPattern, b Binding) (entitlementPattern, error) {
+	v, ok := b[p.placeholder]
+	if !ok {
+		return entitlementPattern{}, fmt.Errorf("%w: %q in requirement %q",
+			ErrUnboundPlaceholder, p.placeholder, p.raw)
+	}
+	if isWildcardName(ec.canonicalName(v)) || strings.Contains(v, ":") {
+		return entitlementPattern{}, fmt.Errorf("%w: %q bound to %q in requirement %q",
+			ErrInvalidBoundValue, p.placeholder, v, p.raw)
+	}
+	// Construct directly rather than re-parsing: a bound value containing ':'
+	// would otherwise be re-split into the wrong shape. Callers encode such
+	// values at their boundary.
+	return entitlementPattern{
+		raw:          p.resource + ":" + v + ":" + p.verb,
+		resource:     p.resource,
+		resourceName: v,
+		verb:         p.verb,
+		isPattern:    true,
+	}, nil
+}
+
 // WildcardRequirements returns the requirement strings whose resourceName is a
 // wildcard ("*", empty, or the short/medium syntaxes) — the spellings strict
 // mode rejects outright. Results are de-duplicated and in first-seen order.
@@ -296,7 +427,7 @@ func (ec *EntitlementsChecker) WildcardRequirements(reqs Requirements) []string
 		for _, list := range set {
 			for _, s := range list {
 				p := ec.parsePattern(s)
-				if !p.isPattern || p.placeholder != "" || !isWildcardName(p.resourceName) {
+				if !p.isPattern || p.placeholder != "" || !isWildcardName(ec.canonicalName(p.resourceName)) {
 					continue
 				}
 				if _, dup := seen[s]; dup {
@@ -312,17 +443,208 @@ func (ec *EntitlementsChecker) WildcardRequirements(reqs Requirements) []string
 
 // VerifyEntitlements checks if the user's entitlements satisfy the given security requirements.
 // It returns true if any of the alternative requirement sets (OR'd) is fully satisfied.
+//
+// When a denial cache is configured (see WithDenialCache), a pair that was
+// denied within the cache TTL is denied again without being re-evaluated.
+//
+// Complexity: parsing is linear in the total number of tokens. Evaluation
+// visits each OR branch at most once and stops at the first satisfied one;
+// each requirement token is compared against the caller's entitlements under
+// its scheme plus, for the default scheme, the base and anonymous
+// entitlements. The worst case is therefore O(B × T × (E + P)) for B
+// branches of T tokens each, E entitlements per scheme and P base plus
+// anonymous patterns. Base and anonymous entitlements are consulted in place,
+// never merged into (or appended onto) the caller's map, so their cost does
+// not compound across branches. Named references (WithNamedRequirements) are
+// expanded at each use; they come from trusted configuration and are not
+// counted in this bound.
 func (ec *EntitlementsChecker) VerifyEntitlements(
 	entitlements Entitlements,
 	requirements Requirements,
 ) (result bool) {
+	result, _ = ec.VerifyEntitlementsCtx(context.Background(), entitlements, requirements)
+	return result
+}
+
+// VerifyEntitlementsCtx is VerifyEntitlements for request pipelines: it
+// returns false and ctx.Err() if ctx is done before verification starts or
+// before any OR branch is evaluated, so an expensive policy (many branches,
+// regex or glob names) is abandoned promptly once the request is. A branch
+// already being evaluated runs to completion. A cancelled verification is no
+// denial: it is neither audited nor added to the denial cache.
+//
+// It likewise returns false and an error, without auditing or caching, for
+// requirements nesting references deeper than WithMaxPredicateDepth allows
+// (ErrPredicateTooDeep) and, under WithStrictValidation, for malformed
+// entitlements or requirements.
+//
+// Empty requirements admit every caller without consulting ctx or validating.
+func (ec *EntitlementsChecker) VerifyEntitlementsCtx(ctx context.Context, entitlements Entitlements, requirements Requirements) (bool, error) {
+	ok, _, err := ec.verifyMatchCtx(ctx, entitlements, requirements)
+	return ok, err
+}
+
+// verifyMatchCtx is VerifyEntitlementsCtx, also returning the satisfied
+// branch (see VerifyEntitlementsMatch), and reporting the decision to the
+// decision hook.
+func (ec *EntitlementsChecker) verifyMatchCtx(ctx context.Context, entitlements Entitlements, requirements Requirements) (bool, int, error) {
 	if len(requirements) == 0 {
-		return true
+		ec.countDecision(true, nil, nil)
+		ec.reportDecision(entitlements, requirements, true, -1, false)
+		return true, -1, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return false, -1, err
+	}
+	if ec.strictValidation {
+		if err := ec.validateVerify(entitlements, requirements); err != nil {
+			return false, -1, err
+		}
+	}
+	if ec.brokeGlass(entitlements) {
+		ec.reportDecision(entitlements, requirements, true, -1, true)
+		return true, -1, nil
+	}
+	ok, branch, err := ec.verifyEntitlementsCtx(ctx, entitlements, requirements)
+	if err != nil {
+		return false, -1, err
+	}
+	ec.reportDecision(entitlements, requirements, ok, branch, false)
+	return ok, branch, nil
+}
+
+// VerifyEntitlementsMatch is VerifyEntitlements, also returning the index of
+// the OR branch that granted access, e.g. to correlate an allow with a policy
+// line in an audit trail. Branches are evaluated in order and evaluation
+// stops at the first satisfied one, so the index is always the first
+// satisfied branch; under WithBranchCombiner every branch is evaluated and
+// the index is still the first satisfied, if any.
+//
+// The index is -1 when access is denied, and also when it is granted without
+// a branch: for empty requirements, by break-glass (see WithBreakGlass), or by
+// a combiner allowing with no branch satisfied.
+func (ec *EntitlementsChecker) VerifyEntitlementsMatch(entitlements Entitlements, requirements Requirements) (bool, int) {
+	ok, branch, _ := ec.verifyMatchCtx(context.Background(), entitlements, requirements)
+	return ok, branch
+}
+
+// verifyEntitlements is VerifyEntitlements without the break-glass check.
+func (ec *EntitlementsChecker) verifyEntitlements(entitlements Entitlements, requirements Requirements) bool {
+	ok, _, _ := ec.verifyEntitlementsCtx(context.Background(), entitlements, requirements)
+	return ok
+}
+
+// verifyEntitlementsCtx is VerifyEntitlementsCtx without the break-glass
+// check, also returning the satisfied branch (see VerifyEntitlementsMatch).
+func (ec *EntitlementsChecker) verifyEntitlementsCtx(ctx context.Context, entitlements Entitlements, requirements Requirements) (bool, int, error) {
+	if len(requirements) == 0 {
+		ec.countDecision(true, nil, nil)
+		return true, -1, nil
+	}
+
+	var key string
+	if ec.denials != nil {
+		key = fingerprint(entitlements, requirements)
+		if ec.denials.denied(key, ec.clock.Now()) {
+			if ec.denyAudit != nil {
+				held := ec.enabledSchemes(ec.ParseEntitlements(entitlements).patterns)
+				ec.auditDenial(held, ec.ParseRequirements(requirements).patterns, callerFallback(held), true)
+			}
+			ec.countDecision(false, nil, nil)
+			return false, -1, nil
+		}
 	}
 
 	parsedEntitlements := ec.ParseEntitlements(entitlements)
 	parsedRequirements := ec.ParseRequirements(requirements)
-	return ec.VerifyParsedEntitlements(parsedEntitlements, parsedRequirements)
+	result, branch, err := ec.verifyParsedCtx(ctx, parsedEntitlements, parsedRequirements)
+	if err != nil {
+		return false, -1, err
+	}
+
+	if !result && ec.denials != nil {
+		ec.denials.add(key, ec.clock.Now())
+	}
+	return result, branch, nil
+}
+
+// VerifyEntitlementsWithExpiry is VerifyEntitlements for callers that track
+// entitlement lifetimes beside the entitlements rather than in them. expiries
+// maps an entitlement string to the instant it expires; an entitlement whose
+// expiry is at or before now is ignored, as if not presented. A zero now reads
+// the checker's clock (see WithClock). An entitlement with no entry never
+// expires. Entries apply to the string under every scheme.
+//
+// Ignoring expired entitlements can leave a caller with none, in which case
+// they are anonymous like any caller presenting nothing.
+func (ec *EntitlementsChecker) VerifyEntitlementsWithExpiry(
+	entitlements Entitlements,
+	expiries map[string]time.Time,
+	requirements Requirements,
+	now time.Time,
+) bool {
+	return ec.VerifyEntitlements(unexpired(entitlements, expiries, ec.at(now)), requirements)
+}
+
+// unexpired returns entitlements without those whose expiry is at or before
+// now. Every scheme is kept, if only with an empty list.
+func unexpired(entitlements Entitlements, expiries map[string]time.Time, now time.Time) Entitlements {
+	live := make(Entitlements, len(entitlements))
+	for scheme, list := range entitlements {
+		kept := make([]string, 0, len(list))
+		for _, s := range list {
+			if exp, ok := expiries[s]; ok && !now.Before(exp) {
+				continue
+			}
+			kept = append(kept, s)
+		}
+		live[scheme] = kept
+	}
+	return live
+}
+
+// VerifyCoversAll reports whether the caller is entitled to every one of
+// required under scheme (the default scheme when empty), e.g. every resource
+// an "export all data" operation touches. One uncovered item denies the whole
+// set. It is the single-branch, single-scheme AND requirement
+// Requirements{{scheme: required}}, spelled as a flat coverage check; base and
+// anonymous entitlements apply as usual.
+//
+// An empty required set is covered by any caller.
+func (ec *EntitlementsChecker) VerifyCoversAll(entitlements Entitlements, scheme string, required []string) bool {
+	if len(required) == 0 {
+		ec.countDecision(true, nil, nil)
+		return true
+	}
+	if scheme == "" {
+		scheme = ec.defaultScheme
+	}
+	return ec.VerifyEntitlements(entitlements, Requirements{{scheme: required}})
+}
+
+// VerifyWithForbidden reports whether entitlements satisfy required and do
+// NOT satisfy forbidden: the common "must have X, must not have Y" policy,
+// with Y written as ordinary requirements rather than except conditions (see
+// ExceptPrefix). Base and anonymous entitlements count towards both, so an
+// anonymous entitlement can make an anonymous caller forbidden.
+//
+// forbidden is evaluated first, and a forbidden caller is denied without
+// evaluating required. Empty forbidden requirements forbid nothing — unlike
+// empty required ones, which admit every caller. Only the required check
+// consults the denial cache.
+func (ec *EntitlementsChecker) VerifyWithForbidden(entitlements Entitlements, required, forbidden Requirements) bool {
+	if (len(required) > 0 || len(forbidden) > 0) && ec.brokeGlass(entitlements) {
+		return true
+	}
+	if len(forbidden) > 0 {
+		// Not VerifyParsedEntitlements: a caller not forbidden is no denial.
+		held := ec.enabledSchemes(ec.ParseEntitlements(entitlements).patterns)
+		if ec.satisfiesBranches(held, ec.ParseRequirements(forbidden).patterns, callerFallback(held)) {
+			ec.countDecision(false, nil, nil)
+			return false
+		}
+	}
+	return ec.verifyEntitlements(entitlements, required)
 }
 
 // VerifyParsedEntitlements is a high-performance check that uses pre-parsed entitlements
@@ -332,26 +654,95 @@ func (ec *EntitlementsChecker) VerifyParsedEntitlements(
 	entitlements ParsedEntitlements,
 	requirements ParsedRequirements,
 ) (result bool) {
+	result, _, _ = ec.verifyParsedCtx(context.Background(), entitlements, requirements)
+	return
+}
+
+// verifyParsedCtx is VerifyParsedEntitlements, also returning the satisfied
+// branch (see VerifyEntitlementsMatch), and ctx's error if it is done before a
+// branch.
+func (ec *EntitlementsChecker) verifyParsedCtx(ctx context.Context, entitlements ParsedEntitlements, requirements ParsedRequirements) (result bool, branch int, err error) {
 	defer func() {
-		if ec.log != nil {
+		if ec.log != nil && err == nil {
 			ec.log.V(2).Info("Verified parsed entitlements", "result", result)
 		}
 	}()
 
 	if len(requirements.patterns) == 0 {
+		ec.countDecision(true, nil, nil)
+		return true, -1, nil
+	}
+	if err := ec.checkPredicateDepthOf(requirements); err != nil {
+		return false, -1, err
+	}
+
+	held := ec.enabledSchemes(entitlements.patterns)
+	fb := callerFallback(held)
+	result, branch, err = ec.satisfiesBranchesCtx(ctx, held, requirements.patterns, fb)
+	if err != nil {
+		return false, -1, err
+	}
+	ec.countDecision(result, held, branchAt(requirements.patterns, branch))
+	if !result {
+		ec.auditDenial(held, requirements.patterns, fb, false)
+	}
+	return result, branch, nil
+}
+
+// VerifyEntitlementsUsingSchemes is VerifyEntitlements with the caller's
+// entitlements restricted to onlySchemes, e.g. to ask during step-up
+// authentication whether the freshly authenticated scheme alone satisfies a
+// policy. Entitlements under any other scheme are ignored, as if not
+// presented.
+//
+// The base and anonymous entitlements merge into the default scheme, so they
+// apply only when the default scheme is among onlySchemes. Whether the caller
+// is anonymous is still decided on everything they presented: restricting
+// schemes never turns a credentialed caller into an anonymous one, so the
+// result is never more permissive than VerifyEntitlements.
+//
+// The denial cache is not consulted.
+func (ec *EntitlementsChecker) VerifyEntitlementsUsingSchemes(
+	entitlements Entitlements,
+	requirements Requirements,
+	onlySchemes []string,
+) (result bool) {
+	defer func() {
+		if ec.log != nil {
+			ec.log.V(2).Info("Verified entitlements using schemes", "schemes", onlySchemes, "result", result)
+		}
+	}()
+
+	parsedRequirements := ec.ParseRequirements(requirements)
+	if len(parsedRequirements.patterns) == 0 {
+		ec.countDecision(true, nil, nil)
+		return true
+	}
+	if ec.brokeGlass(ec.onlySchemes(entitlements, onlySchemes)) {
 		return true
 	}
 
-	anon := isAnonymousCaller(entitlements.patterns)
-	for _, requirement := range requirements.patterns {
-		if ec.satisfiesAndRequirements(entitlements.patterns, requirement, anon) {
-			result = true
-			return
+	held := ec.enabledSchemes(ec.ParseEntitlements(entitlements).patterns)
+	restricted := make(map[string][]entitlementPattern, len(onlySchemes))
+	listsDefault := false
+	for _, scheme := range onlySchemes {
+		scheme = ec.schemeKey(scheme)
+		if list, ok := held[scheme]; ok {
+			restricted[scheme] = list
 		}
+		listsDefault = listsDefault || scheme == ec.defaultScheme
+	}
+	fb := callerFallback(held)
+	if !listsDefault {
+		fb = fallback{}
 	}
 
-	result = false
-	return
+	result, branch, _ := ec.satisfiesBranchesCtx(context.Background(), restricted, parsedRequirements.patterns, fb)
+	ec.countDecision(result, restricted, branchAt(parsedRequirements.patterns, branch))
+	if !result {
+		ec.auditDenial(restricted, parsedRequirements.patterns, fb, false)
+	}
+	return result
 }
 
 // VerifyResourceEntitlements checks if the user's entitlements satisfy the security requirements
@@ -367,6 +758,9 @@ func (ec *EntitlementsChecker) VerifyResourceEntitlements(
 	if resource == "" || resourceName == "" {
 		return false, fmt.Errorf("resource and resourceName must not be empty")
 	}
+	if ec.brokeGlass(entitlements) {
+		return true, nil
+	}
 
 	parsedEntitlements := ec.ParseEntitlements(entitlements)
 	parsedRequirements := ec.ParseRequirements(requirements)
@@ -399,26 +793,49 @@ func (ec *EntitlementsChecker) VerifyResourceParsedEntitlements(
 	identity := resource + ":" + resourceName + ":" + verb
 	parsedIdentity := ec.parsePattern(identity)
 
-	anon := isAnonymousCaller(parsedEntitlements.patterns)
-	hasIdentity := ec.grantReadyByDefault || ec.hasParsedEntitlement(parsedEntitlements.patterns[ec.defaultScheme], ec.defaultScheme, parsedIdentity, anon)
+	held := ec.enabledSchemes(parsedEntitlements.patterns)
+	fb := callerFallback(held)
+	// A deny on the instance wins over grantReadyByDefault too.
+	readyByDefault := ec.grantsReady(held) && !ec.matcherFor(ec.defaultScheme).denies(held[ec.defaultScheme], parsedIdentity)
+	hasIdentity := readyByDefault || ec.hasParsedEntitlement(held[ec.defaultScheme], ec.defaultScheme, parsedIdentity, fb)
 	if !hasIdentity {
+		ec.auditDenial(held, []map[string][]entitlementPattern{{ec.defaultScheme: {parsedIdentity}}}, fb, false)
+		ec.countDecision(false, nil, nil)
 		return false, nil
 	}
 
 	if len(parsedRequirements.patterns) == 0 {
+		ec.countDecision(true, nil, nil)
 		return true, nil
 	}
 
 	return ec.VerifyParsedEntitlements(parsedEntitlements, parsedRequirements), nil
 }
 
+// WithGrantReadyRequiresAuthenticated makes grantReadyByDefault conditional:
+// the identity requirement of Verify*ResourceEntitlements is granted by default
+// only to a caller holding at least one entitlement under the default scheme,
+// so an anonymous caller, or one authenticated only under other schemes, must
+// hold the identity grant itself. A disabled default scheme (see
+// WithDisabledSchemes) counts as holding nothing. Has no effect when
+// grantReadyByDefault is false.
+//
+// Intended for use during checker construction; not safe for concurrent
+// mutation with verify calls in flight.
+func (ec *EntitlementsChecker) WithGrantReadyRequiresAuthenticated(enabled bool) *EntitlementsChecker {
+	ec.grantReadyRequiresAuth = enabled
+	return ec
+}
+
 // WithBaseEntitlements sets the base entitlements: patterns that apply to
 // every caller (authenticated or anonymous) under the default scheme.
 // Unlike anonymousEntitlements (which apply only when the caller's
 // entitlements map is empty), base entitlements form a floor of grants
 // that every request receives.
 //
-// Replaces any previously set base entitlements. Intended for use during
+// Replaces any previously set base entitlements, unless they exceed the
+// anonymous ceiling (see WithAnonymousCeiling): then the previous ones are
+// kept and AnonymousCeilingErr reports why. Intended for use during
 // checker construction; not safe for concurrent mutation with verify
 // calls in flight.
 func (ec *EntitlementsChecker) WithBaseEntitlements(patterns []string) *EntitlementsChecker {
@@ -426,7 +843,39 @@ func (ec *EntitlementsChecker) WithBaseEntitlements(patterns []string) *Entitlem
 	for i, s := range patterns {
 		parsed[i] = ec.parsePattern(s)
 	}
+	if err := ec.exceedsAnonymousCeiling(parsed); err != nil {
+		ec.anonymousCeilingErr = err
+		return ec
+	}
+	ec.anonymousCeilingErr = nil
 	ec.basePatterns = parsed
+	if ec.denials != nil {
+		ec.denials.clear()
+	}
+	return ec
+}
+
+// WithDenialCache enables a negative cache for VerifyEntitlements: a denied
+// (entitlements, requirements) pair is remembered for ttl, measured on the
+// checker's Clock (see WithClock), and denied again without re-evaluation.
+// Only denials are cached, never allows, and the cache holds at most size
+// entries, evicting the oldest first. A size or ttl <= 0 disables the cache.
+// CacheStats reports its effectiveness.
+//
+// The key is a canonical fingerprint of both maps (scheme keys and tokens
+// sorted; OR-branch order kept). Anonymous and base entitlements are part of
+// the checker's configuration rather than the key, so WithBaseEntitlements
+// clears the cache; there is no dynamic anonymous resolution to invalidate.
+//
+// Intended for use during checker construction; not safe for concurrent
+// mutation with verify calls in flight. The cache itself is safe for
+// concurrent verify calls.
+func (ec *EntitlementsChecker) WithDenialCache(size int, ttl time.Duration) *EntitlementsChecker {
+	if size <= 0 || ttl <= 0 {
+		ec.denials = nil
+		return ec
+	}
+	ec.denials = newDenialCache(size, ttl)
 	return ec
 }
 
@@ -436,6 +885,437 @@ func (ec *EntitlementsChecker) WithLogger(log logr.Logger) *EntitlementsChecker
 	return ec
 }
 
+// ResourceIndicatorPrefix marks an entitlement or requirement token that
+// carries an OAuth2 resource indicator (RFC 8707), e.g.
+// "resource=https://api.example.com". Such a token is always opaque, so it is
+// matched exactly and its URI is never split on ':'.
+//
+// Held under a scheme, it records the audience that scheme's token was issued
+// for; a token issued for several resources carries one per indicator. As a
+// requirement, it demands that the scheme's token was issued for that exact
+// audience.
+const ResourceIndicatorPrefix = "resource="
+
+// WithRegion sets the data-residency region this checker runs in. An
+// entitlement tagged with RegionSuffix, e.g. "pages:/foo:read@region=eu",
+// matches only under a checker whose region equals its tag; an untagged
+// entitlement matches in every region. A tagged entitlement never matches
+// under a checker with no region, so residency fails closed.
+//
+// Tags are compared exactly and apply to every held source — the caller's
+// own, base, and anonymous entitlements. A tag on a requirement is stripped
+// and has no effect: residency is a property of the grant.
+//
+// Intended for use during checker construction; not safe for concurrent
+// mutation with verify calls in flight.
+func (ec *EntitlementsChecker) WithRegion(region string) *EntitlementsChecker {
+	ec.region = region
+	return ec
+}
+
+// WithResourceIndicator sets the audience this checker protects, as an RFC
+// 8707 resource indicator URI. When set, the entitlements of any scheme that
+// carries resource indicators (see ResourceIndicatorPrefix) are ignored
+// unless one of them equals uri, so a token scoped to
+// "https://api.example.com" cannot satisfy a checker for
+// "https://other.example.com". A scheme with no indicator is not
+// audience-restricted and is unaffected. Base and anonymous entitlements are
+// checker configuration and are never filtered. An empty uri disables the
+// check.
+//
+// Intended for use during checker construction; not safe for concurrent
+// mutation with verify calls in flight.
+func (ec *EntitlementsChecker) WithResourceIndicator(uri string) *EntitlementsChecker {
+	ec.resourceIndicator = uri
+	return ec
+}
+
+// WithDefaultSchemeFallback lets the default scheme stand in for a scheme the
+// caller does not hold: when a requirement names a scheme absent from the
+// caller's entitlements, its tokens are checked against the caller's
+// default-scheme entitlements (and the base and anonymous entitlements)
+// instead. A scheme the caller does hold is never substituted, even if its
+// entitlements fall short.
+//
+// Security: this erases the distinction between credential types. A
+// requirement for, say, an "mtls" scheme can then be met by a plain bearer
+// token carrying the same entitlement string, so enable it only while
+// migrating requirements between schemes, and only when every scheme's
+// entitlements come from equally trusted issuers. Defaults to false.
+//
+// Intended for use during checker construction; not safe for concurrent
+// mutation with verify calls in flight.
+func (ec *EntitlementsChecker) WithDefaultSchemeFallback(enabled bool) *EntitlementsChecker {
+	ec.defaultSchemeFallback = enabled
+	return ec
+}
+
+// WithSchemeWildcard lets a requirement listed under a scheme the caller does
+// not hold be met by entitlements under any scheme, for callers that store
+// every grant under one synthetic scheme: each token is then satisfied by an
+// entitlement under any of the caller's schemes (or the base and anonymous
+// entitlements), and an except condition only when no scheme holds what it
+// excludes. A scheme the caller does hold is never searched past, even if its
+// entitlements fall short, and a scheme listed with no tokens, which asks
+// only for the scheme itself, is still missing. WithDefaultSchemeFallback,
+// when also enabled, applies first.
+//
+// Security: like WithDefaultSchemeFallback, only more so, this erases the
+// distinction between credential types — a requirement for an "mtls" scheme
+// can be met by any credential carrying the entitlement string, under any
+// scheme. Enable it only when every scheme's entitlements come from equally
+// trusted issuers, or all come from one. Defaults to false.
+//
+// Intended for use during checker construction; not safe for concurrent
+// mutation with verify calls in flight.
+func (ec *EntitlementsChecker) WithSchemeWildcard(enabled bool) *EntitlementsChecker {
+	ec.schemeWildcard = enabled
+	return ec
+}
+
+// WithDisabledSchemes switches off every entitlement held under the given
+// schemes: during verification they are ignored entirely, exactly as if the
+// caller had not presented them. That includes the anonymous-caller
+// determination, so a caller whose only entitlements sit under a disabled
+// scheme is anonymous and receives the anonymous entitlements. Requirements
+// naming a disabled scheme can then be met only by the base (or anonymous)
+// entitlements of the default scheme. The caller's data is never modified, so
+// re-enabling a scheme restores its grants.
+//
+// This lets operators revoke a credential type system-wide without touching
+// issued tokens. Replaces any previously disabled set; call with no schemes
+// to re-enable all. Intended for use during checker construction; not safe
+// for concurrent mutation with verify calls in flight.
+func (ec *EntitlementsChecker) WithDisabledSchemes(schemes ...string) *EntitlementsChecker {
+	if len(schemes) == 0 {
+		ec.disabledSchemes = nil
+		return ec
+	}
+	ec.disabledSchemes = make(map[string]struct{}, len(schemes))
+	for _, scheme := range schemes {
+		ec.disabledSchemes[scheme] = struct{}{}
+	}
+	return ec
+}
+
+// WithHTTPVerbAliases lets verbs be spelled as HTTP methods. When enabled,
+// a standard method in any case is equivalent to its verb — GET, HEAD and
+// OPTIONS to "read", POST to "create", PUT and PATCH to "update", DELETE to
+// "delete" — in both directions, so a "pages:GET" grant satisfies a
+// "pages:/foo:read" requirement and a "pages:read" grant satisfies
+// "pages:/foo:get". A non-standard method spelled in upper case (e.g.
+// "PURGE") is equivalent to its lower-case verb ("purge"); other verbs still
+// compare exactly. This is the same mapping DefaultRequirementForRoute uses.
+//
+// Methods sharing a verb are equivalent to each other as well: a HEAD grant
+// satisfies a GET requirement. The wildcard verb is never an alias.
+//
+// Intended for use during checker construction; not safe for concurrent
+// mutation with verify calls in flight.
+func (ec *EntitlementsChecker) WithHTTPVerbAliases(enabled bool) *EntitlementsChecker {
+	ec.httpVerbAliases = enabled
+	return ec
+}
+
+// WithVerbGroups defines verb groups: a group verb such as "read*" stands for
+// an explicit list of member verbs, e.g. {"read*": {"read", "readMeta",
+// "readAudit"}}. A requirement for the group is satisfied by a grant of any
+// member, and a grant of the group satisfies a requirement for any member, so
+// "pages:read*" grants "pages:/foo:readAudit" and "pages:readMeta" meets
+// "pages:/foo:read*". Membership is exactly the listed verbs — the name is
+// only a name, and "read*" never matches "reader" by prefix. Two distinct
+// members do not satisfy each other, and groups do not nest.
+//
+// Replaces any previously set groups. Intended for use during checker
+// construction; not safe for concurrent mutation with verify calls in flight.
+func (ec *EntitlementsChecker) WithVerbGroups(groups map[string][]string) *EntitlementsChecker {
+	if len(groups) == 0 {
+		ec.verbGroups = nil
+		return ec
+	}
+	ec.verbGroups = make(map[string]map[string]struct{}, len(groups))
+	for group, members := range groups {
+		set := make(map[string]struct{}, len(members))
+		for _, member := range members {
+			set[member] = struct{}{}
+		}
+		ec.verbGroups[group] = set
+	}
+	return ec
+}
+
+// WithRequirementVerbSeparator lets a requirement name several verbs as
+// alternatives: with separator ",", "pages:/foo:read,write" is satisfied by a
+// grant of read OR of write — not only by one of both — as if the requirement
+// had been written as two OR'd branches. Each alternative is matched like a
+// plain verb, so wildcard verbs, HTTP aliases and verb groups apply to it.
+//
+// The separator means "any of" in a requirement only. A held entitlement's
+// verb is never split: the grant "pages:read,write" carries the single,
+// literal verb "read,write", and satisfies only a requirement spelled the same
+// way. Choose a separator that no verb in use contains.
+//
+// Defaults to "" (disabled), in which a separator in a requirement verb is a
+// literal character. Intended for use during checker construction; not safe
+// for concurrent mutation with verify calls in flight.
+func (ec *EntitlementsChecker) WithRequirementVerbSeparator(separator string) *EntitlementsChecker {
+	ec.requirementVerbSeparator = separator
+	return ec
+}
+
+// WithRequirementVerbSetSeparator lets a requirement name several verbs that
+// must all be granted: with separator "+", "pages:/foo:read+write" is
+// satisfied only when the caller is granted both read and write on
+// pages:/foo, as if the requirement had been written as the two tokens
+// "pages:/foo:read" and "pages:/foo:write" in the same scheme. The verbs may
+// be granted by different held entitlements, and a deny of any one of them
+// denies the requirement.
+//
+// The verb set is split before any WithRequirementVerbSeparator alternatives,
+// so with separators "+" and "," the verb "read,list+write" requires read or
+// list, and write. As with that option, a held entitlement's verb is never
+// split. Choose a separator that no verb in use contains.
+//
+// Defaults to "" (disabled), in which a separator in a requirement verb is a
+// literal character. Intended for use during checker construction; not safe
+// for concurrent mutation with verify calls in flight.
+func (ec *EntitlementsChecker) WithRequirementVerbSetSeparator(separator string) *EntitlementsChecker {
+	ec.requirementVerbSetSeparator = separator
+	return ec
+}
+
+// WithResourceNameSeparator lets one held entitlement grant several resource
+// names, listed in its resourceName separated by separator: with ",",
+// "pages:/foo,/bar,/baz:read" satisfies "pages:/foo:read" and
+// "pages:/bar:read" but not "pages:/qux:read". Each listed name matches as a
+// resourceName of its own would, so a listed "*" grants every name, globs
+// apply under WithResourceNameGlob and WithMaxResourceNameDepth bounds each
+// name separately. Empty list entries are ignored.
+//
+// The separator lists names in a held entitlement only. A requirement's
+// resourceName is never split — use OR branches to accept any of several
+// names — and Dominates compares a listed name as the literal string it is,
+// so attenuating from a list fails closed. Choose a separator that no
+// resource name in use contains: a name containing it can no longer be
+// granted by spelling it out.
+//
+// Defaults to "" (disabled), in which the separator is a literal character
+// of the name. WithLegacySemantics turns it off. Intended for use during
+// checker construction; not safe for concurrent mutation with verify calls in
+// flight.
+func (ec *EntitlementsChecker) WithResourceNameSeparator(separator string) *EntitlementsChecker {
+	ec.resourceNameSeparator = separator
+	return ec
+}
+
+// WithWildcardVerbs sets the held verbs that grant every verb. By default
+// both "all" and "*" do, so "pages:all" and "pages:*" each satisfy
+// "pages:read"; issuers that emit another sentinel, such as "manage", list it
+// here. The set replaces the default, so list "all" and "*" to keep them. A
+// wildcard verb is honoured only on the held side; a requirement of "pages:*"
+// is met only by a wildcard-verb grant, never by a narrower one, unless
+// WithRequirementAllMeansAny is enabled. Dominates still treats only "all" as
+// dominating, so attenuating from another wildcard verb fails closed. A
+// per-scheme entry of WithWildcardVerbByScheme takes precedence under its
+// scheme, and WithLegacySemantics restores "all" alone.
+//
+// With no verbs, resets to "all" and "*", the default. Intended for use
+// during checker construction; not safe for concurrent mutation with verify
+// calls in flight.
+func (ec *EntitlementsChecker) WithWildcardVerbs(verbs ...string) *EntitlementsChecker {
+	ec.wildcardVerbSet = slices.Clone(verbs)
+	return ec
+}
+
+// WithWildcardVerbByScheme sets, per scheme, the held verb that grants every
+// verb, for schemes whose issuers use a single sentinel of their own (e.g.
+// {"apikey": "*"}). Under a listed scheme only the listed verb is a wildcard —
+// "all", or "*", there is an ordinary, literal verb — so the same entitlement
+// string can mean "every verb" in one scheme and one specific verb in
+// another. Schemes not listed keep the global set (see WithWildcardVerbs).
+// Base and anonymous entitlements are matched under the default scheme and so
+// follow its entry.
+//
+// Replaces any previously set table. Intended for use during checker
+// construction; not safe for concurrent mutation with verify calls in flight.
+func (ec *EntitlementsChecker) WithWildcardVerbByScheme(verbs map[string]string) *EntitlementsChecker {
+	ec.wildcardVerbs = maps.Clone(verbs)
+	return ec
+}
+
+// WithBareTokenVerbByScheme maps a scheme to the verb a bare held token (one
+// with no ':', such as "pages") grants under it: with {"bearer": "read"},
+// "pages" held under bearer also grants "pages:*:read", so it satisfies
+// "pages:/x:read" as well as the opaque requirement "pages". Schemes not
+// listed, the default, keep bare tokens opaque, matching only the identical
+// requirement. Only held tokens expand; a bare requirement stays opaque.
+// A bare "*" names every resource type (see ResourceTypeWildcard), and
+// resource indicators and "!"/"@" tokens are never bare. Base and anonymous
+// entitlements are matched under the default scheme and so follow its entry.
+//
+// Replaces any previously set table. Intended for use during checker
+// construction; not safe for concurrent mutation with verify calls in flight.
+func (ec *EntitlementsChecker) WithBareTokenVerbByScheme(verbs map[string]string) *EntitlementsChecker {
+	ec.bareTokenVerbs = maps.Clone(verbs)
+	return ec
+}
+
+// WithAllCoversOpaque lets a class-wide entitlement carrying the wildcard verb
+// also satisfy an opaque requirement naming its resource type, treating the
+// opaque claim as "any interaction with" the resource: "books:all" (or
+// "books::all", "books:*:all") then satisfies the requirement "books". An
+// instance grant such as "books:/x:all" does not, since it covers one book
+// rather than the resource as a whole. The wildcard verb is the scheme's (see
+// WithWildcardVerbByScheme).
+//
+// Defaults to false, in which an opaque requirement is satisfied only by the
+// identical opaque entitlement.
+//
+// Intended for use during checker construction; not safe for concurrent
+// mutation with verify calls in flight.
+func (ec *EntitlementsChecker) WithAllCoversOpaque(enabled bool) *EntitlementsChecker {
+	ec.allCoversOpaque = enabled
+	return ec
+}
+
+// WithRequirementAllMeansAny changes what a requirement carrying the
+// wildcard verb asks for. By default "books:all" as a requirement demands
+// full access: only a held entitlement that itself grants the wildcard verb
+// satisfies it, so "books:read" does not. Enabled, it reads as "any
+// interaction with": a held entitlement granting any verb on the resource
+// satisfies it, "books:read" included. Resource names match as they would
+// for any other verb. The wildcard verb is the scheme's (see
+// WithWildcardVerbByScheme).
+//
+// Defaults to false. Intended for use during checker construction; not safe
+// for concurrent mutation with verify calls in flight.
+func (ec *EntitlementsChecker) WithRequirementAllMeansAny(enabled bool) *EntitlementsChecker {
+	ec.requirementAllMeansAny = enabled
+	return ec
+}
+
+// WithLegacySemantics pins matching to its original rules, as a safety hatch
+// while upgrading: a held entitlement satisfies a requirement on raw equality,
+// or when both are structured with the same resource, the held verb is the
+// requirement's or "all", and either resourceName is a wildcard or they are
+// equal. The wildcard short-circuit stays symmetric, so a wildcard requirement
+// is satisfied by any grant for the resource and verb.
+//
+// It overrides every option that changes how two tokens match, whether set
+// before or after: WithWildcardVerbs, WithWildcardVerbByScheme,
+// WithHTTPVerbAliases, WithCaseInsensitive, WithResourceNameGlob,
+// WithResourceNamePrefixes, WithResourceNameRegex,
+// WithResourceNameWildcardChar, WithResourceNameSeparator, WithVerbGroups,
+// WithRequirementVerbSeparator, WithRequirementVerbSetSeparator,
+// WithAllCoversOpaque, WithRequirementAllMeansAny, WithBareTokenVerbByScheme
+// and SetVerbImplications. It also disables the "*" wildcard verb and version
+// and resource type wildcards (see VersionWildcardSuffix and
+// ResourceTypeWildcard). Options that decide which entitlements and
+// requirements are consulted (base and anonymous entitlements, strict
+// requirements, disabled schemes, resource indicators, references, except
+// conditions and denies, the resource-name depth bound) still apply, as does
+// a held entitlement's region tag, which only ever narrows a grant.
+//
+// Defaults to false. Intended for use during checker construction; not safe
+// for concurrent mutation with verify calls in flight.
+func (ec *EntitlementsChecker) WithLegacySemantics(enabled bool) *EntitlementsChecker {
+	ec.legacySemantics = enabled
+	return ec
+}
+
+// WithResourceNameGlob enables glob resourceNames such as "/img/*.png" or
+// "/foo*", on the held side, the requirement side, or both. A match succeeds
+// when the two names could refer to at least one common concrete name, so the
+// held "pages:/foo*:read" satisfies the requirement "pages:/foobar*:read" but
+// not "pages:/bar*:read". glob.go documents the syntax and the intersection
+// algorithm.
+//
+// Defaults to false, in which '*', '?' and '\' inside a resourceName are
+// literal characters and only a whole "*" (or empty) resourceName is a
+// wildcard — exactly as before globs existed.
+//
+// Intended for use during checker construction; not safe for concurrent
+// mutation with verify calls in flight.
+func (ec *EntitlementsChecker) WithResourceNameGlob(enabled bool) *EntitlementsChecker {
+	ec.resourceNameGlob = enabled
+	return ec
+}
+
+// WithResourceNamePrefixes makes a held resourceName ending in "/*" or "/**"
+// a path prefix: "pages:/docs/*:read" grants read on every name below
+// "/docs/", at any depth — "/docs/report", "/docs/team/report",
+// "/docs/team/" — but not on "/docs" or "/docs/" themselves, nor on a sibling
+// such as "/docspublic/x", since the prefix ends at a '/'. A held "/*" covers
+// every name starting with '/'.
+//
+// In glob mode (see WithResourceNameGlob) a prefix must itself be literal, so
+// "/docs/*" crosses '/' as "/docs/**" does; a held name such as
+// "/img/*/thumbs/*" keeps its glob meaning. Outside glob mode '*' elsewhere in
+// a name stays literal. The resource-name depth bound (see
+// WithMaxResourceNameDepth) applies to the held name as written.
+//
+// Defaults to false, in which a trailing "/*" is read as it was before:
+// literally, or as a single-segment glob in glob mode. Intended for use during
+// checker construction; not safe for concurrent mutation with verify calls in
+// flight.
+func (ec *EntitlementsChecker) WithResourceNamePrefixes(enabled bool) *EntitlementsChecker {
+	ec.resourceNamePrefixes = enabled
+	return ec
+}
+
+// WithCaseInsensitive compares the resource type and verb of structured
+// entitlements and requirements case-insensitively, for identity providers
+// that emit "Pages:Read" against requirements written "pages:read". Resource
+// names are still compared exactly, since they are often case-sensitive
+// paths, and so are opaque entitlements and scheme names (see
+// WithCaseInsensitiveSchemes). Verbs configured on the checker — wildcard
+// verbs, verb groups, implications, WithKnownVerbs — must be written in
+// lower case to apply. PreparedPolicy.Check takes the generic path while the
+// option is on.
+//
+// Defaults to false: comparisons are case-sensitive. Intended for use during
+// checker construction; not safe for concurrent mutation with verify calls in
+// flight.
+func (ec *EntitlementsChecker) WithCaseInsensitive(enabled bool) *EntitlementsChecker {
+	ec.caseInsensitive = enabled
+	return ec
+}
+
+// WithResourceNameWildcardChar makes wildcard another spelling of '*' in
+// resourceNames, for entitlements stored by upstreams with a different
+// wildcard, such as SQL LIKE's '%': "pages:%:read" then grants every page,
+// and in glob mode "pages:/docs/%:read" every document directly under
+// "/docs", exactly as the '*' spellings do. '*' keeps its meaning, on both
+// sides, everywhere a wildcard name is recognised: matching, strict mode,
+// BindRequirements and WildcardRequirements. Dominates, a package function,
+// still recognises only '*', so attenuating from the other spelling fails
+// closed.
+//
+// With the option set, no resourceName can contain wildcard literally — a
+// URL-encoded "/a%20b" becomes a glob under '%' — so pick a character the
+// names in use never contain. '*' (the default) turns it off, as do ':' and
+// '/', which are separators, and the glob metacharacters '?' and '\'.
+// WithLegacySemantics ignores it in matching. Intended for use during
+// checker construction; not safe for concurrent mutation with verify calls in
+// flight.
+func (ec *EntitlementsChecker) WithResourceNameWildcardChar(wildcard rune) *EntitlementsChecker {
+	switch wildcard {
+	case '*', ':', '/', '?', '\\':
+		ec.resourceNameWildcard = ""
+	default:
+		ec.resourceNameWildcard = string(wildcard)
+	}
+	return ec
+}
+
+// canonicalName is matcher.canonicalName for the checker's own checks.
+func (ec *EntitlementsChecker) canonicalName(name string) string {
+	if ec.resourceNameWildcard == "" {
+		return name
+	}
+	return strings.ReplaceAll(name, ec.resourceNameWildcard, "*")
+}
+
 // WithStrictRequirements rejects wildcard resourceNames on the requirement side.
 // It never affects entitlements, where wildcards remain meaningful.
 //
@@ -462,57 +1342,268 @@ func (ec *EntitlementsChecker) WithStrictRequirements(strict bool) *Entitlements
 	return ec
 }
 
+// grantsReady reports whether the identity requirement is granted by default
+// to a caller presenting held.
+func (ec *EntitlementsChecker) grantsReady(held map[string][]entitlementPattern) bool {
+	return ec.grantReadyByDefault && (!ec.grantReadyRequiresAuth || len(held[ec.defaultScheme]) > 0)
+}
+
 // hasParsedEntitlement checks if the user has a specific entitlement using pre-parsed patterns.
-func (ec *EntitlementsChecker) hasParsedEntitlement(entitlementList []entitlementPattern, scheme string, requirement entitlementPattern, isAnonymousCaller bool) bool {
+func (ec *EntitlementsChecker) hasParsedEntitlement(entitlementList []entitlementPattern, scheme string, requirement entitlementPattern, fb fallback) bool {
+	_, _, ok := ec.findGrant(entitlementList, scheme, requirement, fb)
+	return ok
+}
+
+// findGrant returns the first held pattern that satisfies requirement under
+// scheme, and where it came from: the caller's own entitlementList first,
+// then (for the default scheme) the base and anonymous entitlements.
+func (ec *EntitlementsChecker) findGrant(entitlementList []entitlementPattern, scheme string, requirement entitlementPattern, fb fallback) (entitlementPattern, GrantSource, bool) {
 	// Strict backstop for callers that skip BindRequirements: a wildcard
 	// requirement is an illegal spelling, and an unbound placeholder was never
 	// resolved. Both are unsatisfiable rather than silently admitted — a held
 	// wildcard would otherwise match either one.
-	if ec.strictRequirements && requirement.isPattern {
-		if requirement.placeholder != "" || isWildcardName(requirement.resourceName) {
-			return false
+	if ec.strictRejects(requirement) {
+		return entitlementPattern{}, "", false
+	}
+	return ec.lookupGrant(entitlementList, scheme, requirement, fb)
+}
+
+// strictRejects reports whether strict mode makes requirement unsatisfiable.
+func (ec *EntitlementsChecker) strictRejects(requirement entitlementPattern) bool {
+	return ec.strictRequirements && requirement.isPattern &&
+		(requirement.placeholder != "" || isWildcardName(ec.canonicalName(requirement.resourceName)) ||
+			tooDeep(requirement, ec.maxResourceNameDepth))
+}
+
+// lookupGrant is findGrant without the strict backstop. A verb set (see
+// WithRequirementVerbSetSeparator) needs a grant for each of its verbs; the
+// first one's is returned.
+func (ec *EntitlementsChecker) lookupGrant(entitlementList []entitlementPattern, scheme string, requirement entitlementPattern, fb fallback) (entitlementPattern, GrantSource, bool) {
+	if set := ec.verbSet(requirement); set != nil {
+		var first entitlementPattern
+		var firstSource GrantSource
+		for i, p := range set {
+			grant, source, ok := ec.lookupGrant(entitlementList, scheme, p, fb)
+			if !ok {
+				return entitlementPattern{}, "", false
+			}
+			if i == 0 {
+				first, firstSource = grant, source
+			}
 		}
+		return first, firstSource, true
+	}
+	grant, source, ok := ec.matchGrant(entitlementList, scheme, requirement, fb)
+	if ok || ec.hierarchy == nil {
+		return grant, source, ok
+	}
+	return ec.inheritedGrant(entitlementList, scheme, requirement, fb)
+}
+
+// verbSet splits a structured requirement whose verb joins several verbs with
+// the WithRequirementVerbSetSeparator separator into one requirement per
+// verb, or returns nil when there is nothing to split.
+func (ec *EntitlementsChecker) verbSet(requirement entitlementPattern) []entitlementPattern {
+	separator := ec.requirementVerbSetSeparator
+	if separator == "" || ec.legacySemantics || !requirement.isPattern || !strings.Contains(requirement.verb, separator) {
+		return nil
+	}
+	var set []entitlementPattern
+	for verb := range strings.SplitSeq(requirement.verb, separator) {
+		if verb != "" {
+			p := requirement
+			p.verb = verb
+			set = append(set, p)
+		}
+	}
+	return set
+}
+
+// matchGrant is lookupGrant without the hierarchy walk: it matches
+// requirement itself.
+func (ec *EntitlementsChecker) matchGrant(entitlementList []entitlementPattern, scheme string, requirement entitlementPattern, fb fallback) (entitlementPattern, GrantSource, bool) {
+	m := ec.matcherIn(scheme, fb)
+
+	// A deny the caller holds wins over every grant.
+	if m.denies(entitlementList, requirement) {
+		return entitlementPattern{}, "", false
+	}
+
+	// A scheme whose token was issued for a different audience contributes
+	// nothing to this checker.
+	if ec.resourceIndicator != "" && !allowsResourceIndicator(entitlementList, ec.resourceIndicator) {
+		entitlementList = nil
 	}
 
 	// Caller's own entitlements for this scheme.
 	for _, entitlement := range entitlementList {
-		if entitlement.matches(requirement) {
-			return true
+		if m.matches(entitlement, requirement) {
+			return entitlement, GrantSourceDirect, true
 		}
 	}
 
 	if scheme == ec.defaultScheme {
-		// Base entitlements always apply.
-		for _, pattern := range ec.basePatterns {
-			if pattern.matches(requirement) {
-				return true
+		// Base entitlements always apply (unless the caller opted out).
+		if fb.base {
+			for _, pattern := range ec.basePatterns {
+				if m.matches(pattern, requirement) {
+					return pattern, GrantSourceBase, true
+				}
 			}
 		}
 		// Anonymous entitlements apply only when caller is anonymous.
-		if isAnonymousCaller {
+		if fb.anonymous {
 			for _, pattern := range ec.anonymousPatterns {
-				if pattern.matches(requirement) {
-					return true
+				if m.matches(pattern, requirement) {
+					return pattern, GrantSourceAnonymous, true
 				}
 			}
 		}
 	}
 
-	return false
+	return entitlementPattern{}, "", false
 }
 
+// enabledSchemes returns held without the schemes disabled by
+// WithDisabledSchemes. held itself is returned when nothing is disabled, and
+// is never modified.
+func (ec *EntitlementsChecker) enabledSchemes(held map[string][]entitlementPattern) map[string][]entitlementPattern {
+	if len(ec.disabledSchemes) == 0 {
+		return held
+	}
+	filtered := make(map[string][]entitlementPattern, len(held))
+	for scheme, list := range held {
+		if _, disabled := schemeOption(ec, ec.disabledSchemes, scheme); !disabled {
+			filtered[scheme] = list
+		}
+	}
+	return filtered
+}
+
+// matcherIn is matcherFor in the region fb names, if any.
+func (ec *EntitlementsChecker) matcherIn(scheme string, fb fallback) matcher {
+	m := ec.matcherFor(scheme)
+	if fb.region != "" {
+		m.region = fb.region
+	}
+	return m
+}
+
+// matcherFor resolves the options that shape matching under scheme.
+func (ec *EntitlementsChecker) matcherFor(scheme string) matcher {
+	if ec.legacySemantics {
+		return matcher{wildcardVerbs: legacyWildcardVerbs, region: ec.region, maxDepth: ec.maxResourceNameDepth}
+	}
+	m := matcher{
+		wildcardVerbs:   defaultWildcardVerbs,
+		httpVerbs:       ec.httpVerbAliases,
+		glob:            ec.resourceNameGlob,
+		prefixes:        ec.resourceNamePrefixes,
+		regexes:         ec.resourceNameRegexes,
+		nameWildcard:    ec.resourceNameWildcard,
+		foldCase:        ec.caseInsensitive,
+		region:          ec.region,
+		verbGroups:      ec.verbGroups,
+		verbSeparator:   ec.requirementVerbSeparator,
+		nameSeparator:   ec.resourceNameSeparator,
+		implications:    ec.currentImplications(),
+		allCoversOpaque: ec.allCoversOpaque,
+		allMeansAny:     ec.requirementAllMeansAny,
+		versions:        true,
+		maxDepth:        ec.maxResourceNameDepth,
+	}
+	if len(ec.wildcardVerbSet) > 0 {
+		m.wildcardVerbs = ec.wildcardVerbSet
+	}
+	if verb, ok := schemeOption(ec, ec.wildcardVerbs, scheme); ok {
+		m.wildcardVerbs = []string{verb}
+	}
+	m.bareVerb, _ = schemeOption(ec, ec.bareTokenVerbs, scheme)
+	return m
+}
+
+// defaultWildcardVerbs is the wildcard verb set absent WithWildcardVerbs.
+var defaultWildcardVerbs = []string{"all", "*"}
+
+// legacyWildcardVerbs is the wildcard verb set under WithLegacySemantics.
+var legacyWildcardVerbs = []string{"all"}
+
 func (ec *EntitlementsChecker) parsePattern(s string) entitlementPattern {
 	// 1. Check the interning cache first
 	ec.mu.RLock()
 	p, ok := ec.cache[s]
 	ec.mu.RUnlock()
 	if ok {
+		ec.parseHits.Add(1)
 		return p
 	}
+	ec.parseMisses.Add(1)
+
+	// 2. Strip the attribute suffixes, then parse the form.
+	body, region := cutRegion(s)
+	p = parseForm(body)
+	p.region = region
+
+	// 3. Store in cache if there is room
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+	if len(ec.cache) < maxCacheSize {
+		ec.cache[s] = p
+	}
+	return p
+}
+
+// RegionSuffix tags an entitlement with the data-residency region it is
+// valid in, e.g. "pages:/foo:read@region=eu" (see WithRegion).
+const RegionSuffix = "@region="
+
+// cutRegion splits a trailing RegionSuffix tag off s. A token that is only a
+// tag, or whose tag is empty, is returned unchanged.
+func cutRegion(s string) (body, region string) {
+	i := strings.LastIndex(s, RegionSuffix)
+	if i <= 0 || i+len(RegionSuffix) == len(s) {
+		return s, ""
+	}
+	return s[:i], s[i+len(RegionSuffix):]
+}
+
+// parseForm parses the pattern form of s (attribute suffixes already removed).
+func parseForm(s string) entitlementPattern {
+	var p entitlementPattern
 
-	// 2. Optimization: If no colon is present, it's definitely an opaque form.
-	// This avoids the allocation of strings.Split for simple strings.
-	if !strings.Contains(s, ":") {
+	// Optimization: If no colon is present, it's definitely an opaque form.
+	// This avoids the allocation of strings.Split for simple strings. A
+	// resource indicator is opaque too: its URI would otherwise be split on
+	// the scheme's ':'.
+	if indicator, ok := strings.CutPrefix(s, ResourceIndicatorPrefix); ok {
+		p = entitlementPattern{
+			raw:       s,
+			isPattern: false,
+			indicator: indicator,
+		}
+	} else if cond, ok := exceptCondition(s); ok {
+		inner := parseForm(cond)
+		p = entitlementPattern{
+			raw:       s,
+			isPattern: false,
+			cond:      &inner,
+			except:    true,
+		}
+	} else if n, cond, ok := distinctSchemesToken(s); ok {
+		inner := parseForm(cond)
+		p = entitlementPattern{
+			raw:       s,
+			isPattern: false,
+			cond:      &inner,
+			distinct:  n,
+		}
+	} else if ref := referenceName(s); ref != "" {
+		p = entitlementPattern{
+			raw:       s,
+			isPattern: false,
+			ref:       ref,
+		}
+	} else if !strings.Contains(s, ":") {
 		p = entitlementPattern{
 			raw:       s,
 			isPattern: false,
@@ -546,26 +1637,28 @@ func (ec *EntitlementsChecker) parsePattern(s string) entitlementPattern {
 			}
 		}
 	}
-
-	// 3. Store in cache if there is room
-	ec.mu.Lock()
-	defer ec.mu.Unlock()
-	if len(ec.cache) < maxCacheSize {
-		ec.cache[s] = p
-	}
 	return p
 }
 
-func (ec *EntitlementsChecker) satisfiesAndRequirements(entitlements map[string][]entitlementPattern, requirement map[string][]entitlementPattern, isAnonymousCaller bool) bool {
+func (ec *EntitlementsChecker) satisfiesAndRequirements(entitlements map[string][]entitlementPattern, requirement map[string][]entitlementPattern, fb fallback) bool {
 	for scheme, requirementList := range requirement {
+		scheme = ec.judgedScheme(entitlements, scheme)
 		_, ok := entitlements[scheme]
 		hasFallback := scheme == ec.defaultScheme &&
-			(len(ec.basePatterns) > 0 || (isAnonymousCaller && len(ec.anonymousPatterns) > 0))
-		if !ok && !hasFallback {
+			((fb.base && len(ec.basePatterns) > 0) || (fb.anonymous && len(ec.anonymousPatterns) > 0))
+		if !ok && ec.searchesAllSchemes(requirementList) {
+			for _, p := range requirementList {
+				if !ec.satisfiesUnderAnyScheme(entitlements, p, fb) {
+					return false
+				}
+			}
+			continue
+		}
+		if !ok && !hasFallback && !schemeOptional(requirementList) {
 			return false
 		}
 
-		if !ec.satisfiesRequirement(entitlements, scheme, requirementList, isAnonymousCaller) {
+		if !ec.satisfiesRequirement(entitlements, scheme, requirementList, fb) {
 			return false
 		}
 	}
@@ -573,10 +1666,71 @@ func (ec *EntitlementsChecker) satisfiesAndRequirements(entitlements map[string]
 	return true
 }
 
+// judgedScheme returns the scheme a requirement listed under scheme is judged
+// against: scheme itself, or the default scheme when the caller does not hold
+// scheme and WithDefaultSchemeFallback is enabled.
+func (ec *EntitlementsChecker) judgedScheme(entitlements map[string][]entitlementPattern, scheme string) string {
+	scheme = ec.schemeKey(scheme)
+	if _, ok := entitlements[scheme]; !ok && ec.defaultSchemeFallback {
+		return ec.defaultScheme
+	}
+	return scheme
+}
+
+// searchesAllSchemes reports whether a requirement list under a scheme the
+// caller does not hold is searched for under every scheme (see
+// WithSchemeWildcard).
+func (ec *EntitlementsChecker) searchesAllSchemes(list []entitlementPattern) bool {
+	return ec.schemeWildcard && len(list) > 0
+}
+
+// wildcardSchemes returns, sorted, the schemes a requirement is searched for
+// under by WithSchemeWildcard: every scheme the caller holds, and the default
+// scheme, which carries the base and anonymous entitlements.
+func (ec *EntitlementsChecker) wildcardSchemes(entitlements map[string][]entitlementPattern) []string {
+	schemes := sortedKeys(entitlements)
+	if _, ok := entitlements[ec.defaultScheme]; !ok {
+		schemes = append(schemes, ec.defaultScheme)
+		slices.Sort(schemes)
+	}
+	return schemes
+}
+
+// satisfiesUnderAnyScheme reports whether one requirement token is satisfied
+// under any of the wildcard schemes; an except condition must hold under all
+// of them.
+func (ec *EntitlementsChecker) satisfiesUnderAnyScheme(entitlements map[string][]entitlementPattern, p entitlementPattern, fb fallback) bool {
+	for _, scheme := range ec.wildcardSchemes(entitlements) {
+		ok := ec.satisfiesRequirement(entitlements, scheme, []entitlementPattern{p}, fb)
+		if ok != p.except {
+			return ok
+		}
+	}
+	return p.except
+}
+
 // satisfiesRequirement checks if user entitlements satisfy a single security requirement.
-func (ec *EntitlementsChecker) satisfiesRequirement(entitlements map[string][]entitlementPattern, scheme string, requirement []entitlementPattern, isAnonymousCaller bool) bool {
+func (ec *EntitlementsChecker) satisfiesRequirement(entitlements map[string][]entitlementPattern, scheme string, requirement []entitlementPattern, fb fallback) bool {
 	for _, parsedReq := range requirement {
-		if !ec.hasParsedEntitlement(entitlements[scheme], scheme, parsedReq, isAnonymousCaller) {
+		if parsedReq.ref != "" {
+			if !ec.satisfiesReference(entitlements, parsedReq.ref, fb) {
+				return false
+			}
+			continue
+		}
+		if parsedReq.except {
+			if !ec.satisfiesExcept(entitlements[scheme], scheme, *parsedReq.cond, fb) {
+				return false
+			}
+			continue
+		}
+		if parsedReq.distinct > 0 {
+			if !ec.satisfiesDistinctSchemes(entitlements, parsedReq.distinct, *parsedReq.cond, fb) {
+				return false
+			}
+			continue
+		}
+		if !ec.hasParsedEntitlement(entitlements[scheme], scheme, parsedReq, fb) {
 			return false
 		}
 	}
@@ -594,6 +1748,20 @@ type entitlementPattern struct {
 	// Meaningful only on the requirement side; held-side placeholders are
 	// literal text.
 	placeholder string
+	// indicator is the audience URI of a "resource=<uri>" token, else "".
+	indicator string
+	// ref is the referenced name of an "@name" token, else "". Meaningful
+	// only on the requirement side; a held "@name" is literal text.
+	ref string
+	// region is the "@region=<region>" suffix of a held pattern, else "".
+	region string
+	// cond is the parsed condition wrapped by an except ("!condition") or
+	// distinct-schemes ("#<n>:condition") token, else nil; except or distinct
+	// says which. Meaningful only on the requirement side; on the held side
+	// either token is literal text.
+	cond     *entitlementPattern
+	except   bool
+	distinct int
 }
 
 // ErrUnboundPlaceholder is returned by BindRequirements when a requirement
@@ -638,6 +1806,42 @@ func isWildcardName(n string) bool {
 	return n == "" || n == "*"
 }
 
+// allowsResourceIndicator reports whether a scheme's entitlements are usable
+// by a checker protecting uri: true when the list carries no resource
+// indicator at all, or carries uri among them.
+func allowsResourceIndicator(entitlements []entitlementPattern, uri string) bool {
+	restricted := false
+	for _, e := range entitlements {
+		if e.indicator == "" {
+			continue
+		}
+		if e.indicator == uri {
+			return true
+		}
+		restricted = true
+	}
+	return !restricted
+}
+
+// fallback selects which checker-level entitlements back the caller's own
+// under the default scheme.
+type fallback struct {
+	// base enables the base entitlements (see WithBaseEntitlements).
+	base bool
+	// anonymous enables the anonymous entitlements; set only for an
+	// anonymous caller.
+	anonymous bool
+	// region, when set, is the region matching judges region-tagged grants
+	// in, in place of the checker's (see VerifyWithContext).
+	region string
+}
+
+// callerFallback returns the fallback for a caller presenting held: base
+// entitlements always, anonymous ones only when held is anonymous.
+func callerFallback(held map[string][]entitlementPattern) fallback {
+	return fallback{base: true, anonymous: isAnonymousCaller(held)}
+}
+
 // isAnonymousCaller returns true iff the caller provided no entitlements
 // at all (empty map, or every scheme has an empty list).
 func isAnonymousCaller(entitlements map[string][]entitlementPattern) bool {
@@ -679,8 +1883,8 @@ func Dominates(held, requested string) bool {
 		return false
 	}
 
-	// Resource type must match.
-	if hp[0] != rp[0] {
+	// Resource type must match, or the held side covers every version.
+	if !resourceCovers(hp[0], rp[0]) {
 		return false
 	}
 
@@ -716,27 +1920,47 @@ func VerifyAttenuation(held, requested []string) (offender string, ok bool) {
 	return "", true
 }
 
+// IsSubsetEntitlements reports whether sub grants nothing beyond super: every
+// entitlement in sub must be dominated (see Dominates) by an entitlement under
+// the SAME scheme in super. Auth servers use it to check that a re-issued or
+// refresh-derived token does not escalate.
+//
+// Dominance, not request-time matching, is the right predicate here: a
+// specific grant is a subset of a wildcard grant, but a wildcard grant is
+// never a subset of a specific one, although the two would match each other
+// as a held entitlement and a requirement. An empty or nil sub is a subset of
+// anything.
+func IsSubsetEntitlements(sub, super Entitlements) bool {
+	for scheme, list := range sub {
+		if _, ok := VerifyAttenuation(super[scheme], list); !ok {
+			return false
+		}
+	}
+	return true
+}
+
 // Compact returns the subset of entitlements with every entry removed that is
-// strictly dominated by another entry, or that is an exact / equivalent-form
+// strictly subsumed by another entry, or that is an exact / equivalent-form
 // duplicate (e.g. "pages:read", "pages::read", "pages:*:read" collapse to the
 // first-seen one). The result grants exactly the same authority as the input;
 // surviving entries keep their original strings and their first-seen order.
 //
-// It is defined purely in terms of Dominates, so its notion of "broader than"
-// can never drift from attenuation. Opaque and malformed scopes collapse only
-// by exact equality. Compaction is intended for preparing an entitlement array
+// "Broader than" is Dominates widened by the wildcards request-time matching
+// honours and attenuation does not (see subsumes), so "*:*:all" absorbs
+// "pages:read" and "pages:*" absorbs "pages:/foo:write". Opaque and malformed
+// scopes collapse only by exact equality. Compaction is intended for preparing an entitlement array
 // (e.g. before minting a narrowed token); it does not consult the checker's
 // anonymous/base patterns or the intern cache.
 func Compact(entitlements []string) []string {
 	survivors := make([]string, 0, len(entitlements))
 	for i, e := range entitlements {
-		// (1) Drop e if some OTHER entry strictly dominates it.
+		// (1) Drop e if some OTHER entry strictly subsumes it.
 		strictlyDominated := false
 		for j, o := range entitlements {
 			if i == j {
 				continue
 			}
-			if Dominates(o, e) && !Dominates(e, o) {
+			if subsumes(o, e) && !subsumes(e, o) {
 				strictlyDominated = true
 				break
 			}
@@ -745,10 +1969,10 @@ func Compact(entitlements []string) []string {
 			continue
 		}
 		// (2) e is maximal; keep it unless an equivalent survivor is already
-		// present (exact dup or equal form, i.e. mutual dominance).
+		// present (exact dup or equal form, i.e. mutual subsumption).
 		dup := false
 		for _, s := range survivors {
-			if Dominates(s, e) && Dominates(e, s) {
+			if subsumes(s, e) && subsumes(e, s) {
 				dup = true
 				break
 			}
@@ -760,33 +1984,315 @@ func Compact(entitlements []string) []string {
 	return survivors
 }
 
-func (ep entitlementPattern) matches(req entitlementPattern) bool {
+// subsumes reports whether held grants everything requested grants, for
+// Compact: Dominates, with a "*" verb also covering every verb and a "*"
+// resource type every structured type, as in matching.
+func subsumes(held, requested string) bool {
+	if Dominates(held, requested) {
+		return true
+	}
+	h, r := parseForm(held), parseForm(requested)
+	if !h.isPattern || !r.isPattern {
+		return false
+	}
+	if h.resource != ResourceTypeWildcard && !resourceCovers(h.resource, r.resource) {
+		return false
+	}
+	if h.verb != r.verb && !slices.Contains(defaultWildcardVerbs, h.verb) {
+		return false
+	}
+	return isWildcardName(h.resourceName) || h.resourceName == r.resourceName
+}
+
+// CompactEntitlements applies Compact to every scheme of entitlements and
+// returns the result as a new map; the input is not mutated. Entries are only
+// ever absorbed by a broader entry under the SAME scheme — a scheme is an
+// independent credential, so a "pages:*:all" under one scheme says nothing
+// about another. Schemes whose list is empty are kept (empty) so the
+// anonymous-caller determination of the result matches the input.
+func CompactEntitlements(entitlements Entitlements) Entitlements {
+	if entitlements == nil {
+		return nil
+	}
+	out := make(Entitlements, len(entitlements))
+	for scheme, list := range entitlements {
+		out[scheme] = Compact(list)
+	}
+	return out
+}
+
+// matcher carries the checker options that shape a single match, resolved
+// once per scheme so the per-pattern comparison stays cheap.
+type matcher struct {
+	// wildcardVerbs are the held verbs that grant every verb ("all" and "*"
+	// unless set by WithWildcardVerbs or WithWildcardVerbByScheme).
+	wildcardVerbs []string
+	// httpVerbs enables HTTP method aliases (see WithHTTPVerbAliases).
+	httpVerbs bool
+	// glob enables resource-name globs (see WithResourceNameGlob).
+	glob bool
+	// prefixes enables path-prefix resourceNames (see
+	// WithResourceNamePrefixes).
+	prefixes bool
+	// regexes caches regular-expression resourceNames, and is nil unless
+	// they are enabled (see WithResourceNameRegex).
+	regexes *regexCache
+	// foldCase compares resource types and verbs case-insensitively (see
+	// WithCaseInsensitive).
+	foldCase bool
+	// nameWildcard is an extra spelling of '*' in resourceNames, or "" (see
+	// WithResourceNameWildcardChar).
+	nameWildcard string
+	// region is the checker's region (see WithRegion).
+	region string
+	// verbGroups maps a group verb to its member set (see WithVerbGroups).
+	verbGroups map[string]map[string]struct{}
+	// allCoversOpaque lets a class-wide wildcard-verb grant satisfy an opaque
+	// requirement for its resource (see WithAllCoversOpaque).
+	allCoversOpaque bool
+	// bareVerb is the verb a bare held token grants, or "" (see
+	// WithBareTokenVerbByScheme).
+	bareVerb string
+	// allMeansAny lets any held verb satisfy a required wildcard verb (see
+	// WithRequirementAllMeansAny).
+	allMeansAny bool
+	// versions enables version and resource type wildcards (see
+	// VersionWildcardSuffix and ResourceTypeWildcard).
+	versions bool
+	// implications maps a held verb to the verbs it implies (see
+	// SetVerbImplications).
+	implications verbImplications
+	// verbSeparator splits a required verb into alternatives (see
+	// WithRequirementVerbSeparator).
+	verbSeparator string
+	// nameSeparator splits a held resourceName into the names it lists (see
+	// WithResourceNameSeparator).
+	nameSeparator string
+	// maxDepth bounds resourceName depth, 0 for unbounded (see
+	// WithMaxResourceNameDepth).
+	maxDepth int
+}
+
+// matches reports whether the held pattern ep satisfies req.
+func (m matcher) matches(ep, req entitlementPattern) bool {
+	// A region-tagged grant is valid only in its own region, and a deny is
+	// never a grant.
+	if (ep.region != "" && ep.region != m.region) || ep.except {
+		return false
+	}
+
+	// A name past the depth bound never matches, not even itself.
+	if m.heldTooDeep(ep) || tooDeep(req, m.maxDepth) {
+		return false
+	}
+
 	// Exact match is always the fastest path
 	if ep.raw == req.raw {
 		return true
 	}
 
-	// If either is not a pattern (opaque), only exact match (above) works
+	ep = m.expandBare(ep)
+
+	// If either is not a pattern (opaque), only exact match (above) works,
+	// unless a class-wide wildcard-verb grant may cover the opaque resource.
 	if !ep.isPattern || !req.isPattern {
-		return false
+		return m.allCoversOpaque && m.coversOpaque(ep, req)
 	}
 
-	// Resource type must match
-	if ep.resource != req.resource {
+	if m.foldCase {
+		ep, req = foldCase(ep), foldCase(req)
+	}
+
+	// Resource type must match (or the entitlement covers every version, or
+	// every type)
+	if ep.resource != req.resource &&
+		!(m.versions && (ep.resource == ResourceTypeWildcard || resourceCovers(ep.resource, req.resource))) {
 		return false
 	}
 
-	// Verb must match (or entitlement provides "all")
-	if ep.verb != "all" && ep.verb != req.verb {
+	// Verb must match (or entitlement provides the wildcard verb)
+	if !m.verbMatches(ep.verb, req.verb) {
 		return false
 	}
 
-	// Check resource name with wildcard support
-	// Empty string or "*" in either side means all resources
-	if ep.resourceName == "" || ep.resourceName == "*" || req.resourceName == "" || req.resourceName == "*" {
+	// Empty string or "*" as the required name means all resources
+	required := m.canonicalName(req.resourceName)
+	if required == "" || required == "*" {
+		return true
+	}
+
+	// A held name list matches when any listed name does.
+	for _, name := range m.heldNames(ep.resourceName) {
+		if m.nameMatches(m.canonicalName(name), required) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandBare returns a bare held token ep as the class-wide grant of the
+// scheme's bare-token verb, and any other ep unchanged.
+func (m matcher) expandBare(ep entitlementPattern) entitlementPattern {
+	if m.bareVerb == "" || ep.isPattern || ep.raw == "" || ep.indicator != "" || ep.ref != "" || ep.cond != nil ||
+		strings.Contains(ep.raw, ":") {
+		return ep
+	}
+	return entitlementPattern{
+		raw:       ep.raw,
+		resource:  ep.raw,
+		verb:      m.bareVerb,
+		isPattern: true,
+		region:    ep.region,
+	}
+}
+
+// nameMatches reports whether one held resource name grants a required one.
+func (m matcher) nameMatches(held, required string) bool {
+	// Empty string or "*" as the held name means all resources
+	if held == "" || held == "*" {
 		return true
 	}
 
+	// A listed name past the depth bound never matches.
+	if nameTooDeep(held, m.maxDepth) {
+		return false
+	}
+
+	// In regex mode, "~expr" matches the names expr matches in full.
+	if matched, ok := m.regexMatches(held, required); ok {
+		return matched
+	}
+
+	// In prefix mode, "/docs/*" covers every name below "/docs/". In glob
+	// mode it reads as the equivalent "/docs/**", so a glob requirement is
+	// judged by intersection below.
+	if prefix, ok := m.namePrefix(held); ok {
+		if m.glob {
+			held = prefix + "**"
+		} else {
+			rest, ok := strings.CutPrefix(required, prefix)
+			return ok && rest != ""
+		}
+	}
+
+	// In glob mode, a glob on either side matches when the two names could
+	// refer to a common concrete name (see glob.go).
+	if m.glob && (hasGlobMeta(held) || hasGlobMeta(required)) {
+		return globsIntersect(held, required)
+	}
+
 	// Specific resource name must match
-	return ep.resourceName == req.resourceName
+	return held == required
+}
+
+// namePrefix returns, with its trailing '/', the path prefix a held name such
+// as "/docs/*" or "/docs/**" grants in prefix mode (see
+// WithResourceNamePrefixes).
+func (m matcher) namePrefix(name string) (string, bool) {
+	if !m.prefixes {
+		return "", false
+	}
+	for _, suffix := range []string{"/**", "/*"} {
+		if prefix, ok := strings.CutSuffix(name, suffix); ok && !(m.glob && hasGlobMeta(prefix)) {
+			return prefix + "/", true
+		}
+	}
+	return "", false
+}
+
+// foldCase returns p with its resource type and verb in lower case.
+func foldCase(p entitlementPattern) entitlementPattern {
+	p.resource = strings.ToLower(p.resource)
+	p.verb = strings.ToLower(p.verb)
+	return p
+}
+
+// canonicalName spells the wildcard character of name as '*' (see
+// WithResourceNameWildcardChar).
+func (m matcher) canonicalName(name string) string {
+	if m.nameWildcard == "" {
+		return name
+	}
+	return strings.ReplaceAll(name, m.nameWildcard, "*")
+}
+
+// heldNames splits a held resourceName into the names it lists (see
+// WithResourceNameSeparator); a name that is not a list is its only name.
+func (m matcher) heldNames(name string) []string {
+	if m.nameSeparator == "" || !strings.Contains(name, m.nameSeparator) {
+		return []string{name}
+	}
+	var names []string
+	for listed := range strings.SplitSeq(name, m.nameSeparator) {
+		if listed != "" {
+			names = append(names, listed)
+		}
+	}
+	return names
+}
+
+// heldTooDeep reports whether every name ep lists is past the depth bound.
+func (m matcher) heldTooDeep(ep entitlementPattern) bool {
+	if !tooDeep(ep, m.maxDepth) {
+		return false
+	}
+	for _, name := range m.heldNames(ep.resourceName) {
+		if !nameTooDeep(name, m.maxDepth) {
+			return false
+		}
+	}
+	return true
+}
+
+// coversOpaque reports whether ep is a class-wide wildcard-verb grant for the
+// resource an opaque requirement names.
+func (m matcher) coversOpaque(ep, req entitlementPattern) bool {
+	return ep.isPattern && !req.isPattern && req.ref == "" && req.indicator == "" &&
+		m.isWildcardVerb(ep.verb) && isWildcardName(m.canonicalName(ep.resourceName)) &&
+		(ep.resource == req.raw || (m.versions && resourceCovers(ep.resource, req.raw)))
+}
+
+// isWildcardVerb reports whether a held verb grants every verb.
+func (m matcher) isWildcardVerb(verb string) bool {
+	return slices.Contains(m.wildcardVerbs, verb)
+}
+
+// verbMatches reports whether a held verb grants a required verb.
+func (m matcher) verbMatches(held, required string) bool {
+	if m.isWildcardVerb(held) || held == required {
+		return true
+	}
+	if m.verbSeparator != "" && strings.Contains(required, m.verbSeparator) {
+		for alternative := range strings.SplitSeq(required, m.verbSeparator) {
+			if alternative != "" && m.plainVerbMatches(held, alternative) {
+				return true
+			}
+		}
+		return false
+	}
+	return m.plainVerbMatches(held, required)
+}
+
+// plainVerbMatches is verbMatches for a required verb that is not split into
+// alternatives.
+func (m matcher) plainVerbMatches(held, required string) bool {
+	if m.isWildcardVerb(held) || held == required || (m.allMeansAny && m.isWildcardVerb(required)) {
+		return true
+	}
+	if m.httpVerbs && aliasHTTPVerb(held) == aliasHTTPVerb(required) {
+		return true
+	}
+	if _, ok := m.implications[held][required]; ok {
+		return true
+	}
+	if m.verbGroups != nil {
+		// A held group grants each member; a required group accepts any.
+		if _, ok := m.verbGroups[held][required]; ok {
+			return true
+		}
+		if _, ok := m.verbGroups[required][held]; ok {
+			return true
+		}
+	}
+	return false
 }
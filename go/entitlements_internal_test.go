@@ -1,14 +1,19 @@
 package entitlements
 
-// White-box (package entitlements) tests for requirement placeholders and
-// BindRequirements. These live in a separate file from entitlements_test.go
-// (package entitlements_test) because they exercise unexported symbols
-// (placeholderKey) and unexported fields (ParsedRequirements.hasPlaceholder,
-// ParsedRequirements.patterns) that an external test package cannot reach.
+// White-box (package entitlements) tests for requirement placeholders,
+// BindRequirements, and the denial cache. These live in a separate file from
+// entitlements_test.go (package entitlements_test) because they exercise
+// unexported symbols (placeholderKey, fingerprint) and unexported fields
+// (ParsedRequirements.hasPlaceholder, ParsedRequirements.patterns,
+// EntitlementsChecker.denials) that an external test package cannot reach.
 
 import (
 	"errors"
+	"fmt"
 	"testing"
+	"time"
+
+	"github.com/kdex-tech/entitlements/go/entitlementstest"
 )
 
 func TestPlaceholderKey(t *testing.T) {
@@ -270,3 +275,188 @@ func TestWildcardRequirements(t *testing.T) {
 		t.Errorf("expected a strict-clean set to report nothing, got %d", n)
 	}
 }
+
+func TestDenialCacheServesAndExpires(t *testing.T) {
+	clock := entitlementstest.NewFakeClock(time.Unix(1_700_000_000, 0))
+	ec := NewEntitlementsChecker(nil, "bearer", false).WithDenialCache(8, time.Minute).WithClock(clock)
+
+	held := Entitlements{"bearer": {"pages:/foo:read"}}
+	reqs := Requirements{{"bearer": {"pages:/foo:write"}}}
+
+	if ec.VerifyEntitlements(held, reqs) {
+		t.Fatal("expected a denial")
+	}
+	key := fingerprint(held, reqs)
+	if !ec.denials.denied(key, clock.Now()) {
+		t.Fatal("denial should be cached")
+	}
+
+	// Prove the cached denial is what answers: plant a denial for a pair that
+	// would otherwise be allowed, and it is served until the TTL passes.
+	allowed := Requirements{{"bearer": {"pages:/foo:read"}}}
+	ec.denials.add(fingerprint(held, allowed), clock.Now())
+	if ec.VerifyEntitlements(held, allowed) {
+		t.Error("a cached denial should be served without re-evaluation")
+	}
+
+	clock.Advance(time.Minute - time.Nanosecond)
+	if ec.VerifyEntitlements(held, allowed) {
+		t.Error("a denial must be served until the TTL has fully passed")
+	}
+
+	clock.Advance(time.Nanosecond)
+	if !ec.VerifyEntitlements(held, allowed) {
+		t.Error("an expired denial must be re-evaluated")
+	}
+	if ec.denials.denied(key, clock.Now()) {
+		t.Error("denial should have expired after the TTL")
+	}
+}
+
+func TestDenialCacheNeverCachesAllows(t *testing.T) {
+	ec := NewEntitlementsChecker(nil, "bearer", false).WithDenialCache(8, time.Minute)
+	held := Entitlements{"bearer": {"pages:/foo:read"}}
+	reqs := Requirements{{"bearer": {"pages:/foo:read"}}}
+	if !ec.VerifyEntitlements(held, reqs) {
+		t.Fatal("expected an allow")
+	}
+	if ec.denials.order.Len() != 0 {
+		t.Errorf("an allow must not be cached, got %d entries", ec.denials.order.Len())
+	}
+}
+
+func TestDenialCacheEvictsOldest(t *testing.T) {
+	ec := NewEntitlementsChecker(nil, "bearer", false).WithDenialCache(2, time.Minute)
+	held := Entitlements{"bearer": {"pages:/foo:read"}}
+	for _, name := range []string{"/a", "/b", "/c"} {
+		ec.VerifyEntitlements(held, Requirements{{"bearer": {"pages:" + name + ":read"}}})
+	}
+	if ec.denials.order.Len() != 2 {
+		t.Fatalf("expected the cache to stay at its size, got %d", ec.denials.order.Len())
+	}
+	if ec.denials.denied(fingerprint(held, Requirements{{"bearer": {"pages:/a:read"}}}), ec.clock.Now()) {
+		t.Error("the oldest denial should have been evicted")
+	}
+}
+
+func TestDenialCacheClearedByBaseEntitlements(t *testing.T) {
+	ec := NewEntitlementsChecker(nil, "bearer", false).WithDenialCache(8, time.Minute)
+	held := Entitlements{"bearer": {"pages:/foo:read"}}
+	reqs := Requirements{{"bearer": {"public:read"}}}
+	if ec.VerifyEntitlements(held, reqs) {
+		t.Fatal("expected a denial")
+	}
+	ec.WithBaseEntitlements([]string{"public:read"})
+	if !ec.VerifyEntitlements(held, reqs) {
+		t.Error("a denial must not outlive the base entitlements that produced it")
+	}
+}
+
+func TestFingerprintIsCanonical(t *testing.T) {
+	a := fingerprint(
+		Entitlements{"bearer": {"b", "a"}, "oauth2": {"x"}},
+		Requirements{{"oauth2": {"x"}, "bearer": {"a"}}},
+	)
+	b := fingerprint(
+		Entitlements{"oauth2": {"x"}, "bearer": {"a", "b"}},
+		Requirements{{"bearer": {"a"}, "oauth2": {"x"}}},
+	)
+	if a != b {
+		t.Errorf("fingerprints differ for equivalent maps:\n%s\n%s", a, b)
+	}
+	// Token content cannot forge a boundary between two tokens.
+	if fingerprint(Entitlements{"bearer": {"a1:b"}}, nil) == fingerprint(Entitlements{"bearer": {"a", "b"}}, nil) {
+		t.Error("fingerprint must be unambiguous")
+	}
+}
+
+func TestGlobsIntersect(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want bool
+	}{
+		{"/foo*", "/foobar*", true},
+		{"/foo*", "/foobar", true},
+		{"/foo*", "/bar*", false},
+		{"/foo*", "/fo", false},
+		{"*bar", "foo*", true},
+		{"a*c", "ab*", true},
+		{"a*c", "b*", false},
+		{"/img/*.png", "/img/cat.png", true},
+		{"/img/*.png", "/img/cat.jpg", false},
+		{"/img/*.png", "/img/a/cat.png", false}, // '*' does not cross '/'
+		{"/img/*", "/img/*/x", false},
+		{"/img/?.png", "/img/a.png", true},
+		{"/img/?.png", "/img/ab.png", false},
+		{"/img/?", "/img//", false},
+		{"?", "*", true},
+		{"*", "", true},
+		{"**", "*", true},
+		{`/a\*b`, "/a*b", true}, // escaped literal vs glob
+		{`/a\*b`, "/axb", false},
+		{`/a\*b`, `/a\*b`, true},
+		{`/a\?`, "/ab", false},
+		{`trailing\`, `trailing\`, true},
+		{"abc", "abc", true},
+		{"abc", "abd", false},
+		{"/docs/**/*", "/docs/a", false}, // too shallow
+		{"/docs/**/*", "/docs/a/b", true},
+		{"/docs/**/*", "/docs/a/b/c", true},
+		{"/docs/**", "/docs/", false}, // '**' is at least one character
+		{"/docs/**", "/docs/a/b", true},
+		{"/docs/**/*", "/docs/*", false},    // '*' cannot supply the '/'
+		{"/docs/**/*", "/docs/*/*", true},   // glob vs glob
+		{"/docs/**/x", "/docs/**/y", false}, // disjoint leaves
+		{"/docs/**/*", "/docs/?/?", true},
+		{"/a/**", "/b/**", false},
+	}
+	for _, c := range cases {
+		if got := globsIntersect(c.a, c.b); got != c.want {
+			t.Errorf("globsIntersect(%q, %q) = %v, want %v", c.a, c.b, got, c.want)
+		}
+		if got := globsIntersect(c.b, c.a); got != c.want {
+			t.Errorf("globsIntersect(%q, %q) = %v, want %v (symmetry)", c.b, c.a, got, c.want)
+		}
+	}
+}
+
+func TestRegexCacheBound(t *testing.T) {
+	ec := NewEntitlementsChecker(nil, "bearer", false).WithResourceNameRegex(true)
+	c := ec.resourceNameRegexes
+	for i := 0; i < maxCachedRegexes+10; i++ {
+		if c.compile(fmt.Sprintf("ORD-%d", i)) == nil {
+			t.Fatalf("expression %d did not compile", i)
+		}
+	}
+	if len(c.compiled) != maxCachedRegexes {
+		t.Errorf("cache holds %d expressions, want %d", len(c.compiled), maxCachedRegexes)
+	}
+	// The oldest expressions were evicted to make room for the newest.
+	for _, expr := range []string{"ORD-0", "ORD-9"} {
+		if _, ok := c.compiled[expr]; ok {
+			t.Errorf("%q still cached, want evicted", expr)
+		}
+	}
+	if _, ok := c.compiled[fmt.Sprintf("ORD-%d", maxCachedRegexes+9)]; !ok {
+		t.Errorf("newest expression not cached")
+	}
+	// A use refreshes an expression, so the least recently used goes next.
+	c.compile("ORD-10")
+	if re := c.compile("ORD-x"); re == nil || !re.MatchString("ORD-x") {
+		t.Errorf("new expression did not match")
+	}
+	if _, ok := c.compiled["ORD-10"]; !ok {
+		t.Errorf("recently used expression evicted")
+	}
+	if _, ok := c.compiled["ORD-11"]; ok {
+		t.Errorf("least recently used expression not evicted")
+	}
+	// An invalid expression is cached as nil.
+	fresh := NewEntitlementsChecker(nil, "bearer", false).WithResourceNameRegex(true).resourceNameRegexes
+	if fresh.compile("(") != nil {
+		t.Errorf("invalid expression compiled")
+	}
+	if el, ok := fresh.compiled["("]; !ok || el.Value.(*regexEntry).re != nil {
+		t.Errorf("invalid expression not cached as nil")
+	}
+}
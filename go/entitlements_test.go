@@ -1,411 +1,426 @@
 package entitlements_test
 
 import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/kdex-tech/entitlements/go"
+	"github.com/kdex-tech/entitlements/go/entitlementstest"
 	"github.com/stretchr/testify/assert"
 )
 
-func TestEntitlementsChecker_VerifyEntitlements(t *testing.T) {
-	tests := []struct {
-		name                  string
-		anonymousEntitlements []string
-		entitlements          entitlements.Entitlements
-		requirements          entitlements.Requirements
-		want                  bool
-	}{
-		{
-			name:                  "none",
-			anonymousEntitlements: []string{},
-			entitlements:          map[string][]string{},
-			requirements:          entitlements.Requirements{},
-			want:                  true,
+// verifyEntitlementsTests is the core matching table, shared by the tests
+// that must reproduce it under other configurations.
+var verifyEntitlementsTests = []struct {
+	name                  string
+	anonymousEntitlements []string
+	entitlements          entitlements.Entitlements
+	requirements          entitlements.Requirements
+	want                  bool
+}{
+	{
+		name:                  "none",
+		anonymousEntitlements: []string{},
+		entitlements:          map[string][]string{},
+		requirements:          entitlements.Requirements{},
+		want:                  true,
+	},
+	{
+		name:                  "opaque - no entitlements",
+		anonymousEntitlements: []string{},
+		entitlements:          map[string][]string{},
+		requirements: entitlements.Requirements{
+			{"bearer": {"pages"}},
 		},
-		{
-			name:                  "opaque - no entitlements",
-			anonymousEntitlements: []string{},
-			entitlements:          map[string][]string{},
-			requirements: entitlements.Requirements{
-				{"bearer": {"pages"}},
-			},
-			want: false,
+		want: false,
+	},
+	{
+		name:                  "opaque - entitlements match requirement",
+		anonymousEntitlements: []string{},
+		entitlements: map[string][]string{
+			"bearer": {"pages"},
 		},
-		{
-			name:                  "opaque - entitlements match requirement",
-			anonymousEntitlements: []string{},
-			entitlements: map[string][]string{
-				"bearer": {"pages"},
-			},
-			requirements: entitlements.Requirements{
-				{"bearer": {"pages"}},
-			},
-			want: true,
+		requirements: entitlements.Requirements{
+			{"bearer": {"pages"}},
 		},
-		{
-			name:                  "opaque - entitlements does not match requirement",
-			anonymousEntitlements: []string{},
-			entitlements: map[string][]string{
-				"bearer": {"books"},
-			},
-			requirements: entitlements.Requirements{
-				{"bearer": {"pages"}},
-			},
-			want: false,
+		want: true,
+	},
+	{
+		name:                  "opaque - entitlements does not match requirement",
+		anonymousEntitlements: []string{},
+		entitlements: map[string][]string{
+			"bearer": {"books"},
 		},
-		{
-			name:                  "opaque - does not match wildcard specific verb",
-			anonymousEntitlements: []string{},
-			entitlements: map[string][]string{
-				"bearer": {"books"},
-			},
-			requirements: entitlements.Requirements{
-				{"bearer": {"books:read"}},
-			},
-			want: false,
+		requirements: entitlements.Requirements{
+			{"bearer": {"pages"}},
 		},
-		{
-			name:                  "opaque - does not match wildcard all verb",
-			anonymousEntitlements: []string{},
-			entitlements: map[string][]string{
-				"bearer": {"books"},
-			},
-			requirements: entitlements.Requirements{
-				{"bearer": {"books:all"}},
-			},
-			want: false,
+		want: false,
+	},
+	{
+		name:                  "opaque - does not match wildcard specific verb",
+		anonymousEntitlements: []string{},
+		entitlements: map[string][]string{
+			"bearer": {"books"},
 		},
-		{
-			name:                  "opaque - does not match explicit wildcard all verb",
-			anonymousEntitlements: []string{},
-			entitlements: map[string][]string{
-				"bearer": {"books"},
-			},
-			requirements: entitlements.Requirements{
-				{"bearer": {"books:*:all"}},
-			},
-			want: false,
+		requirements: entitlements.Requirements{
+			{"bearer": {"books:read"}},
 		},
-		{
-			name:                  "opaque - wildcard all verb entitlement does not match opaque requirement",
-			anonymousEntitlements: []string{},
-			entitlements: map[string][]string{
-				"bearer": {"books:all"},
-			},
-			requirements: entitlements.Requirements{
-				{"bearer": {"books"}},
-			},
-			want: false,
+		want: false,
+	},
+	{
+		name:                  "opaque - does not match wildcard all verb",
+		anonymousEntitlements: []string{},
+		entitlements: map[string][]string{
+			"bearer": {"books"},
 		},
-		{
-			name:                  "short - entitlements match requirement",
-			anonymousEntitlements: []string{},
-			entitlements: map[string][]string{
-				"bearer": {"pages:read"},
-			},
-			requirements: entitlements.Requirements{
-				{"bearer": {"pages:read"}},
-			},
-			want: true,
+		requirements: entitlements.Requirements{
+			{"bearer": {"books:all"}},
 		},
-		{
-			name:                  "short - entitlements do not match requirement with multiple verbs",
-			anonymousEntitlements: []string{},
-			entitlements: map[string][]string{
-				"bearer": {"pages:read"},
-			},
-			requirements: entitlements.Requirements{
-				{"bearer": {"pages:read", "pages:write"}},
-			},
-			want: false,
+		want: false,
+	},
+	{
+		name:                  "opaque - does not match explicit wildcard all verb",
+		anonymousEntitlements: []string{},
+		entitlements: map[string][]string{
+			"bearer": {"books"},
 		},
-		{
-			name:                  "short - entitlements do not match requirement with multiple verbs",
-			anonymousEntitlements: []string{},
-			entitlements: map[string][]string{
-				"bearer": {"pages:read", "pages:write"},
-			},
-			requirements: entitlements.Requirements{
-				{"bearer": {"pages:read", "pages:write"}},
-			},
-			want: true,
+		requirements: entitlements.Requirements{
+			{"bearer": {"books:*:all"}},
 		},
-		{
-			name:                  "short - entitlement does not match requirement wrong verb",
-			anonymousEntitlements: []string{},
-			entitlements: map[string][]string{
-				"bearer": {"pages:read"},
-			},
-			requirements: entitlements.Requirements{
-				{"bearer": {"pages:write"}},
-			},
-			want: false,
+		want: false,
+	},
+	{
+		name:                  "opaque - wildcard all verb entitlement does not match opaque requirement",
+		anonymousEntitlements: []string{},
+		entitlements: map[string][]string{
+			"bearer": {"books:all"},
 		},
-		{
-			name:                  "short - wildcard entitlement does not match opaque requirement",
-			anonymousEntitlements: []string{},
-			entitlements: map[string][]string{
-				"bearer": {"pages:all"},
-			},
-			requirements: entitlements.Requirements{
-				{"bearer": {"pages"}},
-			},
-			want: false,
+		requirements: entitlements.Requirements{
+			{"bearer": {"books"}},
 		},
-		{
-			name:                  "short - wildcard entitlement matches wildcard requirement",
-			anonymousEntitlements: []string{},
-			entitlements: map[string][]string{
-				"bearer": {"pages:all"},
-			},
-			requirements: entitlements.Requirements{
-				{"bearer": {"pages:all"}},
-			},
-			want: true,
+		want: false,
+	},
+	{
+		name:                  "short - entitlements match requirement",
+		anonymousEntitlements: []string{},
+		entitlements: map[string][]string{
+			"bearer": {"pages:read"},
 		},
-		{
-			name:                  "short - wildcard entitlement matches short requirement",
-			anonymousEntitlements: []string{},
-			entitlements: map[string][]string{
-				"bearer": {"pages:all"},
-			},
-			requirements: entitlements.Requirements{
-				{"bearer": {"pages:read"}},
-			},
-			want: true,
+		requirements: entitlements.Requirements{
+			{"bearer": {"pages:read"}},
 		},
-		{
-			name:                  "long - long entitlement matches short requirement",
-			anonymousEntitlements: []string{},
-			entitlements: map[string][]string{
-				"bearer": {"pages:/foo:read"},
-			},
-			requirements: entitlements.Requirements{
-				{"bearer": {"pages:read"}},
-			},
-			want: true,
+		want: true,
+	},
+	{
+		name:                  "short - entitlements do not match requirement with multiple verbs",
+		anonymousEntitlements: []string{},
+		entitlements: map[string][]string{
+			"bearer": {"pages:read"},
 		},
-		{
-			name:                  "long - long entitlement matches long requirement",
-			anonymousEntitlements: []string{},
-			entitlements: map[string][]string{
-				"bearer": {"pages:/foo:read"},
-			},
-			requirements: entitlements.Requirements{
-				{"bearer": {"pages:/foo:read"}},
-			},
-			want: true,
+		requirements: entitlements.Requirements{
+			{"bearer": {"pages:read", "pages:write"}},
 		},
-		{
-			name:                  "long - long entitlement does not match short requirement wrong verb",
-			anonymousEntitlements: []string{},
-			entitlements: map[string][]string{
-				"bearer": {"pages:/foo:read"},
-			},
-			requirements: entitlements.Requirements{
-				{"bearer": {"pages:write"}},
-			},
-			want: false,
+		want: false,
+	},
+	{
+		name:                  "short - entitlements do not match requirement with multiple verbs",
+		anonymousEntitlements: []string{},
+		entitlements: map[string][]string{
+			"bearer": {"pages:read", "pages:write"},
 		},
-		{
-			name:                  "long - long entitlement matches short requirement by verb",
-			anonymousEntitlements: []string{},
-			entitlements: map[string][]string{
-				"bearer": {"pages:/foo:read"},
-			},
-			requirements: entitlements.Requirements{
-				{"bearer": {"pages:read"}},
-			},
-			want: true,
+		requirements: entitlements.Requirements{
+			{"bearer": {"pages:read", "pages:write"}},
 		},
-		{
-			name:                  "long - long entitlement does not match long requirement wrong resourceName",
-			anonymousEntitlements: []string{},
-			entitlements: map[string][]string{
-				"bearer": {"pages:/foo:read"},
-			},
-			requirements: entitlements.Requirements{
-				{"bearer": {"pages:/bar:read"}},
-			},
-			want: false,
+		want: true,
+	},
+	{
+		name:                  "short - entitlement does not match requirement wrong verb",
+		anonymousEntitlements: []string{},
+		entitlements: map[string][]string{
+			"bearer": {"pages:read"},
 		},
-		{
-			name:                  "long - long entitlement does not match long requirement wrong resource",
-			anonymousEntitlements: []string{},
-			entitlements: map[string][]string{
-				"bearer": {"pages:/foo:read"},
-			},
-			requirements: entitlements.Requirements{
-				{"bearer": {"books:/foo:read"}},
-			},
-			want: false,
+		requirements: entitlements.Requirements{
+			{"bearer": {"pages:write"}},
 		},
-		{
-			name:                  "OR - entitlement does not match by resource",
-			anonymousEntitlements: []string{},
-			entitlements: map[string][]string{
-				"bearer": {"users:/foo:read"},
-			},
-			requirements: entitlements.Requirements{
-				{"bearer": {"books:/foo:read"}},
-				{"bearer": {"pages:/bar:read"}},
-			},
-			want: false,
+		want: false,
+	},
+	{
+		name:                  "short - wildcard entitlement does not match opaque requirement",
+		anonymousEntitlements: []string{},
+		entitlements: map[string][]string{
+			"bearer": {"pages:all"},
 		},
-		{
-			name:                  "OR - entitlement does not match by verb",
-			anonymousEntitlements: []string{},
-			entitlements: map[string][]string{
-				"bearer": {"users:/foo:read"},
-			},
-			requirements: entitlements.Requirements{
-				{"bearer": {"users:/foo:write"}},
-				{"bearer": {"users:/foo:delete"}},
-			},
-			want: false,
+		requirements: entitlements.Requirements{
+			{"bearer": {"pages"}},
 		},
-		{
-			name:                  "OR - entitlement does not match by resourceName",
-			anonymousEntitlements: []string{},
-			entitlements: map[string][]string{
-				"bearer": {"users:/foo:read"},
-			},
-			requirements: entitlements.Requirements{
-				{"bearer": {"users:/bar:read"}},
-				{"bearer": {"users:/baz:read"}},
-			},
-			want: false,
+		want: false,
+	},
+	{
+		name:                  "short - wildcard entitlement matches wildcard requirement",
+		anonymousEntitlements: []string{},
+		entitlements: map[string][]string{
+			"bearer": {"pages:all"},
 		},
-		{
-			name:                  "OR - entitlement matches one of the requirements",
-			anonymousEntitlements: []string{},
-			entitlements: map[string][]string{
-				"bearer": {"users:/foo:read"},
-			},
-			requirements: entitlements.Requirements{
-				{"bearer": {"users:/bar:read"}},
-				{"bearer": {"users:/foo:read"}},
-			},
-			want: true,
+		requirements: entitlements.Requirements{
+			{"bearer": {"pages:all"}},
 		},
-		{
-			name:                  "AND - entitlement does not match all of the requirements",
-			anonymousEntitlements: []string{},
-			entitlements: map[string][]string{
-				"bearer": {"users:/foo:read"},
-			},
-			requirements: entitlements.Requirements{
-				{
-					"bearer": {"users:/bar:read"},
-					"other":  {"users:/foo:read"},
-				},
-			},
-			want: false,
+		want: true,
+	},
+	{
+		name:                  "short - wildcard entitlement matches short requirement",
+		anonymousEntitlements: []string{},
+		entitlements: map[string][]string{
+			"bearer": {"pages:all"},
 		},
-		{
-			name:                  "AND - entitlement matches all of the requirements",
-			anonymousEntitlements: []string{},
-			entitlements: map[string][]string{
+		requirements: entitlements.Requirements{
+			{"bearer": {"pages:read"}},
+		},
+		want: true,
+	},
+	{
+		name:                  "long - long entitlement matches short requirement",
+		anonymousEntitlements: []string{},
+		entitlements: map[string][]string{
+			"bearer": {"pages:/foo:read"},
+		},
+		requirements: entitlements.Requirements{
+			{"bearer": {"pages:read"}},
+		},
+		want: true,
+	},
+	{
+		name:                  "long - long entitlement matches long requirement",
+		anonymousEntitlements: []string{},
+		entitlements: map[string][]string{
+			"bearer": {"pages:/foo:read"},
+		},
+		requirements: entitlements.Requirements{
+			{"bearer": {"pages:/foo:read"}},
+		},
+		want: true,
+	},
+	{
+		name:                  "long - long entitlement does not match short requirement wrong verb",
+		anonymousEntitlements: []string{},
+		entitlements: map[string][]string{
+			"bearer": {"pages:/foo:read"},
+		},
+		requirements: entitlements.Requirements{
+			{"bearer": {"pages:write"}},
+		},
+		want: false,
+	},
+	{
+		name:                  "long - long entitlement matches short requirement by verb",
+		anonymousEntitlements: []string{},
+		entitlements: map[string][]string{
+			"bearer": {"pages:/foo:read"},
+		},
+		requirements: entitlements.Requirements{
+			{"bearer": {"pages:read"}},
+		},
+		want: true,
+	},
+	{
+		name:                  "long - long entitlement does not match long requirement wrong resourceName",
+		anonymousEntitlements: []string{},
+		entitlements: map[string][]string{
+			"bearer": {"pages:/foo:read"},
+		},
+		requirements: entitlements.Requirements{
+			{"bearer": {"pages:/bar:read"}},
+		},
+		want: false,
+	},
+	{
+		name:                  "long - long entitlement does not match long requirement wrong resource",
+		anonymousEntitlements: []string{},
+		entitlements: map[string][]string{
+			"bearer": {"pages:/foo:read"},
+		},
+		requirements: entitlements.Requirements{
+			{"bearer": {"books:/foo:read"}},
+		},
+		want: false,
+	},
+	{
+		name:                  "OR - entitlement does not match by resource",
+		anonymousEntitlements: []string{},
+		entitlements: map[string][]string{
+			"bearer": {"users:/foo:read"},
+		},
+		requirements: entitlements.Requirements{
+			{"bearer": {"books:/foo:read"}},
+			{"bearer": {"pages:/bar:read"}},
+		},
+		want: false,
+	},
+	{
+		name:                  "OR - entitlement does not match by verb",
+		anonymousEntitlements: []string{},
+		entitlements: map[string][]string{
+			"bearer": {"users:/foo:read"},
+		},
+		requirements: entitlements.Requirements{
+			{"bearer": {"users:/foo:write"}},
+			{"bearer": {"users:/foo:delete"}},
+		},
+		want: false,
+	},
+	{
+		name:                  "OR - entitlement does not match by resourceName",
+		anonymousEntitlements: []string{},
+		entitlements: map[string][]string{
+			"bearer": {"users:/foo:read"},
+		},
+		requirements: entitlements.Requirements{
+			{"bearer": {"users:/bar:read"}},
+			{"bearer": {"users:/baz:read"}},
+		},
+		want: false,
+	},
+	{
+		name:                  "OR - entitlement matches one of the requirements",
+		anonymousEntitlements: []string{},
+		entitlements: map[string][]string{
+			"bearer": {"users:/foo:read"},
+		},
+		requirements: entitlements.Requirements{
+			{"bearer": {"users:/bar:read"}},
+			{"bearer": {"users:/foo:read"}},
+		},
+		want: true,
+	},
+	{
+		name:                  "AND - entitlement does not match all of the requirements",
+		anonymousEntitlements: []string{},
+		entitlements: map[string][]string{
+			"bearer": {"users:/foo:read"},
+		},
+		requirements: entitlements.Requirements{
+			{
 				"bearer": {"users:/bar:read"},
 				"other":  {"users:/foo:read"},
 			},
-			requirements: entitlements.Requirements{
-				{
-					"bearer": {"users:/bar:read"},
-					"other":  {"users:/foo:read"},
-				},
-			},
-			want: true,
 		},
-		{
-			name:                  "AND - entitlement does not match scheme of requirement",
-			anonymousEntitlements: []string{},
-			entitlements: map[string][]string{
+		want: false,
+	},
+	{
+		name:                  "AND - entitlement matches all of the requirements",
+		anonymousEntitlements: []string{},
+		entitlements: map[string][]string{
+			"bearer": {"users:/bar:read"},
+			"other":  {"users:/foo:read"},
+		},
+		requirements: entitlements.Requirements{
+			{
 				"bearer": {"users:/bar:read"},
+				"other":  {"users:/foo:read"},
 			},
-			requirements: entitlements.Requirements{
-				{
-					"other": {"users:/bar:read"},
-				},
+		},
+		want: true,
+	},
+	{
+		name:                  "AND - entitlement does not match scheme of requirement",
+		anonymousEntitlements: []string{},
+		entitlements: map[string][]string{
+			"bearer": {"users:/bar:read"},
+		},
+		requirements: entitlements.Requirements{
+			{
+				"other": {"users:/bar:read"},
 			},
-			want: false,
 		},
-		{
-			name:                  "AND - match only scheme",
-			anonymousEntitlements: []string{},
-			entitlements: map[string][]string{
+		want: false,
+	},
+	{
+		name:                  "AND - match only scheme",
+		anonymousEntitlements: []string{},
+		entitlements: map[string][]string{
+			"bearer": {},
+		},
+		requirements: entitlements.Requirements{
+			{
 				"bearer": {},
 			},
-			requirements: entitlements.Requirements{
-				{
-					"bearer": {},
-				},
-			},
-			want: true,
 		},
-		{
-			name:                  "AND - does not match all schemes",
-			anonymousEntitlements: []string{},
-			entitlements: map[string][]string{
+		want: true,
+	},
+	{
+		name:                  "AND - does not match all schemes",
+		anonymousEntitlements: []string{},
+		entitlements: map[string][]string{
+			"bearer": {},
+		},
+		requirements: entitlements.Requirements{
+			{
 				"bearer": {},
+				"oauth2": {},
 			},
-			requirements: entitlements.Requirements{
-				{
-					"bearer": {},
-					"oauth2": {},
-				},
-			},
-			want: false,
 		},
-		{
-			name:                  "AND - matches all schemes",
-			anonymousEntitlements: []string{},
-			entitlements: map[string][]string{
+		want: false,
+	},
+	{
+		name:                  "AND - matches all schemes",
+		anonymousEntitlements: []string{},
+		entitlements: map[string][]string{
+			"bearer": {},
+			"oauth2": {},
+		},
+		requirements: entitlements.Requirements{
+			{
 				"bearer": {},
 				"oauth2": {},
 			},
-			requirements: entitlements.Requirements{
-				{
-					"bearer": {},
-					"oauth2": {},
-				},
-			},
-			want: true,
 		},
-		{
-			name:                  "OR - matches one of the schemes",
-			anonymousEntitlements: []string{},
-			entitlements: map[string][]string{
+		want: true,
+	},
+	{
+		name:                  "OR - matches one of the schemes",
+		anonymousEntitlements: []string{},
+		entitlements: map[string][]string{
+			"bearer": {},
+		},
+		requirements: entitlements.Requirements{
+			{
 				"bearer": {},
 			},
-			requirements: entitlements.Requirements{
-				{
-					"bearer": {},
-				},
-				{
-					"oauth2": {},
-				},
+			{
+				"oauth2": {},
 			},
-			want: true,
 		},
-		{
-			name:                  "OR - matches none of the schemes",
-			anonymousEntitlements: []string{},
-			entitlements: map[string][]string{
-				"bearer": {},
+		want: true,
+	},
+	{
+		name:                  "OR - matches none of the schemes",
+		anonymousEntitlements: []string{},
+		entitlements: map[string][]string{
+			"bearer": {},
+		},
+		requirements: entitlements.Requirements{
+			{
+				"foo": {},
 			},
-			requirements: entitlements.Requirements{
-				{
-					"foo": {},
-				},
-				{
-					"oauth2": {},
-				},
+			{
+				"oauth2": {},
 			},
-			want: false,
 		},
-	}
-	for _, tt := range tests {
+		want: false,
+	},
+}
+
+func TestEntitlementsChecker_VerifyEntitlements(t *testing.T) {
+	for _, tt := range verifyEntitlementsTests {
 		t.Run(tt.name, func(t *testing.T) {
 			ec := entitlements.NewEntitlementsChecker(tt.anonymousEntitlements, "bearer", true)
 			got := ec.VerifyEntitlements(tt.entitlements, tt.requirements)
@@ -565,18 +580,53 @@ func TestEntitlementsChecker_VerifyResourceEntitlements_ReadByDefault_False(t *t
 			want:         false,
 			verb:         "write",
 		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			ec := entitlements.NewEntitlementsChecker(tt.anonymousEntitlements, "", false)
-			var got bool
-			var err error
-			if tt.verb != "" {
-				got, err = ec.VerifyResourceEntitlements(tt.resource, tt.resourceName, tt.entitlements, tt.requirements, tt.verb)
-			} else {
-				got, err = ec.VerifyResourceEntitlements(tt.resource, tt.resourceName, tt.entitlements, tt.requirements)
-			}
-			assert.NoError(t, err)
+		{
+			name:                  "custom identity verb - class-wide write grant",
+			anonymousEntitlements: []string{},
+			resource:              "pages",
+			resourceName:          "foo",
+			entitlements: entitlements.Entitlements{
+				"bearer": {"pages:write"},
+			},
+			requirements: entitlements.Requirements{},
+			want:         true,
+			verb:         "write",
+		},
+		{
+			name:                  "custom identity verb - delete (fails if only write)",
+			anonymousEntitlements: []string{},
+			resource:              "pages",
+			resourceName:          "foo",
+			entitlements: entitlements.Entitlements{
+				"bearer": {"pages:foo:write"},
+			},
+			requirements: entitlements.Requirements{},
+			want:         false,
+			verb:         "delete",
+		},
+		{
+			name:                  "default identity verb is read (fails if only write)",
+			anonymousEntitlements: []string{},
+			resource:              "pages",
+			resourceName:          "foo",
+			entitlements: entitlements.Entitlements{
+				"bearer": {"pages:foo:write"},
+			},
+			requirements: entitlements.Requirements{},
+			want:         false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ec := entitlements.NewEntitlementsChecker(tt.anonymousEntitlements, "", false)
+			var got bool
+			var err error
+			if tt.verb != "" {
+				got, err = ec.VerifyResourceEntitlements(tt.resource, tt.resourceName, tt.entitlements, tt.requirements, tt.verb)
+			} else {
+				got, err = ec.VerifyResourceEntitlements(tt.resource, tt.resourceName, tt.entitlements, tt.requirements)
+			}
+			assert.NoError(t, err)
 			assert.Equal(t, tt.want, got)
 		})
 	}
@@ -976,6 +1026,46 @@ func BenchmarkVerifyResourceEntitlements(b *testing.B) {
 	}
 }
 
+func batchChecks(n int) []entitlements.BatchCheck {
+	checks := make([]entitlements.BatchCheck, n)
+	for i := range checks {
+		checks[i] = entitlements.BatchCheck{
+			Resource:     "pages",
+			ResourceName: fmt.Sprintf("page%d", i),
+			Requirements: entitlements.Requirements{{"bearer": {"other:read"}}},
+		}
+	}
+	return checks
+}
+
+func BenchmarkVerifyEntitlementsBatch(b *testing.B) {
+	ec := entitlements.NewEntitlementsChecker([]string{"public:read"}, "bearer", false)
+	userEntitlements := entitlements.Entitlements{
+		"bearer": {"pages:page1:read", "pages:page7:read", "other:all", "books:read", "films:/a/b:update"},
+	}
+	checks := batchChecks(32)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ec.VerifyEntitlementsBatch(userEntitlements, checks)
+	}
+}
+
+func BenchmarkVerifyResourceEntitlements_Loop(b *testing.B) {
+	ec := entitlements.NewEntitlementsChecker([]string{"public:read"}, "bearer", false)
+	userEntitlements := entitlements.Entitlements{
+		"bearer": {"pages:page1:read", "pages:page7:read", "other:all", "books:read", "films:/a/b:update"},
+	}
+	checks := batchChecks(32)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, c := range checks {
+			_, _ = ec.VerifyResourceEntitlements(c.Resource, c.ResourceName, userEntitlements, c.Requirements, c.Verb)
+		}
+	}
+}
+
 func TestDominates(t *testing.T) {
 	cases := []struct {
 		held, requested string
@@ -1147,6 +1237,10 @@ func TestCompact(t *testing.T) {
 		{"cross-resource kept", []string{"functions::read", "vector_stores:system:read"}, []string{"functions::read", "vector_stores:system:read"}},
 		{"verb non-interference", []string{"functions::read", "functions:/a:create"}, []string{"functions::read", "functions:/a:create"}},
 		{"no redundancy preserves order", []string{"x:/a:read", "x:/b:create"}, []string{"x:/a:read", "x:/b:create"}},
+		{"type and verb wildcards prune", []string{"*:*:all", "pages:read", "books:/x:write"}, []string{"*:*:all"}},
+		{"star verb prunes", []string{"pages:/a:read", "pages:*"}, []string{"pages:*"}},
+		{"star verb equals all", []string{"pages:all", "pages:*"}, []string{"pages:all"}},
+		{"type wildcard keeps opaque and denies", []string{"*:*:all", "admin", "!secrets:read"}, []string{"*:*:all", "admin", "!secrets:read"}},
 	}
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -1189,3 +1283,4686 @@ func TestCompactPreservesAuthority(t *testing.T) {
 		assert.Equal(t, orig, comp, "authority equivalence: "+p.name)
 	}
 }
+
+func TestCompactEntitlements(t *testing.T) {
+	in := entitlements.Entitlements{
+		"bearer": {"pages:/a:read", "pages:*:all", "pages::write", "pages:/b:delete", "books:/x:read"},
+		"oauth2": {"pages:/a:read", "pages:/a:read", "scope1"},
+		"apikey": {},
+	}
+	got := entitlements.CompactEntitlements(in)
+	assert.Equal(t, entitlements.Entitlements{
+		"bearer": {"pages:*:all", "books:/x:read"},
+		"oauth2": {"pages:/a:read", "scope1"},
+		"apikey": {},
+	}, got)
+
+	// The broad grant under bearer must not absorb anything under oauth2, and
+	// the input is left untouched.
+	assert.Len(t, in["bearer"], 5)
+	assert.Len(t, in["oauth2"], 3)
+	assert.Nil(t, entitlements.CompactEntitlements(nil))
+
+	// The type and verb wildcards absorb the narrow grants.
+	assert.Equal(t, entitlements.Entitlements{"bearer": {"*:*:all"}},
+		entitlements.CompactEntitlements(entitlements.Entitlements{"bearer": {"*:*:all", "pages:read", "books:/x:write"}}))
+}
+
+func TestCompactEntitlementsSubsumption(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []string
+		want []string
+	}{
+		{"all verb subsumes a verb", []string{"pages:read", "pages:all"}, []string{"pages:all"}},
+		{"all verb subsumes a named verb", []string{"pages:/a:read", "pages:/a:all"}, []string{"pages:/a:all"}},
+		{"distinct verbs both kept", []string{"pages:read", "pages:write"}, []string{"pages:read", "pages:write"}},
+		{"wildcard name subsumes a name", []string{"pages:/a:read", "pages:*:read"}, []string{"pages:*:read"}},
+		{"short form subsumes a name", []string{"pages:/a:read", "pages:read"}, []string{"pages:read"}},
+		{"distinct names both kept", []string{"pages:/a:read", "pages:/b:read"}, []string{"pages:/a:read", "pages:/b:read"}},
+		{"a name does not subsume its children", []string{"pages:/a:read", "pages:/a/b:read"}, []string{"pages:/a:read", "pages:/a/b:read"}},
+		{"named all does not subsume another name", []string{"pages:/a:all", "pages:/b:read"}, []string{"pages:/a:all", "pages:/b:read"}},
+		{"other resource types kept", []string{"pages:all", "books:read"}, []string{"pages:all", "books:read"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := entitlements.CompactEntitlements(entitlements.Entitlements{"bearer": tt.in})
+			assert.Equal(t, entitlements.Entitlements{"bearer": tt.want}, got)
+		})
+	}
+}
+
+func TestCompactEntitlementsPreservesAuthority(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	in := entitlements.Entitlements{
+		"bearer": {"pages:/a:read", "pages:*:all", "pages:/b:delete", "books:/x:read"},
+		"oauth2": {"scope1", "scope1"},
+	}
+	compacted := entitlements.CompactEntitlements(in)
+	for _, reqs := range []entitlements.Requirements{
+		{{"bearer": {"pages:/z:write"}}},
+		{{"bearer": {"books:/x:read"}}},
+		{{"bearer": {"books:/y:read"}}},
+		{{"bearer": {"pages:/a:read"}, "oauth2": {"scope1"}}},
+		{{"oauth2": {"pages:/a:read"}}},
+	} {
+		assert.Equal(t, ec.VerifyEntitlements(in, reqs), ec.VerifyEntitlements(compacted, reqs), "%v", reqs)
+	}
+}
+
+func TestEntitlementsChecker_ResourceIndicator(t *testing.T) {
+	scoped := entitlements.Entitlements{
+		"bearer": {entitlements.ResourceIndicatorPrefix + "https://api.example.com", "pages:read"},
+	}
+	unscoped := entitlements.Entitlements{"bearer": {"pages:read"}}
+	pagesRead := entitlements.Requirements{{"bearer": {"pages:/foo:read"}}}
+
+	tests := []struct {
+		name         string
+		indicator    string
+		entitlements entitlements.Entitlements
+		requirements entitlements.Requirements
+		want         bool
+	}{
+		{"matching checker indicator", "https://api.example.com", scoped, pagesRead, true},
+		{"mismatched checker indicator", "https://other.example.com", scoped, pagesRead, false},
+		{"unscoped token is not restricted", "https://other.example.com", unscoped, pagesRead, true},
+		{"no checker indicator ignores scoping", "", scoped, pagesRead, true},
+		{
+			name:      "one of several indicators matches",
+			indicator: "https://other.example.com",
+			entitlements: entitlements.Entitlements{"bearer": {
+				"resource=https://api.example.com", "resource=https://other.example.com", "pages:read",
+			}},
+			requirements: pagesRead,
+			want:         true,
+		},
+		{
+			name:         "requirement demands matching indicator",
+			entitlements: scoped,
+			requirements: entitlements.Requirements{{"bearer": {"resource=https://api.example.com", "pages:/foo:read"}}},
+			want:         true,
+		},
+		{
+			name:         "requirement demands mismatched indicator",
+			entitlements: scoped,
+			requirements: entitlements.Requirements{{"bearer": {"resource=https://other.example.com", "pages:/foo:read"}}},
+			want:         false,
+		},
+		{
+			name:         "requirement demands indicator the token lacks",
+			entitlements: unscoped,
+			requirements: entitlements.Requirements{{"bearer": {"resource=https://api.example.com"}}},
+			want:         false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithResourceIndicator(tt.indicator)
+			assert.Equal(t, tt.want, ec.VerifyEntitlements(tt.entitlements, tt.requirements))
+		})
+	}
+}
+
+func TestEntitlementsChecker_ResourceIndicatorLeavesBaseEntitlements(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).
+		WithResourceIndicator("https://other.example.com").
+		WithBaseEntitlements([]string{"public:read"})
+	held := entitlements.Entitlements{"bearer": {"resource=https://api.example.com", "pages:read"}}
+	assert.True(t, ec.VerifyEntitlements(held, entitlements.Requirements{{"bearer": {"public:read"}}}))
+	assert.False(t, ec.VerifyEntitlements(held, entitlements.Requirements{{"bearer": {"pages:read"}}}))
+}
+
+func TestDefaultRequirementForRoute(t *testing.T) {
+	tests := []struct {
+		scheme, method, path string
+		want                 entitlements.Requirements
+	}{
+		{"bearer", "GET", "/pages/{id}", entitlements.Requirements{{"bearer": {"pages:{id}:read"}}}},
+		{"bearer", "get", "/pages/{id}", entitlements.Requirements{{"bearer": {"pages:{id}:read"}}}},
+		{"bearer", "HEAD", "/pages", entitlements.Requirements{{"bearer": {"pages:read"}}}},
+		{"bearer", "OPTIONS", "/pages", entitlements.Requirements{{"bearer": {"pages:read"}}}},
+		{"bearer", "POST", "/pages", entitlements.Requirements{{"bearer": {"pages:create"}}}},
+		{"bearer", "PUT", "/pages/{id}", entitlements.Requirements{{"bearer": {"pages:{id}:update"}}}},
+		{"bearer", "PATCH", "/pages/{id}/title", entitlements.Requirements{{"bearer": {"pages:{id}:update"}}}},
+		{"oauth2", "DELETE", "/books/{isbn}", entitlements.Requirements{{"oauth2": {"books:{isbn}:delete"}}}},
+		{"bearer", "GET", "/pages/foo", entitlements.Requirements{{"bearer": {"pages:read"}}}},
+		{"bearer", "GET", "pages//{id}/", entitlements.Requirements{{"bearer": {"pages:{id}:read"}}}},
+		{"bearer", "PURGE", "/cache", entitlements.Requirements{{"bearer": {"cache:purge"}}}},
+		{"", "GET", "/pages", entitlements.Requirements{{"bearer": {"pages:read"}}}},
+		{"bearer", "GET", "/", nil},
+		{"bearer", "GET", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.want, entitlements.DefaultRequirementForRoute(tt.scheme, tt.method, tt.path))
+		})
+	}
+}
+
+func TestDefaultRequirementForRouteBinds(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	reqs := ec.ParseRequirements(entitlements.DefaultRequirementForRoute("bearer", "GET", "/pages/{id}"))
+	bound, err := ec.BindRequirements(reqs, entitlements.Binding{"id": "foo"})
+	assert.NoError(t, err)
+	held := ec.ParseEntitlements(entitlements.Entitlements{"bearer": {"pages:foo:read"}})
+	assert.True(t, ec.VerifyParsedEntitlements(held, bound))
+	other := ec.ParseEntitlements(entitlements.Entitlements{"bearer": {"pages:bar:read"}})
+	assert.False(t, ec.VerifyParsedEntitlements(other, bound))
+}
+
+func TestEntitlementsChecker_WithWildcardVerbByScheme(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).
+		WithWildcardVerbByScheme(map[string]string{"apikey": "*"})
+
+	tests := []struct {
+		name         string
+		entitlements entitlements.Entitlements
+		requirements entitlements.Requirements
+		want         bool
+	}{
+		{
+			name:         "star is the wildcard verb under apikey",
+			entitlements: entitlements.Entitlements{"apikey": {"pages:*"}},
+			requirements: entitlements.Requirements{{"apikey": {"pages:/foo:write"}}},
+			want:         true,
+		},
+		{
+			name:         "unlisted scheme keeps the default star",
+			entitlements: entitlements.Entitlements{"bearer": {"pages:*"}},
+			requirements: entitlements.Requirements{{"bearer": {"pages:/foo:write"}}},
+			want:         true,
+		},
+		{
+			name:         "literal star still matches a literal star requirement",
+			entitlements: entitlements.Entitlements{"bearer": {"pages:*"}},
+			requirements: entitlements.Requirements{{"bearer": {"pages:/foo:*"}}},
+			want:         true,
+		},
+		{
+			name:         "all is literal under apikey",
+			entitlements: entitlements.Entitlements{"apikey": {"pages:all"}},
+			requirements: entitlements.Requirements{{"apikey": {"pages:/foo:write"}}},
+			want:         false,
+		},
+		{
+			name:         "unlisted scheme falls back to all",
+			entitlements: entitlements.Entitlements{"bearer": {"pages:all"}},
+			requirements: entitlements.Requirements{{"bearer": {"pages:/foo:write"}}},
+			want:         true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ec.VerifyEntitlements(tt.entitlements, tt.requirements))
+		})
+	}
+}
+
+func TestEntitlementsChecker_WithWildcardVerbByScheme_DefaultScheme(t *testing.T) {
+	// Base entitlements are matched under the default scheme and so follow its
+	// wildcard verb.
+	ec := entitlements.NewEntitlementsChecker(nil, "apikey", false).
+		WithWildcardVerbByScheme(map[string]string{"apikey": "*"}).
+		WithBaseEntitlements([]string{"public:*"})
+	assert.True(t, ec.VerifyEntitlements(entitlements.Entitlements{}, entitlements.Requirements{{"apikey": {"public:/x:read"}}}))
+}
+
+func TestEntitlementsChecker_WithWildcardVerbs(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).
+		WithWildcardVerbs("all", "*").
+		WithWildcardVerbByScheme(map[string]string{"apikey": "manage"})
+
+	tests := []struct {
+		name     string
+		scheme   string
+		held     string
+		required string
+		want     bool
+	}{
+		{"star grants any verb", "bearer", "pages:*", "pages:read", true},
+		{"star grants on a name", "bearer", "pages:/foo:*", "pages:/foo:delete", true},
+		{"all still grants", "bearer", "pages:all", "pages:read", true},
+		{"star name wildcard still works", "bearer", "pages:*:read", "pages:/foo:read", true},
+		{"required star not met by narrower verb", "bearer", "pages:read", "pages:*", false},
+		{"required star met by star", "bearer", "pages:*", "pages:*", true},
+		{"scheme entry takes precedence", "apikey", "pages:manage", "pages:read", true},
+		{"star literal under scheme entry", "apikey", "pages:*", "pages:read", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ec.VerifyEntitlements(
+				entitlements.Entitlements{tt.scheme: {tt.held}},
+				entitlements.Requirements{{tt.scheme: {tt.required}}},
+			))
+		})
+	}
+
+	// Without the option, and with no verbs, "all" and "*" are both wildcards.
+	held := entitlements.Entitlements{"bearer": {"pages:*"}}
+	read := entitlements.Requirements{{"bearer": {"pages:read"}}}
+	assert.True(t, entitlements.NewEntitlementsChecker(nil, "bearer", false).VerifyEntitlements(held, read))
+	assert.True(t, ec.WithWildcardVerbs().VerifyEntitlements(held, read))
+	assert.True(t, ec.VerifyEntitlements(entitlements.Entitlements{"bearer": {"pages:all"}}, read))
+
+	// A set without "*" makes it a literal verb; legacy semantics do too.
+	assert.False(t, entitlements.NewEntitlementsChecker(nil, "bearer", false).
+		WithWildcardVerbs("all").VerifyEntitlements(held, read))
+	assert.False(t, entitlements.NewEntitlementsChecker(nil, "bearer", false).
+		WithWildcardVerbs("manage").VerifyEntitlements(entitlements.Entitlements{"bearer": {"pages:all"}}, read))
+	assert.False(t, entitlements.NewEntitlementsChecker(nil, "bearer", false).
+		WithLegacySemantics(true).VerifyEntitlements(held, read))
+}
+
+func TestEntitlementsChecker_WithNamedRequirements(t *testing.T) {
+	ec, err := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithNamedRequirements(map[string]entitlements.Requirements{
+		"reader":       {{"bearer": {"pages:/foo:read"}}},
+		"editorPolicy": {{"bearer": {"@reader", "pages:/foo:update"}}, {"bearer": {"pages:/foo:all"}}},
+		"auditor":      {{"oauth2": {"audit"}}},
+	})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		entitlements entitlements.Entitlements
+		requirements entitlements.Requirements
+		want         bool
+	}{
+		{
+			name:         "reference expands to the named requirement",
+			entitlements: entitlements.Entitlements{"bearer": {"pages:/foo:read", "pages:/foo:update"}},
+			requirements: entitlements.Requirements{{"bearer": {"@editorPolicy"}}},
+			want:         true,
+		},
+		{
+			name:         "any branch of the named requirement satisfies it",
+			entitlements: entitlements.Entitlements{"bearer": {"pages:/foo:all"}},
+			requirements: entitlements.Requirements{{"bearer": {"@editorPolicy"}}},
+			want:         true,
+		},
+		{
+			name:         "nested reference unmet",
+			entitlements: entitlements.Entitlements{"bearer": {"pages:/foo:update"}},
+			requirements: entitlements.Requirements{{"bearer": {"@editorPolicy"}}},
+			want:         false,
+		},
+		{
+			name:         "reference is ANDed with its siblings",
+			entitlements: entitlements.Entitlements{"bearer": {"pages:/foo:read"}},
+			requirements: entitlements.Requirements{{"bearer": {"@reader", "books:read"}}},
+			want:         false,
+		},
+		{
+			name:         "reference carries its own schemes",
+			entitlements: entitlements.Entitlements{"oauth2": {"audit"}},
+			requirements: entitlements.Requirements{{"bearer": {"@auditor"}}},
+			want:         true,
+		},
+		{
+			name:         "unregistered reference at verify time is unsatisfiable",
+			entitlements: entitlements.Entitlements{"bearer": {"@missing"}},
+			requirements: entitlements.Requirements{{"bearer": {"@missing"}}},
+			want:         false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ec.VerifyEntitlements(tt.entitlements, tt.requirements))
+		})
+	}
+}
+
+func TestEntitlementsChecker_WithNamedRequirements_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		named map[string]entitlements.Requirements
+		want  error
+	}{
+		{
+			name:  "undefined reference",
+			named: map[string]entitlements.Requirements{"a": {{"bearer": {"@b"}}}},
+			want:  entitlements.ErrUndefinedReference,
+		},
+		{
+			name:  "self cycle",
+			named: map[string]entitlements.Requirements{"a": {{"bearer": {"@a"}}}},
+			want:  entitlements.ErrReferenceCycle,
+		},
+		{
+			name: "indirect cycle",
+			named: map[string]entitlements.Requirements{
+				"a": {{"bearer": {"@b"}}},
+				"b": {{"bearer": {"pages:read"}}, {"bearer": {"@c"}}},
+				"c": {{"oauth2": {"@a"}}},
+			},
+			want: entitlements.ErrReferenceCycle,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithNamedRequirements(tt.named)
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+
+	// A shared (diamond) reference is not a cycle.
+	_, err := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithNamedRequirements(map[string]entitlements.Requirements{
+		"a": {{"bearer": {"@b", "@c"}}},
+		"b": {{"bearer": {"@d"}}},
+		"c": {{"bearer": {"@d"}}},
+		"d": {{"bearer": {"pages:read"}}},
+	})
+	assert.NoError(t, err)
+}
+
+func TestEntitlementsChecker_WithHTTPVerbAliases(t *testing.T) {
+	tests := []struct {
+		held, required string
+		want           bool
+	}{
+		{"pages:GET", "pages:/foo:read", true},
+		{"pages:read", "pages:/foo:GET", true},
+		{"pages:get", "pages:/foo:read", true},
+		{"pages:HEAD", "pages:/foo:read", true},
+		{"pages:OPTIONS", "pages:/foo:read", true},
+		{"pages:POST", "pages:/foo:create", true},
+		{"pages:create", "pages:/foo:post", true},
+		{"pages:PUT", "pages:/foo:update", true},
+		{"pages:PATCH", "pages:/foo:update", true},
+		{"pages:DELETE", "pages:/foo:delete", true},
+		{"pages:HEAD", "pages:/foo:GET", true},
+		{"pages:GET", "pages:/foo:create", false},
+		{"pages:POST", "pages:/foo:read", false},
+		{"pages:PURGE", "pages:/foo:purge", true},
+		{"pages:purge", "pages:/foo:PURGE", true},
+		{"pages:Purge", "pages:/foo:purge", false},
+		{"pages:PURGE", "pages:/foo:delete", false},
+		{"pages:all", "pages:/foo:GET", true},
+		{"pages:GET", "pages:/foo:all", false},
+		{"pages", "GET", false},
+	}
+	on := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithHTTPVerbAliases(true)
+	off := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	for _, tt := range tests {
+		t.Run(tt.held+" vs "+tt.required, func(t *testing.T) {
+			held := entitlements.Entitlements{"bearer": {tt.held}}
+			reqs := entitlements.Requirements{{"bearer": {tt.required}}}
+			assert.Equal(t, tt.want, on.VerifyEntitlements(held, reqs))
+		})
+	}
+
+	// Disabled, methods are ordinary verbs.
+	assert.False(t, off.VerifyEntitlements(
+		entitlements.Entitlements{"bearer": {"pages:GET"}},
+		entitlements.Requirements{{"bearer": {"pages:/foo:read"}}},
+	))
+}
+
+func TestEntitlementsChecker_MinimalSatisfyingEntitlements(t *testing.T) {
+	ec, err := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithNamedRequirements(map[string]entitlements.Requirements{
+		"reader": {{"bearer": {"pages:/foo:read", "books:read"}}, {"bearer": {"pages:/foo:all"}}},
+	})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		requirements entitlements.Requirements
+		want         entitlements.Entitlements
+	}{
+		{"no requirements", entitlements.Requirements{}, entitlements.Entitlements{}},
+		{
+			name: "fewest AND-entries wins",
+			requirements: entitlements.Requirements{
+				{"bearer": {"pages:/foo:read", "pages:/foo:update"}, "oauth2": {"scope1"}},
+				{"bearer": {"admin"}, "oauth2": {"scope2"}},
+				{"bearer": {"pages:/foo:read", "books:read", "files:read"}},
+			},
+			want: entitlements.Entitlements{"bearer": {"admin"}, "oauth2": {"scope2"}},
+		},
+		{
+			name: "ties go to the earliest branch",
+			requirements: entitlements.Requirements{
+				{"bearer": {"pages:/a:read"}},
+				{"bearer": {"pages:/b:read"}},
+			},
+			want: entitlements.Entitlements{"bearer": {"pages:/a:read"}},
+		},
+		{
+			name:         "wildcard and all requirements yield broad grants",
+			requirements: entitlements.Requirements{{"bearer": {"pages:read", "books:*:all"}}},
+			want:         entitlements.Entitlements{"bearer": {"pages:read", "books:*:all"}},
+		},
+		{
+			name:         "duplicate tokens are emitted once",
+			requirements: entitlements.Requirements{{"bearer": {"pages:read", "pages:read"}}},
+			want:         entitlements.Entitlements{"bearer": {"pages:read"}},
+		},
+		{
+			name:         "references expand to their minimal grants",
+			requirements: entitlements.Requirements{{"bearer": {"@reader", "books:/x:write"}}},
+			want:         entitlements.Entitlements{"bearer": {"pages:/foo:all", "books:/x:write"}},
+		},
+		{
+			name: "branches with unregistered references are skipped",
+			requirements: entitlements.Requirements{
+				{"bearer": {"@missing"}},
+				{"bearer": {"pages:/a:read", "pages:/b:read"}},
+			},
+			want: entitlements.Entitlements{"bearer": {"pages:/a:read", "pages:/b:read"}},
+		},
+		{
+			name:         "unsatisfiable requirements",
+			requirements: entitlements.Requirements{{"bearer": {"@missing"}}},
+			want:         nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ec.MinimalSatisfyingEntitlements(tt.requirements)
+			assert.Equal(t, tt.want, got)
+			if got != nil {
+				assert.True(t, ec.VerifyEntitlements(got, tt.requirements), "the result must satisfy the requirements")
+			}
+		})
+	}
+}
+
+func TestRequireWorkflow(t *testing.T) {
+	reqs := entitlements.RequireWorkflow("bearer", "orders", "o-1", "submit", "approve", "submit")
+	assert.Equal(t, entitlements.Requirements{{"bearer": {"orders:o-1:submit", "orders:o-1:approve"}}}, reqs)
+
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	tests := []struct {
+		name string
+		held []string
+		want bool
+	}{
+		{"every step granted", []string{"orders:o-1:submit", "orders:o-1:approve"}, true},
+		{"missing approve", []string{"orders:o-1:submit"}, false},
+		{"missing submit", []string{"orders:o-1:approve"}, false},
+		{"steps on another instance", []string{"orders:o-1:submit", "orders:o-2:approve"}, false},
+		{"class-wide grants cover the steps", []string{"orders:submit", "orders::approve"}, true},
+		{"all covers every step", []string{"orders:o-1:all"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ec.VerifyEntitlements(entitlements.Entitlements{"bearer": tt.held}, reqs))
+		})
+	}
+
+	assert.Equal(t, entitlements.Requirements{{"bearer": {"orders::submit"}}}, entitlements.RequireWorkflow("", "orders", "", "submit"))
+	assert.Nil(t, entitlements.RequireWorkflow("bearer", "orders", "o-1"))
+}
+
+func TestEntitlementsChecker_WithDisabledSchemes(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker([]string{"public:read"}, "bearer", false).
+		WithDisabledSchemes("apikey")
+
+	tests := []struct {
+		name         string
+		entitlements entitlements.Entitlements
+		requirements entitlements.Requirements
+		want         bool
+	}{
+		{
+			name:         "disabled scheme no longer satisfies its requirement",
+			entitlements: entitlements.Entitlements{"apikey": {"pages:read"}},
+			requirements: entitlements.Requirements{{"apikey": {"pages:/foo:read"}}},
+			want:         false,
+		},
+		{
+			name:         "disabled scheme fails its AND",
+			entitlements: entitlements.Entitlements{"bearer": {"pages:read"}, "apikey": {"books:read"}},
+			requirements: entitlements.Requirements{{"bearer": {"pages:/foo:read"}, "apikey": {"books:/x:read"}}},
+			want:         false,
+		},
+		{
+			name:         "enabled scheme is unaffected",
+			entitlements: entitlements.Entitlements{"bearer": {"pages:read"}, "apikey": {"books:read"}},
+			requirements: entitlements.Requirements{{"bearer": {"pages:/foo:read"}}},
+			want:         true,
+		},
+		{
+			name:         "caller holding only a disabled scheme is anonymous",
+			entitlements: entitlements.Entitlements{"apikey": {"pages:read"}},
+			requirements: entitlements.Requirements{{"bearer": {"public:/x:read"}}},
+			want:         true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ec.VerifyEntitlements(tt.entitlements, tt.requirements))
+		})
+	}
+
+	// Re-enabling restores the grants; the caller's data was never touched.
+	held := entitlements.Entitlements{"apikey": {"pages:read"}}
+	reqs := entitlements.Requirements{{"apikey": {"pages:/foo:read"}}}
+	assert.False(t, ec.VerifyEntitlements(held, reqs))
+	assert.True(t, ec.WithDisabledSchemes().VerifyEntitlements(held, reqs))
+	assert.Equal(t, entitlements.Entitlements{"apikey": {"pages:read"}}, held)
+}
+
+func TestEntitlementsChecker_WithDisabledSchemes_Resource(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithDisabledSchemes("bearer")
+	ok, err := ec.VerifyResourceEntitlements("pages", "/foo", entitlements.Entitlements{"bearer": {"pages:all"}}, nil)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestEntitlementsChecker_WithResourceNameGlob(t *testing.T) {
+	tests := []struct {
+		name     string
+		held     string
+		required string
+		glob     bool
+		want     bool
+	}{
+		{"overlapping globs", "pages:/foo*:read", "pages:/foobar*:read", true, true},
+		{"disjoint globs", "pages:/foo*:read", "pages:/bar*:read", true, false},
+		{"held glob matches literal", "pages:/foo*:read", "pages:/foobar:read", true, true},
+		{"requirement glob matches literal grant", "pages:/foobar:read", "pages:/foo*:read", true, true},
+		{"glob respects verb", "pages:/foo*:read", "pages:/foobar:write", true, false},
+		{"whole star still crosses segments", "pages:*:read", "pages:/a/b:read", true, true},
+		{"globs are literal when disabled", "pages:/foo*:read", "pages:/foobar:read", false, false},
+		{"literal star matches itself when disabled", "pages:/foo*:read", "pages:/foo*:read", false, true},
+		{"extension glob", "files:/img/*.png:read", "files:/img/logo.png:read", true, true},
+		{"extension glob rejects other extension", "files:/img/*.png:read", "files:/img/logo.jpg:read", true, false},
+		{"star does not cross segments", "files:/img/*.png:read", "files:/img/icons/logo.png:read", true, false},
+		{"question mark matches one character", "files:/img/?.png:read", "files:/img/a.png:read", true, true},
+		{"question mark rejects two characters", "files:/img/?.png:read", "files:/img/ab.png:read", true, false},
+		{"question mark does not match separator", "files:/img?a.png:read", "files:/img/a.png:read", true, false},
+		{"escaped star is literal", `files:/img/\*.png:read`, "files:/img/logo.png:read", true, false},
+		{"escaped star matches escaped star", `files:/img/\*.png:read`, `files:/img/\*.png:read`, true, true},
+		{"escaped question mark is literal", `files:/img/\?.png:read`, "files:/img/a.png:read", true, false},
+		{"extension glob is literal when disabled", "files:/img/*.png:read", "files:/img/logo.png:read", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithResourceNameGlob(tt.glob)
+			got := ec.VerifyEntitlements(
+				entitlements.Entitlements{"bearer": {tt.held}},
+				entitlements.Requirements{{"bearer": {tt.required}}},
+			)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEntitlementsChecker_WithRegion(t *testing.T) {
+	tests := []struct {
+		name     string
+		region   string
+		held     string
+		required string
+		want     bool
+	}{
+		{"tagged grant in its region", "eu", "pages:/foo:read@region=eu", "pages:/foo:read", true},
+		{"tagged grant in another region", "us", "pages:/foo:read@region=eu", "pages:/foo:read", false},
+		{"tagged grant under a region-less checker", "", "pages:/foo:read@region=eu", "pages:/foo:read", false},
+		{"untagged grant in any region", "us", "pages:/foo:read", "pages:/foo:read", true},
+		{"tagged wildcard grant", "eu", "pages:all@region=eu", "pages:/foo:write", true},
+		{"tagged opaque grant", "eu", "email@region=eu", "email", true},
+		{"tagged opaque grant in another region", "us", "email@region=eu", "email", false},
+		{"requirement tag has no effect", "us", "pages:/foo:read", "pages:/foo:read@region=eu", true},
+		{"identical tagged strings still honor the region", "us", "email@region=eu", "email@region=eu", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithRegion(tt.region)
+			got := ec.VerifyEntitlements(
+				entitlements.Entitlements{"bearer": {tt.held}},
+				entitlements.Requirements{{"bearer": {tt.required}}},
+			)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEntitlementsChecker_WithRegion_BaseEntitlements(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).
+		WithRegion("us").
+		WithBaseEntitlements([]string{"public:read@region=eu"})
+	assert.False(t, ec.VerifyEntitlements(entitlements.Entitlements{}, entitlements.Requirements{{"bearer": {"public:/x:read"}}}))
+}
+
+func TestEntitlementsChecker_WithDefaultSchemeFallback(t *testing.T) {
+	tests := []struct {
+		name         string
+		fallback     bool
+		entitlements entitlements.Entitlements
+		requirements entitlements.Requirements
+		want         bool
+	}{
+		{
+			name:         "fallback hits the default scheme",
+			fallback:     true,
+			entitlements: entitlements.Entitlements{"bearer": {"pages:read"}},
+			requirements: entitlements.Requirements{{"oauth2": {"pages:/foo:read"}}},
+			want:         true,
+		},
+		{
+			name:         "fallback misses when the default scheme lacks the grant",
+			fallback:     true,
+			entitlements: entitlements.Entitlements{"bearer": {"books:read"}},
+			requirements: entitlements.Requirements{{"oauth2": {"pages:/foo:read"}}},
+			want:         false,
+		},
+		{
+			name:         "held scheme is never substituted",
+			fallback:     true,
+			entitlements: entitlements.Entitlements{"bearer": {"pages:read"}, "oauth2": {"books:read"}},
+			requirements: entitlements.Requirements{{"oauth2": {"pages:/foo:read"}}},
+			want:         false,
+		},
+		{
+			name:         "disabled by default",
+			entitlements: entitlements.Entitlements{"bearer": {"pages:read"}},
+			requirements: entitlements.Requirements{{"oauth2": {"pages:/foo:read"}}},
+			want:         false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithDefaultSchemeFallback(tt.fallback)
+			assert.Equal(t, tt.want, ec.VerifyEntitlements(tt.entitlements, tt.requirements))
+		})
+	}
+
+	// The fallback reaches the base entitlements of the default scheme too.
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).
+		WithDefaultSchemeFallback(true).
+		WithBaseEntitlements([]string{"public:read"})
+	assert.True(t, ec.VerifyEntitlements(
+		entitlements.Entitlements{"apikey": {"x"}},
+		entitlements.Requirements{{"oauth2": {"public:/x:read"}}},
+	))
+}
+
+func TestEntitlementsChecker_WithSchemeWildcard(t *testing.T) {
+	tests := []struct {
+		name         string
+		entitlements entitlements.Entitlements
+		requirements entitlements.Requirements
+		want         bool
+	}{
+		{
+			name:         "bearer entitlement satisfies an oauth2 requirement",
+			entitlements: entitlements.Entitlements{"bearer": {"pages:read"}},
+			requirements: entitlements.Requirements{{"oauth2": {"pages:/foo:read"}}},
+			want:         true,
+		},
+		{
+			name:         "any scheme, not only the default",
+			entitlements: entitlements.Entitlements{"synthetic": {"pages:read"}},
+			requirements: entitlements.Requirements{{"oauth2": {"pages:/foo:read"}}},
+			want:         true,
+		},
+		{
+			name:         "tokens may be found under different schemes",
+			entitlements: entitlements.Entitlements{"a": {"pages:read"}, "b": {"books:read"}},
+			requirements: entitlements.Requirements{{"oauth2": {"pages:/foo:read", "books:/x:read"}}},
+			want:         true,
+		},
+		{
+			name:         "misses when no scheme holds the grant",
+			entitlements: entitlements.Entitlements{"bearer": {"books:read"}},
+			requirements: entitlements.Requirements{{"oauth2": {"pages:/foo:read"}}},
+			want:         false,
+		},
+		{
+			name:         "held scheme is never searched past",
+			entitlements: entitlements.Entitlements{"bearer": {"pages:read"}, "oauth2": {"books:read"}},
+			requirements: entitlements.Requirements{{"oauth2": {"pages:/foo:read"}}},
+			want:         false,
+		},
+		{
+			name:         "scheme listed with no tokens is still missing",
+			entitlements: entitlements.Entitlements{"bearer": {"pages:read"}},
+			requirements: entitlements.Requirements{{"oauth2": {}}},
+			want:         false,
+		},
+		{
+			name:         "except condition holds under no scheme",
+			entitlements: entitlements.Entitlements{"bearer": {"pages:read"}, "other": {"banned"}},
+			requirements: entitlements.Requirements{{"oauth2": {"pages:/foo:read", "!banned"}}},
+			want:         false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithSchemeWildcard(true)
+			assert.Equal(t, tt.want, ec.VerifyEntitlements(tt.entitlements, tt.requirements))
+			assert.Equal(t, tt.want, ec.PrepareFor(tt.requirements).Check(tt.entitlements), "prepared")
+			assert.Equal(t, tt.want, ec.Explain(tt.entitlements, tt.requirements).Allowed, "explain")
+
+			off := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+			assert.False(t, off.VerifyEntitlements(tt.entitlements, tt.requirements), "disabled by default")
+		})
+	}
+
+	t.Run("explain attributes the scheme found under", func(t *testing.T) {
+		ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithSchemeWildcard(true)
+		d := ec.Explain(
+			entitlements.Entitlements{"synthetic": {"pages:read"}},
+			entitlements.Requirements{{"oauth2": {"pages:/foo:read"}}})
+		assert.Equal(t, []entitlements.Grant{
+			{Scheme: "synthetic", Requirement: "pages:/foo:read", Entitlement: "pages:read", Source: entitlements.GrantSourceDirect},
+		}, d.Grants)
+	})
+
+	t.Run("audit reports unmet tokens, not a missing scheme", func(t *testing.T) {
+		var records []entitlements.DenyRecord
+		ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).
+			WithSchemeWildcard(true).
+			WithDenyAuditTrail(func(r entitlements.DenyRecord) { records = append(records, r) })
+		assert.False(t, ec.VerifyEntitlements(
+			entitlements.Entitlements{"synthetic": {"pages:/bar:read"}},
+			entitlements.Requirements{{"oauth2": {"pages:/foo:read"}}}))
+		assert.Len(t, records, 1)
+		assert.Empty(t, records[0].Branches[0].MissingSchemes)
+		assert.Equal(t, []entitlements.UnmetRequirement{
+			{Scheme: "oauth2", Requirement: "pages:/foo:read", NearMiss: "pages:/bar:read"},
+		}, records[0].Unmet())
+	})
+}
+
+func TestEntitlementsChecker_AccessReport(t *testing.T) {
+	catalog := []entitlements.ResourceVerb{
+		{Resource: "pages", ResourceName: "/foo", Verb: "read"},
+		{Resource: "books", ResourceName: "/x", Verb: "read"},
+		{Resource: "pages", ResourceName: "/foo", Verb: "delete"},
+		{Resource: "public", Verb: "read"},
+	}
+	ec := entitlements.NewEntitlementsChecker([]string{"books:read"}, "bearer", false).
+		WithBaseEntitlements([]string{"public:read"})
+
+	report := ec.AccessReport(entitlements.Entitlements{"oauth2": {"pages:/foo:all"}}, catalog)
+	assert.Equal(t, entitlements.Report{Resources: []entitlements.ResourceReport{
+		{Resource: "pages", Entries: []entitlements.ReportEntry{
+			{ResourceVerb: catalog[0], Granted: true, Scheme: "oauth2", Entitlement: "pages:/foo:all", Source: entitlements.GrantSourceDirect},
+			{ResourceVerb: catalog[2], Granted: true, Scheme: "oauth2", Entitlement: "pages:/foo:all", Source: entitlements.GrantSourceDirect},
+		}},
+		{Resource: "books", Entries: []entitlements.ReportEntry{
+			{ResourceVerb: catalog[1]},
+		}},
+		{Resource: "public", Entries: []entitlements.ReportEntry{
+			{ResourceVerb: catalog[3], Granted: true, Scheme: "bearer", Entitlement: "public:read", Source: entitlements.GrantSourceBase},
+		}},
+	}}, report)
+
+	// An anonymous caller is attributed the anonymous grant.
+	anon := ec.AccessReport(entitlements.Entitlements{}, catalog)
+	books := anon.Resources[1].Entries[0]
+	assert.True(t, books.Granted)
+	assert.Equal(t, entitlements.GrantSourceAnonymous, books.Source)
+	assert.Equal(t, "books:read", books.Entitlement)
+	assert.False(t, anon.Resources[0].Entries[0].Granted)
+
+	assert.Equal(t, "pages\n"+
+		"  pages:/foo:read    granted by oauth2 \"pages:/foo:all\" (direct)\n"+
+		"  pages:/foo:delete  granted by oauth2 \"pages:/foo:all\" (direct)\n"+
+		"books\n"+
+		"  books:/x:read      denied\n"+
+		"public\n"+
+		"  public::read       granted by bearer \"public:read\" (base)\n",
+		report.String())
+}
+
+func TestEntitlementsChecker_WithVerbGroups(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithVerbGroups(map[string][]string{
+		"read*":  {"read", "readMeta", "readAudit"},
+		"write*": {"create", "update"},
+	})
+
+	tests := []struct {
+		held, required string
+		want           bool
+	}{
+		{"pages:readAudit", "pages:/foo:read*", true},
+		{"pages:read", "pages:/foo:read*", true},
+		{"pages:read*", "pages:/foo:readMeta", true},
+		{"pages:read*", "pages:/foo:read*", true},
+		{"pages:read*", "pages:/foo:create", false},
+		{"pages:update", "pages:/foo:read*", false},
+		{"pages:reader", "pages:/foo:read*", false},
+		{"pages:read*", "pages:/foo:reader", false},
+		{"pages:readMeta", "pages:/foo:readAudit", false},
+		{"pages:read*", "pages:/foo:write*", false},
+		{"books:read*", "pages:/foo:read", false},
+		{"pages:all", "pages:/foo:read*", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.held+" vs "+tt.required, func(t *testing.T) {
+			got := ec.VerifyEntitlements(
+				entitlements.Entitlements{"bearer": {tt.held}},
+				entitlements.Requirements{{"bearer": {tt.required}}},
+			)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	// Without groups, a group name is an ordinary verb.
+	plain := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	assert.False(t, plain.VerifyEntitlements(
+		entitlements.Entitlements{"bearer": {"pages:read*"}},
+		entitlements.Requirements{{"bearer": {"pages:/foo:read"}}},
+	))
+}
+
+// TestEntitlementsChecker_LargeRequirements guards the documented
+// O(branches × tokens × entitlements) bound of VerifyEntitlements against an
+// accidental super-linear regression, using a large-but-legal input in which
+// no branch is satisfied so every one is evaluated.
+func TestEntitlementsChecker_LargeRequirements(t *testing.T) {
+	const (
+		branches        = 5000
+		schemesPerSet   = 5
+		tokensPerScheme = 10
+		heldPerScheme   = 100
+	)
+	schemes := []string{"bearer", "oauth2", "apikey", "mtls", "session"}
+
+	held := entitlements.Entitlements{}
+	for _, scheme := range schemes {
+		for i := range heldPerScheme {
+			held[scheme] = append(held[scheme], fmt.Sprintf("res%d:/name%d:read", i, i))
+		}
+	}
+
+	reqs := make(entitlements.Requirements, branches)
+	for b := range branches {
+		set := make(map[string][]string, schemesPerSet)
+		for _, scheme := range schemes[:schemesPerSet] {
+			for i := range tokensPerScheme {
+				// Every token is held except the last of each branch, so each
+				// branch does its full share of matching before failing.
+				token := fmt.Sprintf("res%d:/name%d:read", i, i)
+				if scheme == schemes[schemesPerSet-1] && i == tokensPerScheme-1 {
+					token = fmt.Sprintf("missing%d:/name:read", b)
+				}
+				set[scheme] = append(set[scheme], token)
+			}
+		}
+		reqs[b] = set
+	}
+
+	ec := entitlements.NewEntitlementsChecker([]string{"public:read"}, "bearer", false).
+		WithBaseEntitlements([]string{"base:read"})
+
+	start := time.Now()
+	assert.False(t, ec.VerifyEntitlements(held, reqs))
+	// Generous enough for a loaded CI runner; a quadratic regression in any
+	// dimension blows well past it.
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestIsSubsetEntitlements(t *testing.T) {
+	super := entitlements.Entitlements{
+		"bearer": {"pages::all", "books:/x:read"},
+		"oauth2": {"scope1"},
+	}
+	tests := []struct {
+		name string
+		sub  entitlements.Entitlements
+		want bool
+	}{
+		{"empty", entitlements.Entitlements{}, true},
+		{"nil", nil, true},
+		{"identical", super, true},
+		{"specific under wildcard", entitlements.Entitlements{"bearer": {"pages:/foo:read"}}, true},
+		{"verb under all", entitlements.Entitlements{"bearer": {"pages:write"}}, true},
+		{"narrowed across schemes", entitlements.Entitlements{"bearer": {"books:/x:read"}, "oauth2": {"scope1"}}, true},
+		{"wildcard over specific escalates", entitlements.Entitlements{"bearer": {"books:read"}}, false},
+		{"all over specific verb escalates", entitlements.Entitlements{"bearer": {"books:/x:all"}}, false},
+		{"new resource escalates", entitlements.Entitlements{"bearer": {"files:/a:read"}}, false},
+		{"new opaque scope escalates", entitlements.Entitlements{"oauth2": {"scope2"}}, false},
+		{"grant moved to another scheme escalates", entitlements.Entitlements{"oauth2": {"books:/x:read"}}, false},
+		{"empty list under missing scheme", entitlements.Entitlements{"apikey": {}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, entitlements.IsSubsetEntitlements(tt.sub, super))
+		})
+	}
+
+	// Implication runs one way: "pages:all" implies "pages:read", under each
+	// scheme, but not the other way round.
+	all := entitlements.Entitlements{"bearer": {"pages:all"}, "oauth2": {"books:all"}}
+	read := entitlements.Entitlements{"bearer": {"pages:read"}, "oauth2": {"books:read"}}
+	assert.True(t, entitlements.IsSubsetEntitlements(read, all))
+	assert.False(t, entitlements.IsSubsetEntitlements(all, read))
+	assert.False(t, entitlements.IsSubsetEntitlements(read,
+		entitlements.Entitlements{"bearer": {"pages:all"}, "oauth2": {"books:read:x"}}))
+}
+
+func TestEntitlementsChecker_VerifyEntitlementsUsingSchemes(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker([]string{"public:read"}, "bearer", false).
+		WithBaseEntitlements([]string{"health:read"})
+
+	held := entitlements.Entitlements{
+		"bearer": {"pages:read"},
+		"mfa":    {"payments:approve"},
+	}
+	tests := []struct {
+		name         string
+		entitlements entitlements.Entitlements
+		requirements entitlements.Requirements
+		schemes      []string
+		want         bool
+	}{
+		{"fresh scheme satisfies", held, entitlements.Requirements{{"mfa": {"payments:approve"}}}, []string{"mfa"}, true},
+		{"older scheme ignored", held, entitlements.Requirements{{"bearer": {"pages:read"}}}, []string{"mfa"}, false},
+		{"AND across schemes needs both", held, entitlements.Requirements{{"bearer": {"pages:read"}, "mfa": {"payments:approve"}}}, []string{"mfa"}, false},
+		{"both schemes listed", held, entitlements.Requirements{{"bearer": {"pages:read"}, "mfa": {"payments:approve"}}}, []string{"bearer", "mfa"}, true},
+		{"base applies with default scheme", held, entitlements.Requirements{{"bearer": {"health:read"}}}, []string{"bearer"}, true},
+		{"base dropped without default scheme", entitlements.Entitlements{"mfa": {"x"}}, entitlements.Requirements{{"bearer": {"health:read"}}}, []string{"mfa"}, false},
+		{"anonymous applies with default scheme", entitlements.Entitlements{}, entitlements.Requirements{{"bearer": {"public:read"}}}, []string{"bearer"}, true},
+		{"restriction does not make caller anonymous", held, entitlements.Requirements{{"bearer": {"public:read"}}}, []string{"bearer", "other"}, false},
+		{"no schemes", held, entitlements.Requirements{{"mfa": {"payments:approve"}}}, nil, false},
+		{"empty requirements", held, entitlements.Requirements{}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ec.VerifyEntitlementsUsingSchemes(tt.entitlements, tt.requirements, tt.schemes))
+		})
+	}
+
+	// Restricting schemes flips a decision the full check allows.
+	reqs := entitlements.Requirements{{"bearer": {"pages:read"}}}
+	assert.True(t, ec.VerifyEntitlements(held, reqs))
+	assert.False(t, ec.VerifyEntitlementsUsingSchemes(held, reqs, []string{"mfa"}))
+}
+
+func TestEntitlementsChecker_VerifyEntitlementsWithExpiry(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker([]string{"public:read"}, "bearer", false)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	held := entitlements.Entitlements{
+		"bearer": {"pages:read", "books:read", "files:read"},
+	}
+	expiries := map[string]time.Time{
+		"pages:read": now.Add(-time.Minute), // expired
+		"books:read": now.Add(time.Minute),  // valid
+		"files:read": now,                   // expires exactly now
+	}
+	tests := []struct {
+		name         string
+		entitlements entitlements.Entitlements
+		requirement  string
+		want         bool
+	}{
+		{"expired ignored", held, "pages:read", false},
+		{"valid kept", held, "books:read", true},
+		{"expiry at now is expired", held, "files:read", false},
+		{"no entry never expires", entitlements.Entitlements{"bearer": {"users:read"}}, "users:read", true},
+		{"all expired is anonymous", entitlements.Entitlements{"bearer": {"pages:read"}}, "public:read", true},
+		{"valid caller not anonymous", held, "public:read", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reqs := entitlements.Requirements{{"bearer": {tt.requirement}}}
+			assert.Equal(t, tt.want, ec.VerifyEntitlementsWithExpiry(tt.entitlements, expiries, reqs, now))
+		})
+	}
+}
+
+func TestEntitlementsChecker_VerifyCoversAll(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	held := entitlements.Entitlements{
+		"bearer": {"pages:read", "books:/a:read", "books:/b:read"},
+		"oauth2": {"export"},
+	}
+	tests := []struct {
+		name     string
+		scheme   string
+		required []string
+		want     bool
+	}{
+		{"all covered", "bearer", []string{"pages:/x:read", "books:/a:read", "books:/b:read"}, true},
+		{"one uncovered denies", "bearer", []string{"books:/a:read", "books:/b:read", "books:/c:read"}, false},
+		{"empty scheme is default", "", []string{"books:/a:read"}, true},
+		{"other scheme", "oauth2", []string{"export"}, true},
+		{"other scheme uncovered", "oauth2", []string{"export", "import"}, false},
+		{"missing scheme", "apikey", []string{"export"}, false},
+		{"empty set", "apikey", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ec.VerifyCoversAll(held, tt.scheme, tt.required))
+		})
+	}
+}
+
+func TestEntitlementsChecker_WithRequirementAllMeansAny(t *testing.T) {
+	tests := []struct {
+		name        string
+		held        string
+		requirement string
+		strict      bool
+		anyVerb     bool
+	}{
+		{"all grants all", "books:all", "books:all", true, true},
+		{"specific verb", "books:read", "books:all", false, true},
+		{"specific verb on a named requirement", "books:read", "books:/x:all", false, true},
+		{"named grant on its own name", "books:/x:write", "books:/x:all", false, true},
+		{"named grant on another name", "books:/y:write", "books:/x:all", false, false},
+		{"other resource", "pages:read", "books:all", false, false},
+		{"opaque", "books", "books:all", false, false},
+		{"specific requirement unchanged", "books:read", "books:write", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			held := entitlements.Entitlements{"bearer": {tt.held}}
+			reqs := entitlements.Requirements{{"bearer": {tt.requirement}}}
+
+			ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+			assert.Equal(t, tt.strict, ec.VerifyEntitlements(held, reqs), "default")
+			assert.Equal(t, tt.strict, ec.PrepareFor(reqs).Check(held), "default prepared")
+
+			ec = entitlements.NewEntitlementsChecker(nil, "bearer", false).WithRequirementAllMeansAny(true)
+			assert.Equal(t, tt.anyVerb, ec.VerifyEntitlements(held, reqs), "enabled")
+			assert.Equal(t, tt.anyVerb, ec.PrepareFor(reqs).Check(held), "enabled prepared")
+		})
+	}
+
+	t.Run("follows scheme wildcard verb", func(t *testing.T) {
+		ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).
+			WithRequirementAllMeansAny(true).
+			WithWildcardVerbByScheme(map[string]string{"apikey": "*"})
+		held := entitlements.Entitlements{"apikey": {"books:read"}}
+		assert.True(t, ec.VerifyEntitlements(held, entitlements.Requirements{{"apikey": {"books:*"}}}))
+		assert.False(t, ec.VerifyEntitlements(held, entitlements.Requirements{{"apikey": {"books:all"}}}))
+	})
+
+	t.Run("legacy semantics override", func(t *testing.T) {
+		ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).
+			WithRequirementAllMeansAny(true).
+			WithLegacySemantics(true)
+		assert.False(t, ec.VerifyEntitlements(
+			entitlements.Entitlements{"bearer": {"books:read"}},
+			entitlements.Requirements{{"bearer": {"books:all"}}}))
+	})
+}
+
+func TestEntitlementsChecker_WithBareTokenVerbByScheme(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).
+		WithBareTokenVerbByScheme(map[string]string{"bearer": "read"})
+
+	tests := []struct {
+		name        string
+		scheme      string
+		held        string
+		requirement string
+		expected    bool
+	}{
+		{"bearer bare token grants read", "bearer", "pages", "pages:/x:read", true},
+		{"bearer bare token grants class-wide read", "bearer", "pages", "pages:read", true},
+		{"bearer bare token grants no other verb", "bearer", "pages", "pages:/x:write", false},
+		{"bearer bare token grants no other resource", "bearer", "pages", "books:/x:read", false},
+		{"bearer bare token still matches itself", "bearer", "pages", "pages", true},
+		{"bearer bare wildcard covers every resource", "bearer", "*", "books:/x:read", true},
+		{"bearer bare requirement stays opaque", "bearer", "pages:read", "pages", false},
+		{"bearer deny is not bare", "bearer", "!pages", "pages:/x:read", false},
+		{"api-key bare token is opaque", "api-key", "pages", "pages:/x:read", false},
+		{"api-key bare token matches itself", "api-key", "pages", "pages", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			held := entitlements.Entitlements{tt.scheme: {tt.held}}
+			reqs := entitlements.Requirements{{tt.scheme: {tt.requirement}}}
+			assert.Equal(t, tt.expected, ec.VerifyEntitlements(held, reqs))
+			assert.Equal(t, tt.expected, ec.PrepareFor(reqs).Check(held), "prepared")
+		})
+	}
+
+	t.Run("default keeps bare tokens opaque", func(t *testing.T) {
+		ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+		assert.False(t, ec.VerifyEntitlements(
+			entitlements.Entitlements{"bearer": {"pages"}},
+			entitlements.Requirements{{"bearer": {"pages:/x:read"}}}))
+	})
+
+	t.Run("granted resource names", func(t *testing.T) {
+		names := entitlements.ResourceNameList{"/a", "/b"}
+		held := entitlements.Entitlements{"bearer": {"pages"}, "api-key": {"pages"}}
+		assert.Equal(t, []string{"/a", "/b"}, ec.GrantedResourceNames(held, "bearer", "pages", "read", names))
+		assert.Empty(t, ec.GrantedResourceNames(held, "api-key", "pages", "read", names))
+	})
+}
+
+func TestEntitlementsChecker_WithAllCoversOpaque(t *testing.T) {
+	tests := []struct {
+		name        string
+		held        string
+		requirement string
+		strict      bool
+		enabled     bool
+	}{
+		{"short all covers opaque", "books:all", "books", false, true},
+		{"medium all covers opaque", "books::all", "books", false, true},
+		{"explicit wildcard all covers opaque", "books:*:all", "books", false, true},
+		{"instance all does not cover opaque", "books:/x:all", "books", false, false},
+		{"specific verb does not cover opaque", "books:read", "books", false, false},
+		{"other resource does not cover opaque", "pages:all", "books", false, false},
+		{"opaque does not cover structured", "books", "books:read", false, false},
+		{"identical opaque", "books", "books", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			held := entitlements.Entitlements{"bearer": {tt.held}}
+			reqs := entitlements.Requirements{{"bearer": {tt.requirement}}}
+
+			ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+			assert.Equal(t, tt.strict, ec.VerifyEntitlements(held, reqs), "default")
+
+			ec = entitlements.NewEntitlementsChecker(nil, "bearer", false).WithAllCoversOpaque(true)
+			assert.Equal(t, tt.enabled, ec.VerifyEntitlements(held, reqs), "enabled")
+		})
+	}
+
+	t.Run("follows scheme wildcard verb", func(t *testing.T) {
+		ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).
+			WithAllCoversOpaque(true).
+			WithWildcardVerbByScheme(map[string]string{"apikey": "*"})
+		reqs := entitlements.Requirements{{"apikey": {"books"}}}
+		assert.True(t, ec.VerifyEntitlements(entitlements.Entitlements{"apikey": {"books:*"}}, reqs))
+		assert.False(t, ec.VerifyEntitlements(entitlements.Entitlements{"apikey": {"books:all"}}, reqs))
+	})
+}
+
+func TestRequirementsFromTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		tag     string
+		want    entitlements.Requirements
+		wantErr string
+	}{
+		{"blank", "  ", nil, ""},
+		{"single token", "bearer=pages:read", entitlements.Requirements{{"bearer": {"pages:read"}}}, ""},
+		{"token list", "bearer=pages:read,books:/x:update", entitlements.Requirements{{"bearer": {"pages:read", "books:/x:update"}}}, ""},
+		{"AND across schemes", "bearer=pages:read  oauth2=scope1", entitlements.Requirements{{"bearer": {"pages:read"}, "oauth2": {"scope1"}}}, ""},
+		{"OR branches", "bearer=pages:{id}:delete | bearer=admin:all", entitlements.Requirements{{"bearer": {"pages:{id}:delete"}}, {"bearer": {"admin:all"}}}, ""},
+		{"repeated scheme accumulates", "bearer=a bearer=b", entitlements.Requirements{{"bearer": {"a", "b"}}}, ""},
+		{"scheme presence only", "mtls=", entitlements.Requirements{{"mtls": {}}}, ""},
+		{"reference", "bearer=@editorPolicy", entitlements.Requirements{{"bearer": {"@editorPolicy"}}}, ""},
+		{"empty branch", "bearer=a |", nil, "branch 2 is empty"},
+		{"missing scheme", "pages:read", nil, `clause "pages:read" has no scheme`},
+		{"empty scheme", "=pages:read", nil, `invalid scheme ""`},
+		{"scheme with colon", "pages:read=x", nil, `invalid scheme "pages:read"`},
+		{"empty token", "bearer=a,,b", nil, "empty token"},
+		{"trailing comma", "bearer=a,", nil, "empty token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := entitlements.RequirementsFromTag(tt.tag)
+			if tt.wantErr != "" {
+				assert.ErrorIs(t, err, entitlements.ErrInvalidTag)
+				assert.ErrorContains(t, err, tt.wantErr)
+				assert.Nil(t, got)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEntitlementsChecker_Except(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker([]string{"public:read"}, "bearer", false).
+		WithBaseEntitlements([]string{"health:read"})
+
+	active := entitlements.Entitlements{"bearer": {"pages:read", "admin:all"}}
+	suspended := entitlements.Entitlements{"bearer": {"pages:read", "admin:all", "suspended"}}
+
+	readOrAdmin := entitlements.Requirements{
+		{"bearer": {"pages:read"}},
+		{"bearer": {"admin:all"}},
+	}
+	tests := []struct {
+		name         string
+		entitlements entitlements.Entitlements
+		requirements entitlements.Requirements
+		want         bool
+	}{
+		{"everyone except: active", active, entitlements.Requirements{}.Except("bearer", "suspended"), true},
+		{"everyone except: suspended", suspended, entitlements.Requirements{}.Except("bearer", "suspended"), false},
+		{"everyone except: anonymous", entitlements.Entitlements{}, entitlements.Requirements{}.Except("bearer", "suspended"), true},
+		{"everyone except: other scheme only", entitlements.Entitlements{"oauth2": {"x"}}, entitlements.Requirements{}.Except("", "suspended"), true},
+		{"Except applies to every branch: active", active, readOrAdmin.Except("bearer", "suspended"), true},
+		{"Except applies to every branch: suspended", suspended, readOrAdmin.Except("bearer", "suspended"), false},
+		{"except in one branch: other branch still passes", suspended, entitlements.Requirements{
+			{"bearer": {"pages:read", "!suspended"}},
+			{"bearer": {"admin:all"}},
+		}, true},
+		{"except in one branch: only that branch gated", suspended, entitlements.Requirements{
+			{"bearer": {"pages:read", "!suspended"}},
+			{"bearer": {"books:read"}},
+		}, false},
+		{"wildcard condition", active, entitlements.Requirements{{"bearer": {"pages:/x:read", "!admin:/x:delete"}}}, false},
+		{"base entitlement counts", active, entitlements.Requirements{{"bearer": {"!health:read"}}}, false},
+		{"anonymous entitlement counts", entitlements.Entitlements{}, entitlements.Requirements{{"bearer": {"!public:read"}}}, false},
+		{"negated reference fails closed", active, entitlements.Requirements{{"bearer": {"!@policy"}}}, false},
+		{"unbound placeholder fails closed", active, entitlements.Requirements{{"bearer": {"!pages:{id}:read"}}}, false},
+		{"held except token is literal", entitlements.Entitlements{"bearer": {"!pages:read"}}, entitlements.Requirements{{"bearer": {"pages:read"}}}, false},
+		{"bare prefix is opaque", entitlements.Entitlements{"bearer": {"!"}}, entitlements.Requirements{{"bearer": {"!"}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ec.VerifyEntitlements(tt.entitlements, tt.requirements))
+		})
+	}
+
+	t.Run("Except copies", func(t *testing.T) {
+		reqs := entitlements.Requirements{{"bearer": {"pages:read"}}}
+		got := reqs.Except("", "suspended")
+		assert.Equal(t, entitlements.Requirements{{"bearer": {"pages:read", "!suspended"}}}, got)
+		assert.Equal(t, entitlements.Requirements{{"bearer": {"pages:read"}}}, reqs)
+	})
+
+	t.Run("strict allows wildcard condition", func(t *testing.T) {
+		strict := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithStrictRequirements(true)
+		reqs := entitlements.Requirements{{"bearer": {"pages:{id}:read", "!admin:all"}}}
+		bound, err := strict.BindRequirements(strict.ParseRequirements(reqs), entitlements.Binding{"id": "/x"})
+		assert.NoError(t, err)
+		assert.True(t, strict.VerifyParsedEntitlements(strict.ParseEntitlements(entitlements.Entitlements{"bearer": {"pages:/x:read"}}), bound))
+		assert.False(t, strict.VerifyParsedEntitlements(strict.ParseEntitlements(active), bound))
+	})
+
+	t.Run("placeholder condition binds", func(t *testing.T) {
+		reqs := ec.ParseRequirements(entitlements.Requirements{{"bearer": {"pages:read", "!blocked:{id}:read"}}})
+		bound, err := ec.BindRequirements(reqs, entitlements.Binding{"id": "x"})
+		assert.NoError(t, err)
+		assert.True(t, ec.VerifyParsedEntitlements(ec.ParseEntitlements(active), bound))
+		blocked := entitlements.Entitlements{"bearer": {"pages:read", "blocked:x:read"}}
+		assert.False(t, ec.VerifyParsedEntitlements(ec.ParseEntitlements(blocked), bound))
+
+		_, err = ec.BindRequirements(reqs, entitlements.Binding{})
+		assert.ErrorIs(t, err, entitlements.ErrUnboundPlaceholder)
+	})
+
+	t.Run("minimal grants skip conditions", func(t *testing.T) {
+		got := ec.MinimalSatisfyingEntitlements(entitlements.Requirements{{"bearer": {"pages:read", "!suspended"}}})
+		assert.Equal(t, entitlements.Entitlements{"bearer": {"pages:read"}}, got)
+	})
+}
+
+func TestEntitlementsChecker_EntitlementUsage(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).
+		WithBaseEntitlements([]string{"health:read"})
+	ec, err := ec.WithNamedRequirements(map[string]entitlements.Requirements{
+		"reader": {{"bearer": {"pages:/a:read"}}},
+	})
+	assert.NoError(t, err)
+
+	held := entitlements.Entitlements{
+		"bearer": {"pages:read", "books:/x:all", "users:read"},
+		"oauth2": {"scope1"},
+	}
+	named := map[string]entitlements.Requirements{
+		"readPage":    {{"bearer": {"pages:/foo:read"}}},
+		"readAll":     {{"bearer": {"pages:/foo:read", "books:/x:read"}}},
+		"editBook":    {{"bearer": {"books:/x:update"}, "oauth2": {"scope1"}}},
+		"firstBranch": {{"bearer": {"admin:all"}}, {"bearer": {"users:/u:read"}}, {"bearer": {"pages:/p:read"}}},
+		"viaRef":      {{"bearer": {"@reader", "!suspended"}}},
+		"baseOnly":    {{"bearer": {"health:read"}}},
+		"empty":       {},
+		"denied":      {{"bearer": {"admin:all"}}},
+	}
+	assert.Equal(t, map[string][]string{
+		"readPage":    {"pages:read"},
+		"readAll":     {"pages:read", "books:/x:all"},
+		"editBook":    {"books:/x:all", "scope1"},
+		"firstBranch": {"users:read"},
+		"viaRef":      {"pages:read"},
+		"baseOnly":    {},
+		"empty":       {},
+	}, ec.EntitlementUsage(held, named))
+}
+
+func TestEntitlementsChecker_WithLegacySemantics(t *testing.T) {
+	withOptions := func(ec *entitlements.EntitlementsChecker) *entitlements.EntitlementsChecker {
+		return ec.
+			WithWildcardVerbByScheme(map[string]string{"bearer": "*", "oauth2": "*"}).
+			WithHTTPVerbAliases(true).
+			WithResourceNameGlob(true).
+			WithVerbGroups(map[string][]string{"read": {"write"}, "write": {"read"}}).
+			WithAllCoversOpaque(true)
+	}
+
+	for _, tt := range verifyEntitlementsTests {
+		t.Run(tt.name, func(t *testing.T) {
+			ec := entitlements.NewEntitlementsChecker(tt.anonymousEntitlements, "bearer", true)
+			ec = withOptions(ec.WithLegacySemantics(true))
+			assert.Equal(t, tt.want, ec.VerifyEntitlements(tt.entitlements, tt.requirements))
+		})
+	}
+
+	t.Run("options diverge without legacy", func(t *testing.T) {
+		diverged := 0
+		for _, tt := range verifyEntitlementsTests {
+			ec := withOptions(entitlements.NewEntitlementsChecker(tt.anonymousEntitlements, "bearer", true))
+			if ec.VerifyEntitlements(tt.entitlements, tt.requirements) != tt.want {
+				diverged++
+			}
+		}
+		assert.Positive(t, diverged)
+	})
+
+	t.Run("region tag still honoured", func(t *testing.T) {
+		ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithRegion("us").WithLegacySemantics(true)
+		reqs := entitlements.Requirements{{"bearer": {"pages:/a:read"}}}
+		assert.False(t, ec.VerifyEntitlements(entitlements.Entitlements{"bearer": {"pages:read@region=eu"}}, reqs))
+		assert.True(t, ec.VerifyEntitlements(entitlements.Entitlements{"bearer": {"pages:read@region=us"}}, reqs))
+	})
+}
+
+func TestLoadSignedPolicy(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+	verify := func(data, sig []byte) error {
+		if !ed25519.Verify(pub, data, sig) {
+			return errors.New("bad signature")
+		}
+		return nil
+	}
+
+	bundle := []byte(`{
+		"anonymousEntitlements": ["public:read"],
+		"baseEntitlements": ["health:read"],
+		"namedRequirements": {"editor": [{"bearer": ["pages:update"]}]}
+	}`)
+	sig := ed25519.Sign(priv, bundle)
+
+	t.Run("valid", func(t *testing.T) {
+		ec, err := entitlements.LoadSignedPolicy(bundle, sig, verify)
+		assert.NoError(t, err)
+		assert.True(t, ec.VerifyEntitlements(entitlements.Entitlements{}, entitlements.Requirements{{"bearer": {"public:read"}}}))
+		assert.True(t, ec.VerifyEntitlements(entitlements.Entitlements{"bearer": {"x"}}, entitlements.Requirements{{"bearer": {"health:read"}}}))
+		assert.True(t, ec.VerifyEntitlements(entitlements.Entitlements{"bearer": {"pages:all"}}, entitlements.Requirements{{"bearer": {"@editor"}}}))
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		tampered := []byte(strings.Replace(string(bundle), "public:read", "admin:all", 1))
+		ec, err := entitlements.LoadSignedPolicy(tampered, sig, verify)
+		assert.ErrorIs(t, err, entitlements.ErrPolicySignature)
+		assert.Nil(t, ec)
+	})
+
+	t.Run("no verify func", func(t *testing.T) {
+		_, err := entitlements.LoadSignedPolicy(bundle, sig, nil)
+		assert.ErrorIs(t, err, entitlements.ErrPolicySignature)
+	})
+
+	invalid := []struct {
+		name string
+		data string
+	}{
+		{"malformed", `{"anonymousEntitlements": [`},
+		{"unknown field", `{"anonymous": ["public:read"]}`},
+		{"trailing data", `{} {}`},
+		{"undefined reference", `{"namedRequirements": {"a": [{"bearer": ["@missing"]}]}}`},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []byte(tt.data)
+			_, err := entitlements.LoadSignedPolicy(data, ed25519.Sign(priv, data), verify)
+			assert.ErrorIs(t, err, entitlements.ErrInvalidPolicy)
+		})
+	}
+}
+
+func TestEntitlementsChecker_WithResourceNameGlob_Depth(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithResourceNameGlob(true)
+	held := entitlements.Entitlements{"bearer": {"pages:/docs/**/*:read"}}
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"/docs", false},
+		{"/docs/a", false},
+		{"/docs/a/b", true},
+		{"/docs/a/b/c", true},
+		{"/other/a/b", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reqs := entitlements.Requirements{{"bearer": {"pages:" + tt.name + ":read"}}}
+			assert.Equal(t, tt.want, ec.VerifyEntitlements(held, reqs))
+		})
+	}
+}
+
+func TestPreparedPolicy_Check(t *testing.T) {
+	configs := map[string]func() *entitlements.EntitlementsChecker{
+		"default": func() *entitlements.EntitlementsChecker {
+			return entitlements.NewEntitlementsChecker([]string{"public:read"}, "bearer", false)
+		},
+		"base and strict": func() *entitlements.EntitlementsChecker {
+			return entitlements.NewEntitlementsChecker(nil, "bearer", false).
+				WithBaseEntitlements([]string{"health:read", "books:/a:read"}).
+				WithStrictRequirements(true)
+		},
+		"matching options": func() *entitlements.EntitlementsChecker {
+			return entitlements.NewEntitlementsChecker(nil, "bearer", false).
+				WithAllCoversOpaque(true).
+				WithResourceNameGlob(true).
+				WithVerbGroups(map[string][]string{"write": {"create", "update"}}).
+				WithDisabledSchemes("apikey")
+		},
+		"scheme fallback": func() *entitlements.EntitlementsChecker {
+			return entitlements.NewEntitlementsChecker(nil, "bearer", false).WithDefaultSchemeFallback(true)
+		},
+	}
+
+	extra := []struct {
+		entitlements entitlements.Entitlements
+		requirements entitlements.Requirements
+	}{
+		{entitlements.Entitlements{}, entitlements.Requirements{{"bearer": {"public:read"}}}},
+		{entitlements.Entitlements{"bearer": {"x"}}, entitlements.Requirements{{"bearer": {"health:read", "books:/a:read"}}}},
+		{entitlements.Entitlements{"bearer": {"books:all"}}, entitlements.Requirements{{"bearer": {"books"}}}},
+		{entitlements.Entitlements{"bearer": {"pages:/a*:write"}}, entitlements.Requirements{{"bearer": {"pages:/ab:update"}}}},
+		{entitlements.Entitlements{"apikey": {"k"}}, entitlements.Requirements{{"apikey": {"k"}}}},
+		{entitlements.Entitlements{"bearer": {"pages:read"}}, entitlements.Requirements{{"oauth2": {"pages:/x:read"}}}},
+		{entitlements.Entitlements{"bearer": {"pages:read", "suspended"}}, entitlements.Requirements{{"bearer": {"pages:read", "!suspended"}}}},
+		{entitlements.Entitlements{"bearer": {"pages:read"}}, entitlements.Requirements{{"bearer": {"pages:read"}}, {"oauth2": {}}}},
+		{entitlements.Entitlements{"bearer": {"*:*:read"}}, entitlements.Requirements{{"bearer": {"pages:/a:read", "books:read"}}}},
+		{entitlements.Entitlements{"bearer": {"*:read", "!secrets:read"}}, entitlements.Requirements{{"bearer": {"secrets:/k:read"}}}},
+	}
+
+	for name, newChecker := range configs {
+		t.Run(name, func(t *testing.T) {
+			ec := newChecker()
+			for _, tt := range verifyEntitlementsTests {
+				want := ec.VerifyEntitlements(tt.entitlements, tt.requirements)
+				assert.Equal(t, want, ec.PrepareFor(tt.requirements).Check(tt.entitlements), tt.name)
+			}
+			for i, tt := range extra {
+				want := ec.VerifyEntitlements(tt.entitlements, tt.requirements)
+				assert.Equal(t, want, ec.PrepareFor(tt.requirements).Check(tt.entitlements), "extra %d", i)
+			}
+		})
+	}
+}
+
+// largePolicy is a single requirement with many alternatives, checked
+// against a caller whose grant satisfies only the last one.
+func largePolicy() (entitlements.Entitlements, entitlements.Requirements) {
+	var reqs entitlements.Requirements
+	for i := range 200 {
+		reqs = append(reqs, map[string][]string{
+			"bearer": {fmt.Sprintf("res%d:/a:read", i), fmt.Sprintf("res%d:/b:update", i)},
+		})
+	}
+	held := entitlements.Entitlements{"bearer": {"res199:all", "other:read", "more:read"}}
+	return held, reqs
+}
+
+func BenchmarkVerifyEntitlements_LargePolicy(b *testing.B) {
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	held, reqs := largePolicy()
+	parsed := ec.ParseRequirements(reqs)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ec.VerifyParsedEntitlements(ec.ParseEntitlements(held), parsed)
+	}
+}
+
+func BenchmarkVerifyEntitlements_Uncompiled_LargePolicy(b *testing.B) {
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	held, reqs := largePolicy()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ec.VerifyEntitlements(held, reqs)
+	}
+}
+
+func BenchmarkVerifyCompiled_LargePolicy(b *testing.B) {
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	held, reqs := largePolicy()
+	compiled, err := ec.CompileRequirements(reqs)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ec.VerifyCompiled(held, compiled)
+	}
+}
+
+func BenchmarkPreparedPolicy_Check_LargePolicy(b *testing.B) {
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	held, reqs := largePolicy()
+	policy := ec.PrepareFor(reqs)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		policy.Check(held)
+	}
+}
+
+func TestEntitlementsFromCognito(t *testing.T) {
+	mapping := map[string][]string{
+		"editors": {"pages:read", "pages:update"},
+		"readers": {"pages:read", "books:read"},
+	}
+	tests := []struct {
+		name    string
+		claims  map[string]any
+		scheme  string
+		want    entitlements.Entitlements
+		wantErr bool
+	}{
+		{
+			name:   "multiple groups unioned",
+			claims: map[string]any{"cognito:groups": []any{"editors", "readers"}},
+			want:   entitlements.Entitlements{"bearer": {"pages:read", "pages:update", "books:read"}},
+		},
+		{
+			name:   "unmapped group ignored",
+			claims: map[string]any{"cognito:groups": []any{"auditors", "readers"}},
+			scheme: "cognito",
+			want:   entitlements.Entitlements{"cognito": {"pages:read", "books:read"}},
+		},
+		{
+			name:   "string slice",
+			claims: map[string]any{"cognito:groups": []string{"editors"}},
+			want:   entitlements.Entitlements{"bearer": {"pages:read", "pages:update"}},
+		},
+		{
+			name:   "only unmapped groups",
+			claims: map[string]any{"cognito:groups": []any{"auditors"}},
+			want:   entitlements.Entitlements{"bearer": {}},
+		},
+		{
+			name:   "missing claim",
+			claims: map[string]any{"sub": "u1"},
+			want:   entitlements.Entitlements{"bearer": {}},
+		},
+		{
+			name:    "not an array",
+			claims:  map[string]any{"cognito:groups": "editors"},
+			wantErr: true,
+		},
+		{
+			name:    "non-string element",
+			claims:  map[string]any{"cognito:groups": []any{"editors", 7}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := entitlements.EntitlementsFromCognito(tt.claims, mapping, tt.scheme)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, entitlements.ErrInvalidClaim)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEntitlementsChecker_WithGrantReadyRequiresAuthenticated(t *testing.T) {
+	tests := []struct {
+		name          string
+		entitlements  entitlements.Entitlements
+		unconditional bool
+		conditional   bool
+	}{
+		{"anonymous", entitlements.Entitlements{}, true, false},
+		{"empty default scheme", entitlements.Entitlements{"bearer": {}}, true, false},
+		{"other scheme only", entitlements.Entitlements{"oauth2": {"scope1"}}, true, false},
+		{"authenticated", entitlements.Entitlements{"bearer": {"books:read"}}, true, true},
+		{"holds identity", entitlements.Entitlements{"bearer": {"pages:/foo:read"}}, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ec := entitlements.NewEntitlementsChecker(nil, "bearer", true)
+			got, err := ec.VerifyResourceEntitlements("pages", "/foo", tt.entitlements, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.unconditional, got, "unconditional")
+
+			ec.WithGrantReadyRequiresAuthenticated(true)
+			got, err = ec.VerifyResourceEntitlements("pages", "/foo", tt.entitlements, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.conditional, got, "conditional")
+		})
+	}
+
+	t.Run("no effect without grantReadyByDefault", func(t *testing.T) {
+		ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithGrantReadyRequiresAuthenticated(true)
+		got, err := ec.VerifyResourceEntitlements("pages", "/foo", entitlements.Entitlements{"bearer": {"books:read"}}, nil)
+		assert.NoError(t, err)
+		assert.False(t, got)
+	})
+
+	t.Run("anonymous holding identity via anonymous entitlements", func(t *testing.T) {
+		ec := entitlements.NewEntitlementsChecker([]string{"pages:read"}, "bearer", true).WithGrantReadyRequiresAuthenticated(true)
+		got, err := ec.VerifyResourceEntitlements("pages", "/foo", entitlements.Entitlements{}, nil)
+		assert.NoError(t, err)
+		assert.True(t, got)
+	})
+}
+
+func TestEntitlementsChecker_VersionedResources(t *testing.T) {
+	tests := []struct {
+		name        string
+		held        string
+		requirement string
+		want        bool
+	}{
+		{"exact version", "pages.v2:read", "pages.v2:/a:read", true},
+		{"other version", "pages.v2:read", "pages.v1:/a:read", false},
+		{"all versions", "pages.*:read", "pages.v1:/a:read", true},
+		{"all versions multi-digit", "pages.*:/a:all", "pages.v12:/a:update", true},
+		{"all versions wrong verb", "pages.*:read", "pages.v1:/a:update", false},
+		{"all versions wrong base", "pages.*:read", "books.v1:/a:read", false},
+		{"all versions not unversioned", "pages.*:read", "pages:/a:read", false},
+		{"all versions not non-version suffix", "pages.*:read", "pages.beta:/a:read", false},
+		{"all versions not bare v", "pages.*:read", "pages.v:/a:read", false},
+		{"requirement wildcard is literal", "pages.v1:read", "pages.*:/a:read", false},
+		{"wildcard requirement needs wildcard grant", "pages.*:read", "pages.*:/a:read", true},
+		{"unversioned exact", "pages:read", "pages:/a:read", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+			held := entitlements.Entitlements{"bearer": {tt.held}}
+			reqs := entitlements.Requirements{{"bearer": {tt.requirement}}}
+			assert.Equal(t, tt.want, ec.VerifyEntitlements(held, reqs))
+			assert.Equal(t, tt.want, ec.PrepareFor(reqs).Check(held), "prepared")
+			assert.Equal(t, tt.want, entitlements.Dominates(tt.held, tt.requirement), "Dominates")
+		})
+	}
+
+	t.Run("legacy disables version wildcard", func(t *testing.T) {
+		ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithLegacySemantics(true)
+		held := entitlements.Entitlements{"bearer": {"pages.*:read"}}
+		assert.False(t, ec.VerifyEntitlements(held, entitlements.Requirements{{"bearer": {"pages.v1:/a:read"}}}))
+	})
+
+	t.Run("all covers opaque version", func(t *testing.T) {
+		ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithAllCoversOpaque(true)
+		held := entitlements.Entitlements{"bearer": {"pages.*:all"}}
+		reqs := entitlements.Requirements{{"bearer": {"pages.v3"}}}
+		assert.True(t, ec.VerifyEntitlements(held, reqs))
+		assert.True(t, ec.PrepareFor(reqs).Check(held))
+	})
+}
+
+func TestValidateEntitlements(t *testing.T) {
+	tests := []struct {
+		name         string
+		entitlements entitlements.Entitlements
+		wantErrs     []string
+	}{
+		{"nil", nil, nil},
+		{"valid", entitlements.Entitlements{
+			"bearer": {"pages:read", "pages::read", "pages:/a:all", "email", "a:b:c:d", "pages:read@region=eu"},
+			"oauth2": {"resource=https://api.example.com", "scope1"},
+			"mtls":   {},
+		}, nil},
+		{"empty scheme", entitlements.Entitlements{"": {"pages:read"}}, []string{"empty scheme name"}},
+		{"empty string", entitlements.Entitlements{"bearer": {""}}, []string{`scheme "bearer": "" is empty`}},
+		{"empty resource short", entitlements.Entitlements{"bearer": {":read"}}, []string{`":read" has an empty resource type`}},
+		{"empty resource long", entitlements.Entitlements{"bearer": {":/a:read"}}, []string{`":/a:read" has an empty resource type`}},
+		{"empty verb", entitlements.Entitlements{"bearer": {"pages::"}}, []string{`"pages::" has an empty verb`}},
+		{"empty verb with region", entitlements.Entitlements{"bearer": {"pages:@region=eu"}}, []string{`has an empty verb`}},
+		{"empty indicator", entitlements.Entitlements{"oauth2": {"resource="}}, []string{`scheme "oauth2": "resource=" has an empty resource indicator`}},
+		{"only denies", entitlements.Entitlements{
+			"bearer": {"!pages:write", "!secrets:read"},
+			"oauth2": {"files:read", "!files:/a:read"},
+		}, []string{`scheme "bearer": holds only denies`}},
+		{"every problem reported", entitlements.Entitlements{
+			"":       {":read"},
+			"bearer": {"pages:read", "", "pages:"},
+		}, []string{
+			"empty scheme name",
+			`scheme "": ":read" has an empty resource type`,
+			`scheme "bearer": "" is empty`,
+			`scheme "bearer": "pages:" has an empty verb`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := entitlements.ValidateEntitlements(tt.entitlements)
+			if tt.wantErrs == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, entitlements.ErrInvalidEntitlement)
+			lines := strings.Split(err.Error(), "\n")
+			assert.Len(t, lines, len(tt.wantErrs))
+			for i, want := range tt.wantErrs {
+				assert.Contains(t, lines[i], want)
+			}
+		})
+	}
+}
+
+func TestEntitlementsChecker_WithClock(t *testing.T) {
+	clock := entitlementstest.NewFakeClock(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithClock(clock)
+
+	// Expiry is evaluated at an explicit instant; drive it from the clock.
+	held := entitlements.Entitlements{"bearer": {"pages:read"}}
+	expiries := map[string]time.Time{"pages:read": clock.Now().Add(time.Hour)}
+	reqs := entitlements.Requirements{{"bearer": {"pages:read"}}}
+
+	assert.True(t, ec.VerifyEntitlementsWithExpiry(held, expiries, reqs, clock.Now()))
+	clock.Advance(time.Hour - time.Second)
+	assert.True(t, ec.VerifyEntitlementsWithExpiry(held, expiries, reqs, clock.Now()), "just before expiry")
+	clock.Advance(time.Second)
+	assert.False(t, ec.VerifyEntitlementsWithExpiry(held, expiries, reqs, clock.Now()), "at expiry")
+
+	t.Run("zero instant reads the clock", func(t *testing.T) {
+		clock := entitlementstest.NewFakeClock(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+		ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithClock(clock)
+		expiries := map[string]time.Time{"pages:read": clock.Now().Add(time.Hour)}
+
+		assert.True(t, ec.VerifyEntitlementsWithExpiry(held, expiries, reqs, time.Time{}))
+		assert.True(t, ec.HasAnyEffectiveEntitlementWithExpiry(held, expiries, time.Time{}))
+		clock.Advance(time.Hour - time.Second)
+		assert.True(t, ec.VerifyEntitlementsWithExpiry(held, expiries, reqs, time.Time{}), "just before expiry")
+		assert.True(t, ec.HasAnyEffectiveEntitlementWithExpiry(held, expiries, time.Time{}), "just before expiry")
+		clock.Advance(time.Second)
+		assert.False(t, ec.VerifyEntitlementsWithExpiry(held, expiries, reqs, time.Time{}), "at expiry")
+		assert.False(t, ec.HasAnyEffectiveEntitlementWithExpiry(held, expiries, time.Time{}), "at expiry")
+
+		// An explicit instant still wins over the clock.
+		assert.True(t, ec.VerifyEntitlementsWithExpiry(held, expiries, reqs, clock.Now().Add(-time.Minute)))
+	})
+
+	// A nil clock restores the system clock.
+	assert.NotPanics(t, func() {
+		ec.WithClock(nil).WithDenialCache(1, time.Minute).VerifyEntitlements(held, entitlements.Requirements{{"bearer": {"x"}}})
+	})
+}
+
+func TestRequireDistinctSchemes(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker([]string{"payments:approve"}, "bearer", false).
+		WithBaseEntitlements([]string{"payments:approve"})
+
+	reqs := entitlements.RequireDistinctSchemes(2, "payments:/p1:approve")
+	assert.Equal(t, entitlements.Requirements{{"bearer": {"#2:payments:/p1:approve"}}}, reqs)
+
+	tests := []struct {
+		name         string
+		entitlements entitlements.Entitlements
+		want         bool
+	}{
+		{"one scheme denied", entitlements.Entitlements{"bearer": {"payments:approve"}}, false},
+		{"two grants one scheme denied", entitlements.Entitlements{"bearer": {"payments:approve", "payments:/p1:approve"}}, false},
+		{"two schemes allowed", entitlements.Entitlements{"bearer": {"payments:approve"}, "mfa": {"payments:/p1:all"}}, true},
+		{"second scheme lacks grant", entitlements.Entitlements{"bearer": {"payments:approve"}, "mfa": {"otp"}}, false},
+		{"two schemes, neither default", entitlements.Entitlements{"password": {"payments:approve"}, "mfa": {"payments:approve"}}, true},
+		{"base and anonymous do not count", entitlements.Entitlements{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ec.VerifyEntitlements(tt.entitlements, reqs))
+			assert.Equal(t, tt.want, ec.PrepareFor(reqs).Check(tt.entitlements), "prepared")
+		})
+	}
+
+	t.Run("n below 1 is 1", func(t *testing.T) {
+		reqs := entitlements.RequireDistinctSchemes(0, "x")
+		assert.Equal(t, entitlements.Requirements{{"bearer": {"#1:x"}}}, reqs)
+		assert.True(t, ec.VerifyEntitlements(entitlements.Entitlements{"mfa": {"x"}}, reqs))
+	})
+
+	t.Run("composes with other tokens", func(t *testing.T) {
+		reqs := entitlements.Requirements{{"bearer": {"pages:read", "#2:admin"}}}
+		held := entitlements.Entitlements{"bearer": {"pages:read", "admin"}, "mfa": {"admin"}}
+		assert.True(t, ec.VerifyEntitlements(held, reqs))
+		assert.False(t, ec.VerifyEntitlements(entitlements.Entitlements{"bearer": {"pages:read", "admin"}}, reqs))
+	})
+
+	t.Run("disabled scheme does not count", func(t *testing.T) {
+		ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithDisabledSchemes("mfa")
+		held := entitlements.Entitlements{"bearer": {"x"}, "mfa": {"x"}}
+		assert.False(t, ec.VerifyEntitlements(held, entitlements.RequireDistinctSchemes(2, "x")))
+	})
+
+	t.Run("placeholder binds", func(t *testing.T) {
+		parsed := ec.ParseRequirements(entitlements.RequireDistinctSchemes(2, "payments:{id}:approve"))
+		held := ec.ParseEntitlements(entitlements.Entitlements{"bearer": {"payments:p1:approve"}, "mfa": {"payments:p1:approve"}})
+		assert.False(t, ec.VerifyParsedEntitlements(held, parsed), "unbound")
+		bound, err := ec.BindRequirements(parsed, entitlements.Binding{"id": "p1"})
+		assert.NoError(t, err)
+		assert.True(t, ec.VerifyParsedEntitlements(held, bound))
+	})
+
+	t.Run("malformed tokens are literal", func(t *testing.T) {
+		for _, token := range []string{"#0:x", "#-1:x", "#+2:x", "#2:", "#x:y", "#2"} {
+			held := entitlements.Entitlements{"bearer": {token}}
+			assert.True(t, ec.VerifyEntitlements(held, entitlements.Requirements{{"bearer": {token}}}), token)
+		}
+	})
+
+	t.Run("minimal grants skip distinct-schemes branches", func(t *testing.T) {
+		got := ec.MinimalSatisfyingEntitlements(entitlements.Requirements{{"bearer": {"#2:x"}}, {"bearer": {"y", "z"}}})
+		assert.Equal(t, entitlements.Entitlements{"bearer": {"y", "z"}}, got)
+	})
+}
+
+func TestEntitlementsChecker_VerifyEntitlementsMatch(t *testing.T) {
+	requirements := entitlements.Requirements{
+		{"bearer": {"admin"}},
+		{"bearer": {"pages:/a:read"}, "oauth2": {"files:read"}},
+		{"bearer": {"pages:write"}},
+		{"bearer": {"pages:/a:read"}},
+	}
+	tests := []struct {
+		name    string
+		held    entitlements.Entitlements
+		allowed bool
+		branch  int
+	}{
+		{"first branch", entitlements.Entitlements{"bearer": {"admin", "pages:all"}}, true, 0},
+		{"multi-scheme branch", entitlements.Entitlements{"bearer": {"pages:/a:read"}, "oauth2": {"files:read"}}, true, 1},
+		{"first of several satisfied", entitlements.Entitlements{"bearer": {"pages:all"}}, true, 2},
+		{"last branch", entitlements.Entitlements{"bearer": {"pages:/a:read"}}, true, 3},
+		{"denied", entitlements.Entitlements{"bearer": {"pages:/b:delete"}}, false, -1},
+	}
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, branch := ec.VerifyEntitlementsMatch(tt.held, requirements)
+			assert.Equal(t, tt.allowed, allowed)
+			assert.Equal(t, tt.branch, branch)
+			assert.Equal(t, ec.VerifyEntitlements(tt.held, requirements), allowed)
+			assert.Equal(t, ec.Explain(tt.held, requirements).Branch, branch)
+		})
+	}
+
+	t.Run("empty requirements", func(t *testing.T) {
+		allowed, branch := ec.VerifyEntitlementsMatch(nil, nil)
+		assert.True(t, allowed)
+		assert.Equal(t, -1, branch)
+	})
+
+	t.Run("branch combiner", func(t *testing.T) {
+		ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithBranchCombiner(entitlements.AtLeastBranches(2))
+		allowed, branch := ec.VerifyEntitlementsMatch(entitlements.Entitlements{"bearer": {"pages:all"}}, requirements)
+		assert.True(t, allowed)
+		assert.Equal(t, 2, branch)
+
+		allowed, branch = ec.VerifyEntitlementsMatch(entitlements.Entitlements{"bearer": {"admin"}}, requirements)
+		assert.False(t, allowed)
+		assert.Equal(t, -1, branch)
+	})
+
+	t.Run("cached denial", func(t *testing.T) {
+		ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithDenialCache(16, time.Minute)
+		held := entitlements.Entitlements{"bearer": {"pages:/b:delete"}}
+		for range 2 {
+			allowed, branch := ec.VerifyEntitlementsMatch(held, requirements)
+			assert.False(t, allowed)
+			assert.Equal(t, -1, branch)
+		}
+	})
+}
+
+func TestEntitlementsChecker_Explain(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker([]string{"books:read"}, "bearer", false).
+		WithBaseEntitlements([]string{"public:read"})
+	requirements := entitlements.Requirements{{"bearer": {"books:/x:read"}}}
+
+	// An anonymous caller is allowed on the anonymous merge alone.
+	anon := ec.Explain(entitlements.Entitlements{}, requirements)
+	assert.Equal(t, entitlements.Decision{Allowed: true, Branch: 0, Grants: []entitlements.Grant{
+		{Scheme: "bearer", Requirement: "books:/x:read", Entitlement: "books:read", Source: entitlements.GrantSourceAnonymous},
+	}}, anon)
+	assert.True(t, anon.AnonymousOnly())
+
+	// The same decision for a caller holding the grant is attributed to them.
+	own := ec.Explain(entitlements.Entitlements{"bearer": {"books:/x:all"}}, requirements)
+	assert.Equal(t, entitlements.Decision{Allowed: true, Branch: 0, Grants: []entitlements.Grant{
+		{Scheme: "bearer", Requirement: "books:/x:read", Entitlement: "books:/x:all", Source: entitlements.GrantSourceDirect},
+	}}, own)
+	assert.False(t, own.AnonymousOnly())
+
+	// Mixed branches attribute each token to its own source.
+	mixed := ec.Explain(
+		entitlements.Entitlements{"bearer": {"pages:read"}},
+		entitlements.Requirements{{"bearer": {"books:read"}}, {"bearer": {"pages:/a:read", "public:read"}}},
+	)
+	assert.Equal(t, entitlements.Decision{Allowed: true, Branch: 1, Grants: []entitlements.Grant{
+		{Scheme: "bearer", Requirement: "pages:/a:read", Entitlement: "pages:read", Source: entitlements.GrantSourceDirect},
+		{Scheme: "bearer", Requirement: "public:read", Entitlement: "public:read", Source: entitlements.GrantSourceBase},
+	}}, mixed)
+	assert.False(t, mixed.AnonymousOnly())
+
+	assert.Equal(t, entitlements.Decision{Branch: -1},
+		ec.Explain(entitlements.Entitlements{"bearer": {"pages:read"}}, entitlements.Requirements{{"bearer": {"pages:write"}}}))
+	assert.Equal(t, entitlements.Decision{Allowed: true, Branch: -1}, ec.Explain(entitlements.Entitlements{}, nil))
+
+	for _, tt := range verifyEntitlementsTests {
+		t.Run(tt.name, func(t *testing.T) {
+			ec := entitlements.NewEntitlementsChecker(tt.anonymousEntitlements, "bearer", true)
+			assert.Equal(t, tt.want, ec.Explain(tt.entitlements, tt.requirements).Allowed)
+		})
+	}
+}
+
+func TestEntitlementsChecker_WithMaxResourceNameDepth(t *testing.T) {
+	tests := []struct {
+		name         string
+		entitlements []string
+		requirement  string
+		want         bool
+	}{
+		{"requirement at the limit", []string{"pages:/a/b/c:read"}, "pages:/a/b/c:read", true},
+		{"requirement beyond the limit", []string{"pages::read"}, "pages:/a/b/c/d:read", false},
+		{"identical tokens beyond the limit", []string{"pages:/a/b/c/d:read"}, "pages:/a/b/c/d:read", false},
+		{"entitlement beyond the limit", []string{"pages:/a/b/c/*:read"}, "pages:/a/b/c/x:read", false},
+		{"entitlement glob at the limit", []string{"pages:/a/b/*:read"}, "pages:/a/b/c:read", true},
+		{"empty segments are not counted", []string{"pages:read"}, "pages://a//b/c/:read", true},
+		{"wildcard entitlement unaffected", []string{"pages:read"}, "pages:/a/b:read", true},
+		{"opaque unaffected", []string{"a/b/c/d/e"}, "a/b/c/d/e", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).
+				WithResourceNameGlob(true).
+				WithMaxResourceNameDepth(3)
+			assert.Equal(t, tt.want, ec.VerifyEntitlements(
+				entitlements.Entitlements{"bearer": tt.entitlements},
+				entitlements.Requirements{{"bearer": {tt.requirement}}},
+			))
+		})
+	}
+
+	// Without a bound, any depth matches.
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	deep := "pages:/" + strings.Repeat("a/", 50) + "b:read"
+	assert.True(t, ec.VerifyEntitlements(entitlements.Entitlements{"bearer": {deep}}, entitlements.Requirements{{"bearer": {deep}}}))
+	assert.False(t, ec.WithMaxResourceNameDepth(50).
+		VerifyEntitlements(entitlements.Entitlements{"bearer": {deep}}, entitlements.Requirements{{"bearer": {deep}}}))
+
+	// Strict mode rejects a too-deep requirement when binding.
+	strict := entitlements.NewEntitlementsChecker(nil, "bearer", false).
+		WithStrictRequirements(true).
+		WithMaxResourceNameDepth(2)
+	_, err := strict.BindRequirements(strict.ParseRequirements(entitlements.Requirements{{"bearer": {"pages:/a/b/c:read"}}}), nil)
+	assert.ErrorIs(t, err, entitlements.ErrResourceNameTooDeep)
+	_, err = strict.BindRequirements(strict.ParseRequirements(entitlements.Requirements{{"bearer": {"pages:/a/b:read"}}}), nil)
+	assert.NoError(t, err)
+	assert.False(t, strict.VerifyEntitlements(
+		entitlements.Entitlements{"bearer": {"pages:read"}},
+		entitlements.Requirements{{"bearer": {"pages:/a/b/c:read"}}},
+	))
+}
+
+func TestLoadRequirementsDir(t *testing.T) {
+	fsys := fstest.MapFS{
+		"policies/orders.yaml":   {Data: []byte("- bearer: [\"orders:read\"]\n- apikey: [orders-service]\n")},
+		"policies/public.yaml":   {Data: []byte("[]\n")},
+		"policies/README.md":     {Data: []byte("not a policy")},
+		"policies/nested/x.yaml": {Data: []byte("- bearer: [x]\n")},
+	}
+	loaded, err := entitlements.LoadRequirementsDir(fsys, "policies")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]entitlements.Requirements{
+		"orders": {{"bearer": {"orders:read"}}, {"apikey": {"orders-service"}}},
+		"public": {},
+	}, loaded)
+
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	assert.True(t, ec.VerifyEntitlements(entitlements.Entitlements{"apikey": {"orders-service"}}, loaded["orders"]))
+	assert.False(t, ec.VerifyEntitlements(entitlements.Entitlements{"bearer": {"pages:read"}}, loaded["orders"]))
+
+	// Every invalid file is reported, by name.
+	fsys["policies/bad.yaml"] = &fstest.MapFile{Data: []byte("bearer: orders:read\n")}
+	fsys["policies/empty.yaml"] = &fstest.MapFile{Data: []byte("# nothing yet\n")}
+	fsys["policies/null.yaml"] = &fstest.MapFile{Data: []byte("~\n")}
+	fsys["policies/two.yaml"] = &fstest.MapFile{Data: []byte("- bearer: [a]\n---\n- bearer: [b]\n")}
+	loaded, err = entitlements.LoadRequirementsDir(fsys, "policies")
+	assert.Nil(t, loaded)
+	assert.ErrorIs(t, err, entitlements.ErrInvalidRequirements)
+	for _, file := range []string{"policies/bad.yaml", "policies/empty.yaml", "policies/null.yaml", "policies/two.yaml"} {
+		assert.Contains(t, err.Error(), file)
+	}
+	assert.NotContains(t, err.Error(), "orders.yaml")
+
+	_, err = entitlements.LoadRequirementsDir(fsys, "missing")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestEntitlementsChecker_WithRequirementVerbSeparator(t *testing.T) {
+	tests := []struct {
+		name        string
+		held        []string
+		requirement string
+		want        bool
+	}{
+		{"first alternative held", []string{"pages:read"}, "pages:/foo:read,write", true},
+		{"second alternative held", []string{"pages:/foo:write"}, "pages:/foo:read,write", true},
+		{"no alternative held", []string{"pages:delete"}, "pages:/foo:read,write", false},
+		{"wildcard verb", []string{"pages:all"}, "pages:/foo:read,write", true},
+		{"short form", []string{"pages:/foo:write"}, "pages:read,write", true},
+		{"empty alternatives ignored", []string{"pages:read"}, "pages:/foo:,read,", true},
+		// In an entitlement the comma is literal: "read,write" is one verb.
+		{"held comma is not split", []string{"pages:read,write"}, "pages:/foo:read", false},
+		{"held comma matches identical requirement", []string{"pages:read,write"}, "pages:/foo:read,write", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithRequirementVerbSeparator(",")
+			assert.Equal(t, tt.want, ec.VerifyEntitlements(
+				entitlements.Entitlements{"bearer": tt.held},
+				entitlements.Requirements{{"bearer": {tt.requirement}}},
+			))
+		})
+	}
+
+	// Disabled by default: the comma is part of the verb.
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	assert.False(t, ec.VerifyEntitlements(
+		entitlements.Entitlements{"bearer": {"pages:read"}},
+		entitlements.Requirements{{"bearer": {"pages:/foo:read,write"}}},
+	))
+
+	// The separator is configurable, and alternatives honour verb options.
+	ec = entitlements.NewEntitlementsChecker(nil, "bearer", false).
+		WithRequirementVerbSeparator("|").
+		WithHTTPVerbAliases(true)
+	assert.True(t, ec.VerifyEntitlements(
+		entitlements.Entitlements{"bearer": {"pages:GET"}},
+		entitlements.Requirements{{"bearer": {"pages:/foo:update|read"}}},
+	))
+	assert.False(t, ec.VerifyEntitlements(
+		entitlements.Entitlements{"bearer": {"pages:read"}},
+		entitlements.Requirements{{"bearer": {"pages:/foo:update,read"}}},
+	))
+}
+
+func TestEntitlementsChecker_WithRequirementVerbSetSeparator(t *testing.T) {
+	tests := []struct {
+		name        string
+		held        []string
+		requirement string
+		want        bool
+	}{
+		{"both verbs held", []string{"pages:read", "pages:/foo:write"}, "pages:/foo:read+write", true},
+		{"only read held", []string{"pages:read"}, "pages:/foo:read+write", false},
+		{"only write held", []string{"pages:/foo:write"}, "pages:/foo:read+write", false},
+		{"wildcard verb", []string{"pages:all"}, "pages:/foo:read+write", true},
+		{"short form", []string{"pages:read", "pages:write"}, "pages:read+write", true},
+		{"denied verb", []string{"pages:all", "!pages:write"}, "pages:/foo:read+write", false},
+		{"empty verbs ignored", []string{"pages:read"}, "pages:/foo:+read+", true},
+		{"with alternatives", []string{"pages:list", "pages:write"}, "pages:/foo:read,list+write", true},
+		{"alternatives unmet", []string{"pages:delete", "pages:write"}, "pages:/foo:read,list+write", false},
+		// In an entitlement the plus is literal: "read+write" is one verb.
+		{"held plus is not split", []string{"pages:read+write"}, "pages:/foo:read", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).
+				WithRequirementVerbSetSeparator("+").
+				WithRequirementVerbSeparator(",")
+			held := entitlements.Entitlements{"bearer": tt.held}
+			requirements := entitlements.Requirements{{"bearer": {tt.requirement}}}
+			assert.Equal(t, tt.want, ec.VerifyEntitlements(held, requirements))
+			assert.Equal(t, tt.want, ec.PrepareFor(requirements).Check(held))
+		})
+	}
+
+	// Disabled by default: the plus is part of the verb.
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	assert.False(t, ec.VerifyEntitlements(
+		entitlements.Entitlements{"bearer": {"pages:read", "pages:write"}},
+		entitlements.Requirements{{"bearer": {"pages:/foo:read+write"}}},
+	))
+
+	// Base entitlements grant verbs of a set alongside the caller's own.
+	ec = entitlements.NewEntitlementsChecker(nil, "bearer", false).
+		WithBaseEntitlements([]string{"pages:read"}).
+		WithRequirementVerbSetSeparator("&")
+	assert.True(t, ec.VerifyEntitlements(
+		entitlements.Entitlements{"bearer": {"pages:write"}},
+		entitlements.Requirements{{"bearer": {"pages:/foo:read&write"}}},
+	))
+
+	// Every verb of a set is checked against WithKnownVerbs.
+	ec = entitlements.NewEntitlementsChecker(nil, "bearer", false).
+		WithRequirementVerbSetSeparator("+").
+		WithKnownVerbs("read", "write")
+	assert.NoError(t, ec.ValidateRequirements(entitlements.Requirements{{"bearer": {"pages:read+write"}}}))
+	assert.ErrorIs(t, ec.ValidateRequirements(entitlements.Requirements{{"bearer": {"pages:read+erase"}}}), entitlements.ErrInvalidRequirement)
+
+	// Legacy semantics read the plus literally.
+	ec = entitlements.NewEntitlementsChecker(nil, "bearer", false).
+		WithRequirementVerbSetSeparator("+").
+		WithLegacySemantics(true)
+	assert.False(t, ec.VerifyEntitlements(
+		entitlements.Entitlements{"bearer": {"pages:read", "pages:write"}},
+		entitlements.Requirements{{"bearer": {"pages:/foo:read+write"}}},
+	))
+}
+
+func TestEntitlementsChecker_CacheStats(t *testing.T) {
+	clock := entitlementstest.NewFakeClock(time.Unix(0, 0))
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).
+		WithClock(clock).
+		WithDenialCache(2, time.Minute)
+
+	stats := ec.CacheStats()
+	assert.Equal(t, entitlements.CacheStats{Capacity: 10000}, stats.Parse)
+	assert.Equal(t, &entitlements.CacheStats{Capacity: 2}, stats.Denial)
+
+	held := entitlements.Entitlements{"bearer": {"pages:read"}}
+	denied := entitlements.Requirements{{"bearer": {"pages:write"}}}
+	assert.False(t, ec.VerifyEntitlements(held, denied)) // parse misses, denial miss
+	assert.False(t, ec.VerifyEntitlements(held, denied)) // denial hit
+	assert.True(t, ec.VerifyEntitlements(held, entitlements.Requirements{{"bearer": {"pages:read"}}}))
+
+	stats = ec.CacheStats()
+	assert.Equal(t, entitlements.CacheStats{Hits: 2, Misses: 2, Size: 2, Capacity: 10000}, stats.Parse)
+	assert.Equal(t, &entitlements.CacheStats{Hits: 1, Misses: 2, Size: 1, Capacity: 2}, stats.Denial)
+
+	// Filling the denial cache evicts the oldest entry; an expired one is a miss.
+	for _, verb := range []string{"a", "b"} {
+		ec.VerifyEntitlements(held, entitlements.Requirements{{"bearer": {"pages:" + verb}}})
+	}
+	clock.Advance(time.Minute)
+	assert.False(t, ec.VerifyEntitlements(held, entitlements.Requirements{{"bearer": {"pages:b"}}}))
+	stats = ec.CacheStats()
+	assert.Equal(t, &entitlements.CacheStats{Hits: 1, Misses: 5, Evictions: 1, Size: 2, Capacity: 2}, stats.Denial)
+
+	assert.Nil(t, entitlements.NewEntitlementsChecker(nil, "bearer", false).CacheStats().Denial)
+}
+
+func TestEntitlementsChecker_CacheStatsConcurrent(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithDenialCache(8, time.Minute)
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 100 {
+				ec.VerifyEntitlements(
+					entitlements.Entitlements{"bearer": {fmt.Sprintf("pages:/%d:read", i)}},
+					entitlements.Requirements{{"bearer": {fmt.Sprintf("pages:/%d:read", j%10)}}},
+				)
+				ec.CacheStats()
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := ec.CacheStats()
+	assert.Equal(t, uint64(8*100), stats.Denial.Hits+stats.Denial.Misses)
+	assert.LessOrEqual(t, stats.Denial.Size, 8)
+}
+
+func TestEntitlementsChecker_TypeWildcardWithDeny(t *testing.T) {
+	admin := []string{"*:*:read", "!secrets:*:read"}
+	tests := []struct {
+		name        string
+		held        []string
+		requirement string
+		want        bool
+	}{
+		{"type wildcard grants any type", []string{"*:*:read"}, "pages:/foo:read", true},
+		{"type wildcard short form", []string{"*:read"}, "books::read", true},
+		{"type wildcard keeps its verb", []string{"*:*:read"}, "pages:/foo:write", false},
+		{"type wildcard keeps its name", []string{"*:/foo:read"}, "pages:/bar:read", false},
+		{"type wildcard is not opaque", []string{"*:*:read"}, "secrets", false},
+		{"type wildcard grants class-wide", []string{"*:*:read"}, "pages:read", true},
+		{"type wildcard with all verb", []string{"*:*:all"}, "secrets:/db:delete", true},
+		{"bare star is opaque", []string{"*"}, "pages", false},
+		{"bare star grants no type", []string{"*"}, "pages:read", false},
+		{"bare star matches itself", []string{"*"}, "*", true},
+		{"requirement type wildcard is literal", []string{"pages:read"}, "*:/foo:read", false},
+		{"deny carves out a type", admin, "secrets:/db:read", false},
+		{"deny leaves other types", admin, "pages:/foo:read", true},
+		{"deny defeats class-wide requirement", admin, "secrets::read", false},
+		{"deny wins over specific grant", append([]string{"secrets:/db:read"}, admin...), "secrets:/db:read", false},
+		{"deny of one name", []string{"pages:read", "!pages:/admin:read"}, "pages:/admin:read", false},
+		{"deny of one name leaves others", []string{"pages:read", "!pages:/admin:read"}, "pages:/home:read", true},
+		{"deny is not a grant", []string{"!secrets:read"}, "secrets:/db:read", false},
+		{"deny of other verb", []string{"*:all", "!secrets:write"}, "secrets:/db:read", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+			assert.Equal(t, tt.want, ec.VerifyEntitlements(
+				entitlements.Entitlements{"bearer": tt.held},
+				entitlements.Requirements{{"bearer": {tt.requirement}}},
+			))
+		})
+	}
+
+	// A deny wins over base entitlements under its scheme, but not elsewhere.
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithBaseEntitlements([]string{"health:read"})
+	assert.False(t, ec.VerifyEntitlements(
+		entitlements.Entitlements{"bearer": {"!health:read"}},
+		entitlements.Requirements{{"bearer": {"health:read"}}},
+	))
+	assert.True(t, ec.VerifyEntitlements(
+		entitlements.Entitlements{"bearer": {"*:read"}, "oauth2": {"!secrets:read"}},
+		entitlements.Requirements{{"bearer": {"secrets:/db:read"}}},
+	))
+
+	// Legacy semantics drops the type wildcard; denies still apply.
+	legacy := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithLegacySemantics(true)
+	assert.False(t, legacy.VerifyEntitlements(
+		entitlements.Entitlements{"bearer": {"*:*:read"}},
+		entitlements.Requirements{{"bearer": {"pages:/foo:read"}}},
+	))
+	assert.False(t, legacy.VerifyEntitlements(
+		entitlements.Entitlements{"bearer": {"pages:read", "!pages:/a:read"}},
+		entitlements.Requirements{{"bearer": {"pages:/a:read"}}},
+	))
+
+	// Attenuation does not expand the type wildcard, so it fails closed.
+	assert.False(t, entitlements.IsSubsetEntitlements(
+		entitlements.Entitlements{"bearer": {"secrets:read"}},
+		entitlements.Entitlements{"bearer": admin},
+	))
+}
+
+func TestEntitlementsChecker_HasAnyEffectiveEntitlement(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker([]string{"public:read"}, "bearer", false).
+		WithDisabledSchemes("legacy").
+		WithResourceIndicator("https://api.example.com").
+		WithRegion("eu")
+
+	tests := []struct {
+		name         string
+		entitlements entitlements.Entitlements
+		want         bool
+	}{
+		{"nothing", entitlements.Entitlements{}, false},
+		{"empty lists", entitlements.Entitlements{"bearer": {}}, false},
+		{"one grant", entitlements.Entitlements{"bearer": {"pages:read"}}, true},
+		{"opaque grant", entitlements.Entitlements{"bearer": {"email"}}, true},
+		{"only a disabled scheme", entitlements.Entitlements{"legacy": {"pages:read"}}, false},
+		{"other audience", entitlements.Entitlements{"bearer": {"resource=https://other.example.com", "pages:read"}}, false},
+		{"this audience", entitlements.Entitlements{"bearer": {"resource=https://api.example.com", "pages:read"}}, true},
+		{"only an indicator", entitlements.Entitlements{"bearer": {"resource=https://api.example.com"}}, false},
+		{"only denies", entitlements.Entitlements{"bearer": {"!secrets:read"}}, false},
+		{"other region", entitlements.Entitlements{"bearer": {"pages:read@region=us"}}, false},
+		{"this region", entitlements.Entitlements{"bearer": {"pages:read@region=eu"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ec.HasAnyEffectiveEntitlement(tt.entitlements))
+		})
+	}
+
+	now := time.Unix(1000, 0)
+	held := entitlements.Entitlements{"bearer": {"pages:read", "books:read"}, "legacy": {"admin"}}
+	expiries := map[string]time.Time{"pages:read": now, "books:read": now.Add(-time.Second)}
+	assert.False(t, ec.HasAnyEffectiveEntitlementWithExpiry(held, expiries, now))
+	assert.True(t, ec.HasAnyEffectiveEntitlementWithExpiry(held, expiries, now.Add(-time.Second)))
+	assert.True(t, ec.HasAnyEffectiveEntitlementWithExpiry(held, nil, now))
+}
+
+func TestEntitlementsChecker_WithBranchCombiner(t *testing.T) {
+	twoOfThree := func(results []bool) bool {
+		satisfied := 0
+		for _, ok := range results {
+			if ok {
+				satisfied++
+			}
+		}
+		return satisfied >= 2
+	}
+	requirements := entitlements.Requirements{
+		{"bearer": {"pages:read"}},
+		{"bearer": {"books:read"}},
+		{"apikey": {"reports"}},
+	}
+	tests := []struct {
+		name         string
+		entitlements entitlements.Entitlements
+		want         bool
+	}{
+		{"none", entitlements.Entitlements{"bearer": {"x"}}, false},
+		{"one of three", entitlements.Entitlements{"bearer": {"pages:read"}}, false},
+		{"two of three", entitlements.Entitlements{"bearer": {"pages:read"}, "apikey": {"reports"}}, true},
+		{"three of three", entitlements.Entitlements{"bearer": {"pages:read", "books:read"}, "apikey": {"reports"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithBranchCombiner(twoOfThree)
+			assert.Equal(t, tt.want, ec.VerifyEntitlements(tt.entitlements, requirements))
+			assert.Equal(t, tt.want, ec.PrepareFor(requirements).Check(tt.entitlements))
+			assert.Equal(t, tt.want, ec.Explain(tt.entitlements, requirements).Allowed)
+
+			atLeast := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithBranchCombiner(entitlements.AtLeastBranches(2))
+			assert.Equal(t, tt.want, atLeast.VerifyEntitlements(tt.entitlements, requirements))
+		})
+	}
+
+	// Explain attributes every satisfied branch.
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithBranchCombiner(twoOfThree)
+	assert.Equal(t, entitlements.Decision{Allowed: true, Branch: 1, Grants: []entitlements.Grant{
+		{Scheme: "bearer", Requirement: "books:read", Entitlement: "books:all", Source: entitlements.GrantSourceDirect},
+		{Scheme: "apikey", Requirement: "reports", Entitlement: "reports", Source: entitlements.GrantSourceDirect},
+	}}, ec.Explain(entitlements.Entitlements{"bearer": {"books:all"}, "apikey": {"reports"}}, requirements))
+
+	// Empty requirements still admit everyone; nil restores OR.
+	assert.True(t, ec.VerifyEntitlements(entitlements.Entitlements{}, nil))
+	ec.WithBranchCombiner(nil)
+	assert.True(t, ec.VerifyEntitlements(entitlements.Entitlements{"bearer": {"pages:read"}}, requirements))
+
+	all := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithBranchCombiner(entitlements.AllBranches)
+	assert.False(t, all.VerifyEntitlements(entitlements.Entitlements{"bearer": {"pages:read", "books:read"}}, requirements))
+	assert.True(t, all.VerifyEntitlements(entitlements.Entitlements{"bearer": {"pages:read", "books:read"}, "apikey": {"reports"}}, requirements))
+
+	// Changing the combiner drops denials cached under the old one.
+	cached := entitlements.NewEntitlementsChecker(nil, "bearer", false).
+		WithDenialCache(8, time.Minute).
+		WithBranchCombiner(entitlements.AllBranches)
+	held := entitlements.Entitlements{"bearer": {"pages:read"}}
+	assert.False(t, cached.VerifyEntitlements(held, requirements))
+	cached.WithBranchCombiner(nil)
+	assert.True(t, cached.VerifyEntitlements(held, requirements))
+}
+
+// prefixIndex is a ResourceNameIndex answering from a sorted slice by the
+// literal prefix of the pattern, over-approximating glob matches.
+type prefixIndex []string
+
+func (p prefixIndex) Match(pattern string) []string {
+	prefix := pattern
+	if i := strings.IndexAny(pattern, `*?\`); i >= 0 {
+		prefix = pattern[:i]
+	} else {
+		i, found := slices.BinarySearch(p, pattern)
+		if !found {
+			return nil
+		}
+		return p[i : i+1]
+	}
+	start, _ := slices.BinarySearch(p, prefix)
+	end := start
+	for end < len(p) && strings.HasPrefix(p[end], prefix) {
+		end++
+	}
+	return p[start:end]
+}
+
+func TestEntitlementsChecker_GrantedResourceNames(t *testing.T) {
+	names := []string{"/docs/a", "/docs/a/b", "/docs/b", "/img/x.png", "/img/y.jpg", "/secret", "/*"}
+	sorted := slices.Sorted(slices.Values(names))
+
+	tests := []struct {
+		name         string
+		entitlements []string
+		want         []string
+	}{
+		{"class-wide grant", []string{"pages:read"}, []string{"/*", "/docs/a", "/docs/a/b", "/docs/b", "/img/x.png", "/img/y.jpg", "/secret"}},
+		{"specific grants", []string{"pages:/docs/a:read", "pages:/nope:read", "pages:/secret:all"}, []string{"/docs/a", "/secret"}},
+		{"glob grant", []string{"pages:/img/*.png:read"}, []string{"/img/x.png"}},
+		{"deep glob grant", []string{"pages:/docs/**:read"}, []string{"/docs/a", "/docs/a/b", "/docs/b"}},
+		{"deny carves out", []string{"pages:read", "!pages:/secret:read", "!pages:/img/*:read"}, []string{"/*", "/docs/a", "/docs/a/b", "/docs/b"}},
+		{"type wildcard", []string{"*:/secret:read"}, []string{"/secret"}},
+		{"other verb", []string{"pages:write"}, nil},
+		{"other resource", []string{"books:read"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithResourceNameGlob(true)
+			held := entitlements.Entitlements{"bearer": tt.entitlements}
+			got := ec.GrantedResourceNames(held, "", "pages", "read", entitlements.ResourceNameList(names))
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, got, ec.GrantedResourceNames(held, "bearer", "pages", "read", prefixIndex(sorted)))
+		})
+	}
+
+	// Without glob mode a glob-looking grant is literal, whatever the index returns.
+	ec := entitlements.NewEntitlementsChecker([]string{"pages:/docs/b:read"}, "bearer", false).
+		WithBaseEntitlements([]string{"pages:/docs/a:read"})
+	held := entitlements.Entitlements{"bearer": {"pages:/img/*:read"}}
+	assert.Equal(t, []string{"/docs/a"}, ec.GrantedResourceNames(held, "", "pages", "read", entitlements.ResourceNameList(names)))
+	assert.Equal(t, []string{"/docs/a"}, ec.GrantedResourceNames(held, "", "pages", "read", prefixIndex(sorted)))
+
+	// Anonymous callers enumerate through the anonymous entitlements.
+	assert.Equal(t, []string{"/docs/a", "/docs/b"},
+		ec.GrantedResourceNames(entitlements.Entitlements{}, "", "pages", "read", entitlements.ResourceNameList(names)))
+}
+
+func TestEntitlementsChecker_VerifyWithForbidden(t *testing.T) {
+	required := entitlements.Requirements{{"bearer": {"pages:read"}}}
+	forbidden := entitlements.Requirements{{"bearer": {"suspended"}}, {"bearer": {"pages:/admin:read"}}}
+	tests := []struct {
+		name         string
+		entitlements entitlements.Entitlements
+		want         bool
+	}{
+		{"required, not forbidden", entitlements.Entitlements{"bearer": {"pages:/foo:read"}}, true},
+		{"required and forbidden", entitlements.Entitlements{"bearer": {"pages:read", "suspended"}}, false},
+		{"not required, not forbidden", entitlements.Entitlements{"bearer": {"books:read"}}, false},
+		{"not required, forbidden", entitlements.Entitlements{"bearer": {"suspended"}}, false},
+		{"forbidden through another branch", entitlements.Entitlements{"bearer": {"pages:read"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+			assert.Equal(t, tt.want, ec.VerifyWithForbidden(tt.entitlements, required, forbidden))
+		})
+	}
+
+	ec := entitlements.NewEntitlementsChecker([]string{"public:read"}, "bearer", false)
+	// Empty forbidden forbids nothing; empty required admits everyone.
+	assert.True(t, ec.VerifyWithForbidden(entitlements.Entitlements{"bearer": {"pages:read"}}, required, nil))
+	assert.True(t, ec.VerifyWithForbidden(entitlements.Entitlements{}, nil, forbidden))
+	// Anonymous entitlements count towards forbidden.
+	assert.False(t, ec.VerifyWithForbidden(entitlements.Entitlements{}, nil, entitlements.Requirements{{"bearer": {"public:read"}}}))
+}
+
+func TestEntitlementsFromInstanceSets(t *testing.T) {
+	sets := map[string][]string{
+		"pages": {"foo", "/bar", "foo"},
+		"books": {"b-1"},
+		"empty": {},
+		"bad":   {"", "*", "a:b", "img*", "ok"},
+	}
+	assert.Equal(t, entitlements.Entitlements{"bearer": {
+		"bad:/ok:read", "books:/b-1:read", "pages:/foo:read", "pages:/bar:read",
+	}}, entitlements.EntitlementsFromInstanceSets("", sets, ""))
+	assert.Equal(t, entitlements.Entitlements{"oauth2": {
+		"bad:/ok:write", "books:/b-1:write", "pages:/foo:write", "pages:/bar:write",
+	}}, entitlements.EntitlementsFromInstanceSets("oauth2", sets, "write"))
+	assert.Equal(t, entitlements.Entitlements{"bearer": {}},
+		entitlements.EntitlementsFromInstanceSets("", map[string][]string{"pages": {}}, "read"))
+
+	// The expanded grants name exactly the listed instances.
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithResourceNameGlob(true)
+	held := entitlements.EntitlementsFromInstanceSets("bearer", sets, "read")
+	assert.True(t, ec.VerifyEntitlements(held, entitlements.Requirements{{"bearer": {"pages:/foo:read"}}}))
+	assert.False(t, ec.VerifyEntitlements(held, entitlements.Requirements{{"bearer": {"pages:/baz:read"}}}))
+	assert.False(t, ec.VerifyEntitlements(held, entitlements.Requirements{{"bearer": {"pages:/foo:write"}}}))
+	assert.False(t, ec.VerifyEntitlements(held, entitlements.Requirements{{"bearer": {"empty:/x:read"}}}))
+	assert.False(t, ec.VerifyEntitlements(held, entitlements.Requirements{{"bearer": {"bad:/imgs:read"}}}))
+}
+
+func TestEntitlementsChecker_WithDenyAuditTrail(t *testing.T) {
+	clock := entitlementstest.NewFakeClock(time.Unix(100, 0))
+	var records []entitlements.DenyRecord
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).
+		WithClock(clock).
+		WithDenyAuditTrail(func(r entitlements.DenyRecord) { records = append(records, r) })
+
+	requirements := entitlements.Requirements{
+		{"bearer": {"pages:read", "pages:write", "pages:delete"}},
+		{"bearer": {"books:read", "books:write"}, "apikey": {"reports"}},
+		{"bearer": {"pages:read", "pages:write"}},
+	}
+	held := entitlements.Entitlements{"bearer": {"pages:read", "books:read"}}
+
+	// Allows never fire.
+	assert.True(t, ec.VerifyEntitlements(entitlements.Entitlements{"bearer": {"pages:all"}}, requirements))
+	assert.True(t, ec.PrepareFor(requirements).Check(entitlements.Entitlements{"bearer": {"pages:all"}}))
+	assert.True(t, ec.VerifyEntitlements(held, nil))
+	assert.Empty(t, records)
+
+	assert.False(t, ec.VerifyEntitlements(held, requirements))
+	assert.Equal(t, []entitlements.DenyRecord{{
+		Time: time.Unix(100, 0),
+		Branches: []entitlements.BranchAnalysis{
+			{Unmet: []entitlements.UnmetRequirement{
+				{Scheme: "bearer", Requirement: "pages:write", NearMiss: "pages:read"},
+				{Scheme: "bearer", Requirement: "pages:delete", NearMiss: "pages:read"},
+			}},
+			{MissingSchemes: []string{"apikey"}, Unmet: []entitlements.UnmetRequirement{
+				{Scheme: "apikey", Requirement: "reports"},
+				{Scheme: "bearer", Requirement: "books:write", NearMiss: "books:read"},
+			}},
+			{Unmet: []entitlements.UnmetRequirement{{Scheme: "bearer", Requirement: "pages:write", NearMiss: "pages:read"}}},
+		},
+		Closest: 2,
+	}}, records)
+	assert.Equal(t, []entitlements.UnmetRequirement{{Scheme: "bearer", Requirement: "pages:write", NearMiss: "pages:read"}}, records[0].Unmet())
+
+	// Every deciding path reports its denials.
+	records = nil
+	assert.False(t, ec.PrepareFor(requirements).Check(held))
+	assert.False(t, ec.VerifyEntitlementsUsingSchemes(held, requirements, []string{"bearer"}))
+	ok, err := ec.VerifyResourceEntitlements("pages", "/foo", held, nil, "write")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Len(t, records, 3)
+	assert.Equal(t, []entitlements.UnmetRequirement{{Scheme: "bearer", Requirement: "pages:/foo:write", NearMiss: "pages:read"}}, records[2].Unmet())
+
+	// A cached denial reports too, flagged as cached.
+	records = nil
+	ec.WithDenialCache(8, time.Minute)
+	assert.False(t, ec.VerifyEntitlements(held, requirements))
+	assert.False(t, ec.VerifyEntitlements(held, requirements))
+	assert.Len(t, records, 2)
+	assert.False(t, records[0].Cached)
+	assert.True(t, records[1].Cached)
+	assert.Equal(t, records[0].Branches, records[1].Branches)
+
+	// Being forbidden is not an unmet requirement, and not being forbidden
+	// is no denial.
+	records = nil
+	assert.True(t, ec.VerifyWithForbidden(held, nil, entitlements.Requirements{{"bearer": {"suspended"}}}))
+	assert.False(t, ec.VerifyWithForbidden(held, nil, entitlements.Requirements{{"bearer": {"pages:read"}}}))
+	assert.Empty(t, records)
+}
+
+func TestEntitlementsChecker_SetVerbImplications(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	assert.NoError(t, ec.SetVerbImplications(map[string][]string{
+		"admin": {"write"},
+		"write": {"read", "list"},
+	}))
+
+	tests := []struct {
+		held     string
+		required string
+		want     bool
+	}{
+		{"pages:write", "pages:/foo:read", true},
+		{"pages:write", "pages:/foo:list", true},
+		{"pages:admin", "pages:/foo:read", true}, // transitive
+		{"pages:admin", "pages:/foo:write", true},
+		{"pages:read", "pages:/foo:write", false}, // one way only
+		{"pages:write", "pages:/foo:admin", false},
+		{"books:write", "pages:/foo:read", false},
+		{"pages:all", "pages:/foo:admin", true}, // all still outranks every verb
+		{"pages:all", "pages:/foo:read", true},
+		{"pages:admin", "pages:/foo:all", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.held+" "+tt.required, func(t *testing.T) {
+			assert.Equal(t, tt.want, ec.VerifyEntitlements(
+				entitlements.Entitlements{"bearer": {tt.held}},
+				entitlements.Requirements{{"bearer": {tt.required}}},
+			))
+		})
+	}
+
+	// A cyclic table is rejected and the current one kept.
+	for _, table := range []map[string][]string{
+		{"a": {"b"}, "b": {"c"}, "c": {"a"}},
+		{"a": {"a"}},
+	} {
+		err := ec.SetVerbImplications(table)
+		assert.ErrorIs(t, err, entitlements.ErrVerbImplicationCycle)
+	}
+	assert.EqualError(t, ec.SetVerbImplications(map[string][]string{"a": {"b"}, "b": {"a"}}),
+		"entitlements: verb implication cycle: a -> b -> a")
+	held := entitlements.Entitlements{"bearer": {"pages:admin"}}
+	read := entitlements.Requirements{{"bearer": {"pages:/foo:read"}}}
+	assert.True(t, ec.VerifyEntitlements(held, read))
+
+	// Clearing the table, or legacy semantics, drops implication.
+	assert.False(t, entitlements.NewEntitlementsChecker(nil, "bearer", false).WithLegacySemantics(true).
+		VerifyEntitlements(held, read))
+	assert.NoError(t, ec.SetVerbImplications(nil))
+	assert.False(t, ec.VerifyEntitlements(held, read))
+
+	// Swapping clears denials cached under the old table.
+	ec.WithDenialCache(8, time.Minute)
+	assert.False(t, ec.VerifyEntitlements(held, read))
+	assert.NoError(t, ec.SetVerbImplications(map[string][]string{"admin": {"read"}}))
+	assert.True(t, ec.VerifyEntitlements(held, read))
+}
+
+func TestEntitlementsChecker_SetVerbImplicationsConcurrent(t *testing.T) {
+	// Both tables imply read from write and from admin, the latter only
+	// transitively in the second; a partially read table would deny.
+	tables := []map[string][]string{
+		{"admin": {"read", "write"}, "write": {"read"}},
+		{"admin": {"write"}, "write": {"read"}},
+	}
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	assert.NoError(t, ec.SetVerbImplications(tables[0]))
+	policy := ec.PrepareFor(entitlements.Requirements{{"bearer": {"pages:/foo:read"}}})
+
+	done := make(chan struct{})
+	swapped := make(chan struct{})
+	go func() {
+		defer close(swapped)
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+				assert.NoError(t, ec.SetVerbImplications(tables[i%2]))
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 500 {
+				for _, verb := range []string{"write", "admin"} {
+					held := entitlements.Entitlements{"bearer": {"pages:" + verb}}
+					assert.True(t, ec.VerifyEntitlements(held, entitlements.Requirements{{"bearer": {"pages:/foo:read"}}}))
+					assert.True(t, policy.Check(held))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+	<-swapped
+}
+
+func TestEntitlementsChecker_WithCaseInsensitiveSchemes(t *testing.T) {
+	tests := []struct {
+		name         string
+		entitlements entitlements.Entitlements
+		requirements entitlements.Requirements
+		want         bool
+	}{
+		{"Bearer satisfies bearer", entitlements.Entitlements{"Bearer": {"pages:read"}}, entitlements.Requirements{{"bearer": {"pages:/a:read"}}}, true},
+		{"bearer satisfies BEARER", entitlements.Entitlements{"bearer": {"pages:read"}}, entitlements.Requirements{{"BEARER": {"pages:/a:read"}}}, true},
+		{"AND over folded schemes", entitlements.Entitlements{"Bearer": {"pages:read"}, "ApiKey": {"k"}}, entitlements.Requirements{{"bearer": {"pages:read"}, "apikey": {"k"}}}, true},
+		{"spellings merge", entitlements.Entitlements{"Bearer": {"pages:read"}, "bearer": {"books:read"}}, entitlements.Requirements{{"bearer": {"pages:read", "books:read"}}}, true},
+		{"other scheme still distinct", entitlements.Entitlements{"OAuth2": {"pages:read"}}, entitlements.Requirements{{"bearer": {"pages:read"}}}, false},
+		{"base merges into folded default", entitlements.Entitlements{"BEARER": {"x"}}, entitlements.Requirements{{"Bearer": {"health:read"}}}, true},
+		{"anonymous merges into folded default", entitlements.Entitlements{}, entitlements.Requirements{{"BeArEr": {"public:read"}}}, true},
+		{"disabled scheme folds", entitlements.Entitlements{"LEGACY": {"pages:read"}}, entitlements.Requirements{{"legacy": {"pages:read"}}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ec := entitlements.NewEntitlementsChecker([]string{"public:read"}, "bearer", false).
+				WithBaseEntitlements([]string{"health:read"}).
+				WithDisabledSchemes("Legacy").
+				WithCaseInsensitiveSchemes(true)
+			assert.Equal(t, tt.want, ec.VerifyEntitlements(tt.entitlements, tt.requirements))
+			assert.Equal(t, tt.want, ec.PrepareFor(tt.requirements).Check(tt.entitlements))
+		})
+	}
+
+	// Off by default.
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	assert.False(t, ec.VerifyEntitlements(entitlements.Entitlements{"Bearer": {"pages:read"}}, entitlements.Requirements{{"bearer": {"pages:read"}}}))
+
+	// Per-scheme options and scheme arguments fold too.
+	ec = entitlements.NewEntitlementsChecker(nil, "bearer", false).
+		WithWildcardVerbByScheme(map[string]string{"ApiKey": "*"}).
+		WithCaseInsensitiveSchemes(true)
+	assert.True(t, ec.VerifyEntitlements(entitlements.Entitlements{"APIKEY": {"pages:*"}}, entitlements.Requirements{{"apikey": {"pages:/a:write"}}}))
+	assert.True(t, ec.VerifyEntitlementsUsingSchemes(
+		entitlements.Entitlements{"Bearer": {"pages:read"}, "apikey": {"k"}},
+		entitlements.Requirements{{"bearer": {"pages:read"}}},
+		[]string{"BEARER"},
+	))
+	assert.Equal(t, []string{"/a"}, ec.GrantedResourceNames(
+		entitlements.Entitlements{"Bearer": {"pages:/a:read"}}, "BEARER", "pages", "read", entitlements.ResourceNameList{"/a", "/b"}))
+
+	// A named requirement registered before the option folds as well.
+	named := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	_, err := named.WithNamedRequirements(map[string]entitlements.Requirements{"editor": {{"Bearer": {"pages:write"}}}})
+	assert.NoError(t, err)
+	named.WithCaseInsensitiveSchemes(true)
+	assert.True(t, named.VerifyEntitlements(entitlements.Entitlements{"bearer": {"pages:write"}}, entitlements.Requirements{{"bearer": {"@editor"}}}))
+}
+
+func TestEntitlementsChecker_WithSchemeAliases(t *testing.T) {
+	tests := []struct {
+		name         string
+		entitlements entitlements.Entitlements
+		requirements entitlements.Requirements
+		want         bool
+	}{
+		{"case alias satisfies the canonical scheme", entitlements.Entitlements{"Bearer": {"pages:read"}}, entitlements.Requirements{{"bearer": {"pages:/a:read"}}}, true},
+		{"name alias satisfies the canonical scheme", entitlements.Entitlements{"jwt": {"pages:read"}}, entitlements.Requirements{{"bearer": {"pages:/a:read"}}}, true},
+		{"canonical scheme satisfies an alias", entitlements.Entitlements{"bearer": {"pages:read"}}, entitlements.Requirements{{"jwt": {"pages:/a:read"}}}, true},
+		{"aliases merge", entitlements.Entitlements{"Bearer": {"pages:read"}, "jwt": {"books:read"}}, entitlements.Requirements{{"bearer": {"pages:read", "books:read"}}}, true},
+		{"other case still distinct", entitlements.Entitlements{"BEARER": {"pages:read"}}, entitlements.Requirements{{"bearer": {"pages:read"}}}, false},
+		{"other scheme still distinct", entitlements.Entitlements{"oauth2": {"pages:read"}}, entitlements.Requirements{{"bearer": {"pages:read"}}}, false},
+		{"base merges into an aliased default", entitlements.Entitlements{"jwt": {"x"}}, entitlements.Requirements{{"bearer": {"health:read"}}}, true},
+		{"aliases are not chained", entitlements.Entitlements{"token": {"pages:read"}}, entitlements.Requirements{{"bearer": {"pages:read"}}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).
+				WithBaseEntitlements([]string{"health:read"}).
+				WithSchemeAliases(map[string]string{"Bearer": "bearer", "jwt": "bearer", "token": "jwt"})
+			before := fmt.Sprint(tt.entitlements, tt.requirements)
+			assert.Equal(t, tt.want, ec.VerifyEntitlements(tt.entitlements, tt.requirements))
+			assert.Equal(t, tt.want, ec.PrepareFor(tt.requirements).Check(tt.entitlements), "prepared")
+			assert.Equal(t, before, fmt.Sprint(tt.entitlements, tt.requirements), "caller's maps untouched")
+		})
+	}
+
+	// Off by default.
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	assert.False(t, ec.VerifyEntitlements(entitlements.Entitlements{"jwt": {"pages:read"}}, entitlements.Requirements{{"bearer": {"pages:read"}}}))
+
+	// Combined with case folding, aliases match in any case.
+	ec = entitlements.NewEntitlementsChecker(nil, "bearer", false).
+		WithCaseInsensitiveSchemes(true).
+		WithSchemeAliases(map[string]string{"JWT": "Bearer"})
+	assert.True(t, ec.VerifyEntitlements(entitlements.Entitlements{"jwt": {"pages:read"}}, entitlements.Requirements{{"BEARER": {"pages:read"}}}))
+
+	// The default scheme is never aliased away.
+	ec = entitlements.NewEntitlementsChecker(nil, "bearer", false).
+		WithBaseEntitlements([]string{"health:read"}).
+		WithSchemeAliases(map[string]string{"bearer": "oauth2"})
+	assert.True(t, ec.VerifyEntitlements(entitlements.Entitlements{"bearer": {"x"}}, entitlements.Requirements{{"bearer": {"health:read"}}}))
+	assert.False(t, ec.VerifyEntitlements(entitlements.Entitlements{"bearer": {"pages:read"}}, entitlements.Requirements{{"oauth2": {"pages:read"}}}))
+
+	// Per-scheme options and scheme arguments follow aliases.
+	ec = entitlements.NewEntitlementsChecker(nil, "bearer", false).
+		WithWildcardVerbByScheme(map[string]string{"key": "*"}).
+		WithSchemeAliases(map[string]string{"key": "apikey", "jwt": "bearer"})
+	assert.True(t, ec.VerifyEntitlements(entitlements.Entitlements{"apikey": {"pages:*"}}, entitlements.Requirements{{"apikey": {"pages:/a:write"}}}))
+	assert.Equal(t, []string{"/a"}, ec.GrantedResourceNames(
+		entitlements.Entitlements{"jwt": {"pages:/a:read"}}, "jwt", "pages", "read", entitlements.ResourceNameList{"/a", "/b"}))
+}
+
+func TestEntitlementsChecker_WithMaxPredicateDepth(t *testing.T) {
+	// chain nests references depth levels deep: n1 -> n2 -> ... -> n<depth>.
+	chain := func(depth int) map[string]entitlements.Requirements {
+		named := make(map[string]entitlements.Requirements, depth)
+		for i := 1; i < depth; i++ {
+			named[fmt.Sprintf("n%d", i)] = entitlements.Requirements{{"bearer": {fmt.Sprintf("@n%d", i+1)}}}
+		}
+		named[fmt.Sprintf("n%d", depth)] = entitlements.Requirements{{"bearer": {"pages:read"}}}
+		return named
+	}
+	held := entitlements.Entitlements{"bearer": {"pages:read"}}
+	requirements := entitlements.Requirements{{"bearer": {"@n1"}}}
+
+	t.Run("just under the limit", func(t *testing.T) {
+		ec, err := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithMaxPredicateDepth(4)
+		assert.NoError(t, err)
+		_, err = ec.WithNamedRequirements(chain(3))
+		assert.NoError(t, err)
+		ok, err := ec.VerifyEntitlementsCtx(context.Background(), held, requirements)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		_, err = ec.BindRequirements(ec.ParseRequirements(requirements), nil)
+		assert.NoError(t, err)
+	})
+
+	t.Run("requirement beyond the limit", func(t *testing.T) {
+		ec, err := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithMaxPredicateDepth(4)
+		assert.NoError(t, err)
+		_, err = ec.WithNamedRequirements(chain(4))
+		assert.NoError(t, err)
+		ok, err := ec.VerifyEntitlementsCtx(context.Background(), held, requirements)
+		assert.ErrorIs(t, err, entitlements.ErrPredicateTooDeep)
+		assert.ErrorContains(t, err, "@n1 -> n2 -> n3 -> n4")
+		assert.False(t, ok)
+		_, err = ec.BindRequirements(ec.ParseRequirements(requirements), nil)
+		assert.ErrorIs(t, err, entitlements.ErrPredicateTooDeep)
+		assert.False(t, ec.VerifyEntitlements(held, requirements))
+		// A name one level shallower is within the limit.
+		assert.True(t, ec.VerifyEntitlements(held, entitlements.Requirements{{"bearer": {"@n2"}}}))
+	})
+
+	t.Run("beyond the limit", func(t *testing.T) {
+		ec, err := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithMaxPredicateDepth(4)
+		assert.NoError(t, err)
+		_, err = ec.WithNamedRequirements(chain(5))
+		assert.ErrorIs(t, err, entitlements.ErrPredicateTooDeep)
+		assert.ErrorContains(t, err, "n1 -> n2 -> n3 -> n4 -> n5")
+		// The names were not registered.
+		assert.False(t, ec.VerifyEntitlements(held, requirements))
+	})
+
+	t.Run("limit set after registration", func(t *testing.T) {
+		ec, err := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithNamedRequirements(chain(5))
+		assert.NoError(t, err)
+		_, err = ec.WithMaxPredicateDepth(4)
+		assert.ErrorIs(t, err, entitlements.ErrPredicateTooDeep)
+		// The limit was left unset.
+		assert.True(t, ec.VerifyEntitlements(held, requirements))
+		_, err = ec.WithMaxPredicateDepth(5)
+		assert.NoError(t, err)
+		// Referencing n1 adds a level.
+		assert.False(t, ec.VerifyEntitlements(held, requirements))
+		_, err = ec.WithMaxPredicateDepth(6)
+		assert.NoError(t, err)
+		assert.True(t, ec.VerifyEntitlements(held, requirements))
+	})
+
+	t.Run("diamond depth is the longest path", func(t *testing.T) {
+		ec, err := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithMaxPredicateDepth(2)
+		assert.NoError(t, err)
+		_, err = ec.WithNamedRequirements(map[string]entitlements.Requirements{
+			"a": {{"bearer": {"@b", "@c"}}},
+			"b": {{"bearer": {"pages:read"}}},
+			"c": {{"bearer": {"@d"}}},
+			"d": {{"bearer": {"pages:read"}}},
+		})
+		assert.ErrorIs(t, err, entitlements.ErrPredicateTooDeep)
+		assert.ErrorContains(t, err, "a -> c -> d")
+	})
+}
+
+func TestEntitlementsChecker_AllMissing(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	requirements := entitlements.Requirements{
+		{"bearer": {"pages:/foo:read", "pages:/foo:update"}},
+		{"bearer": {"pages:/foo:read"}, "oauth2": {"audit:read"}},
+		{"bearer": {"books:read", "pages:/foo:delete"}},
+	}
+
+	gaps := ec.AllMissing(entitlements.Entitlements{"bearer": {"pages:read"}}, requirements)
+	assert.Equal(t, []entitlements.BranchGap{
+		{Unmet: []entitlements.UnmetRequirement{{Scheme: "bearer", Requirement: "pages:/foo:update", NearMiss: "pages:read"}}},
+		{
+			MissingSchemes: []string{"oauth2"},
+			Unmet:          []entitlements.UnmetRequirement{{Scheme: "oauth2", Requirement: "audit:read"}},
+		},
+		{Unmet: []entitlements.UnmetRequirement{
+			{Scheme: "bearer", Requirement: "books:read"},
+			{Scheme: "bearer", Requirement: "pages:/foo:delete", NearMiss: "pages:read"},
+		}},
+	}, gaps)
+	for _, gap := range gaps {
+		assert.False(t, gap.Satisfied())
+	}
+
+	// A satisfied branch has an empty gap; the others still report theirs.
+	gaps = ec.AllMissing(entitlements.Entitlements{"bearer": {"pages:read"}, "oauth2": {"audit:read"}}, requirements)
+	assert.Len(t, gaps, 3)
+	assert.False(t, gaps[0].Satisfied())
+	assert.True(t, gaps[1].Satisfied())
+	assert.Equal(t, entitlements.BranchGap{}, gaps[1])
+	assert.Len(t, gaps[2].Unmet, 2)
+
+	assert.Nil(t, ec.AllMissing(entitlements.Entitlements{"bearer": {"pages:read"}}, nil))
+}
+
+func TestEntitlementsChecker_WithBreakGlass(t *testing.T) {
+	var records []entitlements.BreakGlassRecord
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).
+		WithClock(entitlementstest.NewFakeClock(now)).
+		WithBreakGlass(func(e entitlements.Entitlements) (bool, string) {
+			if slices.Contains(e["emergency"], "incident-42") {
+				return true, "incident 42: database outage"
+			}
+			return false, ""
+		}, func(r entitlements.BreakGlassRecord) {
+			records = append(records, r)
+		})
+
+	glass := entitlements.Entitlements{"bearer": {"pages:read"}, "emergency": {"incident-42"}}
+	admin := entitlements.Requirements{{"bearer": {"secrets:/db:delete"}}}
+
+	assert.True(t, ec.VerifyEntitlements(glass, admin))
+	assert.Equal(t, []entitlements.BreakGlassRecord{{Time: now, Reason: "incident 42: database outage"}}, records)
+
+	// Ordinary callers are judged by the requirements and leave no record.
+	assert.False(t, ec.VerifyEntitlements(entitlements.Entitlements{"bearer": {"pages:read"}}, admin))
+	assert.Len(t, records, 1)
+
+	// Forbidden requirements, resource checks and prepared policies give way
+	// too, each with a record.
+	assert.True(t, ec.VerifyWithForbidden(glass, admin, entitlements.Requirements{{"bearer": {"pages:read"}}}))
+	ok, err := ec.VerifyResourceEntitlements("secrets", "/db", glass, admin, "delete")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, ec.PrepareFor(admin).Check(glass))
+	assert.Len(t, records, 4)
+
+	// Restricting schemes hides a break-glass claim under another scheme.
+	assert.False(t, ec.VerifyEntitlementsUsingSchemes(glass, admin, []string{"bearer"}))
+	assert.True(t, ec.VerifyEntitlementsUsingSchemes(glass, admin, []string{"emergency"}))
+	assert.Len(t, records, 5)
+
+	// Explain marks the decision without recording it.
+	d := ec.Explain(glass, admin)
+	assert.Equal(t, entitlements.Decision{
+		Allowed:          true,
+		Branch:           -1,
+		BreakGlass:       true,
+		BreakGlassReason: "incident 42: database outage",
+	}, d)
+	assert.False(t, ec.Explain(entitlements.Entitlements{"bearer": {"secrets:delete"}}, admin).BreakGlass)
+	assert.Len(t, records, 5)
+
+	// Without a record sink break-glass is off.
+	plain := entitlements.NewEntitlementsChecker(nil, "bearer", false).
+		WithBreakGlass(func(entitlements.Entitlements) (bool, string) { return true, "always" }, nil)
+	assert.False(t, plain.VerifyEntitlements(glass, admin))
+}
+
+func TestEntitlementsChecker_WithHierarchyResolver(t *testing.T) {
+	// org /acme → folder /f1 → doc /d1, and doc /d2 directly under /acme.
+	parents := map[entitlements.ResourceRef]entitlements.ResourceRef{
+		{Resource: "doc", ResourceName: "/d1"}:    {Resource: "folder", ResourceName: "/f1"},
+		{Resource: "folder", ResourceName: "/f1"}: {Resource: "org", ResourceName: "/acme"},
+		{Resource: "doc", ResourceName: "/d2"}:    {Resource: "org", ResourceName: "/acme"},
+	}
+	resolve := func(resource, resourceName string) (*entitlements.ResourceRef, bool) {
+		parent, ok := parents[entitlements.ResourceRef{Resource: resource, ResourceName: resourceName}]
+		return &parent, ok
+	}
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithHierarchyResolver(resolve)
+
+	tests := []struct {
+		name     string
+		held     []string
+		required string
+		want     bool
+	}{
+		{"direct grant", []string{"doc:/d1:read"}, "doc:/d1:read", true},
+		{"inherited from parent", []string{"folder:/f1:read"}, "doc:/d1:read", true},
+		{"inherited from grandparent", []string{"org:/acme:read"}, "doc:/d1:read", true},
+		{"inherited by another branch", []string{"org:/acme:read"}, "doc:/d2:read", true},
+		{"not inherited by a sibling", []string{"folder:/f1:read"}, "doc:/d2:read", false},
+		{"not inherited upwards", []string{"doc:/d1:read"}, "folder:/f1:read", false},
+		{"verb is kept", []string{"org:/acme:read"}, "doc:/d1:write", false},
+		{"wildcard verb inherited", []string{"org:/acme:all"}, "doc:/d1:write", true},
+		{"no parent", []string{"org:/acme:read"}, "doc:/d9:read", false},
+		{"class-wide not resolved", []string{"org:/acme:read"}, "doc::read", false},
+		{"deny on child blocks", []string{"org:/acme:read", "!doc:/d1:read"}, "doc:/d1:read", false},
+		{"deny on parent blocks", []string{"org:/acme:read", "!folder:/f1:read"}, "doc:/d1:read", false},
+		{"deny elsewhere leaves", []string{"org:/acme:read", "!folder:/f1:read"}, "doc:/d2:read", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			held := entitlements.Entitlements{"bearer": tt.held}
+			required := entitlements.Requirements{{"bearer": {tt.required}}}
+			assert.Equal(t, tt.want, ec.VerifyEntitlements(held, required))
+			assert.Equal(t, tt.want, ec.PrepareFor(required).Check(held))
+		})
+	}
+
+	// Explain attributes an inherited grant to the ancestor's entitlement.
+	d := ec.Explain(entitlements.Entitlements{"bearer": {"org:/acme:read"}},
+		entitlements.Requirements{{"bearer": {"doc:/d1:read"}}})
+	assert.True(t, d.Allowed)
+	assert.Equal(t, "org:/acme:read", d.Grants[0].Entitlement)
+
+	ok, err := ec.VerifyResourceEntitlements("doc", "/d1",
+		entitlements.Entitlements{"bearer": {"folder:/f1:read"}}, nil)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestEntitlementsChecker_WithHierarchyResolverCycle(t *testing.T) {
+	// /a → /b → /c → /a.
+	next := map[string]string{"/a": "/b", "/b": "/c", "/c": "/a"}
+	calls := 0
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).
+		WithHierarchyResolver(func(resource, resourceName string) (*entitlements.ResourceRef, bool) {
+			calls++
+			parent, ok := next[resourceName]
+			return &entitlements.ResourceRef{Resource: resource, ResourceName: parent}, ok
+		})
+
+	assert.False(t, ec.VerifyEntitlements(
+		entitlements.Entitlements{"bearer": {"node:/z:read"}},
+		entitlements.Requirements{{"bearer": {"node:/a:read"}}},
+	))
+	assert.Equal(t, 3, calls)
+
+	// A grant met before the cycle closes is still found.
+	assert.True(t, ec.VerifyEntitlements(
+		entitlements.Entitlements{"bearer": {"node:/c:read"}},
+		entitlements.Requirements{{"bearer": {"node:/a:read"}}},
+	))
+}
+
+func TestEntitlementsChecker_DenyPrecedence(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker([]string{"pages:read"}, "bearer", true)
+	secret := entitlements.Requirements{{"bearer": {"pages:/secret:read"}}}
+
+	// A deny overrides a wildcard grant, and one more specific than a grant.
+	assert.False(t, ec.VerifyEntitlements(entitlements.Entitlements{"bearer": {"pages:all", "!pages:/secret:read"}}, secret))
+	assert.False(t, ec.VerifyEntitlements(entitlements.Entitlements{"bearer": {"pages:read", "!pages:/secret:read"}}, secret))
+	assert.True(t, ec.VerifyEntitlements(entitlements.Entitlements{"bearer": {"pages:all", "!pages:/secret:read"}},
+		entitlements.Requirements{{"bearer": {"pages:/public:read"}}}))
+	assert.True(t, ec.VerifyEntitlements(entitlements.Entitlements{"bearer": {"pages:all", "!pages:/secret:read"}},
+		entitlements.Requirements{{"bearer": {"pages:/secret:write"}}}))
+
+	// Deny wins over grantReadyByDefault for the identity requirement.
+	ok, err := ec.VerifyResourceEntitlements("pages", "/secret",
+		entitlements.Entitlements{"bearer": {"!pages:/secret:read"}}, nil)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = ec.VerifyResourceEntitlements("pages", "/public",
+		entitlements.Entitlements{"bearer": {"!pages:/secret:read"}}, nil)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// Anonymous callers receive the anonymous grant; a caller presenting
+	// only a deny is not anonymous.
+	assert.True(t, ec.VerifyEntitlements(entitlements.Entitlements{}, secret))
+	assert.False(t, ec.VerifyEntitlements(entitlements.Entitlements{"bearer": {"!pages:/other:read"}}, secret))
+}
+
+func TestEntitlementsChecker_VerifyEntitlementsExplained(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	requirements := entitlements.Requirements{
+		{"bearer": {"pages:/foo:update", "books:read"}},
+		{"bearer": {"pages:/foo:read"}, "mtls": {"clients:verified"}},
+	}
+	held := entitlements.Entitlements{"bearer": {"pages:/bar:update", "pages:/foo:read", "!books:read"}}
+
+	ok, explanation := ec.VerifyEntitlementsExplained(held, requirements)
+	assert.False(t, ok)
+	assert.Equal(t, &entitlements.Explanation{
+		Branches: []entitlements.BranchAnalysis{
+			{Unmet: []entitlements.UnmetRequirement{
+				{Scheme: "bearer", Requirement: "pages:/foo:update", NearMiss: "pages:/bar:update"},
+				{Scheme: "bearer", Requirement: "books:read"},
+			}},
+			{
+				MissingSchemes: []string{"mtls"},
+				Unmet:          []entitlements.UnmetRequirement{{Scheme: "mtls", Requirement: "clients:verified"}},
+			},
+		},
+		Closest: 0,
+	}, explanation)
+
+	b, err := json.Marshal(explanation)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"branches": [
+			{"unmet": [
+				{"scheme": "bearer", "requirement": "pages:/foo:update", "nearMiss": "pages:/bar:update"},
+				{"scheme": "bearer", "requirement": "books:read"}
+			]},
+			{"missingSchemes": ["mtls"], "unmet": [{"scheme": "mtls", "requirement": "clients:verified"}]}
+		],
+		"closest": 0
+	}`, string(b))
+
+	// An allowed caller gets no explanation.
+	ok, explanation = ec.VerifyEntitlementsExplained(held, entitlements.Requirements{{"bearer": {"pages:/foo:read"}}})
+	assert.True(t, ok)
+	assert.Nil(t, explanation)
+	ok, explanation = ec.VerifyEntitlementsExplained(held, nil)
+	assert.True(t, ok)
+	assert.Nil(t, explanation)
+}
+
+func TestEntitlementsChecker_WithResourceNameSeparator(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithResourceNameSeparator(",")
+	tests := []struct {
+		name     string
+		held     string
+		required string
+		want     bool
+	}{
+		{"first listed name", "pages:/foo,/bar,/baz:read", "pages:/foo:read", true},
+		{"middle listed name", "pages:/foo,/bar,/baz:read", "pages:/bar:read", true},
+		{"last listed name", "pages:/foo,/bar,/baz:read", "pages:/baz:read", true},
+		{"unlisted name", "pages:/foo,/bar,/baz:read", "pages:/qux:read", false},
+		{"prefix of a listed name", "pages:/foo,/bar:read", "pages:/fo:read", false},
+		{"verb still applies", "pages:/foo,/bar:read", "pages:/foo:write", false},
+		{"listed wildcard", "pages:/foo,*:read", "pages:/qux:read", true},
+		{"empty entries ignored", "pages:,/foo,:read", "pages:/qux:read", false},
+		{"class-wide requirement", "pages:/foo,/bar:read", "pages::read", true},
+		{"requirement is not split", "pages:/foo:read", "pages:/foo,/bar:read", false},
+		{"other resource type", "pages:/foo,/bar:read", "books:/foo:read", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			held := entitlements.Entitlements{"bearer": {tt.held}}
+			reqs := entitlements.Requirements{{"bearer": {tt.required}}}
+			assert.Equal(t, tt.want, ec.VerifyEntitlements(held, reqs))
+			assert.Equal(t, tt.want, ec.PrepareFor(reqs).Check(held), "prepared")
+		})
+	}
+
+	// Disabled by default: the separator is part of the name.
+	held := entitlements.Entitlements{"bearer": {"pages:/foo,/bar:read"}}
+	assert.False(t, entitlements.NewEntitlementsChecker(nil, "bearer", false).
+		VerifyEntitlements(held, entitlements.Requirements{{"bearer": {"pages:/foo:read"}}}))
+	assert.True(t, entitlements.NewEntitlementsChecker(nil, "bearer", false).
+		VerifyEntitlements(held, entitlements.Requirements{{"bearer": {"pages:/foo,/bar:read"}}}))
+
+	// A separator other than "," leaves commas in names alone.
+	pipe := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithResourceNameSeparator("|")
+	assert.True(t, pipe.VerifyEntitlements(
+		entitlements.Entitlements{"bearer": {"pages:/a,b|/c:read"}},
+		entitlements.Requirements{{"bearer": {"pages:/a,b:read"}}},
+	))
+
+	// The depth bound applies to each listed name.
+	deep := entitlements.NewEntitlementsChecker(nil, "bearer", false).
+		WithResourceNameSeparator(",").WithMaxResourceNameDepth(1)
+	assert.True(t, deep.VerifyEntitlements(
+		entitlements.Entitlements{"bearer": {"pages:/a/b,/c:read"}},
+		entitlements.Requirements{{"bearer": {"pages:/c:read"}}},
+	))
+
+	// GrantedResourceNames lists each listed name.
+	assert.Equal(t, []string{"/bar", "/foo"}, ec.GrantedResourceNames(held, "", "pages", "read",
+		entitlements.ResourceNameList{"/foo", "/bar", "/qux"}))
+}
+
+func TestEntitlementsChecker_WithAnonymousCeiling(t *testing.T) {
+	ceiling := entitlements.Requirements{
+		{"bearer": {"pages:read", "health"}},
+		{"bearer": {"docs:/public:all"}, "oauth2": {"secrets:all"}},
+	}
+	tests := []struct {
+		name      string
+		anonymous []string
+		base      []string
+		offender  string
+	}{
+		{"within", []string{"pages:/home:read", "pages:read", "health", "docs:/public:read"}, nil, ""},
+		{"nothing anonymous", nil, nil, ""},
+		{"deny is not a grant", []string{"!secrets:read"}, nil, ""},
+		{"wider verb", []string{"pages:/home:write"}, nil, "pages:/home:write"},
+		{"wider name", []string{"docs:read"}, nil, "docs:read"},
+		{"other scheme's ceiling", []string{"secrets:read"}, nil, "secrets:read"},
+		{"opaque not listed", []string{"metrics"}, nil, "metrics"},
+		{"type wildcard", []string{"*:*:read"}, nil, "*:*:read"},
+		{"base entitlement", []string{"pages:read"}, []string{"admin:all"}, "admin:all"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ec := entitlements.NewEntitlementsChecker(tt.anonymous, "bearer", false).WithBaseEntitlements(tt.base)
+			_, err := ec.WithAnonymousCeiling(ceiling)
+			if tt.offender == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, entitlements.ErrAnonymousCeiling)
+			assert.ErrorContains(t, err, fmt.Sprintf("%q", tt.offender))
+		})
+	}
+}
+
+func TestEntitlementsChecker_WithAnonymousCeiling_LaterBase(t *testing.T) {
+	ec, err := entitlements.NewEntitlementsChecker(nil, "bearer", false).
+		WithAnonymousCeiling(entitlements.Requirements{{"bearer": {"public:read"}}})
+	assert.NoError(t, err)
+	secret := entitlements.Requirements{{"bearer": {"secrets:/k:read"}}}
+
+	// Base entitlements set after the ceiling are checked against it and,
+	// exceeding it, not applied.
+	ec.WithBaseEntitlements([]string{"secrets:all"})
+	assert.ErrorIs(t, ec.AnonymousCeilingErr(), entitlements.ErrAnonymousCeiling)
+	assert.ErrorContains(t, ec.AnonymousCeilingErr(), `"secrets:all"`)
+	assert.False(t, ec.VerifyEntitlements(nil, secret))
+
+	ec.WithBaseEntitlements([]string{"public:/index:read"})
+	assert.NoError(t, ec.AnonymousCeilingErr())
+	assert.True(t, ec.VerifyEntitlements(nil, entitlements.Requirements{{"bearer": {"public:/index:read"}}}))
+	assert.False(t, ec.VerifyEntitlements(nil, secret))
+}
+
+func TestParseEntitlement(t *testing.T) {
+	tests := []struct {
+		in        string
+		want      entitlements.Entitlement
+		canonical string
+	}{
+		{"pages:/foo:read", entitlements.Entitlement{Resource: "pages", ResourceName: "/foo", Verb: "read"}, "pages:/foo:read"},
+		{"pages::read", entitlements.Entitlement{Resource: "pages", Verb: "read"}, "pages:read"},
+		{"pages:read", entitlements.Entitlement{Resource: "pages", Verb: "read"}, "pages:read"},
+		{"pages:*:all", entitlements.Entitlement{Resource: "pages", ResourceName: "*", Verb: "all"}, "pages:*:all"},
+		{"admin", entitlements.Entitlement{Resource: "admin", Opaque: true}, "admin"},
+		{"pages:/foo:read@region=eu", entitlements.Entitlement{Resource: "pages", ResourceName: "/foo", Verb: "read", Region: "eu"}, "pages:/foo:read@region=eu"},
+		{"resource=https://api.example.com", entitlements.Entitlement{Resource: "resource=https://api.example.com", Opaque: true}, "resource=https://api.example.com"},
+		{"!secrets:read", entitlements.Entitlement{Resource: "!secrets:read", Opaque: true}, "!secrets:read"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			e, err := entitlements.ParseEntitlement(tt.in)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, e)
+			assert.Equal(t, tt.canonical, e.String())
+
+			again, err := entitlements.ParseEntitlement(e.String())
+			assert.NoError(t, err)
+			assert.Equal(t, e, again)
+		})
+	}
+
+	for _, in := range []string{"", "a:b:c:d", "a:b:c:d:e", ":read", "pages::", "pages:", "resource="} {
+		t.Run("invalid "+in, func(t *testing.T) {
+			_, err := entitlements.ParseEntitlement(in)
+			assert.ErrorIs(t, err, entitlements.ErrInvalidEntitlement)
+		})
+	}
+}
+
+func TestCanonicalizeEntitlement(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"pages:read", "pages:*:read"},
+		{"pages::read", "pages:*:read"},
+		{"pages:*:read", "pages:*:read"},
+		{"pages:/foo:read", "pages:/foo:read"},
+		{"pages::read@region=eu", "pages:*:read@region=eu"},
+		{"admin", "admin"},
+		{"resource=https://api.example.com", "resource=https://api.example.com"},
+		{"!secrets:read", "!secrets:read"},
+		{"@editors", "@editors"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := entitlements.CanonicalizeEntitlement(tt.in)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+
+			again, err := entitlements.CanonicalizeEntitlement(got)
+			assert.NoError(t, err)
+			assert.Equal(t, got, again, "idempotent")
+		})
+	}
+
+	for _, in := range []string{"", ":read", "pages::", "a:b:c:d"} {
+		_, err := entitlements.CanonicalizeEntitlement(in)
+		assert.ErrorIs(t, err, entitlements.ErrInvalidEntitlement, in)
+	}
+}
+
+func TestCanonicalizeEntitlements(t *testing.T) {
+	in := entitlements.Entitlements{
+		"bearer": {"pages:read", "pages::read", "pages:*:read", "pages:/a:write", "email", ":read"},
+		"apikey": {},
+	}
+	assert.Equal(t, entitlements.Entitlements{
+		"bearer": {"pages:*:read", "pages:/a:write", "email", ":read"},
+		"apikey": {},
+	}, entitlements.CanonicalizeEntitlements(in))
+	assert.Len(t, in["bearer"], 6, "input untouched")
+	assert.Nil(t, entitlements.CanonicalizeEntitlements(nil))
+
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	for _, reqs := range []entitlements.Requirements{
+		{{"bearer": {"pages:/z:read"}}},
+		{{"bearer": {"pages:/a:write"}}},
+		{{"bearer": {"pages:/z:write"}}},
+		{{"bearer": {"email"}}},
+	} {
+		assert.Equal(t, ec.VerifyEntitlements(in, reqs), ec.VerifyEntitlements(entitlements.CanonicalizeEntitlements(in), reqs), "%v", reqs)
+	}
+}
+
+func TestEntitlementsChecker_ExpandEntitlement(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithKnownVerbs("read", "write", "delete")
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"pages:all", []string{"pages:*:all", "pages:*:delete", "pages:*:read", "pages:*:write"}},
+		{"pages:/a:all", []string{"pages:/a:all", "pages:/a:delete", "pages:/a:read", "pages:/a:write"}},
+		{"pages::read", []string{"pages:*:read"}},
+		{"pages:/a:write", []string{"pages:/a:write"}},
+		{"pages:/a:publish", []string{"pages:/a:publish"}},
+		{"pages:all@region=eu", []string{"pages:*:all@region=eu", "pages:*:delete@region=eu", "pages:*:read@region=eu", "pages:*:write@region=eu"}},
+		{"admin", []string{"admin"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			e, err := entitlements.ParseEntitlement(tt.in)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, ec.ExpandEntitlement(e))
+		})
+	}
+
+	t.Run("no known verbs", func(t *testing.T) {
+		ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+		assert.Equal(t, []string{"pages:*:all"}, ec.ExpandEntitlement(entitlements.Entitlement{Resource: "pages", Verb: "all"}))
+	})
+
+	t.Run("verb groups and wildcard verbs", func(t *testing.T) {
+		ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).
+			WithKnownVerbs("read", "write", "delete").
+			WithWildcardVerbs("*").
+			WithVerbGroups(map[string][]string{"edit": {"read", "write"}})
+		assert.Equal(t, []string{"pages:*:edit", "pages:*:read", "pages:*:write"},
+			ec.ExpandEntitlement(entitlements.Entitlement{Resource: "pages", Verb: "edit"}))
+		assert.Equal(t, []string{"pages:*:all"},
+			ec.ExpandEntitlement(entitlements.Entitlement{Resource: "pages", Verb: "all"}))
+		assert.Equal(t, []string{"pages:*:*", "pages:*:delete", "pages:*:read", "pages:*:write"},
+			ec.ExpandEntitlement(entitlements.Entitlement{Resource: "pages", Verb: "*"}))
+	})
+}
+
+func TestEntitlementsChecker_ValidateEntitlement(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	for _, s := range []string{"pages:/foo:read", "pages:read", "pages:raed", "email"} {
+		assert.NoError(t, ec.ValidateEntitlement(s), s)
+	}
+	for _, s := range []string{"", ":read", "pages::", "a:b:c:d"} {
+		assert.ErrorIs(t, ec.ValidateEntitlement(s), entitlements.ErrInvalidEntitlement, s)
+	}
+
+	ec.WithKnownVerbs("read", "write").WithWildcardVerbByScheme(map[string]string{"apikey": "manage"})
+	assert.NoError(t, ec.ValidateEntitlement("pages:read"))
+	assert.NoError(t, ec.ValidateEntitlement("pages:all"))
+	assert.NoError(t, ec.ValidateEntitlement("pages:manage"))
+	assert.NoError(t, ec.ValidateEntitlement("email"))
+	err := ec.ValidateEntitlement("pages:/foo:raed")
+	assert.ErrorIs(t, err, entitlements.ErrInvalidEntitlement)
+	assert.ErrorContains(t, err, `"pages:/foo:raed" has unknown verb "raed"`)
+}
+
+func TestEntitlementsChecker_ValidateRequirements(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithKnownVerbs("read", "write")
+
+	assert.NoError(t, ec.ValidateRequirements(entitlements.Requirements{
+		{"bearer": {"pages:/foo:read", "pages:{id}:write", "email", "@editors", "!pages:all", "#2:pages:read"}},
+		{"oauth2": {"resource=https://api.example.com"}},
+	}))
+	assert.NoError(t, ec.ValidateRequirements(nil))
+
+	err := ec.ValidateRequirements(entitlements.Requirements{
+		{"bearer": {"pages:read", "pages::"}},
+		{"bearer": {"a:b:c:d"}, "apikey": {""}},
+		{"bearer": {"!pages:raed", "pages:/foo:delete"}},
+	})
+	assert.ErrorIs(t, err, entitlements.ErrInvalidRequirement)
+	assert.EqualError(t, err, strings.Join([]string{
+		`entitlements: invalid requirement: branch 0, scheme "bearer", token 1: "pages::" has an empty verb`,
+		`entitlements: invalid requirement: branch 1, scheme "apikey", token 0: "" is empty`,
+		`entitlements: invalid requirement: branch 1, scheme "bearer", token 0: "a:b:c:d" has too many colons`,
+		`entitlements: invalid requirement: branch 2, scheme "bearer", token 0: "!pages:raed" has a condition that has unknown verb "raed"`,
+		`entitlements: invalid requirement: branch 2, scheme "bearer", token 1: "pages:/foo:delete" has unknown verb "delete"`,
+	}, "\n"))
+
+	// Verb alternatives are checked one by one.
+	ec.WithRequirementVerbSeparator(",")
+	assert.NoError(t, ec.ValidateRequirements(entitlements.Requirements{{"bearer": {"pages:read,write"}}}))
+	assert.ErrorContains(t, ec.ValidateRequirements(entitlements.Requirements{{"bearer": {"pages:read,wirte"}}}),
+		`unknown verb "wirte"`)
+}
+
+func TestEntitlementsChecker_VerifyEntitlementsValidated(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithKnownVerbs("read", "write")
+	read := entitlements.Requirements{{"bearer": {"pages:/foo:read"}}}
+
+	ok, err := ec.VerifyEntitlementsValidated(entitlements.Entitlements{"bearer": {"pages:read"}}, read)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = ec.VerifyEntitlementsValidated(entitlements.Entitlements{"bearer": {"pages:write"}}, read)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// A typo is an error, not a silent non-match.
+	ok, err = ec.VerifyEntitlementsValidated(entitlements.Entitlements{"bearer": {"pages:raed"}}, read)
+	assert.ErrorIs(t, err, entitlements.ErrInvalidEntitlement)
+	assert.ErrorContains(t, err, `scheme "bearer"`)
+	assert.False(t, ok)
+
+	ok, err = ec.VerifyEntitlementsValidated(entitlements.Entitlements{"bearer": {"pages:all"}},
+		entitlements.Requirements{{"bearer": {"pages:/foo:raed"}}})
+	assert.ErrorIs(t, err, entitlements.ErrInvalidRequirement)
+	assert.False(t, ok)
+}
+
+func TestEntitlementsChecker_WithStrictValidation(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithKnownVerbs("read", "write")
+	read := entitlements.Requirements{{"bearer": {"pages:/foo:read"}}}
+	typo := entitlements.Entitlements{"bearer": {"pages:raed", "pages:all"}}
+
+	// Off by default: the typo silently never matches.
+	ok, err := ec.VerifyEntitlementsCtx(context.Background(), typo, read)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ec.WithStrictValidation(true)
+	ok, err = ec.VerifyEntitlementsCtx(context.Background(), typo, read)
+	assert.ErrorIs(t, err, entitlements.ErrInvalidEntitlement)
+	assert.False(t, ok)
+	assert.False(t, ec.VerifyEntitlements(typo, read))
+
+	ok, err = ec.VerifyEntitlementsCtx(context.Background(), entitlements.Entitlements{"bearer": {"pages:all"}},
+		entitlements.Requirements{{"bearer": {"pages:/foo:raed"}}})
+	assert.ErrorIs(t, err, entitlements.ErrInvalidRequirement)
+	assert.False(t, ok)
+
+	ok, err = ec.VerifyEntitlementsCtx(context.Background(), entitlements.Entitlements{"bearer": {"pages:read"}}, read)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestEntitlementsChecker_WithResourceNameWildcardChar(t *testing.T) {
+	requirements := []string{"pages:/foo:read", "pages:/docs/a:read", "pages:/docs/a/b:read", "pages::read", "pages:/foo:write"}
+	tests := []struct {
+		name string
+		glob bool
+		star string
+	}{
+		{"whole name", false, "pages:*:read"},
+		{"short form untouched", false, "pages:read"},
+		{"glob", true, "pages:/docs/*:read"},
+		{"glob double star", true, "pages:/docs/**:read"},
+		{"glob prefix", true, "pages:/f*:read"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			star := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithResourceNameGlob(tt.glob)
+			percent := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithResourceNameGlob(tt.glob).
+				WithResourceNameWildcardChar('%')
+			held := strings.ReplaceAll(tt.star, "*", "%")
+			for _, required := range requirements {
+				want := star.VerifyEntitlements(
+					entitlements.Entitlements{"bearer": {tt.star}}, entitlements.Requirements{{"bearer": {required}}})
+				assert.Equal(t, want, percent.VerifyEntitlements(
+					entitlements.Entitlements{"bearer": {held}}, entitlements.Requirements{{"bearer": {required}}}),
+					"%s against %s", held, required)
+				// '*' keeps its meaning.
+				assert.Equal(t, want, percent.VerifyEntitlements(
+					entitlements.Entitlements{"bearer": {tt.star}}, entitlements.Requirements{{"bearer": {required}}}),
+					"%s against %s", tt.star, required)
+			}
+		})
+	}
+
+	// Without the option '%' is literal.
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	assert.False(t, ec.VerifyEntitlements(entitlements.Entitlements{"bearer": {"pages:%:read"}},
+		entitlements.Requirements{{"bearer": {"pages:/foo:read"}}}))
+
+	// Strict mode and binding recognise the spelling as a wildcard too.
+	strict := entitlements.NewEntitlementsChecker(nil, "bearer", false).
+		WithStrictRequirements(true).WithResourceNameWildcardChar('%')
+	_, err := strict.BindRequirements(strict.ParseRequirements(entitlements.Requirements{{"bearer": {"pages:%:read"}}}), nil)
+	assert.ErrorIs(t, err, entitlements.ErrWildcardRequirement)
+	_, err = strict.BindRequirements(strict.ParseRequirements(entitlements.Requirements{{"bearer": {"pages:{id}:read"}}}),
+		entitlements.Binding{"id": "%"})
+	assert.ErrorIs(t, err, entitlements.ErrInvalidBoundValue)
+
+	// Separators are refused, leaving '*' alone.
+	colon := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithResourceNameWildcardChar(':')
+	assert.True(t, colon.VerifyEntitlements(entitlements.Entitlements{"bearer": {"pages:*:read"}},
+		entitlements.Requirements{{"bearer": {"pages:/foo:read"}}}))
+}
+
+func TestEntitlementsChecker_WithCaseInsensitive(t *testing.T) {
+	tests := []struct {
+		name            string
+		caseInsensitive bool
+		held            []string
+		required        []string
+		expected        bool
+	}{
+		{"disabled: case differs", false, []string{"Pages:READ"}, []string{"pages:read"}, false},
+		{"disabled: exact case", false, []string{"pages:read"}, []string{"pages:read"}, true},
+		{"enabled: case differs", true, []string{"Pages:READ"}, []string{"pages:read"}, true},
+		{"enabled: requirement upper case", true, []string{"pages:read"}, []string{"PAGES:Read"}, true},
+		{"enabled: long form", true, []string{"Pages:foo:READ"}, []string{"pages:foo:read"}, true},
+		{"enabled: resource name stays case-sensitive", true, []string{"pages:Foo:read"}, []string{"pages:foo:read"}, false},
+		{"enabled: wildcard verb", true, []string{"Pages:ALL"}, []string{"pages:delete"}, true},
+		{"enabled: opaque stays exact", true, []string{"Admin"}, []string{"admin"}, false},
+		{"enabled: different verb", true, []string{"Pages:READ"}, []string{"pages:write"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithCaseInsensitive(tt.caseInsensitive)
+			requirements := entitlements.Requirements{{"bearer": tt.required}}
+			held := entitlements.Entitlements{"bearer": tt.held}
+			assert.Equal(t, tt.expected, ec.VerifyEntitlements(held, requirements))
+			assert.Equal(t, tt.expected, ec.PrepareFor(requirements).Check(held))
+		})
+	}
+
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithCaseInsensitive(true)
+	names := ec.GrantedResourceNames(entitlements.Entitlements{"bearer": {"Pages:foo:READ"}},
+		"bearer", "pages", "read", entitlements.ResourceNameList{"foo", "bar"})
+	assert.Equal(t, []string{"foo"}, names)
+}
+
+func TestEntitlementsChecker_WithResourceNamePrefixes(t *testing.T) {
+	tests := []struct {
+		name     string
+		glob     bool
+		held     string
+		required string
+		expected bool
+	}{
+		{"direct child", false, "pages:/docs/*:read", "pages:/docs/report:read", true},
+		{"nested path", false, "pages:/docs/*:read", "pages:/docs/team/report:read", true},
+		{"double star", false, "pages:/docs/**:read", "pages:/docs/team/report:read", true},
+		{"trailing slash below prefix", false, "pages:/docs/*:read", "pages:/docs/team/:read", true},
+		{"prefix itself", false, "pages:/docs/*:read", "pages:/docs:read", false},
+		{"prefix itself with trailing slash", false, "pages:/docs/*:read", "pages:/docs/:read", false},
+		{"sibling prefix", false, "pages:/docs/*:read", "pages:/docspublic/x:read", false},
+		{"sibling prefix sharing a segment", false, "pages:/docs/team/*:read", "pages:/docs/teams/x:read", false},
+		{"root prefix", false, "pages:/*:read", "pages:/docs/team/report:read", true},
+		{"wrong verb", false, "pages:/docs/*:read", "pages:/docs/report:write", false},
+		{"exact name still matches", false, "pages:/docs/report:read", "pages:/docs/report:read", true},
+		{"glob: nested path", true, "pages:/docs/*:read", "pages:/docs/team/report:read", true},
+		{"glob: sibling prefix", true, "pages:/docs/*:read", "pages:/docspublic/x:read", false},
+		{"glob: glob requirement below prefix", true, "pages:/docs/*:read", "pages:/docs/team/*.md:read", true},
+		{"glob: inner glob keeps one segment", true, "pages:/img/*/thumbs/*:read", "pages:/img/a/thumbs/b/c:read", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).
+				WithResourceNamePrefixes(true).
+				WithResourceNameGlob(tt.glob)
+			assert.Equal(t, tt.expected, ec.VerifyEntitlements(
+				entitlements.Entitlements{"bearer": {tt.held}},
+				entitlements.Requirements{{"bearer": {tt.required}}}))
+		})
+	}
+
+	// Disabled, a trailing "/*" is a literal name.
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	assert.False(t, ec.VerifyEntitlements(entitlements.Entitlements{"bearer": {"pages:/docs/*:read"}},
+		entitlements.Requirements{{"bearer": {"pages:/docs/team/report:read"}}}))
+
+	ec = entitlements.NewEntitlementsChecker(nil, "bearer", false).WithResourceNamePrefixes(true)
+	names := ec.GrantedResourceNames(entitlements.Entitlements{"bearer": {"pages:/docs/*:read"}}, "bearer", "pages", "read",
+		entitlements.ResourceNameList{"/docs", "/docs/a", "/docs/team/b", "/docspublic/x"})
+	assert.Equal(t, []string{"/docs/a", "/docs/team/b"}, names)
+}
+
+func TestEntitlementsChecker_VerifyWithContext(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := entitlementstest.NewFakeClock(now)
+	requirements := entitlements.Requirements{
+		{"bearer": {"users:{self}:read", "tenants:{tenant}:read", "projects:{project}:read"}},
+	}
+	held := entitlements.Entitlements{"bearer": {"users:alice:read", "tenants:acme:read", "projects:apollo:read"}}
+	rc := entitlements.RequestContext{
+		Subject:    "alice",
+		Tenant:     "acme",
+		Region:     "eu",
+		Attributes: entitlements.Binding{"project": "apollo"},
+		Expiries:   map[string]time.Time{"tenants:acme:read": now.Add(time.Hour)},
+		Clock:      clock,
+	}
+
+	tests := []struct {
+		name     string
+		rc       func(entitlements.RequestContext) entitlements.RequestContext
+		expected bool
+	}{
+		{"self, tenant and attributes bound", func(rc entitlements.RequestContext) entitlements.RequestContext { return rc }, true},
+		{"another subject", func(rc entitlements.RequestContext) entitlements.RequestContext {
+			rc.Subject = "bob"
+			return rc
+		}, false},
+		{"another tenant", func(rc entitlements.RequestContext) entitlements.RequestContext {
+			rc.Tenant = "globex"
+			return rc
+		}, false},
+		{"subject unbound", func(rc entitlements.RequestContext) entitlements.RequestContext {
+			rc.Subject = ""
+			return rc
+		}, false},
+		{"subject outranks attributes", func(rc entitlements.RequestContext) entitlements.RequestContext {
+			rc.Attributes = entitlements.Binding{"project": "apollo", "self": "bob"}
+			return rc
+		}, true},
+		{"wildcard attribute denied", func(rc entitlements.RequestContext) entitlements.RequestContext {
+			rc.Attributes = entitlements.Binding{"project": "*"}
+			return rc
+		}, false},
+		{"tenant entitlement expired", func(rc entitlements.RequestContext) entitlements.RequestContext {
+			rc.Clock = entitlementstest.NewFakeClock(now.Add(time.Hour))
+			return rc
+		}, false},
+		{"another region", func(rc entitlements.RequestContext) entitlements.RequestContext {
+			rc.Region = "us"
+			return rc
+		}, false},
+		{"no region", func(rc entitlements.RequestContext) entitlements.RequestContext {
+			rc.Region = ""
+			return rc
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithRegion("eu")
+			d := ec.VerifyWithContext(tt.rc(rc), held, requirements)
+			assert.Equal(t, tt.expected, d.Allowed)
+			if d.Allowed {
+				assert.Equal(t, 0, d.Branch)
+				assert.Len(t, d.Grants, 3)
+			} else {
+				assert.Equal(t, -1, d.Branch)
+			}
+		})
+	}
+
+	// A checker with no region matches region-tagged grants in the request's.
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	assert.True(t, ec.VerifyWithContext(rc, held, requirements).Allowed)
+	tagged := entitlements.Entitlements{"bearer": {"users:alice:read@region=eu", "tenants:acme:read", "projects:apollo:read@region=eu"}}
+	assert.True(t, ec.VerifyWithContext(rc, tagged, requirements).Allowed)
+	rc.Region = "us"
+	assert.False(t, ec.VerifyWithContext(rc, tagged, requirements).Allowed)
+	rc.Region = ""
+	assert.False(t, ec.VerifyWithContext(rc, tagged, requirements).Allowed)
+
+	// Break-glass applies after expiry and is recorded.
+	var records []entitlements.BreakGlassRecord
+	ec = entitlements.NewEntitlementsChecker(nil, "bearer", false).
+		WithClock(clock).
+		WithBreakGlass(func(e entitlements.Entitlements) (bool, string) {
+			return slices.Contains(e["bearer"], "ops:oncall"), "incident"
+		}, func(r entitlements.BreakGlassRecord) { records = append(records, r) })
+	d := ec.VerifyWithContext(entitlements.RequestContext{}, entitlements.Entitlements{"bearer": {"ops:oncall"}}, requirements)
+	assert.True(t, d.Allowed)
+	assert.True(t, d.BreakGlass)
+	assert.Equal(t, "incident", d.BreakGlassReason)
+	assert.Equal(t, []entitlements.BreakGlassRecord{{Time: now, Reason: "incident"}}, records)
+
+	d = ec.VerifyWithContext(entitlements.RequestContext{Expiries: map[string]time.Time{"ops:oncall": now}},
+		entitlements.Entitlements{"bearer": {"ops:oncall"}}, requirements)
+	assert.False(t, d.Allowed)
+	assert.Len(t, records, 1)
+}
+
+func TestEntitlementsChecker_WithResourceNameRegex(t *testing.T) {
+	tests := []struct {
+		name     string
+		regex    bool
+		held     string
+		required string
+		expected bool
+	}{
+		{"matching numeric id", true, `orders:~^ORD-\d{4}$:read`, "orders:ORD-1234:read", true},
+		{"non-matching id", true, `orders:~^ORD-\d{4}$:read`, "orders:ORD-12345:read", false},
+		{"implicitly anchored", true, `orders:~ORD-\d{4}:read`, "orders:XORD-1234:read", false},
+		{"alternation anchored as a whole", true, `orders:~ORD|INV:read`, "orders:INVOICE:read", false},
+		{"wrong verb", true, `orders:~^ORD-\d{4}$:read`, "orders:ORD-1234:write", false},
+		{"invalid regex matches nothing", true, `orders:~^ORD-(:read`, "orders:ORD-(:read", false},
+		{"requirement regex needs the same regex", true, `orders:~^ORD-\d{4}$:read`, `orders:~^ORD-\d{4}$:read`, true},
+		{"requirement regex is not expanded", true, "orders:ORD-1234:read", `orders:~^ORD-\d{4}$:read`, false},
+		{"wildcard grant satisfies requirement regex", true, "orders:*:read", `orders:~^ORD-\d{4}$:read`, true},
+		{"literal when disabled", false, `orders:~^ORD-\d{4}$:read`, "orders:ORD-1234:read", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithResourceNameRegex(tt.regex)
+			requirements := entitlements.Requirements{{"bearer": {tt.required}}}
+			held := entitlements.Entitlements{"bearer": {tt.held}}
+			assert.Equal(t, tt.expected, ec.VerifyEntitlements(held, requirements))
+			// Cached expressions give the same answer.
+			assert.Equal(t, tt.expected, ec.VerifyEntitlements(held, requirements))
+		})
+	}
+
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithResourceNameRegex(true)
+
+	err := ec.ValidateEntitlement(`orders:~^ORD-(\d{4}:read`)
+	assert.ErrorIs(t, err, entitlements.ErrInvalidEntitlement)
+	assert.ErrorContains(t, err, "invalid regular expression")
+	assert.NoError(t, ec.ValidateEntitlement(`orders:~^ORD-\d{4}$:read`))
+
+	err = ec.ValidateRequirements(entitlements.Requirements{{"bearer": {`orders:~[:read`}}})
+	assert.ErrorIs(t, err, entitlements.ErrInvalidRequirement)
+	assert.ErrorContains(t, err, "invalid regular expression")
+
+	ok, err := ec.VerifyEntitlementsValidated(entitlements.Entitlements{"bearer": {`orders:~(:read`}},
+		entitlements.Requirements{{"bearer": {"orders:ORD-1234:read"}}})
+	assert.False(t, ok)
+	assert.ErrorIs(t, err, entitlements.ErrInvalidEntitlement)
+
+	// Disabled, an expression is an ordinary name and nothing to validate.
+	plain := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	assert.NoError(t, plain.ValidateEntitlement(`orders:~^ORD-(\d{4}:read`))
+
+	names := ec.GrantedResourceNames(entitlements.Entitlements{"bearer": {`orders:~ORD-\d{4}:read`}}, "bearer", "orders", "read",
+		entitlements.ResourceNameList{"ORD-1234", "ORD-12", "INV-1234"})
+	assert.Equal(t, []string{"ORD-1234"}, names)
+}
+
+func TestEntitlementsChecker_VerifyEntitlementsBatch(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker([]string{"public:read"}, "bearer", false)
+	held := entitlements.Entitlements{"bearer": {"pages:foo:read", "pages:bar:all", "other:read"}}
+	checks := []entitlements.BatchCheck{
+		{Resource: "pages", ResourceName: "foo", Requirements: entitlements.Requirements{{"bearer": {"other:read"}}}},
+		{Resource: "pages", ResourceName: "foo", Verb: "write"},
+		{Resource: "pages", ResourceName: "bar", Verb: "write"},
+		{Resource: "pages", ResourceName: "baz"},
+		{Resource: "pages", ResourceName: "foo", Requirements: entitlements.Requirements{{"bearer": {"admin"}}}},
+		{Resource: "", ResourceName: "foo"},
+	}
+
+	results := ec.VerifyEntitlementsBatch(held, checks)
+	assert.Equal(t, []bool{true, false, true, false, false, false}, results)
+
+	// Each result agrees with VerifyResourceEntitlements.
+	for i, c := range checks {
+		ok, _ := ec.VerifyResourceEntitlements(c.Resource, c.ResourceName, held, c.Requirements, c.Verb)
+		assert.Equal(t, ok, results[i], "check %d", i)
+	}
+
+	// An anonymous caller is judged on the anonymous entitlements in every
+	// check.
+	anon := ec.VerifyEntitlementsBatch(nil, []entitlements.BatchCheck{
+		{Resource: "public", ResourceName: "x"},
+		{Resource: "pages", ResourceName: "foo"},
+	})
+	assert.Equal(t, []bool{true, false}, anon)
+
+	assert.Empty(t, ec.VerifyEntitlementsBatch(held, nil))
+
+	// Break-glass is consulted and recorded once for the whole batch.
+	var records []entitlements.BreakGlassRecord
+	ec.WithBreakGlass(func(e entitlements.Entitlements) (bool, string) {
+		return slices.Contains(e["bearer"], "ops:oncall"), "incident"
+	}, func(r entitlements.BreakGlassRecord) { records = append(records, r) })
+	results = ec.VerifyEntitlementsBatch(entitlements.Entitlements{"bearer": {"ops:oncall"}}, checks)
+	assert.Equal(t, []bool{true, true, true, true, true, false}, results)
+	assert.Len(t, records, 1)
+}
+
+func TestEntitlementsChecker_VerifyEntitlementsCtx(t *testing.T) {
+	requirements := entitlements.Requirements{
+		{"bearer": {"pages:/a:read"}},
+		{"bearer": {"pages:/b:read"}},
+		{"bearer": {"pages:/c:read"}},
+	}
+	held := entitlements.Entitlements{"bearer": {"pages:/c:read"}}
+
+	t.Run("not cancelled", func(t *testing.T) {
+		ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+		ok, err := ec.VerifyEntitlementsCtx(context.Background(), held, requirements)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		ok, err = ec.VerifyEntitlementsCtx(context.Background(), entitlements.Entitlements{"bearer": {"pages:/d:read"}}, requirements)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("already cancelled", func(t *testing.T) {
+		ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		ok, err := ec.VerifyEntitlementsCtx(ctx, held, requirements)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.False(t, ok)
+
+		// Empty requirements admit without consulting ctx.
+		ok, err = ec.VerifyEntitlementsCtx(ctx, held, nil)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("deadline exceeded", func(t *testing.T) {
+		ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		defer cancel()
+		_, err := ec.VerifyEntitlementsCtx(ctx, held, requirements)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("cancelled between branches", func(t *testing.T) {
+		// The resolver runs while the first branch is evaluated and cancels
+		// the request; no later branch is evaluated.
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var resolved []string
+		var records []entitlements.DenyRecord
+		ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).
+			WithDenialCache(16, time.Minute).
+			WithDenyAuditTrail(func(r entitlements.DenyRecord) { records = append(records, r) }).
+			WithHierarchyResolver(func(resource, resourceName string) (*entitlements.ResourceRef, bool) {
+				resolved = append(resolved, resourceName)
+				cancel()
+				return nil, false
+			})
+		ok, err := ec.VerifyEntitlementsCtx(ctx, held, requirements)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.False(t, ok)
+		assert.Equal(t, []string{"/a"}, resolved)
+		assert.Empty(t, records, "a cancelled verification is not a denial")
+
+		// Nor was it cached: the same pair is still allowed.
+		assert.True(t, ec.VerifyEntitlements(held, requirements))
+	})
+}
+
+func TestEntitlementsChecker_CompileRequirements(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker([]string{"public:read"}, "bearer", false)
+	requirements := entitlements.Requirements{
+		{"bearer": {"pages:/a:read", "books:read"}},
+		{"bearer": {"admin"}},
+	}
+	compiled, err := ec.CompileRequirements(requirements)
+	assert.NoError(t, err)
+
+	cases := []entitlements.Entitlements{
+		{"bearer": {"pages:/a:read", "books:read"}},
+		{"bearer": {"pages:/a:read"}},
+		{"bearer": {"admin"}},
+		{"bearer": {"pages:all", "books:all"}},
+		nil,
+	}
+	for i, held := range cases {
+		assert.Equal(t, ec.VerifyEntitlements(held, requirements), ec.VerifyCompiled(held, compiled), "case %d", i)
+	}
+
+	// Later changes to the caller's requirements do not affect the compiled
+	// form.
+	requirements[1]["bearer"][0] = "pages:read"
+	assert.False(t, ec.VerifyCompiled(entitlements.Entitlements{"bearer": {"pages:/b:read"}}, compiled))
+	assert.True(t, ec.VerifyCompiled(entitlements.Entitlements{"bearer": {"admin"}}, compiled))
+
+	// A form compiled by another checker is verified under this one.
+	strict := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	assert.False(t, strict.VerifyCompiled(nil, compiled))
+	assert.True(t, strict.VerifyCompiled(entitlements.Entitlements{"bearer": {"admin"}}, compiled))
+
+	// Neither does it consult or fill this checker's denial cache, nor fire
+	// its decision hook.
+	var events []entitlements.DecisionEvent
+	cached := entitlements.NewEntitlementsChecker(nil, "bearer", false).
+		WithDenialCache(16, time.Hour).
+		WithDecisionHook(func(e entitlements.DecisionEvent) { events = append(events, e) })
+	original := entitlements.Requirements{
+		{"bearer": {"pages:/a:read", "books:read"}},
+		{"bearer": {"admin"}},
+	}
+	partial := entitlements.Entitlements{"bearer": {"pages:/a:read"}}
+	assert.False(t, cached.VerifyEntitlements(partial, original))
+	assert.Len(t, events, 1)
+	before := *cached.CacheStats().Denial
+	events = nil
+	assert.False(t, cached.VerifyCompiled(partial, compiled))
+	assert.False(t, cached.VerifyCompiled(nil, compiled))
+	assert.True(t, cached.VerifyCompiled(entitlements.Entitlements{"bearer": {"admin"}}, compiled))
+	assert.Equal(t, before, *cached.CacheStats().Denial)
+	assert.Empty(t, events)
+
+	assert.False(t, ec.VerifyCompiled(entitlements.Entitlements{"bearer": {"admin"}}, nil))
+
+	// Malformed requirements do not compile.
+	compiled, err = ec.CompileRequirements(entitlements.Requirements{{"bearer": {"pages::"}}})
+	assert.ErrorIs(t, err, entitlements.ErrInvalidRequirement)
+	assert.Nil(t, compiled)
+
+	// Empty requirements compile and admit every caller.
+	compiled, err = ec.CompileRequirements(nil)
+	assert.NoError(t, err)
+	assert.True(t, ec.VerifyCompiled(nil, compiled))
+}
+
+func TestEntitlementsChecker_VerifyCompiled_Concurrent(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	held, requirements := largePolicy()
+	compiled, err := ec.CompileRequirements(requirements)
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				if !ec.VerifyCompiled(held, compiled) {
+					t.Error("compiled requirements denied")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestEntitlementsChecker_ConcurrentUse(t *testing.T) {
+	// Run with -race (make test-race): a checker with every cache and
+	// callback enabled, shared by many goroutines across its read paths.
+	var mu sync.Mutex
+	denials := 0
+	ec := entitlements.NewEntitlementsChecker([]string{"public:read"}, "bearer", false).
+		WithBaseEntitlements([]string{"health:read"}).
+		WithDenialCache(16, time.Minute).
+		WithResourceNameGlob(true).
+		WithResourceNameRegex(true).
+		WithDenyAuditTrail(func(entitlements.DenyRecord) {
+			mu.Lock()
+			denials++
+			mu.Unlock()
+		}).
+		WithHierarchyResolver(func(resource, resourceName string) (*entitlements.ResourceRef, bool) {
+			if resourceName == "/docs/a" {
+				return &entitlements.ResourceRef{Resource: resource, ResourceName: "/docs"}, true
+			}
+			return nil, false
+		})
+	_, err := ec.WithNamedRequirements(map[string]entitlements.Requirements{
+		"editor": {{"bearer": {"pages:update"}}},
+	})
+	assert.NoError(t, err)
+
+	requirements := entitlements.Requirements{
+		{"bearer": {"pages:/docs/a:read"}},
+		{"bearer": {"@editor"}},
+		{"bearer": {"orders:ORD-1234:read"}},
+	}
+	policy := ec.PrepareFor(requirements)
+	compiled, err := ec.CompileRequirements(requirements)
+	assert.NoError(t, err)
+	callers := []struct {
+		held entitlements.Entitlements
+		want bool
+	}{
+		{entitlements.Entitlements{"bearer": {"pages:/docs:read"}}, true},
+		{entitlements.Entitlements{"bearer": {"pages:update"}}, true},
+		{entitlements.Entitlements{"bearer": {`orders:~ORD-\d{4}:read`}}, true},
+		{entitlements.Entitlements{"bearer": {"pages:/img/*.png:read"}}, false},
+		{entitlements.Entitlements{}, false},
+	}
+
+	var wg sync.WaitGroup
+	for g := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 200 {
+				c := callers[(g+i)%len(callers)]
+				assert.Equal(t, c.want, ec.VerifyEntitlements(c.held, requirements))
+				assert.Equal(t, c.want, policy.Check(c.held))
+				assert.Equal(t, c.want, ec.VerifyCompiled(c.held, compiled))
+				assert.Equal(t, c.want, ec.Explain(c.held, requirements).Allowed)
+				ok, err := ec.VerifyEntitlementsCtx(context.Background(), c.held, requirements)
+				assert.NoError(t, err)
+				assert.Equal(t, c.want, ok)
+				ec.AllMissing(c.held, requirements)
+				ec.VerifyEntitlementsBatch(c.held, []entitlements.BatchCheck{{Resource: "public", ResourceName: "x"}})
+				ec.GrantedResourceNames(c.held, "bearer", "pages", "read", entitlements.ResourceNameList{"/docs", "/docs/a"})
+				ec.CacheStats()
+			}
+		}()
+	}
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Positive(t, denials)
+}
+
+func TestEntitlementsChecker_AnonymousEntitlementsMerge(t *testing.T) {
+	// Anonymous entitlements are consulted in place, never merged into the
+	// caller's map: each applies once, and only to a caller presenting
+	// nothing.
+	anonymous := []string{"pages:/a:read", "pages:/b:read"}
+	requirements := entitlements.Requirements{{"bearer": {"pages:/a:read", "pages:/b:read"}}}
+
+	tests := []struct {
+		name    string
+		held    entitlements.Entitlements
+		allowed bool
+		grants  []entitlements.Grant
+	}{
+		{"nil entitlements", nil, true, []entitlements.Grant{
+			{Scheme: "bearer", Requirement: "pages:/a:read", Entitlement: "pages:/a:read", Source: entitlements.GrantSourceAnonymous},
+			{Scheme: "bearer", Requirement: "pages:/b:read", Entitlement: "pages:/b:read", Source: entitlements.GrantSourceAnonymous},
+		}},
+		{"empty entitlements map", entitlements.Entitlements{}, true, []entitlements.Grant{
+			{Scheme: "bearer", Requirement: "pages:/a:read", Entitlement: "pages:/a:read", Source: entitlements.GrantSourceAnonymous},
+			{Scheme: "bearer", Requirement: "pages:/b:read", Entitlement: "pages:/b:read", Source: entitlements.GrantSourceAnonymous},
+		}},
+		{"empty default scheme", entitlements.Entitlements{"bearer": {}}, true, []entitlements.Grant{
+			{Scheme: "bearer", Requirement: "pages:/a:read", Entitlement: "pages:/a:read", Source: entitlements.GrantSourceAnonymous},
+			{Scheme: "bearer", Requirement: "pages:/b:read", Entitlement: "pages:/b:read", Source: entitlements.GrantSourceAnonymous},
+		}},
+		{"default scheme holding some anonymous entitlements", entitlements.Entitlements{"bearer": {"pages:/a:read"}}, false, nil},
+		{"non-default scheme only", entitlements.Entitlements{"apikey": {"pages:/a:read"}}, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ec := entitlements.NewEntitlementsChecker(anonymous, "bearer", false)
+			var before entitlements.Entitlements
+			if tt.held != nil {
+				before = make(entitlements.Entitlements, len(tt.held))
+				for scheme, list := range tt.held {
+					before[scheme] = slices.Clone(list)
+				}
+			}
+
+			assert.Equal(t, tt.allowed, ec.VerifyEntitlements(tt.held, requirements))
+			d := ec.Explain(tt.held, requirements)
+			assert.Equal(t, tt.allowed, d.Allowed)
+			assert.Equal(t, tt.grants, d.Grants)
+			assert.Equal(t, before, tt.held, "caller's entitlements were modified")
+
+			// Repeating the check never accumulates entitlements.
+			assert.Equal(t, tt.allowed, ec.VerifyEntitlements(tt.held, requirements))
+			assert.Equal(t, before, tt.held)
+		})
+	}
+
+	// A caller holding some of the anonymous entitlements gets the rest from
+	// base entitlements, if configured, but never from anonymous ones.
+	ec := entitlements.NewEntitlementsChecker(anonymous, "bearer", false).WithBaseEntitlements([]string{"pages:/b:read"})
+	d := ec.Explain(entitlements.Entitlements{"bearer": {"pages:/a:read"}}, requirements)
+	assert.True(t, d.Allowed)
+	assert.Equal(t, []entitlements.Grant{
+		{Scheme: "bearer", Requirement: "pages:/a:read", Entitlement: "pages:/a:read", Source: entitlements.GrantSourceDirect},
+		{Scheme: "bearer", Requirement: "pages:/b:read", Entitlement: "pages:/b:read", Source: entitlements.GrantSourceBase},
+	}, d.Grants)
+}
+
+func TestRequirements_NilBranch(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	tests := []struct {
+		name         string
+		requirements entitlements.Requirements
+		wantBranch   int
+	}{
+		{"sole nil branch", entitlements.Requirements{nil}, 0},
+		{"nil before a branch", entitlements.Requirements{nil, {"bearer": {"pages:read"}}}, 0},
+		{"nil after an unmet branch", entitlements.Requirements{{"bearer": {"pages:write"}}, nil}, 1},
+	}
+	callers := []entitlements.Entitlements{
+		nil,
+		{},
+		{"bearer": {"files:read"}},
+		{"bearer": {"pages:read"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NoError(t, ec.ValidateRequirements(tt.requirements))
+			compiled, err := ec.CompileRequirements(tt.requirements)
+			assert.NoError(t, err)
+			parsed := ec.ParseRequirements(tt.requirements)
+			for _, caller := range callers {
+				assert.True(t, ec.VerifyEntitlements(caller, tt.requirements), "caller %v", caller)
+				assert.True(t, ec.PrepareFor(tt.requirements).Check(caller), "caller %v", caller)
+				assert.True(t, ec.VerifyCompiled(caller, compiled), "caller %v", caller)
+				assert.True(t, ec.VerifyParsedEntitlements(ec.ParseEntitlements(caller), parsed), "caller %v", caller)
+				ok, branch := ec.VerifyEntitlementsMatch(caller, tt.requirements)
+				assert.True(t, ok, "caller %v", caller)
+				assert.Equal(t, tt.wantBranch, branch, "caller %v", caller)
+			}
+		})
+	}
+
+	t.Run("same as an empty branch", func(t *testing.T) {
+		data, err := json.Marshal(entitlements.Requirements{nil})
+		assert.NoError(t, err)
+		assert.JSONEq(t, `[{}]`, string(data))
+		assert.Equal(t, entitlements.Requirements{{}}.String(), entitlements.Requirements{nil}.String())
+	})
+}
+
+func TestRequirements_JSON(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		requirements := entitlements.Requirements{
+			{"bearer": {"pages:/foo:read", "books:read"}, "apikey": {"admin"}},
+			{"bearer": {"!pages:/secret:read"}},
+			{},
+		}
+		data, err := json.Marshal(requirements)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `[{"apikey":["admin"],"bearer":["pages:/foo:read","books:read"]},{"bearer":["!pages:/secret:read"]},{}]`, string(data))
+
+		var decoded entitlements.Requirements
+		assert.NoError(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, requirements, decoded)
+	})
+
+	t.Run("stable empty forms", func(t *testing.T) {
+		data, err := json.Marshal(entitlements.Requirements(nil))
+		assert.NoError(t, err)
+		assert.Equal(t, "[]", string(data))
+
+		data, err = json.Marshal(entitlements.Requirements{{"bearer": nil}})
+		assert.NoError(t, err)
+		assert.Equal(t, `[{"bearer":[]}]`, string(data))
+
+		var decoded entitlements.Requirements
+		assert.NoError(t, json.Unmarshal([]byte(`[{"bearer":[]}]`), &decoded))
+		assert.Equal(t, entitlements.Requirements{{"bearer": {}}}, decoded)
+
+		decoded = entitlements.Requirements{{"bearer": {"x"}}}
+		assert.NoError(t, json.Unmarshal([]byte(`null`), &decoded))
+		assert.Nil(t, decoded)
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		tests := []struct {
+			name string
+			json string
+			want string
+		}{
+			{"invalid token", `[{"bearer":["pages::"]}]`, `branch 0, scheme "bearer", token 0: "pages::" has an empty verb`},
+			{"empty token", `[{"bearer":["pages:read"]},{"bearer":[""]}]`, `branch 1, scheme "bearer", token 0: "" is empty`},
+			{"invalid condition", `[{"bearer":["pages:read","!:read"]}]`, `token 1: "!:read" has a condition that has an empty resource type`},
+			{"too many colons", `[{"bearer":["a:b:c:d"]}]`, "has too many colons"},
+			{"duplicate scheme", `[{"bearer":["pages:read"],"bearer":["admin"]}]`, `branch 0: scheme "bearer" listed twice`},
+			{"empty scheme name", `[{"":["pages:read"]}]`, "branch 0: empty scheme name"},
+			{"branch not an object", `[["pages:read"]]`, "branch 0: expected"},
+			{"null branch", `[null]`, "branch 0: expected"},
+			{"not an array", `{"bearer":["pages:read"]}`, "expected"},
+			{"non-string token", `[{"bearer":[1]}]`, `scheme "bearer"`},
+			{"null token", `[{"bearer":[null]}]`, `"" is empty`},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var decoded entitlements.Requirements
+				err := json.Unmarshal([]byte(tt.json), &decoded)
+				assert.ErrorIs(t, err, entitlements.ErrInvalidRequirement)
+				assert.ErrorContains(t, err, tt.want)
+				assert.Nil(t, decoded, "a rejected requirement must not decode partially")
+			})
+		}
+	})
+}
+
+func TestEntitlements_JSON(t *testing.T) {
+	held := entitlements.Entitlements{"bearer": {"pages:/foo:read", "admin"}, "apikey": {}}
+	data, err := json.Marshal(held)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"apikey":[],"bearer":["pages:/foo:read","admin"]}`, string(data))
+
+	var decoded entitlements.Entitlements
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, held, decoded)
+
+	data, err = json.Marshal(entitlements.Entitlements(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, "{}", string(data))
+
+	tests := []struct {
+		name string
+		json string
+		want string
+	}{
+		{"invalid entitlement", `{"bearer":["pages::"]}`, `scheme "bearer": "pages::" has an empty verb`},
+		{"empty resource indicator", `{"bearer":["resource="]}`, "has an empty resource indicator"},
+		{"empty scheme name", `{"":["pages:read"]}`, "empty scheme name"},
+		{"duplicate scheme", `{"bearer":["admin"],"bearer":["pages:read"]}`, `scheme "bearer" listed twice`},
+		{"not an object", `[]`, "expected"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var decoded entitlements.Entitlements
+			err := json.Unmarshal([]byte(tt.json), &decoded)
+			assert.ErrorIs(t, err, entitlements.ErrInvalidEntitlement)
+			assert.ErrorContains(t, err, tt.want)
+			assert.Nil(t, decoded)
+		})
+	}
+}
+
+func TestMergeEntitlements(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     entitlements.Entitlements
+		expected entitlements.Entitlements
+	}{
+		{
+			"overlapping schemes",
+			entitlements.Entitlements{"bearer": {"pages:read", "books:read"}},
+			entitlements.Entitlements{"bearer": {"books:read", "admin", "pages:read", "films:read"}},
+			entitlements.Entitlements{"bearer": {"pages:read", "books:read", "admin", "films:read"}},
+		},
+		{
+			"disjoint schemes",
+			entitlements.Entitlements{"bearer": {"pages:read"}},
+			entitlements.Entitlements{"x-api-key": {"admin"}},
+			entitlements.Entitlements{"bearer": {"pages:read"}, "x-api-key": {"admin"}},
+		},
+		{
+			"duplicates within one input",
+			entitlements.Entitlements{"bearer": {"pages:read", "pages:read"}},
+			nil,
+			entitlements.Entitlements{"bearer": {"pages:read"}},
+		},
+		{
+			"empty scheme kept",
+			entitlements.Entitlements{"bearer": {"pages:read"}},
+			entitlements.Entitlements{"x-api-key": {}},
+			entitlements.Entitlements{"bearer": {"pages:read"}, "x-api-key": {}},
+		},
+		{"nil a", nil, entitlements.Entitlements{"bearer": {"admin"}}, entitlements.Entitlements{"bearer": {"admin"}}},
+		{"nil b", entitlements.Entitlements{"bearer": {"admin"}}, nil, entitlements.Entitlements{"bearer": {"admin"}}},
+		{"both nil", nil, nil, entitlements.Entitlements{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aBefore, _ := json.Marshal(tt.a)
+			bBefore, _ := json.Marshal(tt.b)
+
+			merged := entitlements.MergeEntitlements(tt.a, tt.b)
+			assert.Equal(t, tt.expected, merged)
+
+			// Neither input is modified, even through the result.
+			for scheme, list := range merged {
+				if len(list) > 0 {
+					merged[scheme][0] = "changed"
+				}
+			}
+			aAfter, _ := json.Marshal(tt.a)
+			bAfter, _ := json.Marshal(tt.b)
+			assert.JSONEq(t, string(aBefore), string(aAfter))
+			assert.JSONEq(t, string(bBefore), string(bAfter))
+		})
+	}
+}
+
+func TestEntitlementsChecker_MissingEntitlements(t *testing.T) {
+	requirements := entitlements.Requirements{
+		{"bearer": {"pages:/a:read", "pages:/a:update", "books:read"}, "mtls": {"client:trusted"}},
+		{"bearer": {"pages:/a:read", "admin"}},
+		{"apikey": {"reports:read"}, "bearer": {"reports:export"}},
+	}
+	tests := []struct {
+		name     string
+		held     entitlements.Entitlements
+		expected entitlements.Requirements
+	}{
+		{
+			"satisfied branch",
+			entitlements.Entitlements{"bearer": {"pages:/a:read", "admin"}},
+			nil,
+		},
+		{
+			"closest branch lacks one token",
+			entitlements.Entitlements{"bearer": {"pages:/a:read"}},
+			entitlements.Requirements{{"bearer": {"admin"}}},
+		},
+		{
+			"multi-scheme branch lacks tokens under both schemes",
+			entitlements.Entitlements{"bearer": {"pages:/a:read", "pages:/a:update", "books:read", "admin:x"}, "mtls": {}},
+			entitlements.Requirements{{"mtls": {"client:trusted"}}},
+		},
+		{
+			"ties go to the first branch",
+			entitlements.Entitlements{"bearer": {"pages:/a:read", "pages:/a:update"}, "mtls": {"client:trusted"}},
+			entitlements.Requirements{{"bearer": {"books:read"}}},
+		},
+		{
+			"nothing held",
+			entitlements.Entitlements{},
+			entitlements.Requirements{{"bearer": {"pages:/a:read", "admin"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+			missing := ec.MissingEntitlements(tt.held, requirements)
+			assert.Equal(t, tt.expected, missing)
+			if missing != nil {
+				// Granting what is missing satisfies the requirement.
+				assert.True(t, ec.VerifyEntitlements(entitlements.MergeEntitlements(tt.held, entitlements.Entitlements(missing[0])), requirements))
+			}
+		})
+	}
+
+	// Both branches lack one token, but the first also lacks a scheme.
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	assert.Equal(t, entitlements.Requirements{{"bearer": {"admin"}}},
+		ec.MissingEntitlements(entitlements.Entitlements{"bearer": {"reports:export"}}, entitlements.Requirements{
+			{"apikey": {"reports:read"}, "bearer": {"reports:export"}},
+			{"bearer": {"admin"}},
+		}))
+
+	// A scheme that must be present, with no token unmet, is listed empty.
+	assert.Equal(t, entitlements.Requirements{{"apikey": {}}},
+		ec.MissingEntitlements(entitlements.Entitlements{"bearer": {"x"}}, entitlements.Requirements{{"apikey": {}}}))
+
+	assert.Nil(t, ec.MissingEntitlements(entitlements.Entitlements{"bearer": {"x"}}, nil))
+}
+
+func TestEntitlementsFromScopeClaim(t *testing.T) {
+	tests := []struct {
+		name      string
+		scheme    string
+		scope     string
+		expected  entitlements.Entitlements
+		strictErr string
+	}{
+		{"splits on spaces", "", "pages:read files:/x:write", entitlements.Entitlements{"bearer": {"pages:read", "files:/x:write"}}, ""},
+		{"collapses whitespace", "oauth2", "  pages:read \t\n files:/x:write  ", entitlements.Entitlements{"oauth2": {"pages:read", "files:/x:write"}}, ""},
+		{"removes duplicates", "", "pages:read admin pages:read", entitlements.Entitlements{"bearer": {"pages:read", "admin"}}, ""},
+		{"opaque scopes kept", "", "openid profile email", entitlements.Entitlements{"bearer": {"openid", "profile", "email"}}, ""},
+		{"empty claim", "", "", entitlements.Entitlements{"bearer": {}}, ""},
+		{"whitespace-only claim", "", " \t ", entitlements.Entitlements{"bearer": {}}, ""},
+		{"malformed tokens", "", "pages:read :read files:: resource=", entitlements.Entitlements{"bearer": {"pages:read"}},
+			`scope ":read" has an empty resource type`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, entitlements.EntitlementsFromScopeClaim(tt.scheme, tt.scope))
+
+			strict, err := entitlements.EntitlementsFromScopeClaimStrict(tt.scheme, tt.scope)
+			if tt.strictErr == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, strict)
+				return
+			}
+			assert.ErrorIs(t, err, entitlements.ErrInvalidClaim)
+			assert.ErrorContains(t, err, tt.strictErr)
+			assert.ErrorContains(t, err, `scope "files::" has an empty verb`)
+			assert.ErrorContains(t, err, `scope "resource=" has an empty resource indicator`)
+			assert.Nil(t, strict)
+		})
+	}
+}
+
+func TestRequirementsFromOpenAPISecurity(t *testing.T) {
+	tests := []struct {
+		name     string
+		security []map[string][]string
+		expected entitlements.Requirements
+		allowed  []entitlements.Entitlements
+		denied   []entitlements.Entitlements
+	}{
+		{
+			name:     "empty security is public",
+			security: []map[string][]string{},
+			expected: entitlements.Requirements{},
+			allowed:  []entitlements.Entitlements{nil, {"oauth2": {"pages:read"}}},
+		},
+		{
+			name:     "nil security is public",
+			expected: entitlements.Requirements{},
+			allowed:  []entitlements.Entitlements{nil},
+		},
+		{
+			name:     "empty entry makes authentication optional",
+			security: []map[string][]string{{}, {"apiKey": nil}},
+			expected: entitlements.Requirements{{}, {"apiKey": {}}},
+			allowed:  []entitlements.Entitlements{nil, {"apiKey": {}}},
+		},
+		{
+			name:     "single scheme with scopes",
+			security: []map[string][]string{{"oauth2": {"pages:read", "pages:write"}}},
+			expected: entitlements.Requirements{{"oauth2": {"pages:read", "pages:write"}}},
+			allowed:  []entitlements.Entitlements{{"oauth2": {"pages:read", "pages:write"}}, {"oauth2": {"pages:all"}}},
+			denied:   []entitlements.Entitlements{nil, {"oauth2": {"pages:read"}}, {"bearer": {"pages:all"}}},
+		},
+		{
+			name:     "single scheme without scopes requires the scheme",
+			security: []map[string][]string{{"apiKey": {}}},
+			expected: entitlements.Requirements{{"apiKey": {}}},
+			allowed:  []entitlements.Entitlements{{"apiKey": {}}, {"apiKey": {"anything"}}},
+			denied:   []entitlements.Entitlements{nil, {"oauth2": {"pages:all"}}},
+		},
+		{
+			name:     "schemes in one entry are ANDed",
+			security: []map[string][]string{{"apiKey": {}, "oauth2": {"pages:read"}}},
+			expected: entitlements.Requirements{{"apiKey": {}, "oauth2": {"pages:read"}}},
+			allowed:  []entitlements.Entitlements{{"apiKey": {}, "oauth2": {"pages:read"}}},
+			denied:   []entitlements.Entitlements{{"apiKey": {}}, {"oauth2": {"pages:read"}}},
+		},
+		{
+			name:     "entries are ORed",
+			security: []map[string][]string{{"apiKey": {}}, {"oauth2": {"pages:read"}}},
+			expected: entitlements.Requirements{{"apiKey": {}}, {"oauth2": {"pages:read"}}},
+			allowed:  []entitlements.Entitlements{{"apiKey": {}}, {"oauth2": {"pages:read"}}},
+			denied:   []entitlements.Entitlements{nil, {"oauth2": {"pages:write"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requirements := entitlements.RequirementsFromOpenAPISecurity(tt.security)
+			assert.Equal(t, tt.expected, requirements)
+
+			ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+			for _, e := range tt.allowed {
+				assert.True(t, ec.VerifyEntitlements(e, requirements), "%v", e)
+			}
+			for _, e := range tt.denied {
+				assert.False(t, ec.VerifyEntitlements(e, requirements), "%v", e)
+			}
+		})
+	}
+
+	t.Run("result shares nothing with security", func(t *testing.T) {
+		security := []map[string][]string{{"oauth2": {"pages:read"}}}
+		requirements := entitlements.RequirementsFromOpenAPISecurity(security)
+		security[0]["oauth2"][0] = "pages:write"
+		assert.Equal(t, "pages:read", requirements[0]["oauth2"][0])
+	})
+}
+
+func TestRequirementsBuilder(t *testing.T) {
+	t.Run("two-branch OR with multi-scheme AND", func(t *testing.T) {
+		got, err := entitlements.NewRequirementsBuilder().
+			Or().Scheme("bearer", "pages:read").And().Scheme("oauth2", "files:write").
+			Or().Scheme("apikey", "admin", "pages:/{id}:write").
+			Build()
+		assert.NoError(t, err)
+		assert.Equal(t, entitlements.Requirements{
+			{"bearer": {"pages:read"}, "oauth2": {"files:write"}},
+			{"apikey": {"admin", "pages:/{id}:write"}},
+		}, got)
+	})
+
+	t.Run("repeated scheme appends", func(t *testing.T) {
+		got, err := entitlements.NewRequirementsBuilder().
+			Scheme("bearer", "pages:read").And().Scheme("bearer", "files:read").
+			Build()
+		assert.NoError(t, err)
+		assert.Equal(t, entitlements.Requirements{{"bearer": {"pages:read", "files:read"}}}, got)
+	})
+
+	t.Run("scheme without tokens", func(t *testing.T) {
+		got, err := entitlements.NewRequirementsBuilder().Scheme("apikey").Build()
+		assert.NoError(t, err)
+		assert.Equal(t, entitlements.Requirements{{"apikey": {}}}, got)
+	})
+
+	t.Run("stray Or adds no empty branch", func(t *testing.T) {
+		got, err := entitlements.NewRequirementsBuilder().
+			Or().Or().Scheme("bearer", "pages:read").Or().
+			Build()
+		assert.NoError(t, err)
+		assert.Equal(t, entitlements.Requirements{{"bearer": {"pages:read"}}}, got)
+
+		ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+		assert.False(t, ec.VerifyEntitlements(entitlements.Entitlements{"bearer": {"files:read"}}, got))
+	})
+
+	t.Run("empty builder", func(t *testing.T) {
+		got, err := entitlements.NewRequirementsBuilder().Build()
+		assert.NoError(t, err)
+		assert.Equal(t, entitlements.Requirements{}, got)
+	})
+
+	t.Run("invalid tokens", func(t *testing.T) {
+		got, err := entitlements.NewRequirementsBuilder().
+			Scheme("bearer", "pages:read", ":read").
+			Or().Scheme("", "admin").Scheme("oauth2", "a:b:c:d").
+			Build()
+		assert.Nil(t, got)
+		assert.ErrorIs(t, err, entitlements.ErrInvalidRequirement)
+		assert.ErrorContains(t, err, `branch 0, scheme "bearer", token 1: ":read" has an empty resource type`)
+		assert.ErrorContains(t, err, "branch 1: empty scheme name")
+		assert.ErrorContains(t, err, `branch 1, scheme "oauth2", token 0: "a:b:c:d"`)
+	})
+
+	t.Run("build shares nothing with the builder", func(t *testing.T) {
+		b := entitlements.NewRequirementsBuilder().Scheme("bearer", "pages:read")
+		first, err := b.Build()
+		assert.NoError(t, err)
+		b.Scheme("bearer", "files:read")
+		assert.Equal(t, entitlements.Requirements{{"bearer": {"pages:read"}}}, first)
+	})
+}
+
+func TestEntitlementsAndRequirements_String(t *testing.T) {
+	e := entitlements.Entitlements{
+		"oauth2": {"files:write"},
+		"bearer": {"pages:write", "pages:read"},
+		"apikey": {"", "a b", "x|y"},
+	}
+	r := entitlements.Requirements{
+		{"oauth2": {"files:write"}, "bearer": {"pages:read"}},
+		{},
+		{"apikey": {"admin"}},
+	}
+
+	assert.Equal(t, `{apikey: ["", "a b", "x|y"], bearer: [pages:read, pages:write], oauth2: [files:write]}`, e.String())
+	assert.Equal(t, `[{bearer: [pages:read], oauth2: [files:write]} | {} | {apikey: [admin]}]`, r.String())
+	assert.Equal(t, `entitlements.Entitlements{"apikey": {"", "a b", "x|y"}, "bearer": {"pages:read", "pages:write"}, "oauth2": {"files:write"}}`, fmt.Sprintf("%#v", e))
+	assert.Equal(t, `entitlements.Requirements{{"bearer": {"pages:read"}, "oauth2": {"files:write"}}, {}, {"apikey": {"admin"}}}`, fmt.Sprintf("%#v", r))
+	assert.Equal(t, e.String(), fmt.Sprint(e))
+	assert.Equal(t, r.String(), fmt.Sprintf("%v", r))
+
+	// Rendering sorts copies, never the caller's slices.
+	assert.Equal(t, []string{"pages:write", "pages:read"}, e["bearer"])
+
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, e.String(), entitlements.Entitlements{
+			"bearer": {"pages:read", "pages:write"},
+			"apikey": {"x|y", "a b", ""},
+			"oauth2": {"files:write"},
+		}.String())
+	}
+
+	assert.Equal(t, "{}", entitlements.Entitlements(nil).String())
+	assert.Equal(t, "[]", entitlements.Requirements(nil).String())
+	assert.Equal(t, "[]", entitlements.Requirements{}.String())
+	assert.Equal(t, "entitlements.Requirements{}", fmt.Sprintf("%#v", entitlements.Requirements(nil)))
+	assert.Equal(t, "{bearer: []}", entitlements.Entitlements{"bearer": nil}.String())
+}
+
+func TestEntitlementsChecker_WithDecisionHook(t *testing.T) {
+	clock := entitlementstest.NewFakeClock(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	var events []entitlements.DecisionEvent
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).
+		WithClock(clock).
+		WithDecisionHook(func(e entitlements.DecisionEvent) {
+			events = append(events, e)
+		})
+	requirements := entitlements.Requirements{
+		{"bearer": {"admin"}},
+		{"bearer": {"pages:read"}},
+	}
+
+	allowed := entitlements.Entitlements{"bearer": {"pages:read"}}
+	assert.True(t, ec.VerifyEntitlements(allowed, requirements))
+	denied := entitlements.Entitlements{"bearer": {"pages:write"}}
+	assert.False(t, ec.VerifyEntitlements(denied, requirements))
+
+	assert.Equal(t, []entitlements.DecisionEvent{
+		{Time: clock.Now(), Entitlements: allowed, Requirements: requirements, Allowed: true, Branch: 1},
+		{Time: clock.Now(), Entitlements: denied, Requirements: requirements, Allowed: false, Branch: -1},
+	}, events)
+
+	t.Run("fires once per call", func(t *testing.T) {
+		events = nil
+		ec.VerifyEntitlementsMatch(allowed, requirements)
+		_, _ = ec.VerifyEntitlementsCtx(context.Background(), allowed, requirements)
+		ec.VerifyCoversAll(allowed, "", []string{"pages:read"})
+		ec.VerifyEntitlements(allowed, nil)
+		assert.Len(t, events, 4)
+		assert.True(t, events[3].Allowed)
+		assert.Equal(t, -1, events[3].Branch)
+	})
+
+	t.Run("cannot change the decision", func(t *testing.T) {
+		tamper := entitlements.NewEntitlementsChecker(nil, "bearer", false).
+			WithDecisionHook(func(e entitlements.DecisionEvent) {
+				e.Allowed = true
+				e.Entitlements["bearer"][0] = "admin"
+				e.Requirements[0]["bearer"][0] = "pages:write"
+			})
+		held := entitlements.Entitlements{"bearer": {"pages:write"}}
+		assert.False(t, tamper.VerifyEntitlements(held, requirements))
+		assert.Equal(t, "pages:write", held["bearer"][0])
+		assert.Equal(t, "admin", requirements[0]["bearer"][0])
+	})
+
+	t.Run("cancelled verification is not reported", func(t *testing.T) {
+		events = nil
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := ec.VerifyEntitlementsCtx(ctx, allowed, requirements)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Empty(t, events)
+	})
+
+	t.Run("break-glass", func(t *testing.T) {
+		events = nil
+		ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).
+			WithBreakGlass(func(entitlements.Entitlements) (bool, string) { return true, "incident" }, func(entitlements.BreakGlassRecord) {}).
+			WithDecisionHook(func(e entitlements.DecisionEvent) { events = append(events, e) })
+		assert.True(t, ec.VerifyEntitlements(denied, requirements))
+		assert.Len(t, events, 1)
+		assert.True(t, events[0].Allowed)
+		assert.True(t, events[0].BreakGlass)
+		assert.Equal(t, -1, events[0].Branch)
+	})
+}
+
+// fakeMetricsSink counts what WithMetricsSink reports.
+type fakeMetricsSink struct {
+	mu      sync.Mutex
+	allow   int
+	deny    int
+	schemes map[string]int
+}
+
+func (s *fakeMetricsSink) IncAllow() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.allow++
+}
+
+func (s *fakeMetricsSink) IncDeny() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deny++
+}
+
+func (s *fakeMetricsSink) IncSchemeMatch(scheme string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.schemes == nil {
+		s.schemes = make(map[string]int)
+	}
+	s.schemes[scheme]++
+}
+
+func TestEntitlementsChecker_WithMetricsSink(t *testing.T) {
+	sink := &fakeMetricsSink{}
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithMetricsSink(sink)
+	requirements := entitlements.Requirements{
+		{"bearer": {"pages:read"}, "oauth2": {"files:read"}},
+		{"apikey": {"admin"}},
+	}
+
+	assert.True(t, ec.VerifyEntitlements(entitlements.Entitlements{"bearer": {"pages:read"}, "oauth2": {"files:read"}}, requirements))
+	assert.True(t, ec.VerifyEntitlements(entitlements.Entitlements{"apikey": {"admin"}}, requirements))
+	assert.True(t, ec.PrepareFor(requirements).Check(entitlements.Entitlements{"apikey": {"admin"}}))
+	assert.False(t, ec.VerifyEntitlements(entitlements.Entitlements{"bearer": {"pages:read"}}, requirements))
+	assert.False(t, ec.PrepareFor(requirements).Check(nil))
+	assert.True(t, ec.VerifyEntitlements(nil, nil))
+	ok, err := ec.VerifyResourceEntitlements("pages", "/a", entitlements.Entitlements{"bearer": {"files:read"}}, nil)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.Equal(t, 4, sink.allow)
+	assert.Equal(t, 3, sink.deny)
+	assert.Equal(t, map[string]int{"bearer": 1, "oauth2": 1, "apikey": 2}, sink.schemes)
+
+	t.Run("unset sink", func(t *testing.T) {
+		ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithMetricsSink(nil)
+		assert.True(t, ec.VerifyEntitlements(entitlements.Entitlements{"apikey": {"admin"}}, requirements))
+	})
+
+	t.Run("break-glass counts one allow", func(t *testing.T) {
+		sink := &fakeMetricsSink{}
+		ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).
+			WithMetricsSink(sink).
+			WithBreakGlass(func(entitlements.Entitlements) (bool, string) { return true, "incident" }, func(entitlements.BreakGlassRecord) {})
+		assert.True(t, ec.VerifyEntitlements(nil, requirements))
+		assert.Equal(t, 1, sink.allow)
+		assert.Empty(t, sink.schemes)
+	})
+
+	t.Run("cancelled verification is not counted", func(t *testing.T) {
+		sink := &fakeMetricsSink{}
+		ec := entitlements.NewEntitlementsChecker(nil, "bearer", false).WithMetricsSink(sink)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := ec.VerifyEntitlementsCtx(ctx, nil, requirements)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Zero(t, sink.allow+sink.deny)
+	})
+}
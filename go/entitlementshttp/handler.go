@@ -0,0 +1,135 @@
+// Package entitlementshttp exposes an EntitlementsChecker over HTTP, e.g. as
+// the decision endpoint of a sidecar authorization service (CheckHandler), or
+// as middleware enforcing requirements in front of a handler (Middleware).
+package entitlementshttp
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/kdex-tech/entitlements/go"
+)
+
+// maxRequestBytes bounds the body CheckHandler will decode.
+const maxRequestBytes = 1 << 20
+
+// CheckRequest is the body CheckHandler accepts.
+type CheckRequest struct {
+	Entitlements entitlements.Entitlements `json:"entitlements"`
+	Requirements entitlements.Requirements `json:"requirements"`
+}
+
+// CheckResponse is the body CheckHandler returns.
+type CheckResponse struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason"`
+	// Decision attributes an allowed check (see EntitlementsChecker.Explain),
+	// and Explanation analyses a denied one (see
+	// EntitlementsChecker.VerifyEntitlementsExplained). Both are set only by a
+	// handler built WithExplain.
+	Decision    *entitlements.Decision    `json:"decision,omitempty"`
+	Explanation *entitlements.Explanation `json:"explanation,omitempty"`
+}
+
+// Reasons reported in CheckResponse.
+const (
+	ReasonAllowed          = "allowed"
+	ReasonDenied           = "requirements not satisfied"
+	ReasonMalformedRequest = "malformed request"
+	ReasonMethodNotAllowed = "method not allowed"
+)
+
+// CheckHandlerOption configures CheckHandler.
+type CheckHandlerOption func(*checkHandler)
+
+// WithExplain makes CheckHandler explain every verified check: an allowed
+// response carries the Decision naming the grants that satisfied it, and a
+// denied one the Explanation of how each branch fell short. Explanations
+// reveal the caller's entitlements and the requirement's structure, so enable
+// it only where the client is trusted with them, such as a sidecar's own
+// diagnostics. Off by default.
+func WithExplain() CheckHandlerOption {
+	return func(h *checkHandler) {
+		h.explain = true
+	}
+}
+
+type checkHandler struct {
+	explain bool
+}
+
+// CheckHandler returns a handler that decodes a JSON CheckRequest from a POST
+// body, verifies it with ec, and writes a JSON CheckResponse:
+//
+//   - 200 OK with allowed=true when the requirements are satisfied;
+//   - 403 Forbidden with allowed=false when they are not;
+//   - 400 Bad Request for a body that is not a single CheckRequest object
+//     (unknown fields are rejected, and the body is capped at 1 MiB);
+//   - 405 Method Not Allowed for any method other than POST.
+//
+// Every non-200 response has allowed=false, so a client that only inspects
+// the body still fails closed. Omitted or empty requirements allow, exactly as
+// VerifyEntitlements does. WithExplain adds the decision's explanation to 200
+// and 403 responses.
+func CheckHandler(ec *entitlements.EntitlementsChecker, opts ...CheckHandlerOption) http.Handler {
+	h := &checkHandler{}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeResponse(w, http.StatusMethodNotAllowed, CheckResponse{Reason: ReasonMethodNotAllowed})
+			return
+		}
+
+		req, err := decodeCheckRequest(r.Body)
+		if err != nil {
+			writeResponse(w, http.StatusBadRequest, CheckResponse{Reason: ReasonMalformedRequest + ": " + err.Error()})
+			return
+		}
+
+		if h.explain {
+			h.writeExplained(w, ec, req)
+			return
+		}
+		if !ec.VerifyEntitlements(req.Entitlements, req.Requirements) {
+			writeResponse(w, http.StatusForbidden, CheckResponse{Reason: ReasonDenied})
+			return
+		}
+		writeResponse(w, http.StatusOK, CheckResponse{Allowed: true, Reason: ReasonAllowed})
+	})
+}
+
+// writeExplained verifies req as VerifyEntitlementsExplained does and writes
+// the response with its Decision or Explanation.
+func (h *checkHandler) writeExplained(w http.ResponseWriter, ec *entitlements.EntitlementsChecker, req CheckRequest) {
+	ok, explanation := ec.VerifyEntitlementsExplained(req.Entitlements, req.Requirements)
+	if !ok {
+		writeResponse(w, http.StatusForbidden, CheckResponse{Reason: ReasonDenied, Explanation: explanation})
+		return
+	}
+	decision := ec.Explain(req.Entitlements, req.Requirements)
+	writeResponse(w, http.StatusOK, CheckResponse{Allowed: true, Reason: ReasonAllowed, Decision: &decision})
+}
+
+func decodeCheckRequest(body io.Reader) (CheckRequest, error) {
+	var req CheckRequest
+	dec := json.NewDecoder(io.LimitReader(body, maxRequestBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		return CheckRequest{}, err
+	}
+	if dec.More() {
+		return CheckRequest{}, errors.New("unexpected data after the request object")
+	}
+	return req, nil
+}
+
+func writeResponse(w http.ResponseWriter, status int, resp CheckResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
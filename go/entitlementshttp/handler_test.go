@@ -0,0 +1,154 @@
+package entitlementshttp_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kdex-tech/entitlements/go"
+	"github.com/kdex-tech/entitlements/go/entitlementshttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckHandler(t *testing.T) {
+	handler := entitlementshttp.CheckHandler(entitlements.NewEntitlementsChecker(nil, "bearer", false))
+
+	tests := []struct {
+		name        string
+		method      string
+		body        string
+		wantStatus  int
+		wantAllowed bool
+		wantReason  string
+	}{
+		{
+			name:        "allowed",
+			method:      http.MethodPost,
+			body:        `{"entitlements":{"bearer":["pages:read"]},"requirements":[{"bearer":["pages:/foo:read"]}]}`,
+			wantStatus:  http.StatusOK,
+			wantAllowed: true,
+			wantReason:  entitlementshttp.ReasonAllowed,
+		},
+		{
+			name:       "denied",
+			method:     http.MethodPost,
+			body:       `{"entitlements":{"bearer":["pages:read"]},"requirements":[{"bearer":["pages:/foo:write"]}]}`,
+			wantStatus: http.StatusForbidden,
+			wantReason: entitlementshttp.ReasonDenied,
+		},
+		{
+			name:        "no requirements allows",
+			method:      http.MethodPost,
+			body:        `{"entitlements":{}}`,
+			wantStatus:  http.StatusOK,
+			wantAllowed: true,
+			wantReason:  entitlementshttp.ReasonAllowed,
+		},
+		{
+			name:       "malformed json",
+			method:     http.MethodPost,
+			body:       `{"entitlements":`,
+			wantStatus: http.StatusBadRequest,
+			wantReason: entitlementshttp.ReasonMalformedRequest,
+		},
+		{
+			name:       "wrong shape",
+			method:     http.MethodPost,
+			body:       `{"entitlements":["pages:read"]}`,
+			wantStatus: http.StatusBadRequest,
+			wantReason: entitlementshttp.ReasonMalformedRequest,
+		},
+		{
+			name:       "unknown field",
+			method:     http.MethodPost,
+			body:       `{"entitlement":{"bearer":["pages:read"]}}`,
+			wantStatus: http.StatusBadRequest,
+			wantReason: entitlementshttp.ReasonMalformedRequest,
+		},
+		{
+			name:       "trailing data",
+			method:     http.MethodPost,
+			body:       `{"entitlements":{}} {}`,
+			wantStatus: http.StatusBadRequest,
+			wantReason: entitlementshttp.ReasonMalformedRequest,
+		},
+		{
+			name:       "wrong method",
+			method:     http.MethodGet,
+			wantStatus: http.StatusMethodNotAllowed,
+			wantReason: entitlementshttp.ReasonMethodNotAllowed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, "/check", strings.NewReader(tt.body)))
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+			var resp entitlementshttp.CheckResponse
+			assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+			assert.Equal(t, tt.wantAllowed, resp.Allowed)
+			assert.True(t, strings.HasPrefix(resp.Reason, tt.wantReason), "reason %q", resp.Reason)
+		})
+	}
+}
+
+func TestCheckHandler_WithExplain(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	handler := entitlementshttp.CheckHandler(ec, entitlementshttp.WithExplain())
+
+	check := func(t *testing.T, h http.Handler, body string) (int, entitlementshttp.CheckResponse) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/check", strings.NewReader(body)))
+		var resp entitlementshttp.CheckResponse
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		return rec.Code, resp
+	}
+
+	t.Run("allowed carries the decision", func(t *testing.T) {
+		status, resp := check(t, handler,
+			`{"entitlements":{"bearer":["pages:read"]},"requirements":[{"bearer":["pages:/foo:write"]},{"bearer":["pages:/foo:read"]}]}`)
+		assert.Equal(t, http.StatusOK, status)
+		assert.True(t, resp.Allowed)
+		assert.Nil(t, resp.Explanation)
+		if assert.NotNil(t, resp.Decision) {
+			assert.True(t, resp.Decision.Allowed)
+			assert.Equal(t, 1, resp.Decision.Branch)
+			assert.Equal(t, []entitlements.Grant{{
+				Scheme:      "bearer",
+				Requirement: "pages:/foo:read",
+				Entitlement: "pages:read",
+				Source:      entitlements.GrantSourceDirect,
+			}}, resp.Decision.Grants)
+		}
+	})
+
+	t.Run("denied carries the explanation", func(t *testing.T) {
+		status, resp := check(t, handler,
+			`{"entitlements":{"bearer":["pages:read"]},"requirements":[{"bearer":["pages:/foo:write"]},{"oauth2":["files:read"]}]}`)
+		assert.Equal(t, http.StatusForbidden, status)
+		assert.False(t, resp.Allowed)
+		assert.Equal(t, entitlementshttp.ReasonDenied, resp.Reason)
+		assert.Nil(t, resp.Decision)
+		assert.Equal(t, &entitlements.Explanation{
+			Branches: []entitlements.BranchAnalysis{
+				{Unmet: []entitlements.UnmetRequirement{{Scheme: "bearer", Requirement: "pages:/foo:write", NearMiss: "pages:read"}}},
+				{MissingSchemes: []string{"oauth2"}, Unmet: []entitlements.UnmetRequirement{{Scheme: "oauth2", Requirement: "files:read"}}},
+			},
+		}, resp.Explanation)
+	})
+
+	t.Run("off by default", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		entitlementshttp.CheckHandler(ec).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/check",
+			strings.NewReader(`{"entitlements":{"bearer":["pages:read"]},"requirements":[{"bearer":["pages:/foo:read"]}]}`)))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NotContains(t, rec.Body.String(), "decision")
+		assert.NotContains(t, rec.Body.String(), "explanation")
+	})
+}
@@ -0,0 +1,78 @@
+package entitlementshttp
+
+import (
+	"net/http"
+
+	"github.com/kdex-tech/entitlements/go"
+)
+
+// MiddlewareOption configures Middleware.
+type MiddlewareOption func(*middleware)
+
+// WithDeniedStatus sets the status Middleware writes on a denial; 403
+// Forbidden by default.
+func WithDeniedStatus(status int) MiddlewareOption {
+	return func(m *middleware) {
+		m.status = status
+	}
+}
+
+// WithDeniedBody sets the body, and its content type, Middleware writes on a
+// denial, replacing the default JSON CheckResponse with allowed=false.
+func WithDeniedBody(contentType string, body []byte) MiddlewareOption {
+	return func(m *middleware) {
+		m.contentType = contentType
+		m.body = body
+	}
+}
+
+type middleware struct {
+	status      int
+	contentType string
+	body        []byte
+}
+
+// Middleware returns net/http middleware that admits a request to the next
+// handler only when checker verifies the entitlements extract maps from it —
+// from headers or JWT claims, say — against the requirements extract maps
+// from it, typically from route metadata. Verification runs under the
+// request's context (see VerifyEntitlementsCtx).
+//
+// A denied request is answered with 403 Forbidden and a JSON CheckResponse
+// with allowed=false, unless WithDeniedStatus or WithDeniedBody say
+// otherwise, and the next handler is not called. A request whose context ends
+// during verification is answered with 503 Service Unavailable, never passed
+// on. As with VerifyEntitlements, empty requirements admit every request:
+// extract must return the route's requirements for every protected route.
+func Middleware(checker *entitlements.EntitlementsChecker, extract func(*http.Request) (entitlements.Entitlements, entitlements.Requirements), opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	m := &middleware{status: http.StatusForbidden}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			held, requirements := extract(r)
+			ok, err := checker.VerifyEntitlementsCtx(r.Context(), held, requirements)
+			switch {
+			case err != nil:
+				http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			case !ok:
+				m.deny(w)
+			default:
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+func (m *middleware) deny(w http.ResponseWriter) {
+	if m.body == nil {
+		writeResponse(w, m.status, CheckResponse{Reason: ReasonDenied})
+		return
+	}
+	if m.contentType != "" {
+		w.Header().Set("Content-Type", m.contentType)
+	}
+	w.WriteHeader(m.status)
+	_, _ = w.Write(m.body)
+}
@@ -0,0 +1,113 @@
+package entitlementshttp_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kdex-tech/entitlements/go"
+	"github.com/kdex-tech/entitlements/go/entitlementshttp"
+	"github.com/stretchr/testify/assert"
+)
+
+// extractHeader maps the X-Entitlements header to bearer entitlements and
+// requires read on the requested path.
+func extractHeader(r *http.Request) (entitlements.Entitlements, entitlements.Requirements) {
+	held := entitlements.Entitlements{}
+	if h := r.Header.Get("X-Entitlements"); h != "" {
+		held["bearer"] = strings.Split(h, ",")
+	}
+	return held, entitlements.Requirements{{"bearer": {"pages:" + r.URL.Path + ":read"}}}
+}
+
+func TestMiddleware(t *testing.T) {
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("page"))
+	})
+
+	tests := []struct {
+		name            string
+		opts            []entitlementshttp.MiddlewareOption
+		header          string
+		wantStatus      int
+		wantBody        string
+		wantContentType string
+	}{
+		{
+			name:            "allowed",
+			header:          "pages:/foo:read",
+			wantStatus:      http.StatusOK,
+			wantBody:        "page",
+			wantContentType: "text/plain; charset=utf-8",
+		},
+		{
+			name:            "denied",
+			header:          "pages:/bar:read",
+			wantStatus:      http.StatusForbidden,
+			wantBody:        `{"allowed":false,"reason":"requirements not satisfied"}` + "\n",
+			wantContentType: "application/json",
+		},
+		{
+			name:            "anonymous denied",
+			wantStatus:      http.StatusForbidden,
+			wantBody:        `{"allowed":false,"reason":"requirements not satisfied"}` + "\n",
+			wantContentType: "application/json",
+		},
+		{
+			name:            "configured status and body",
+			opts:            []entitlementshttp.MiddlewareOption{entitlementshttp.WithDeniedStatus(http.StatusNotFound), entitlementshttp.WithDeniedBody("text/plain", []byte("not found"))},
+			header:          "pages:/bar:read",
+			wantStatus:      http.StatusNotFound,
+			wantBody:        "not found",
+			wantContentType: "text/plain",
+		},
+		{
+			name:            "configured status keeps the default body",
+			opts:            []entitlementshttp.MiddlewareOption{entitlementshttp.WithDeniedStatus(http.StatusUnauthorized)},
+			wantStatus:      http.StatusUnauthorized,
+			wantBody:        `{"allowed":false,"reason":"requirements not satisfied"}` + "\n",
+			wantContentType: "application/json",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := entitlementshttp.Middleware(ec, extractHeader, tt.opts...)(next)
+			req := httptest.NewRequest(http.MethodGet, "/foo", nil)
+			if tt.header != "" {
+				req.Header.Set("X-Entitlements", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, tt.wantBody, rec.Body.String())
+			assert.Equal(t, tt.wantContentType, rec.Header().Get("Content-Type"))
+			if tt.wantStatus != http.StatusOK {
+				var resp entitlementshttp.CheckResponse
+				if json.Unmarshal(rec.Body.Bytes(), &resp) == nil {
+					assert.False(t, resp.Allowed)
+				}
+			}
+		})
+	}
+}
+
+func TestMiddleware_CancelledRequest(t *testing.T) {
+	called := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true })
+	handler := entitlementshttp.Middleware(entitlements.NewEntitlementsChecker(nil, "bearer", false), extractHeader)(next)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, "/foo", nil).WithContext(ctx)
+	req.Header.Set("X-Entitlements", "pages:/foo:read")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.False(t, called)
+}
@@ -0,0 +1,54 @@
+package entitlementspb
+
+import (
+	"strings"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/kdex-tech/entitlements/go"
+	rpcstatus "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+)
+
+// DeniedReasonHeader is the response header CheckResponse sets to the reason
+// for a denial.
+const DeniedReasonHeader = "x-entitlements-denied-reason"
+
+// CheckResponse returns d as an Envoy ext_authz CheckResponse, for an
+// ext_authz gRPC server (authv3.AuthorizationServer) to return from Check.
+//
+// An allowed decision has status OK and an empty ok_response, so the request
+// proceeds unchanged. A denied one has status PERMISSION_DENIED with reason
+// as its message, and a denied_response of HTTP 403 carrying reason as its
+// body and in the DeniedReasonHeader header; line breaks in reason are
+// replaced by spaces so it is a valid header value. An empty reason sends no
+// header and an empty body. Choose reason for the client's eyes — a
+// summary of an Explanation, say, rather than the caller's entitlements.
+func CheckResponse(d entitlements.Decision, reason string) *authv3.CheckResponse {
+	if d.Allowed {
+		return &authv3.CheckResponse{
+			Status: &rpcstatus.Status{Code: int32(codes.OK)},
+			HttpResponse: &authv3.CheckResponse_OkResponse{
+				OkResponse: &authv3.OkHttpResponse{},
+			},
+		}
+	}
+
+	reason = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ").Replace(reason)
+	denied := &authv3.DeniedHttpResponse{
+		Status: &typev3.HttpStatus{Code: typev3.StatusCode_Forbidden},
+		Body:   reason,
+	}
+	if reason != "" {
+		denied.Headers = []*corev3.HeaderValueOption{{
+			Header: &corev3.HeaderValue{Key: DeniedReasonHeader, Value: reason},
+		}}
+	}
+	return &authv3.CheckResponse{
+		Status: &rpcstatus.Status{Code: int32(codes.PermissionDenied), Message: reason},
+		HttpResponse: &authv3.CheckResponse_DeniedResponse{
+			DeniedResponse: denied,
+		},
+	}
+}
@@ -0,0 +1,65 @@
+package entitlementspb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/kdex-tech/entitlements/go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrPermissionDenied is wrapped by the error UnaryAuthorizer returns for a
+// denied call; UnaryServerInterceptor reports it as codes.PermissionDenied.
+var ErrPermissionDenied = errors.New("entitlementspb: permission denied")
+
+// Resolver maps a gRPC call, by its context and full method name
+// ("/package.Service/Method"), to the caller's entitlements — typically from
+// the incoming metadata (metadata.FromIncomingContext) or a verified token
+// an earlier interceptor put in ctx — and the method's requirements.
+type Resolver func(ctx context.Context, fullMethod string) (entitlements.Entitlements, entitlements.Requirements)
+
+// UnaryAuthorizer returns the authorization step of a gRPC unary server
+// interceptor. It verifies the entitlements resolve returns against the
+// method's requirements under ctx (see VerifyEntitlementsCtx) and returns
+// nil to admit the call, an error wrapping ErrPermissionDenied and naming the
+// method when verification fails, or ctx's error when ctx ends first. As with
+// VerifyEntitlements, empty requirements admit every call, so resolve must
+// return requirements for every protected method.
+//
+// UnaryServerInterceptor wraps it for a grpc.Server; use the step directly
+// to authorize from an interceptor chain of your own.
+func UnaryAuthorizer(checker *entitlements.EntitlementsChecker, resolve Resolver) func(ctx context.Context, fullMethod string) error {
+	return func(ctx context.Context, fullMethod string) error {
+		held, requirements := resolve(ctx, fullMethod)
+		ok, err := checker.VerifyEntitlementsCtx(ctx, held, requirements)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrPermissionDenied, fullMethod)
+		}
+		return nil
+	}
+}
+
+// UnaryServerInterceptor returns a gRPC unary server interceptor that
+// authorizes each call as UnaryAuthorizer does before invoking its handler.
+// A denied call fails with codes.PermissionDenied, naming the method, and a
+// call whose ctx ends first with the code matching ctx's error
+// (codes.Canceled or codes.DeadlineExceeded). Install it with
+// grpc.UnaryInterceptor or grpc.ChainUnaryInterceptor.
+func UnaryServerInterceptor(checker *entitlements.EntitlementsChecker, resolve Resolver) grpc.UnaryServerInterceptor {
+	authorize := UnaryAuthorizer(checker, resolve)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := authorize(ctx, info.FullMethod); err != nil {
+			if errors.Is(err, ErrPermissionDenied) {
+				return nil, status.Error(codes.PermissionDenied, err.Error())
+			}
+			return nil, status.FromContextError(err).Err()
+		}
+		return handler(ctx, req)
+	}
+}
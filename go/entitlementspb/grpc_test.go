@@ -0,0 +1,73 @@
+package entitlementspb_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kdex-tech/entitlements/go"
+	"github.com/kdex-tech/entitlements/go/entitlementspb"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type metadataKey struct{}
+
+func TestUnaryAuthorizer(t *testing.T) {
+	methods := map[string]entitlements.Requirements{
+		"/pages.v1.Pages/Get":    {{"bearer": {"pages:read"}}},
+		"/pages.v1.Pages/Delete": {{"bearer": {"pages:delete"}}},
+	}
+	resolve := func(ctx context.Context, fullMethod string) (entitlements.Entitlements, entitlements.Requirements) {
+		held, _ := ctx.Value(metadataKey{}).([]string)
+		return entitlements.Entitlements{"bearer": held}, methods[fullMethod]
+	}
+	checker := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	authorize := entitlementspb.UnaryAuthorizer(checker, resolve)
+	interceptor := entitlementspb.UnaryServerInterceptor(checker, resolve)
+
+	tests := []struct {
+		name       string
+		held       []string
+		method     string
+		wantCalled bool
+		wantCode   codes.Code
+	}{
+		{"allowed", []string{"pages:read"}, "/pages.v1.Pages/Get", true, codes.OK},
+		{"denied", []string{"pages:read"}, "/pages.v1.Pages/Delete", false, codes.PermissionDenied},
+		{"no entitlements denied", nil, "/pages.v1.Pages/Get", false, codes.PermissionDenied},
+		{"method without requirements allowed", nil, "/health.v1.Health/Check", true, codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := func(ctx context.Context, req any) (any, error) {
+				called = true
+				return "ok", nil
+			}
+			ctx := context.WithValue(context.Background(), metadataKey{}, tt.held)
+			resp, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+
+			assert.Equal(t, tt.wantCalled, called)
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			if tt.wantCode != codes.OK {
+				assert.ErrorContains(t, err, tt.method)
+				assert.ErrorIs(t, authorize(ctx, tt.method), entitlementspb.ErrPermissionDenied)
+				assert.Nil(t, resp)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, "ok", resp)
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), metadataKey{}, []string{"pages:read"}))
+	cancel()
+	err := authorize(ctx, "/pages.v1.Pages/Get")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, errors.Is(err, entitlementspb.ErrPermissionDenied))
+	_, err = interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/pages.v1.Pages/Get"}, nil)
+	assert.Equal(t, codes.Canceled, status.Code(err))
+}
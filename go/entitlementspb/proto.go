@@ -0,0 +1,190 @@
+// Package entitlementspb converts Entitlements and Requirements to and from
+// the compact binary protobuf form described by entitlements.proto, for
+// passing them between services without JSON, turns a Decision into an
+// Envoy ext_authz CheckResponse (see CheckResponse), and provides a gRPC
+// unary server interceptor (see UnaryServerInterceptor).
+//
+// The encoding is hand-written against the proto3 wire format, so it needs no
+// generated code; any protobuf implementation using entitlements.proto reads
+// and writes the same bytes. Schemes are encoded in sorted order, so equal
+// inputs encode identically. The package is a module of its own, so the gRPC
+// runtime and the Envoy API are dependencies of importers of this package
+// only, never of importers of package entitlements.
+//
+// Round trips are lossless up to the nil/empty distinction, which the wire
+// form cannot carry: a scheme's nil list decodes as an empty one, and
+// Requirements with no branches decode as nil.
+package entitlementspb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/kdex-tech/entitlements/go"
+)
+
+// ErrInvalidProto is returned when data is not a valid encoding.
+var ErrInvalidProto = errors.New("entitlementspb: invalid protobuf encoding")
+
+// Field numbers from entitlements.proto.
+const (
+	fieldTokens   = 1 // Tokens.tokens
+	fieldSchemes  = 1 // Entitlements.schemes
+	fieldMapKey   = 1 // map entry key
+	fieldMapValue = 2 // map entry value
+	fieldBranches = 1 // Requirements.branches
+)
+
+// Wire types used by the schema, and the ones a decoder must skip.
+const (
+	wireVarint = 0
+	wireI64    = 1
+	wireBytes  = 2
+	wireI32    = 5
+)
+
+// EntitlementsToProto encodes e as an Entitlements message.
+func EntitlementsToProto(e entitlements.Entitlements) []byte {
+	return appendEntitlements(nil, e)
+}
+
+// RequirementsToProto encodes r as a Requirements message.
+func RequirementsToProto(r entitlements.Requirements) []byte {
+	var b []byte
+	for _, branch := range r {
+		b = appendBytesField(b, fieldBranches, appendEntitlements(nil, branch))
+	}
+	return b
+}
+
+// EntitlementsFromProto decodes an Entitlements message. A decoded set is
+// never nil. Unknown fields are skipped, as protobuf requires; a malformed
+// message returns an error wrapping ErrInvalidProto.
+func EntitlementsFromProto(data []byte) (entitlements.Entitlements, error) {
+	e, err := decodeEntitlements(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidProto, err)
+	}
+	return e, nil
+}
+
+// RequirementsFromProto decodes a Requirements message, with errors as for
+// EntitlementsFromProto.
+func RequirementsFromProto(data []byte) (entitlements.Requirements, error) {
+	var r entitlements.Requirements
+	err := eachField(data, func(num int, typ int, value []byte) error {
+		if num != fieldBranches || typ != wireBytes {
+			return nil
+		}
+		branch, err := decodeEntitlements(value)
+		if err != nil {
+			return err
+		}
+		r = append(r, branch)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidProto, err)
+	}
+	return r, nil
+}
+
+func appendEntitlements(b []byte, e map[string][]string) []byte {
+	for _, scheme := range slices.Sorted(maps.Keys(e)) {
+		var tokens []byte
+		for _, t := range e[scheme] {
+			tokens = appendBytesField(tokens, fieldTokens, []byte(t))
+		}
+		entry := appendBytesField(nil, fieldMapKey, []byte(scheme))
+		entry = appendBytesField(entry, fieldMapValue, tokens)
+		b = appendBytesField(b, fieldSchemes, entry)
+	}
+	return b
+}
+
+func decodeEntitlements(data []byte) (entitlements.Entitlements, error) {
+	e := entitlements.Entitlements{}
+	err := eachField(data, func(num int, typ int, value []byte) error {
+		if num != fieldSchemes || typ != wireBytes {
+			return nil
+		}
+		var scheme string
+		tokens := []string{}
+		err := eachField(value, func(num int, typ int, value []byte) error {
+			switch {
+			case num == fieldMapKey && typ == wireBytes:
+				scheme = string(value)
+			case num == fieldMapValue && typ == wireBytes:
+				// A repeated map value is merged, as protobuf requires.
+				return eachField(value, func(num int, typ int, value []byte) error {
+					if num == fieldTokens && typ == wireBytes {
+						tokens = append(tokens, string(value))
+					}
+					return nil
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		// The last entry for a key wins, as protobuf requires of maps.
+		e[scheme] = tokens
+		return nil
+	})
+	return e, err
+}
+
+func appendBytesField(b []byte, num int, value []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(num)<<3|wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(value)))
+	return append(b, value...)
+}
+
+// eachField calls fn with each field of the message in data, passing the
+// payload of length-delimited fields and nil for the others.
+func eachField(data []byte, fn func(num int, typ int, value []byte) error) error {
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errors.New("truncated tag")
+		}
+		data = data[n:]
+		num, typ := int(tag>>3), int(tag&7)
+		if num == 0 {
+			return errors.New("field number 0")
+		}
+
+		var value []byte
+		switch typ {
+		case wireVarint:
+			if _, n = binary.Uvarint(data); n <= 0 {
+				return fmt.Errorf("field %d: truncated varint", num)
+			}
+		case wireI64:
+			n = 8
+		case wireI32:
+			n = 4
+		case wireBytes:
+			size, m := binary.Uvarint(data)
+			if m <= 0 || size > uint64(len(data)-m) {
+				return fmt.Errorf("field %d: truncated length-delimited value", num)
+			}
+			value = data[m : m+int(size)]
+			n = m + int(size)
+		default:
+			return fmt.Errorf("field %d: unsupported wire type %d", num, typ)
+		}
+		if n > len(data) {
+			return fmt.Errorf("field %d: truncated value", num)
+		}
+		data = data[n:]
+		if err := fn(num, typ, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
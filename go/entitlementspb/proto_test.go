@@ -0,0 +1,153 @@
+package entitlementspb_test
+
+import (
+	"testing"
+
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/kdex-tech/entitlements/go"
+	"github.com/kdex-tech/entitlements/go/entitlementspb"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestEntitlementsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		in   entitlements.Entitlements
+	}{
+		{"empty", entitlements.Entitlements{}},
+		{"one scheme", entitlements.Entitlements{"bearer": {"pages:/foo:read", "pages:all"}}},
+		{"several schemes", entitlements.Entitlements{
+			"bearer": {"pages:read", "email"},
+			"apikey": {"reports"},
+			"oauth2": {"resource=https://api.example.com", "books:/a:read@region=eu", "!secrets:read"},
+		}},
+		{"empty list", entitlements.Entitlements{"bearer": {}}},
+		{"empty strings", entitlements.Entitlements{"": {"", "x"}}},
+		{"unicode", entitlements.Entitlements{"bearer": {"pages:/ünï/çødé:read"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := entitlementspb.EntitlementsFromProto(entitlementspb.EntitlementsToProto(tt.in))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.in, got)
+		})
+	}
+
+	// A nil list decodes as an empty one.
+	got, err := entitlementspb.EntitlementsFromProto(entitlementspb.EntitlementsToProto(entitlements.Entitlements{"bearer": nil}))
+	assert.NoError(t, err)
+	assert.Equal(t, entitlements.Entitlements{"bearer": {}}, got)
+}
+
+func TestRequirementsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		in   entitlements.Requirements
+	}{
+		{"none", nil},
+		{"one branch", entitlements.Requirements{{"bearer": {"pages:read"}}}},
+		{"several branches", entitlements.Requirements{
+			{"bearer": {"pages:/foo:read", "!suspended"}, "apikey": {"reports"}},
+			{"oauth2": {"@admin"}},
+			{"bearer": {}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := entitlementspb.RequirementsFromProto(entitlementspb.RequirementsToProto(tt.in))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.in, got)
+		})
+	}
+}
+
+func TestEntitlementsToProto_WireForm(t *testing.T) {
+	// schemes {key: "a", value {tokens: "x"}}
+	want := []byte{0x0a, 0x08, 0x0a, 0x01, 'a', 0x12, 0x03, 0x0a, 0x01, 'x'}
+	assert.Equal(t, want, entitlementspb.EntitlementsToProto(entitlements.Entitlements{"a": {"x"}}))
+
+	// Schemes are sorted, so equal sets encode identically.
+	e := entitlements.Entitlements{"c": {"1"}, "a": {"2"}, "b": {"3"}}
+	first := entitlementspb.EntitlementsToProto(e)
+	for range 10 {
+		assert.Equal(t, first, entitlementspb.EntitlementsToProto(e))
+	}
+
+	// Unknown fields of every wire type are skipped.
+	unknown := append([]byte{
+		0x10, 0x96, 0x01, // field 2, varint 150
+		0x19, 1, 2, 3, 4, 5, 6, 7, 8, // field 3, fixed64
+		0x25, 1, 2, 3, 4, // field 4, fixed32
+		0x2a, 0x01, 'z', // field 5, bytes
+	}, want...)
+	got, err := entitlementspb.EntitlementsFromProto(unknown)
+	assert.NoError(t, err)
+	assert.Equal(t, entitlements.Entitlements{"a": {"x"}}, got)
+}
+
+func TestFromProto_Invalid(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"truncated tag":      {0x80},
+		"truncated length":   {0x0a, 0x05, 0x0a},
+		"truncated varint":   {0x10, 0x80},
+		"truncated fixed64":  {0x19, 1, 2},
+		"field number 0":     {0x02, 0x00},
+		"group wire type":    {0x0b},
+		"bad nested message": {0x0a, 0x02, 0x0a, 0x05},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := entitlementspb.EntitlementsFromProto(data)
+			assert.ErrorIs(t, err, entitlementspb.ErrInvalidProto)
+			_, err = entitlementspb.RequirementsFromProto(data)
+			assert.ErrorIs(t, err, entitlementspb.ErrInvalidProto)
+		})
+	}
+}
+
+func TestCheckResponse(t *testing.T) {
+	t.Run("allow", func(t *testing.T) {
+		got := entitlementspb.CheckResponse(entitlements.Decision{Allowed: true, Branch: 0}, "ignored")
+		assert.Equal(t, int32(codes.OK), got.GetStatus().GetCode())
+		assert.Empty(t, got.GetStatus().GetMessage())
+		assert.NotNil(t, got.GetOkResponse())
+		assert.Nil(t, got.GetDeniedResponse())
+	})
+
+	t.Run("deny", func(t *testing.T) {
+		reason := "missing pages:/foo:write"
+		got := entitlementspb.CheckResponse(entitlements.Decision{Branch: -1}, reason)
+		assert.Equal(t, int32(codes.PermissionDenied), got.GetStatus().GetCode())
+		assert.Equal(t, reason, got.GetStatus().GetMessage())
+		assert.Nil(t, got.GetOkResponse())
+
+		denied := got.GetDeniedResponse()
+		assert.Equal(t, typev3.StatusCode_Forbidden, denied.GetStatus().GetCode())
+		assert.Equal(t, reason, denied.GetBody())
+		if assert.Len(t, denied.GetHeaders(), 1) {
+			assert.Equal(t, entitlementspb.DeniedReasonHeader, denied.GetHeaders()[0].GetHeader().GetKey())
+			assert.Equal(t, reason, denied.GetHeaders()[0].GetHeader().GetValue())
+		}
+
+		// The response marshals as the Envoy API expects.
+		_, err := proto.Marshal(got)
+		assert.NoError(t, err)
+		assert.NoError(t, got.Validate())
+	})
+
+	t.Run("deny reason is one header line", func(t *testing.T) {
+		got := entitlementspb.CheckResponse(entitlements.Decision{Branch: -1}, "a\r\nb\nc")
+		assert.Equal(t, "a b c", got.GetStatus().GetMessage())
+		assert.Equal(t, "a b c", got.GetDeniedResponse().GetBody())
+		assert.Equal(t, "a b c", got.GetDeniedResponse().GetHeaders()[0].GetHeader().GetValue())
+	})
+
+	t.Run("deny without reason", func(t *testing.T) {
+		got := entitlementspb.CheckResponse(entitlements.Decision{Branch: -1}, "")
+		assert.Equal(t, int32(codes.PermissionDenied), got.GetStatus().GetCode())
+		assert.Empty(t, got.GetStatus().GetMessage())
+		assert.Empty(t, got.GetDeniedResponse().GetHeaders())
+		assert.Empty(t, got.GetDeniedResponse().GetBody())
+	})
+}
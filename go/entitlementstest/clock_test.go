@@ -0,0 +1,27 @@
+package entitlementstest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kdex-tech/entitlements/go"
+	"github.com/kdex-tech/entitlements/go/entitlementstest"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ entitlements.Clock = (*entitlementstest.FakeClock)(nil)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := entitlementstest.NewFakeClock(start)
+	assert.Equal(t, start, clock.Now())
+
+	clock.Advance(time.Hour)
+	assert.Equal(t, start.Add(time.Hour), clock.Now())
+
+	clock.Advance(-time.Minute)
+	assert.Equal(t, start.Add(59*time.Minute), clock.Now())
+
+	clock.Set(start)
+	assert.Equal(t, start, clock.Now())
+}
@@ -0,0 +1,68 @@
+package entitlements_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+
+	"github.com/kdex-tech/entitlements/go"
+)
+
+// pageHandlers declares each handler's requirements next to it.
+type pageHandlers struct {
+	Read   http.HandlerFunc `entitlements:"bearer=pages:read"`
+	Delete http.HandlerFunc `entitlements:"bearer=pages:delete | bearer=admin:all"`
+}
+
+// authorize wraps next with a check of the caller's entitlements, here read
+// from a header for brevity.
+func authorize(ec *entitlements.EntitlementsChecker, reqs entitlements.Requirements, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		held := entitlements.Entitlements{"bearer": strings.Fields(r.Header.Get("X-Entitlements"))}
+		if !ec.VerifyEntitlements(held, reqs) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func ExampleRequirementsFromTag() {
+	ec := entitlements.NewEntitlementsChecker(nil, "bearer", false)
+	ok := func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNoContent) }
+	handlers := pageHandlers{Read: ok, Delete: ok}
+
+	mux := http.NewServeMux()
+	for _, route := range []struct {
+		pattern, field string
+		handler        http.HandlerFunc
+	}{
+		{"GET /pages/{id}", "Read", handlers.Read},
+		{"DELETE /pages/{id}", "Delete", handlers.Delete},
+	} {
+		f, _ := reflect.TypeOf(handlers).FieldByName(route.field)
+		reqs, err := entitlements.RequirementsFromTag(f.Tag.Get("entitlements"))
+		if err != nil {
+			panic(err) // a malformed tag is a programming error; fail at startup
+		}
+		mux.Handle(route.pattern, authorize(ec, reqs, route.handler))
+	}
+
+	for _, c := range []struct{ method, held string }{
+		{http.MethodGet, "pages:read"},
+		{http.MethodDelete, "pages:read"},
+		{http.MethodDelete, "admin:all"},
+	} {
+		r := httptest.NewRequest(c.method, "/pages/foo", nil)
+		r.Header.Set("X-Entitlements", c.held)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		fmt.Println(c.method, c.held, w.Code)
+	}
+	// Output:
+	// GET pages:read 204
+	// DELETE pages:read 403
+	// DELETE admin:all 204
+}
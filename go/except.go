@@ -0,0 +1,112 @@
+package entitlements
+
+import "strings"
+
+// ExceptPrefix marks a requirement token as an except condition: "!suspended"
+// is satisfied when the caller holds NO entitlement matching "suspended"
+// under the token's scheme, counting base and anonymous entitlements like any
+// other grant. The condition may be any non-reference token form; a wildcard
+// condition such as "!pages:write" excludes every caller holding any grant it
+// matches, and is accepted under WithStrictRequirements since excluding more
+// callers can only narrow the requirement.
+//
+// An except condition is one AND-token of the branch it appears in, so its
+// placement decides its reach. In one branch of several it gates only that
+// branch, and a caller matching it may still pass another branch; to exclude
+// a caller from the whole requirement, add it to every branch (see
+// Requirements.Except). A branch consisting only of except conditions does not
+// need its scheme to be present, so "everyone except suspended users" admits
+// anonymous callers too.
+//
+// An except condition that cannot be decided fails closed: a placeholder
+// condition left unbound by BindRequirements, and a negated "@name"
+// reference, are unsatisfiable.
+//
+// On the held side a "!" token is a deny, and deny wins: no requirement its
+// condition matches can be satisfied under the token's scheme, whatever else
+// the caller holds, base and anonymous entitlements included. A deny carves
+// exceptions out of a broader grant, such as a type wildcard
+// ("*:*:read" with "!secrets:read", see ResourceTypeWildcard) or a name
+// wildcard ("pages:read" with "!pages:/admin:read"). Matching is symmetric as
+// for any grant, so the deny "!pages:/admin:read" also defeats the class-wide
+// requirement "pages::read"; a deny never satisfies a requirement itself, and
+// a region tag on it limits it to that region.
+//
+// Deny also wins over grantReadyByDefault: a deny under the default scheme
+// matching the identity requirement of Verify*ResourceEntitlements denies it.
+// A deny is an entitlement the caller presents, so a caller holding only
+// denies is not anonymous and receives no anonymous entitlements.
+const ExceptPrefix = "!"
+
+// Except returns a copy of r with the except condition "!"+entitlement (see
+// ExceptPrefix) under scheme ("bearer" when empty) added to every branch, so a
+// caller matching entitlement is denied however many branches they would
+// otherwise satisfy: Except binds looser than the OR of r's branches,
+// r.Except(s, e) meaning "r, and not e". An empty r becomes the single branch
+// "everyone except e".
+func (r Requirements) Except(scheme, entitlement string) Requirements {
+	if scheme == "" {
+		scheme = "bearer"
+	}
+	token := ExceptPrefix + entitlement
+	if len(r) == 0 {
+		return Requirements{{scheme: {token}}}
+	}
+
+	out := make(Requirements, len(r))
+	for i, set := range r {
+		branch := make(map[string][]string, len(set)+1)
+		for s, list := range set {
+			branch[s] = list
+		}
+		branch[scheme] = append(append([]string(nil), set[scheme]...), token)
+		out[i] = branch
+	}
+	return out
+}
+
+// exceptCondition returns the condition of an except token. A "!" alone, or
+// followed by another "!", is not an except token.
+func exceptCondition(s string) (string, bool) {
+	cond, ok := strings.CutPrefix(s, ExceptPrefix)
+	if !ok || cond == "" || strings.HasPrefix(cond, ExceptPrefix) {
+		return "", false
+	}
+	return cond, true
+}
+
+// denies reports whether a deny in entitlementList matches requirement.
+func (m matcher) denies(entitlementList []entitlementPattern, requirement entitlementPattern) bool {
+	for _, ep := range entitlementList {
+		if !ep.except {
+			continue
+		}
+		cond := *ep.cond
+		cond.region = ep.region
+		if m.matches(cond, requirement) {
+			return true
+		}
+	}
+	return false
+}
+
+// holdsDeny reports whether any scheme in held carries a deny.
+func holdsDeny(held map[string][]entitlementPattern) bool {
+	for _, list := range held {
+		for _, ep := range list {
+			if ep.except {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// satisfiesExcept reports whether the caller holds no grant matching cond.
+func (ec *EntitlementsChecker) satisfiesExcept(entitlementList []entitlementPattern, scheme string, cond entitlementPattern, fb fallback) bool {
+	if cond.ref != "" || cond.placeholder != "" {
+		return false
+	}
+	_, _, found := ec.lookupGrant(entitlementList, scheme, cond, fb)
+	return !found
+}
@@ -0,0 +1,51 @@
+package entitlements
+
+import "context"
+
+// Explanation is why VerifyEntitlementsExplained denied a caller: an
+// analysis of every branch of the requirement and which came closest to
+// passing. It marshals to JSON for logging.
+type Explanation struct {
+	// Branches analyses each branch of the requirement, in order.
+	Branches []BranchAnalysis `json:"branches"`
+	// Closest indexes the branch in Branches that came closest to passing:
+	// the fewest missing schemes and unmet tokens, the first on ties.
+	Closest int `json:"closest"`
+}
+
+// VerifyEntitlementsExplained is VerifyEntitlements that, on a denial, also
+// explains it: for every branch, the schemes the caller did not present, the
+// tokens they did not satisfy and, for each such token, the entitlement they
+// hold that nearly matched it (see UnmetRequirement.NearMiss). The
+// explanation is nil when the caller is allowed.
+//
+// The explanation is built only here and only on a denial; VerifyEntitlements
+// pays nothing for it. The denial cache is not consulted, so every denial is
+// evaluated and explained afresh. Break-glass (see WithBreakGlass) and the
+// deny audit trail apply as in VerifyEntitlements.
+func (ec *EntitlementsChecker) VerifyEntitlementsExplained(entitlements Entitlements, requirements Requirements) (bool, *Explanation) {
+	if len(requirements) == 0 {
+		ec.countDecision(true, nil, nil)
+		return true, nil
+	}
+	if ec.brokeGlass(entitlements) {
+		return true, nil
+	}
+	parsed := ec.ParseEntitlements(entitlements)
+	branches := ec.ParseRequirements(requirements).patterns
+	if len(branches) == 0 {
+		ec.countDecision(true, nil, nil)
+		return true, nil
+	}
+
+	held := ec.enabledSchemes(parsed.patterns)
+	fb := callerFallback(held)
+	ok, branch, _ := ec.satisfiesBranchesCtx(context.Background(), held, branches, fb)
+	ec.countDecision(ok, held, branchAt(branches, branch))
+	if ok {
+		return true, nil
+	}
+	ec.auditDenial(held, branches, fb, false)
+	analysis, closest := ec.analyseBranches(held, branches, fb)
+	return false, &Explanation{Branches: analysis, Closest: closest}
+}
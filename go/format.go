@@ -0,0 +1,106 @@
+package entitlements
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// String renders e compactly and deterministically for logs, with schemes and
+// the tokens under each sorted: {bearer: [pages:read, pages:write], oauth2:
+// [x]}. A scheme or token that is empty or contains a character of the
+// notation is quoted. nil and empty render as {}. It has no bearing on
+// matching.
+func (e Entitlements) String() string {
+	var b strings.Builder
+	writeSchemeMap(&b, e)
+	return b.String()
+}
+
+// GoString renders e as a Go composite literal, schemes and tokens sorted, for
+// the %#v verb.
+func (e Entitlements) GoString() string {
+	var b strings.Builder
+	b.WriteString("entitlements.Entitlements")
+	writeGoSchemeMap(&b, e)
+	return b.String()
+}
+
+// String renders r compactly and deterministically for logs: its OR branches,
+// in order, separated by " | ", each with its schemes and their tokens sorted
+// as Entitlements.String renders them: [{bearer: [pages:read], oauth2:
+// [files:write]} | {apikey: [admin]}]. nil and empty render as [], and an
+// empty branch as {}.
+func (r Requirements) String() string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, branch := range r {
+		if i > 0 {
+			b.WriteString(" | ")
+		}
+		writeSchemeMap(&b, branch)
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// GoString renders r as a Go composite literal, branches in order and schemes
+// and tokens sorted, for the %#v verb.
+func (r Requirements) GoString() string {
+	var b strings.Builder
+	b.WriteString("entitlements.Requirements{")
+	for i, branch := range r {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		writeGoSchemeMap(&b, branch)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func writeSchemeMap(b *strings.Builder, m map[string][]string) {
+	b.WriteByte('{')
+	for i, scheme := range sortedKeys(m) {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(quoteNotation(scheme))
+		b.WriteString(": [")
+		for j, token := range slices.Sorted(slices.Values(m[scheme])) {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(quoteNotation(token))
+		}
+		b.WriteByte(']')
+	}
+	b.WriteByte('}')
+}
+
+func writeGoSchemeMap(b *strings.Builder, m map[string][]string) {
+	b.WriteByte('{')
+	for i, scheme := range sortedKeys(m) {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(strconv.Quote(scheme))
+		b.WriteString(": {")
+		for j, token := range slices.Sorted(slices.Values(m[scheme])) {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(strconv.Quote(token))
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte('}')
+}
+
+// quoteNotation quotes s when it would be ambiguous in the String notation.
+func quoteNotation(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\n\",[]{}|") || !strconv.CanBackquote(s) {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,157 @@
+package entitlements
+
+import "strings"
+
+// Resource-name globs (see WithResourceNameGlob).
+//
+// A glob is a resourceName containing any of the metacharacters below; every
+// other character is a literal:
+//
+//	*   any run of characters, not crossing '/'
+//	**  a run of one or more characters, crossing '/'
+//	?   exactly one character other than '/'
+//	\c  the literal character c
+//
+// "**" gives depth-aware names: "/docs/**/*" matches "/docs/a/b" and anything
+// deeper, but not "/docs/a", since the '/' after "**" must still be matched.
+//
+// A whole resourceName of "*" (or empty) keeps its ordinary meaning of "every
+// resource", crossing '/' like it always has.
+//
+// Intersection:
+// A glob may appear on the held side, the requirement side, or both. A match
+// succeeds when the two names could refer to at least one common concrete
+// name — their languages intersect. A literal is a glob matching only itself,
+// so literal-vs-glob reduces to ordinary glob matching, and literal-vs-literal
+// to equality. Two globs are decided by a simultaneous walk over both token
+// sequences: at each pair of positions (i, j)
+//
+//   - a literal pairs with an equal literal, or with a '?' if it is not '/';
+//   - '?' pairs with '?';
+//   - a '*' either ends (advance past it) or absorbs one character the other
+//     side produces at its position — a non-'/' literal, a '?', or, when both
+//     sides are at a star, nothing at all (one of the stars ends).
+//
+// "**" is tokenised as one character of any kind followed by a star that may
+// cross '/'. The former pairs with every single-character token; the latter
+// behaves like '*' but absorbs any single character, '/' included.
+//
+// The names intersect iff both sequences can be exhausted together. Positions
+// already found to fail are memoised, so the walk is O(len(a) × len(b)).
+
+const globMeta = `*?\`
+
+// hasGlobMeta reports whether name is a glob rather than a literal.
+func hasGlobMeta(name string) bool {
+	return strings.ContainsAny(name, globMeta)
+}
+
+type globKind uint8
+
+const (
+	globLiteral  globKind = iota
+	globAny               // ?
+	globStar              // *
+	globAnyChar           // first character of **
+	globDeepStar          // remainder of **
+)
+
+type globToken struct {
+	kind globKind
+	lit  rune
+}
+
+// tokenizeGlob splits a glob into tokens. A trailing lone '\' is a literal.
+func tokenizeGlob(s string) []globToken {
+	runes := []rune(s)
+	toks := make([]globToken, 0, len(runes))
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; r {
+		case '*':
+			if i+1 < len(runes) && runes[i+1] == '*' {
+				i++
+				toks = append(toks, globToken{kind: globAnyChar}, globToken{kind: globDeepStar})
+				continue
+			}
+			toks = append(toks, globToken{kind: globStar})
+		case '?':
+			toks = append(toks, globToken{kind: globAny})
+		case '\\':
+			if i+1 < len(runes) {
+				i++
+			}
+			toks = append(toks, globToken{kind: globLiteral, lit: runes[i]})
+		default:
+			toks = append(toks, globToken{kind: globLiteral, lit: r})
+		}
+	}
+	return toks
+}
+
+// globsIntersect reports whether globs a and b match at least one common
+// concrete name.
+func globsIntersect(a, b string) bool {
+	ta, tb := tokenizeGlob(a), tokenizeGlob(b)
+	failed := make(map[[2]int]struct{})
+
+	var walk func(i, j int) bool
+	walk = func(i, j int) bool {
+		if i == len(ta) && j == len(tb) {
+			return true
+		}
+		key := [2]int{i, j}
+		if _, ok := failed[key]; ok {
+			return false
+		}
+
+		ok := false
+		switch {
+		case i < len(ta) && isStar(ta[i]):
+			// The star ends, or absorbs what b produces at j.
+			ok = walk(i+1, j) || (j < len(tb) && absorbs(ta[i], tb[j]) && walk(i, j+1))
+			if !ok && j < len(tb) && isStar(tb[j]) {
+				ok = walk(i, j+1)
+			}
+		case j < len(tb) && isStar(tb[j]):
+			ok = walk(i, j+1) || (i < len(ta) && absorbs(tb[j], ta[i]) && walk(i+1, j))
+		case i < len(ta) && j < len(tb):
+			ok = tokensPair(ta[i], tb[j]) && walk(i+1, j+1)
+		}
+
+		if !ok {
+			failed[key] = struct{}{}
+		}
+		return ok
+	}
+	return walk(0, 0)
+}
+
+func isStar(t globToken) bool {
+	return t.kind == globStar || t.kind == globDeepStar
+}
+
+// absorbs reports whether star can consume the single character t produces:
+// any character for the star of "**", any but '/' for '*'.
+func absorbs(star, t globToken) bool {
+	if isStar(t) {
+		return false
+	}
+	return star.kind == globDeepStar || t.kind != globLiteral || t.lit != '/'
+}
+
+// tokensPair reports whether two single-character tokens can produce the same
+// character.
+func tokensPair(a, b globToken) bool {
+	switch {
+	case a.kind == globAnyChar || b.kind == globAnyChar:
+		return true
+	case a.kind == globLiteral && b.kind == globLiteral:
+		return a.lit == b.lit
+	case a.kind == globLiteral:
+		return a.lit != '/'
+	case b.kind == globLiteral:
+		return b.lit != '/'
+	default:
+		return true
+	}
+}
@@ -0,0 +1,82 @@
+package entitlements
+
+// ResourceRef names one resource instance: a resource type and a
+// resourceName, as in the requirement "<Resource>:<ResourceName>:<verb>".
+type ResourceRef struct {
+	Resource     string `json:"resource"`
+	ResourceName string `json:"resourceName"`
+}
+
+// HierarchyResolver returns the parent of a resource instance, and false when
+// it has none.
+type HierarchyResolver func(resource, resourceName string) (parent *ResourceRef, ok bool)
+
+// WithHierarchyResolver lets grants on a resource instance be inherited by
+// its descendants, for systems that keep parent-child relationships in a
+// graph rather than in resourceName paths (e.g. org → folder → doc). When no
+// grant matches a requirement "doc:/d1:read", the checker asks resolve for
+// the parent of doc "/d1", checks "folder:/f1:read" in its place, and so on
+// up the hierarchy until a grant matches or resolve reports no parent. The
+// verb is kept at every level: a grant of "folder:/f1:read" is inherited as
+// read, not as anything more.
+//
+// A deny (see ExceptPrefix) the caller holds on an instance blocks
+// inheritance through it: neither that instance nor its descendants inherit
+// a grant from above it. A resolver that leads back to an instance already
+// visited is a cycle, and the walk stops there without a grant. Only
+// requirements naming a single instance are resolved, not class-wide ones
+// ("doc::read") or opaque tokens.
+//
+// resolve runs on the verifying goroutine, possibly several times per check,
+// and must be safe for concurrent use. PreparedPolicy.Check takes the generic
+// path while a resolver is set; GrantedResourceNames lists only names granted
+// on their own resource type, since it cannot enumerate descendants; and a
+// denial cache (see WithDenialCache) will keep answering from before a change
+// to the hierarchy for its TTL. nil disables resolution, the default.
+//
+// Intended for use during checker construction; not safe for concurrent
+// mutation with verify calls in flight.
+func (ec *EntitlementsChecker) WithHierarchyResolver(resolve HierarchyResolver) *EntitlementsChecker {
+	ec.hierarchy = resolve
+	return ec
+}
+
+// inheritedGrant walks the hierarchy above requirement for a grant of its
+// verb on an ancestor.
+func (ec *EntitlementsChecker) inheritedGrant(entitlementList []entitlementPattern, scheme string, requirement entitlementPattern, fb fallback) (entitlementPattern, GrantSource, bool) {
+	if !requirement.isPattern || isWildcardName(ec.canonicalName(requirement.resourceName)) || requirement.placeholder != "" {
+		return entitlementPattern{}, "", false
+	}
+	m := ec.matcherIn(scheme, fb)
+	if m.denies(entitlementList, requirement) {
+		return entitlementPattern{}, "", false
+	}
+
+	current := ResourceRef{Resource: requirement.resource, ResourceName: requirement.resourceName}
+	visited := map[ResourceRef]struct{}{current: {}}
+	for {
+		parent, ok := ec.hierarchy(current.Resource, current.ResourceName)
+		if !ok || parent == nil || parent.Resource == "" || parent.ResourceName == "" {
+			return entitlementPattern{}, "", false
+		}
+		if _, seen := visited[*parent]; seen {
+			if ec.log != nil {
+				ec.log.V(1).Info("Resource hierarchy cycle", "resource", parent.Resource, "resourceName", parent.ResourceName)
+			}
+			return entitlementPattern{}, "", false
+		}
+		visited[*parent] = struct{}{}
+		current = *parent
+
+		inherited := requirement
+		inherited.resource = current.Resource
+		inherited.resourceName = current.ResourceName
+		inherited.raw = current.Resource + ":" + current.ResourceName + ":" + requirement.verb
+		if m.denies(entitlementList, inherited) {
+			return entitlementPattern{}, "", false
+		}
+		if grant, source, ok := ec.matchGrant(entitlementList, scheme, inherited, fb); ok {
+			return grant, source, true
+		}
+	}
+}
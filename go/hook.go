@@ -0,0 +1,75 @@
+package entitlements
+
+import (
+	"slices"
+	"time"
+)
+
+// DecisionEvent is what WithDecisionHook receives for each decision.
+type DecisionEvent struct {
+	// Time is when the decision was made, on the checker's Clock.
+	Time time.Time `json:"time"`
+	// Entitlements and Requirements are copies of those verified, as passed;
+	// the base and anonymous entitlements the checker merges in are not
+	// included.
+	Entitlements Entitlements `json:"entitlements"`
+	Requirements Requirements `json:"requirements"`
+	Allowed      bool         `json:"allowed"`
+	// Branch is the index of the OR branch that granted access, or -1, as
+	// VerifyEntitlementsMatch returns it.
+	Branch int `json:"branch"`
+	// BreakGlass is set when access was granted by the break-glass check
+	// (see WithBreakGlass).
+	BreakGlass bool `json:"breakGlass,omitempty"`
+}
+
+// WithDecisionHook calls hook with a DecisionEvent once for every decision
+// made by VerifyEntitlements, VerifyEntitlementsCtx and
+// VerifyEntitlementsMatch, including when reached through the methods built
+// on them (VerifyCoversAll, VerifyEntitlementsWithExpiry and the like), for
+// an audit log of allows as well as denials. A verification abandoned because
+// its context was done decides nothing and is not reported. Other verify
+// methods and PreparedPolicy.Check do not report; see WithDenyAuditTrail for
+// denials across all of them.
+//
+// hook runs synchronously on the verifying goroutine, after the decision,
+// and must be safe for concurrent use. It cannot change the decision: it
+// returns nothing, and its event holds copies, so mutating them affects
+// neither the result nor the caller's values. Copying costs an allocation
+// per entitlement list and requirement branch; nil disables the hook, the
+// default.
+//
+// Intended for use during checker construction; not safe for concurrent
+// mutation with verify calls in flight.
+func (ec *EntitlementsChecker) WithDecisionHook(hook func(DecisionEvent)) *EntitlementsChecker {
+	ec.decisionHook = hook
+	return ec
+}
+
+// reportDecision emits the DecisionEvent for a decision, if the hook is
+// enabled.
+func (ec *EntitlementsChecker) reportDecision(entitlements Entitlements, requirements Requirements, allowed bool, branch int, breakGlass bool) {
+	if ec.decisionHook == nil {
+		return
+	}
+	ec.decisionHook(DecisionEvent{
+		Time:         ec.clock.Now(),
+		Entitlements: cloneEntitlements(entitlements),
+		Requirements: cloneRequirements(requirements),
+		Allowed:      allowed,
+		Branch:       branch,
+		BreakGlass:   breakGlass,
+	})
+}
+
+// cloneEntitlements deep-copies entitlements.
+func cloneEntitlements(entitlements Entitlements) Entitlements {
+	if entitlements == nil {
+		return nil
+	}
+	clone := make(Entitlements, len(entitlements))
+	for scheme, list := range entitlements {
+		clone[scheme] = slices.Clone(list)
+	}
+	return clone
+}
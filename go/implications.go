@@ -0,0 +1,100 @@
+package entitlements
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrVerbImplicationCycle is returned by SetVerbImplications for a table in
+// which a verb implies itself, directly or through other verbs.
+var ErrVerbImplicationCycle = errors.New("entitlements: verb implication cycle")
+
+// verbImplications maps a held verb to every verb it implies, transitively.
+// A table is never modified once stored, so readers need no lock.
+type verbImplications map[string]map[string]struct{}
+
+// SetVerbImplications replaces the checker's verb hierarchy: table maps a verb
+// to the verbs it implies, e.g. {"admin": {"write"}, "write": {"read"}}, so a
+// held "pages:write" satisfies "pages:/foo:read", and a held "pages:admin"
+// satisfies it too, implication being transitive. It runs one way only: a
+// held "read" does not satisfy a "write" requirement. nil or empty clears the
+// hierarchy.
+//
+// A table with a cycle ("a" implies "b" implies "a", or "a" implies itself)
+// returns an error wrapping ErrVerbImplicationCycle, and the current table is
+// kept.
+//
+// Unlike the With options, SetVerbImplications is safe to call while verify
+// calls are in flight, e.g. to reload configuration without a restart. Each
+// table is swapped in whole, atomically, so every match sees either the old
+// table or the new one, never a mix; a verification spanning a swap may
+// match some tokens under each. Swapping clears the denial cache, whose
+// entries the old table produced. WithLegacySemantics ignores the table.
+func (ec *EntitlementsChecker) SetVerbImplications(table map[string][]string) error {
+	if len(table) == 0 {
+		ec.implications.Store(nil)
+	} else {
+		closure, err := implicationClosure(table)
+		if err != nil {
+			return err
+		}
+		ec.implications.Store(&closure)
+	}
+	if ec.denials != nil {
+		ec.denials.clear()
+	}
+	return nil
+}
+
+// implicationClosure computes the transitive closure of table, rejecting
+// cycles. Verbs are visited in sorted order, so the cycle reported for a
+// given table is deterministic.
+func implicationClosure(table map[string][]string) (verbImplications, error) {
+	closure := make(verbImplications, len(table))
+	onPath := make(map[string]bool)
+	var path []string
+
+	var visit func(verb string) error
+	visit = func(verb string) error {
+		if _, done := closure[verb]; done {
+			return nil
+		}
+		if onPath[verb] {
+			return fmt.Errorf("%w: %s -> %s", ErrVerbImplicationCycle, strings.Join(path, " -> "), verb)
+		}
+		onPath[verb] = true
+		path = append(path, verb)
+
+		implied := make(map[string]struct{})
+		for _, next := range table[verb] {
+			if err := visit(next); err != nil {
+				return err
+			}
+			implied[next] = struct{}{}
+			for v := range closure[next] {
+				implied[v] = struct{}{}
+			}
+		}
+
+		path = path[:len(path)-1]
+		delete(onPath, verb)
+		closure[verb] = implied
+		return nil
+	}
+
+	for _, verb := range sortedKeys(table) {
+		if err := visit(verb); err != nil {
+			return nil, err
+		}
+	}
+	return closure, nil
+}
+
+// currentImplications returns the current table, nil when there is none.
+func (ec *EntitlementsChecker) currentImplications() verbImplications {
+	if t := ec.implications.Load(); t != nil {
+		return *t
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package entitlements
+
+import "strings"
+
+// EntitlementsFromInstanceSets expands a claim listing resource instance ids
+// per resource type, e.g. {"pages": ["foo", "bar"]}, into long-form
+// entitlements granting verb ("read" when empty) on each instance under
+// scheme ("bearer" when empty): "pages:/foo:read" and "pages:/bar:read".
+// An id gets a leading '/' unless it already has one.
+//
+// Resource types are expanded in sorted order and ids in list order, without
+// duplicates. An empty list grants nothing for its resource. Ids that cannot
+// name one instance are dropped rather than widened or misparsed: an empty id,
+// the wildcard "*", an id containing ':', and one containing a glob
+// metacharacter, which WithResourceNameGlob would read as a pattern; so are
+// resource types that are empty or contain ':'. As with
+// EntitlementsFromCognito, the scheme is always present in the result, if
+// only with an empty list.
+func EntitlementsFromInstanceSets(scheme string, sets map[string][]string, verb string) Entitlements {
+	if scheme == "" {
+		scheme = "bearer"
+	}
+	if verb == "" {
+		verb = "read"
+	}
+
+	list := []string{}
+	for _, resource := range sortedKeys(sets) {
+		if resource == "" || strings.Contains(resource, ":") {
+			continue
+		}
+		for _, id := range sets[resource] {
+			if id == "" || id == "*" || strings.Contains(id, ":") || hasGlobMeta(id) {
+				continue
+			}
+			if !strings.HasPrefix(id, "/") {
+				id = "/" + id
+			}
+			list = appendUnique(list, resource+":"+id+":"+verb)
+		}
+	}
+	return Entitlements{scheme: list}
+}
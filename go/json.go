@@ -0,0 +1,145 @@
+package entitlements
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// MarshalJSON encodes e as an object mapping each scheme to its list of
+// entitlement strings, e.g. {"bearer":["pages:read"]}. A nil e encodes as {}
+// and a nil list as [], so the encoding of an empty set is stable.
+func (e Entitlements) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nonNilLists(e))
+}
+
+// UnmarshalJSON decodes the form MarshalJSON produces, rejecting a scheme
+// listed twice in the object and every problem ValidateEntitlements reports,
+// so a malformed claim fails with a descriptive error rather than decoding
+// into entitlements that silently never match. null decodes as nil.
+func (e *Entitlements) UnmarshalJSON(data []byte) error {
+	if isJSONNull(data) {
+		*e = nil
+		return nil
+	}
+	lists, err := decodeSchemeLists(json.NewDecoder(bytes.NewReader(data)))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidEntitlement, err)
+	}
+	if err := ValidateEntitlements(lists); err != nil {
+		return err
+	}
+	*e = lists
+	return nil
+}
+
+// MarshalJSON encodes r as an array of OR'd branches, each an object mapping
+// a scheme to the list of requirement tokens it ANDs — the shape of an
+// OpenAPI security array, e.g. [{"bearer":["pages:read"]},{"apikey":[]}]. A
+// nil r encodes as [] and a nil list as [], so the encoding of an empty
+// requirement is stable.
+func (r Requirements) MarshalJSON() ([]byte, error) {
+	branches := make([]map[string][]string, len(r))
+	for i, branch := range r {
+		branches[i] = nonNilLists(branch)
+	}
+	return json.Marshal(branches)
+}
+
+// UnmarshalJSON decodes the form MarshalJSON produces, rejecting a branch
+// that is not an object, a scheme listed twice in a branch, an empty scheme
+// name, and every token ValidateRequirements would report without checker
+// options (such as WithKnownVerbs), so a malformed policy fails with a
+// descriptive error rather than decoding into a requirement that silently
+// never — or always — matches. An empty branch, {}, is kept: as in OpenAPI
+// it admits every caller. null decodes as nil.
+func (r *Requirements) UnmarshalJSON(data []byte) error {
+	if isJSONNull(data) {
+		*r = nil
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err := expectDelim(dec, '['); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidRequirement, err)
+	}
+	requirements := Requirements{}
+	for dec.More() {
+		branch, err := decodeSchemeLists(dec)
+		if err != nil {
+			return fmt.Errorf("%w: branch %d: %w", ErrInvalidRequirement, len(requirements), err)
+		}
+		if _, ok := branch[""]; ok {
+			return fmt.Errorf("%w: branch %d: empty scheme name", ErrInvalidRequirement, len(requirements))
+		}
+		requirements = append(requirements, branch)
+	}
+	if err := expectDelim(dec, ']'); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidRequirement, err)
+	}
+	if err := validateRequirements(nil, requirements); err != nil {
+		return err
+	}
+	*r = requirements
+	return nil
+}
+
+// decodeSchemeLists decodes the next value of dec as an object of scheme to
+// string list, rejecting duplicate schemes, which encoding/json would
+// otherwise resolve silently in favour of the last.
+func decodeSchemeLists(dec *json.Decoder) (map[string][]string, error) {
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+	lists := make(map[string][]string)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		scheme := tok.(string)
+		if _, dup := lists[scheme]; dup {
+			return nil, fmt.Errorf("scheme %q listed twice", scheme)
+		}
+		var list []string
+		if err := dec.Decode(&list); err != nil {
+			return nil, fmt.Errorf("scheme %q: %w", scheme, err)
+		}
+		if list == nil {
+			list = []string{}
+		}
+		lists[scheme] = list
+	}
+	if err := expectDelim(dec, '}'); err != nil {
+		return nil, err
+	}
+	return lists, nil
+}
+
+// expectDelim consumes the next token of dec, which must be delim.
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("expected %q, found %v", delim, tok)
+	}
+	return nil
+}
+
+// isJSONNull reports whether data is the JSON literal null.
+func isJSONNull(data []byte) bool {
+	return bytes.Equal(bytes.TrimSpace(data), []byte("null"))
+}
+
+// nonNilLists returns lists with every nil list, and a nil map, made empty.
+func nonNilLists(lists map[string][]string) map[string][]string {
+	out := make(map[string][]string, len(lists))
+	for scheme, list := range lists {
+		if list == nil {
+			list = []string{}
+		}
+		out[scheme] = list
+	}
+	return out
+}
@@ -0,0 +1,85 @@
+package entitlements
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RequirementsFileExt is the extension of the policy files LoadRequirementsDir
+// reads.
+const RequirementsFileExt = ".yaml"
+
+// ErrInvalidRequirements is wrapped by every per-file problem
+// LoadRequirementsDir reports.
+var ErrInvalidRequirements = errors.New("entitlements: invalid requirements file")
+
+// LoadRequirementsDir reads every "*.yaml" file directly in dir of fsys and
+// returns its Requirements keyed by file name without the extension, so
+// "orders.yaml" loads as "orders". Each file holds one YAML document in the
+// shape of Requirements, a list of scheme-to-entitlements maps:
+//
+//	[{bearer: ["orders:read"]}, {apikey: ["orders-service"]}]
+//
+// in flow or block style. Subdirectories and other files are ignored. A file
+// that cannot be read, is not valid YAML of that shape, is empty or null, or
+// holds more than one document is a problem; problems are errors wrapping
+// ErrInvalidRequirements that name their file, joined (errors.Join) in
+// file-name order, and no map is returned with them. An empty or null
+// document is rejected rather than loaded as empty Requirements, which would
+// admit every caller; write "[]" to mean that.
+func LoadRequirementsDir(fsys fs.FS, dir string) (map[string]Requirements, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := make(map[string]Requirements)
+	var errs []error
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), RequirementsFileExt)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		file := path.Join(dir, entry.Name())
+		reqs, err := readRequirementsFile(fsys, file)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: %s: %w", ErrInvalidRequirements, file, err))
+			continue
+		}
+		loaded[name] = reqs
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return loaded, nil
+}
+
+func readRequirementsFile(fsys fs.FS, file string) (Requirements, error) {
+	data, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return nil, err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var reqs Requirements
+	if err := dec.Decode(&reqs); err != nil {
+		if err == io.EOF {
+			return nil, errors.New("empty file")
+		}
+		return nil, err
+	}
+	if reqs == nil {
+		return nil, errors.New("empty document")
+	}
+	var extra any
+	if err := dec.Decode(&extra); err != io.EOF {
+		return nil, errors.New("more than one document")
+	}
+	return reqs, nil
+}
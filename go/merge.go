@@ -0,0 +1,28 @@
+package entitlements
+
+// MergeEntitlements returns the union of a and b, e.g. of the entitlements a
+// caller presents in a bearer token and in a custom header. Every scheme of
+// either input is present in the result, if only with an empty list, since
+// whether a scheme is held at all matters to verification. A scheme's list
+// holds a's strings, then those of b's not already present, each once, in
+// first-seen order, so merging is stable and merging a set with itself only
+// de-duplicates it. Schemes and strings are compared exactly.
+//
+// Neither input is modified and the result shares no lists with them. Nil
+// inputs are empty; the result is never nil.
+func MergeEntitlements(a, b Entitlements) Entitlements {
+	merged := make(Entitlements, len(a)+len(b))
+	for _, in := range []Entitlements{a, b} {
+		for _, scheme := range sortedKeys(in) {
+			list, ok := merged[scheme]
+			if !ok {
+				list = make([]string, 0, len(in[scheme]))
+			}
+			for _, s := range in[scheme] {
+				list = appendUnique(list, s)
+			}
+			merged[scheme] = list
+		}
+	}
+	return merged
+}
@@ -0,0 +1,60 @@
+package entitlements
+
+// MetricsSink receives counters for each decision, e.g. to back Prometheus
+// counters (see WithMetricsSink). Implementations must be safe for
+// concurrent use, and should be cheap: they run on the verifying goroutine.
+type MetricsSink interface {
+	// IncAllow counts a decision granting access.
+	IncAllow()
+	// IncDeny counts a decision denying access.
+	IncDeny()
+	// IncSchemeMatch counts, for a decision granted by a requirement branch,
+	// one scheme of that branch.
+	IncSchemeMatch(scheme string)
+}
+
+// WithMetricsSink reports every decision of the verify methods and
+// PreparedPolicy.Check to sink: IncAllow or IncDeny once per decision and,
+// for an allow granted by a requirement branch, IncSchemeMatch once for each
+// scheme of the branch (the first satisfied, under WithBranchCombiner), as
+// judged (see WithDefaultSchemeFallback). Allows without a branch — empty
+// requirements, a break-glass grant, an identity check alone — match no
+// scheme. VerifyEntitlementsBatch counts each check, but a break-glass grant
+// covering the batch once. A verification abandoned because its context was
+// done decides nothing and is not counted, and analysis methods such as
+// Explain are not counted either.
+//
+// nil disables the counters, the default, at the cost of a nil check per
+// decision.
+//
+// Intended for use during checker construction; not safe for concurrent
+// mutation with verify calls in flight.
+func (ec *EntitlementsChecker) WithMetricsSink(sink MetricsSink) *EntitlementsChecker {
+	ec.metrics = sink
+	return ec
+}
+
+// countDecision reports a decision to the metrics sink, if any. branch is the
+// requirement branch that granted an allow, or nil.
+func (ec *EntitlementsChecker) countDecision(allowed bool, held map[string][]entitlementPattern, branch map[string][]entitlementPattern) {
+	if ec.metrics == nil {
+		return
+	}
+	if !allowed {
+		ec.metrics.IncDeny()
+		return
+	}
+	ec.metrics.IncAllow()
+	for _, listed := range sortedKeys(branch) {
+		ec.metrics.IncSchemeMatch(ec.judgedScheme(held, listed))
+	}
+}
+
+// branchAt returns branches[i], or nil when i is out of range (-1 for no
+// branch).
+func branchAt(branches []map[string][]entitlementPattern, i int) map[string][]entitlementPattern {
+	if i < 0 || i >= len(branches) {
+		return nil
+	}
+	return branches[i]
+}
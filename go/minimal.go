@@ -0,0 +1,117 @@
+package entitlements
+
+import "slices"
+
+// MinimalSatisfyingEntitlements returns the smallest entitlement set that
+// satisfies requirements: the tokens of the OR branch with the fewest
+// AND-entries, emitted verbatim as grants under their schemes. A requirement
+// token is its own narrowest satisfying grant, so wildcard and "all"
+// requirements yield correspondingly broad grants ("pages:read" emits the
+// class-wide "pages:read"). Ties go to the earliest branch.
+//
+// An "@name" reference is replaced by the minimal grants of the named
+// requirement and counted at that size. A reference to an unregistered name
+// cannot be satisfied, so a branch containing one is never chosen; if every
+// branch contains one, nil is returned. Placeholders are emitted as literal
+// text — bind them first (BindRequirements) for a grant that matches the
+// bound requirement.
+//
+// Except conditions (see ExceptPrefix) are satisfied by holding nothing and
+// contribute no grants; the result is not checked against them. A
+// distinct-schemes token (see RequireDistinctSchemes) names no schemes to
+// grant under, so a branch containing one is never chosen.
+//
+// An empty requirements needs nothing and yields an empty, non-nil set. The
+// base and anonymous entitlements are not consulted: the result is what a
+// caller holding nothing would need to be granted.
+func (ec *EntitlementsChecker) MinimalSatisfyingEntitlements(requirements Requirements) Entitlements {
+	if len(requirements) == 0 {
+		return Entitlements{}
+	}
+
+	var best Entitlements
+	bestSize := -1
+	for _, branch := range requirements {
+		grants, ok := ec.minimalBranch(branch)
+		if !ok {
+			continue
+		}
+		if size := grantCount(grants); bestSize < 0 || size < bestSize {
+			best, bestSize = grants, size
+		}
+	}
+	return best
+}
+
+// minimalBranch collects the grants that satisfy one AND branch, expanding
+// references. It reports false when the branch references an unregistered
+// name or holds a distinct-schemes token.
+func (ec *EntitlementsChecker) minimalBranch(branch map[string][]string) (Entitlements, bool) {
+	out := Entitlements{}
+	for scheme, list := range branch {
+		for _, s := range list {
+			if _, ok := exceptCondition(s); ok {
+				// Satisfied by holding nothing.
+				continue
+			}
+			if _, _, ok := distinctSchemesToken(s); ok {
+				// The schemes to grant under cannot be chosen here.
+				return nil, false
+			}
+			if ref := referenceName(s); ref != "" {
+				named, ok := ec.namedRequirements[ref]
+				if !ok {
+					return nil, false
+				}
+				grants := ec.MinimalSatisfyingEntitlements(rawRequirements(named))
+				if grants == nil {
+					return nil, false
+				}
+				for refScheme, refList := range grants {
+					for _, g := range refList {
+						out[refScheme] = appendUnique(out[refScheme], g)
+					}
+				}
+				continue
+			}
+			out[scheme] = appendUnique(out[scheme], s)
+		}
+		if _, ok := out[scheme]; !ok && len(list) == 0 {
+			// An empty list still requires the scheme to be present.
+			out[scheme] = []string{}
+		}
+	}
+	return out, true
+}
+
+// rawRequirements recovers the requirement strings of parsed patterns.
+func rawRequirements(parsed []map[string][]entitlementPattern) Requirements {
+	out := make(Requirements, len(parsed))
+	for i, set := range parsed {
+		m := make(map[string][]string, len(set))
+		for scheme, list := range set {
+			raw := make([]string, len(list))
+			for j, p := range list {
+				raw[j] = p.raw
+			}
+			m[scheme] = raw
+		}
+		out[i] = m
+	}
+	return out
+}
+
+func grantCount(e Entitlements) int {
+	n := 0
+	for _, list := range e {
+		n += len(list)
+	}
+	return n
+}
+
+func appendUnique(list []string, s string) []string {
+	if slices.Contains(list, s) {
+		return list
+	}
+	return append(list, s)
+}
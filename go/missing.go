@@ -0,0 +1,77 @@
+package entitlements
+
+// BranchGap is what a caller lacks to satisfy one OR branch of a requirement.
+// Both fields are empty when the branch is satisfied.
+type BranchGap struct {
+	// MissingSchemes lists, sorted, the schemes the branch requires that the
+	// caller did not present, with no base or anonymous entitlements to stand
+	// in for them.
+	MissingSchemes []string `json:"missingSchemes,omitempty"`
+	// Unmet lists the branch's unsatisfied tokens, in scheme order, then
+	// token order.
+	Unmet []UnmetRequirement `json:"unmet,omitempty"`
+}
+
+// Satisfied reports whether the caller lacks nothing for the branch.
+func (g BranchGap) Satisfied() bool {
+	return len(g.MissingSchemes) == 0 && len(g.Unmet) == 0
+}
+
+// AllMissing reports, for every OR branch of requirements in order, what the
+// caller lacks to satisfy it, so a "you are missing the following" page can
+// offer each alternative path to access rather than only the closest one (see
+// DenyRecord.Unmet). Branches are judged one at a time, as VerifyEntitlements
+// judges them, without regard to WithBranchCombiner. Empty requirements have
+// no branches and return nil. The denial cache is not consulted.
+func (ec *EntitlementsChecker) AllMissing(entitlements Entitlements, requirements Requirements) []BranchGap {
+	branches := ec.ParseRequirements(requirements).patterns
+	if len(branches) == 0 {
+		return nil
+	}
+	held := ec.enabledSchemes(ec.ParseEntitlements(entitlements).patterns)
+	fb := callerFallback(held)
+	gaps := make([]BranchGap, len(branches))
+	for i, branch := range branches {
+		if ec.satisfiesAndRequirements(held, branch, fb) {
+			continue
+		}
+		a := ec.analyseBranch(held, branch, fb)
+		gaps[i] = BranchGap{MissingSchemes: a.MissingSchemes, Unmet: a.Unmet}
+	}
+	return gaps
+}
+
+// MissingEntitlements returns what the caller still lacks to satisfy the OR
+// branch of requirements closest to satisfied, as a single-branch Requirements
+// mapping each scheme to its unmet tokens, e.g. for a portal telling a user
+// exactly what to request. A scheme the caller must present but for which no
+// token is unmet is listed with an empty list. Nil is returned when any branch
+// is already satisfied, or for empty requirements.
+//
+// The closest branch is the one with the fewest unmet tokens, a scheme the
+// caller does not present at all counting as one more; ties go to the first
+// branch in order. This is the branch DenyRecord.Closest and
+// Explanation.Closest name. The denial cache is not consulted.
+func (ec *EntitlementsChecker) MissingEntitlements(entitlements Entitlements, requirements Requirements) Requirements {
+	branches := ec.ParseRequirements(requirements).patterns
+	held := ec.enabledSchemes(ec.ParseEntitlements(entitlements).patterns)
+	fb := callerFallback(held)
+	for _, branch := range branches {
+		if ec.satisfiesAndRequirements(held, branch, fb) {
+			return nil
+		}
+	}
+	analysis, closest := ec.analyseBranches(held, branches, fb)
+	if closest < 0 {
+		return nil
+	}
+
+	missing := make(map[string][]string)
+	for _, scheme := range analysis[closest].MissingSchemes {
+		missing[scheme] = []string{}
+	}
+	for _, u := range analysis[closest].Unmet {
+		missing[u.Scheme] = append(missing[u.Scheme], u.Requirement)
+	}
+	return Requirements{missing}
+}
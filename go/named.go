@@ -0,0 +1,241 @@
+package entitlements
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ReferencePrefix marks a requirement token that references a named
+// requirement registered with WithNamedRequirements, e.g. "@editorPolicy".
+const ReferencePrefix = "@"
+
+// ErrUndefinedReference is returned by WithNamedRequirements when a named
+// requirement references a name that is not registered.
+var ErrUndefinedReference = errors.New("entitlements: undefined requirement reference")
+
+// ErrReferenceCycle is returned by WithNamedRequirements when named
+// requirements reference each other in a cycle.
+var ErrReferenceCycle = errors.New("entitlements: requirement reference cycle")
+
+// ErrPredicateTooDeep is returned by WithNamedRequirements and
+// WithMaxPredicateDepth when named requirements nest references deeper than
+// the WithMaxPredicateDepth limit, and by VerifyEntitlementsCtx and
+// BindRequirements when a requirement does.
+var ErrPredicateTooDeep = errors.New("entitlements: requirement references nested too deeply")
+
+// WithNamedRequirements registers named requirements that other requirements
+// may reference with an "@name" token (see ReferencePrefix). A reference is
+// one AND-token of the branch it appears in, and it is satisfied when the
+// named Requirements is — that is, when any of ITS branches is satisfied.
+// The named requirement carries its own schemes, so the scheme key a
+// reference is listed under does not scope it; by convention list references
+// under the default scheme.
+//
+// Named requirements may reference each other. Every reference among them is
+// resolved here, once: a reference to an unregistered name returns
+// ErrUndefinedReference, and a reference cycle returns ErrReferenceCycle. On
+// error the checker is left unchanged. A reference in a requirement passed to
+// verification that names an unregistered policy is unsatisfiable. Names
+// nesting references deeper than WithMaxPredicateDepth allows return
+// ErrPredicateTooDeep.
+//
+// Replaces any previously registered names. Intended for use during checker
+// construction; not safe for concurrent mutation with verify calls in flight.
+func (ec *EntitlementsChecker) WithNamedRequirements(named map[string]Requirements) (*EntitlementsChecker, error) {
+	depths, err := checkReferences(named)
+	if err != nil {
+		return ec, err
+	}
+	if err := checkPredicateDepth(depths, ec.maxPredicateDepth); err != nil {
+		return ec, err
+	}
+
+	parsed := make(map[string][]map[string][]entitlementPattern, len(named))
+	for name, reqs := range named {
+		parsed[name] = ec.ParseRequirements(reqs).patterns
+	}
+	ec.namedRequirements = parsed
+	ec.referenceDepths = depths
+	return ec, nil
+}
+
+// WithMaxPredicateDepth bounds how deeply requirements may nest references.
+// A named requirement referencing no other name has depth 1, and one
+// referencing names has one more than the deepest of them; a requirement
+// passed to verification likewise has one more than the deepest name it
+// references. A depth of 0 (the default) means no limit.
+//
+// The limit is checked against the registered names when it is set and by
+// every later WithNamedRequirements, which return an error wrapping
+// ErrPredicateTooDeep naming the offending reference chain; on error the
+// limit is left unchanged. Evaluation enforces it too: VerifyEntitlementsCtx
+// and BindRequirements return an error wrapping ErrPredicateTooDeep for a
+// requirement referencing a name at or beyond the limit, and the other verify
+// paths deny it.
+//
+// Intended for use during checker construction; not safe for concurrent
+// mutation with verify calls in flight.
+func (ec *EntitlementsChecker) WithMaxPredicateDepth(depth int) (*EntitlementsChecker, error) {
+	depth = max(depth, 0)
+	if err := checkPredicateDepth(ec.referenceDepths, depth); err != nil {
+		return ec, err
+	}
+	ec.maxPredicateDepth = depth
+	return ec, nil
+}
+
+// referenceDepth records how deeply a named requirement nests references, and
+// the chain of names reaching that depth.
+type referenceDepth struct {
+	depth int
+	chain []string
+}
+
+// checkPredicateDepth reports the first name, in sorted order, whose nesting
+// exceeds limit. A limit of 0 means no limit.
+func checkPredicateDepth(depths map[string]referenceDepth, limit int) error {
+	if limit == 0 {
+		return nil
+	}
+	for _, name := range sortedKeys(depths) {
+		if d := depths[name]; d.depth > limit {
+			return fmt.Errorf("%w (%d): %s", ErrPredicateTooDeep, limit, strings.Join(d.chain, " -> "))
+		}
+	}
+	return nil
+}
+
+// checkPredicateDepthOf returns ErrPredicateTooDeep when reqs reference a
+// name nested too deeply for the checker's limit.
+func (ec *EntitlementsChecker) checkPredicateDepthOf(reqs ParsedRequirements) error {
+	if ec.maxPredicateDepth == 0 {
+		return nil
+	}
+	for _, set := range reqs.patterns {
+		for _, scheme := range sortedKeys(set) {
+			for _, p := range set[scheme] {
+				if p.ref == "" {
+					continue
+				}
+				if d := ec.referenceDepths[p.ref]; d.depth+1 > ec.maxPredicateDepth {
+					return fmt.Errorf("%w (%d): %s", ErrPredicateTooDeep, ec.maxPredicateDepth,
+						strings.Join(append([]string{p.raw}, d.chain[1:]...), " -> "))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// satisfiesReference reports whether the named requirement ref is satisfied.
+// An unregistered name, and one too deep to reference under the
+// WithMaxPredicateDepth limit, is unsatisfiable.
+func (ec *EntitlementsChecker) satisfiesReference(entitlements map[string][]entitlementPattern, ref string, fb fallback) bool {
+	named, ok := ec.namedRequirements[ref]
+	if !ok {
+		return false
+	}
+	if ec.maxPredicateDepth > 0 && ec.referenceDepths[ref].depth+1 > ec.maxPredicateDepth {
+		return false
+	}
+	if len(named) == 0 {
+		return true
+	}
+	for _, requirement := range named {
+		if ec.satisfiesAndRequirements(entitlements, requirement, fb) {
+			return true
+		}
+	}
+	return false
+}
+
+// referenceName returns the referenced name when s is an "@name" token, else
+// "". A name never contains ':'.
+func referenceName(s string) string {
+	if name, ok := strings.CutPrefix(s, ReferencePrefix); ok && name != "" && !strings.Contains(name, ":") {
+		return name
+	}
+	return ""
+}
+
+// schemeOptional reports whether a non-empty requirement list consists solely
+// of references and distinct-schemes tokens, which do not depend on the scheme
+// they are listed under, and except conditions, which a caller not presenting
+// the scheme satisfies.
+func schemeOptional(list []entitlementPattern) bool {
+	if len(list) == 0 {
+		return false
+	}
+	for _, p := range list {
+		if p.ref == "" && p.cond == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// checkReferences validates every reference among the named requirements,
+// visiting names in sorted order so the reported error is deterministic, and
+// returns how deeply each name nests references.
+func checkReferences(named map[string]Requirements) (map[string]referenceDepth, error) {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(named))
+	depths := make(map[string]referenceDepth, len(named))
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("%w: %s", ErrReferenceCycle, strings.Join(append(path, name), " -> "))
+		case done:
+			return nil
+		}
+		state[name] = visiting
+		path = append(path, name)
+		deepest := referenceDepth{depth: 1, chain: []string{name}}
+		for _, set := range named[name] {
+			for _, scheme := range sortedKeys(set) {
+				for _, s := range set[scheme] {
+					ref := referenceName(s)
+					if ref == "" {
+						continue
+					}
+					if _, ok := named[ref]; !ok {
+						return fmt.Errorf("%w: %q referenced by %q", ErrUndefinedReference, ref, name)
+					}
+					if err := visit(ref, path); err != nil {
+						return err
+					}
+					if d := depths[ref]; d.depth+1 > deepest.depth {
+						deepest = referenceDepth{depth: d.depth + 1, chain: append([]string{name}, d.chain...)}
+					}
+				}
+			}
+		}
+		depths[name] = deepest
+		state[name] = done
+		return nil
+	}
+
+	for _, name := range sortedKeys(named) {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return depths, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,38 @@
+package entitlements
+
+// RequirementsFromOpenAPISecurity converts an OpenAPI security requirement
+// list (the "security" field of an operation or of the document) into
+// Requirements with the same meaning. The list's entries are alternatives, as
+// Requirements branches are, and the schemes within an entry must all be
+// satisfied, as a branch's schemes must; each entry becomes one branch, in
+// order.
+//
+// Scheme names map unchanged: an entry's keys are the names declared under
+// components.securitySchemes, so the caller's Entitlements must be keyed by
+// those same names (see WithCaseInsensitiveSchemes to relax case). Each
+// scheme's scope list becomes its requirement tokens verbatim, an empty list
+// requiring only that the caller present the scheme, as in OpenAPI. A scope
+// is read as a requirement token, so "pages:read" is a structured entitlement
+// and one beginning with ExceptPrefix, ReferencePrefix or
+// DistinctSchemesPrefix takes that meaning; check untrusted documents with
+// EntitlementsChecker.ValidateRequirements.
+//
+// An empty or nil security list, which declares the operation public,
+// becomes empty Requirements, which admit every caller; so does an empty
+// entry ({}), which makes authentication optional. The caller resolves
+// precedence: pass the operation's security when it declares one, and the
+// document's otherwise. The result shares nothing with security.
+func RequirementsFromOpenAPISecurity(security []map[string][]string) Requirements {
+	if len(security) == 0 {
+		return Requirements{}
+	}
+	requirements := make(Requirements, 0, len(security))
+	for _, entry := range security {
+		branch := make(map[string][]string, len(entry))
+		for scheme, scopes := range entry {
+			branch[scheme] = append([]string{}, scopes...)
+		}
+		requirements = append(requirements, branch)
+	}
+	return requirements
+}
@@ -0,0 +1,67 @@
+package entitlements
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrPolicySignature is returned by LoadSignedPolicy when a policy bundle
+// fails signature verification.
+var ErrPolicySignature = errors.New("entitlements: policy bundle signature verification failed")
+
+// ErrInvalidPolicy is returned by LoadSignedPolicy when a verified policy
+// bundle cannot be decoded or applied.
+var ErrInvalidPolicy = errors.New("entitlements: invalid policy bundle")
+
+// PolicyBundle is the JSON policy a checker is constructed from by
+// LoadSignedPolicy. Fields map onto NewEntitlementsChecker and its options.
+type PolicyBundle struct {
+	AnonymousEntitlements []string                `json:"anonymousEntitlements,omitempty"`
+	DefaultScheme         string                  `json:"defaultScheme,omitempty"`
+	GrantReadyByDefault   bool                    `json:"grantReadyByDefault,omitempty"`
+	BaseEntitlements      []string                `json:"baseEntitlements,omitempty"`
+	NamedRequirements     map[string]Requirements `json:"namedRequirements,omitempty"`
+}
+
+// LoadSignedPolicy constructs a checker from a JSON-encoded PolicyBundle
+// after verify accepts signature over data. The cryptography is the
+// caller's — e.g. ed25519.Verify behind the verify func — so the package
+// stays free of key-management dependencies.
+//
+// Nothing in data is parsed before verify returns nil: a rejected or missing
+// verify returns an error wrapping ErrPolicySignature. A verified bundle that
+// is not valid JSON, carries unknown fields or trailing data, or whose named
+// requirements are malformed (see Requirements.UnmarshalJSON) or do not
+// resolve (see WithNamedRequirements) returns an error wrapping
+// ErrInvalidPolicy. No checker is returned with an error.
+func LoadSignedPolicy(data, signature []byte, verify func(data, sig []byte) error) (*EntitlementsChecker, error) {
+	if verify == nil {
+		return nil, fmt.Errorf("%w: no verify function", ErrPolicySignature)
+	}
+	if err := verify(data, signature); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrPolicySignature, err)
+	}
+
+	var bundle PolicyBundle
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&bundle); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPolicy, err)
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("%w: trailing data after policy", ErrInvalidPolicy)
+	}
+
+	ec := NewEntitlementsChecker(bundle.AnonymousEntitlements, bundle.DefaultScheme, bundle.GrantReadyByDefault)
+	if len(bundle.BaseEntitlements) > 0 {
+		ec.WithBaseEntitlements(bundle.BaseEntitlements)
+	}
+	if len(bundle.NamedRequirements) > 0 {
+		if _, err := ec.WithNamedRequirements(bundle.NamedRequirements); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidPolicy, err)
+		}
+	}
+	return ec, nil
+}
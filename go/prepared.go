@@ -0,0 +1,221 @@
+package entitlements
+
+import (
+	"slices"
+	"strings"
+)
+
+// PreparedPolicy is a requirement compiled by PrepareFor for checking many
+// callers against it. Requirement tokens are indexed by scheme and by the
+// resource (or opaque string) they name, so a check looks each entitlement
+// the caller holds up in the index instead of scanning every requirement
+// token against every entitlement: O(E × k) for E entitlements and k tokens
+// sharing a resource, plus one pass over the branches.
+//
+// A PreparedPolicy is safe for concurrent use by multiple goroutines.
+type PreparedPolicy struct {
+	ec     *EntitlementsChecker
+	parsed ParsedRequirements
+	// tokens holds every indexed requirement token; its position is the
+	// token's id.
+	tokens   []entitlementPattern
+	branches []preparedBranch
+	// index maps a scheme, then a matchKey, to the ids of the tokens listed
+	// under that scheme with that key.
+	index map[string]map[string][]int
+	// versions maps a scheme, then the base of a versioned matchKey, to the
+	// ids of its tokens, for held version wildcards.
+	versions map[string]map[string][]int
+	// generic is set when a token (a reference, an except condition, a
+	// distinct-schemes token or a verb set) is not a plain match and the
+	// policy must be evaluated by the generic path.
+	generic bool
+}
+
+type preparedBranch struct {
+	schemes []string
+	tokens  []int
+}
+
+// PrepareFor compiles requirements into a PreparedPolicy whose Check decides
+// exactly as VerifyEntitlements would (without its denial cache). The
+// checker's options are read at Check time, not here, so the policy follows
+// later configuration — but as with any option, not while checks are in
+// flight.
+//
+// The index covers plain tokens. Requirements containing an "@name"
+// reference, an except condition or a distinct-schemes token, checkers with
+// WithDefaultSchemeFallback or WithSchemeWildcard (which re-scope tokens per
+// caller) or WithHierarchyResolver (which resolves tokens per check),
+// case-insensitive checkers (whose index keys would differ in case), and
+// callers holding a deny (see ExceptPrefix) are still checked correctly, by the
+// generic path, without the speed-up.
+func (ec *EntitlementsChecker) PrepareFor(requirements Requirements) *PreparedPolicy {
+	p := &PreparedPolicy{
+		ec:       ec,
+		parsed:   ec.ParseRequirements(requirements),
+		index:    make(map[string]map[string][]int),
+		versions: make(map[string]map[string][]int),
+	}
+	for _, set := range p.parsed.patterns {
+		var branch preparedBranch
+		for scheme, list := range set {
+			branch.schemes = append(branch.schemes, scheme)
+			for _, req := range list {
+				if req.ref != "" || req.cond != nil || ec.verbSet(req) != nil {
+					p.generic = true
+				}
+				id := len(p.tokens)
+				p.tokens = append(p.tokens, req)
+				branch.tokens = append(branch.tokens, id)
+
+				key := matchKey(req)
+				addToIndex(p.index, scheme, key, id)
+				if base, ok := versionBase(key); ok {
+					addToIndex(p.versions, scheme, base, id)
+				}
+			}
+		}
+		p.branches = append(p.branches, branch)
+	}
+	return p
+}
+
+// Check reports whether entitlements satisfy the prepared requirements.
+func (p *PreparedPolicy) Check(entitlements Entitlements) (result bool) {
+	ec := p.ec
+	defer func() {
+		if ec.log != nil {
+			ec.log.V(2).Info("Checked prepared policy", "result", result)
+		}
+	}()
+
+	if len(p.branches) == 0 {
+		ec.countDecision(true, nil, nil)
+		return true
+	}
+	if ec.brokeGlass(entitlements) {
+		return true
+	}
+	parsed := ec.ParseEntitlements(entitlements)
+	held := ec.enabledSchemes(parsed.patterns)
+	if p.generic || ec.defaultSchemeFallback || ec.schemeWildcard || ec.hierarchy != nil || ec.caseInsensitive || holdsDeny(held) {
+		return ec.VerifyParsedEntitlements(parsed, p.parsed)
+	}
+	fb := callerFallback(held)
+
+	satisfied := make([]bool, len(p.tokens))
+	for scheme, list := range held {
+		if ec.resourceIndicator != "" && !allowsResourceIndicator(list, ec.resourceIndicator) {
+			continue
+		}
+		p.mark(satisfied, scheme, list)
+	}
+	if fb.base {
+		p.mark(satisfied, ec.defaultScheme, ec.basePatterns)
+	}
+	if fb.anonymous {
+		p.mark(satisfied, ec.defaultScheme, ec.anonymousPatterns)
+	}
+
+	ok, branch := p.combine(held, fb, satisfied)
+	ec.countDecision(ok, held, branchAt(p.parsed.patterns, branch))
+	if ok {
+		return true
+	}
+	ec.auditDenial(held, p.parsed.patterns, fb, false)
+	return false
+}
+
+// combine decides the branches from the marked tokens, also returning the
+// index of the first satisfied branch (-1 when denied).
+func (p *PreparedPolicy) combine(held map[string][]entitlementPattern, fb fallback, satisfied []bool) (bool, int) {
+	if p.ec.branchCombiner == nil {
+		for i, branch := range p.branches {
+			if p.branchSatisfied(branch, held, fb, satisfied) {
+				return true, i
+			}
+		}
+		return false, -1
+	}
+	results := make([]bool, len(p.branches))
+	for i, branch := range p.branches {
+		results[i] = p.branchSatisfied(branch, held, fb, satisfied)
+	}
+	if !p.ec.branchCombiner(results) {
+		return false, -1
+	}
+	return true, slices.Index(results, true)
+}
+
+// mark records the tokens under scheme that an entitlement in list matches.
+func (p *PreparedPolicy) mark(satisfied []bool, scheme string, list []entitlementPattern) {
+	keys := p.index[scheme]
+	if keys == nil {
+		return
+	}
+	m := p.ec.matcherFor(scheme)
+	for _, ep := range list {
+		ep = m.expandBare(ep)
+		key := matchKey(ep)
+		if ep.isPattern && m.versions && ep.resource == ResourceTypeWildcard {
+			for _, ids := range keys {
+				p.markCandidates(satisfied, m, ep, ids)
+			}
+			continue
+		}
+		p.markCandidates(satisfied, m, ep, keys[key])
+		if base, ok := strings.CutSuffix(key, VersionWildcardSuffix); ok && ep.isPattern && m.versions {
+			p.markCandidates(satisfied, m, ep, p.versions[scheme][base])
+		}
+	}
+}
+
+func (p *PreparedPolicy) markCandidates(satisfied []bool, m matcher, ep entitlementPattern, ids []int) {
+	for _, id := range ids {
+		if !satisfied[id] && !p.ec.strictRejects(p.tokens[id]) && m.matches(ep, p.tokens[id]) {
+			satisfied[id] = true
+		}
+	}
+}
+
+func addToIndex(index map[string]map[string][]int, scheme, key string, id int) {
+	keys := index[scheme]
+	if keys == nil {
+		keys = make(map[string][]int)
+		index[scheme] = keys
+	}
+	keys[key] = append(keys[key], id)
+}
+
+func (p *PreparedPolicy) branchSatisfied(branch preparedBranch, held map[string][]entitlementPattern, fb fallback, satisfied []bool) bool {
+	ec := p.ec
+	for _, scheme := range branch.schemes {
+		_, ok := held[scheme]
+		hasFallback := scheme == ec.defaultScheme &&
+			((fb.base && len(ec.basePatterns) > 0) || (fb.anonymous && len(ec.anonymousPatterns) > 0))
+		if !ok && !hasFallback {
+			return false
+		}
+	}
+	for _, id := range branch.tokens {
+		if !satisfied[id] {
+			return false
+		}
+	}
+	return true
+}
+
+// matchKey returns the index key of a pattern: its resource when structured,
+// else its raw string. Two patterns can only match when their keys are equal:
+// structured patterns must share a resource, an opaque requirement is matched
+// by the identical string, and WithAllCoversOpaque matches a structured grant
+// against the opaque requirement naming its resource. The exceptions are
+// held wildcards: a version wildcard is looked up by version base instead, and
+// a resource type wildcard against every key.
+func matchKey(p entitlementPattern) string {
+	if p.isPattern {
+		return p.resource
+	}
+	return p.raw
+}
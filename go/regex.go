@@ -0,0 +1,126 @@
+package entitlements
+
+import (
+	"container/list"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// RegexNamePrefix marks a held resourceName as a regular expression under
+// WithResourceNameRegex, e.g. "orders:~ORD-\d{4}:read".
+const RegexNamePrefix = "~"
+
+// maxCachedRegexes bounds a checker's compiled-expression cache, so a flood of
+// distinct expressions cannot grow it without bound.
+const maxCachedRegexes = 1024
+
+// WithResourceNameRegex enables regular-expression resourceNames: a held name
+// starting with RegexNamePrefix, such as "orders:~^ORD-\d{4}$:read", grants
+// every required name the expression (Go's RE2 syntax, see regexp/syntax)
+// matches in full — it is anchored at both ends whether or not it says so, so
+// "~ORD" grants "ORD" but not "XORD-1". Since ':' separates an entitlement's
+// segments, an expression cannot contain one.
+//
+// Expressions are compiled once per checker and cached. One that does not
+// compile matches nothing; ValidateEntitlement and ValidateRequirements report
+// it. On the requirement side an expression is not expanded: it is satisfied
+// only by a wildcard grant or by the identical expression.
+//
+// Defaults to false, in which a leading '~' is an ordinary character.
+// Enabling the option again starts a fresh cache. Intended for use during
+// checker construction; not safe for concurrent mutation with verify calls in
+// flight.
+func (ec *EntitlementsChecker) WithResourceNameRegex(enabled bool) *EntitlementsChecker {
+	ec.resourceNameRegexes = nil
+	if enabled {
+		ec.resourceNameRegexes = newRegexCache()
+	}
+	return ec
+}
+
+// regexCache holds compiled resource-name expressions, and nil for those that
+// did not compile. When the cache is full the least recently used expression
+// is evicted first, so the working set stays cached however many distinct
+// expressions pass through.
+type regexCache struct {
+	mu       sync.Mutex
+	compiled map[string]*list.Element
+	order    *list.List
+}
+
+type regexEntry struct {
+	expr string
+	re   *regexp.Regexp
+}
+
+func newRegexCache() *regexCache {
+	return &regexCache{compiled: make(map[string]*list.Element), order: list.New()}
+}
+
+// compile returns expr compiled and anchored, or nil when it is invalid.
+func (c *regexCache) compile(expr string) *regexp.Regexp {
+	c.mu.Lock()
+	if el, ok := c.compiled[expr]; ok {
+		c.order.MoveToBack(el)
+		c.mu.Unlock()
+		return el.Value.(*regexEntry).re
+	}
+	c.mu.Unlock()
+	re, _ := compileNameRegex(expr)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.compiled[expr]; ok {
+		// Compiled concurrently; keep the cached one.
+		c.order.MoveToBack(el)
+		return el.Value.(*regexEntry).re
+	}
+	for c.order.Len() >= maxCachedRegexes {
+		oldest := c.order.Front()
+		c.order.Remove(oldest)
+		delete(c.compiled, oldest.Value.(*regexEntry).expr)
+	}
+	c.compiled[expr] = c.order.PushBack(&regexEntry{expr: expr, re: re})
+	return re
+}
+
+// compileNameRegex compiles expr, anchored at both ends.
+func compileNameRegex(expr string) (*regexp.Regexp, error) {
+	return regexp.Compile(`^(?:` + expr + `)$`)
+}
+
+// regexMatches reports whether the held expression name grants the required
+// name, and whether name is an expression at all.
+func (m matcher) regexMatches(held, required string) (matched, ok bool) {
+	if m.regexes == nil {
+		return false, false
+	}
+	expr, ok := strings.CutPrefix(held, RegexNamePrefix)
+	if !ok {
+		return false, false
+	}
+	if held == required {
+		return true, true
+	}
+	if strings.HasPrefix(required, RegexNamePrefix) {
+		return false, true
+	}
+	re := m.regexes.compile(expr)
+	return re != nil && re.MatchString(required), true
+}
+
+// regexProblem describes why the resourceName of a structured token does not
+// compile under WithResourceNameRegex, or returns "".
+func (ec *EntitlementsChecker) regexProblem(resourceName string) string {
+	if ec.resourceNameRegexes == nil {
+		return ""
+	}
+	expr, ok := strings.CutPrefix(resourceName, RegexNamePrefix)
+	if !ok {
+		return ""
+	}
+	if _, err := compileNameRegex(expr); err != nil {
+		return "has an invalid regular expression: " + err.Error()
+	}
+	return ""
+}
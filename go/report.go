@@ -0,0 +1,160 @@
+package entitlements
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GrantSource records where a satisfying entitlement came from.
+type GrantSource string
+
+const (
+	// GrantSourceDirect is an entitlement the caller presented.
+	GrantSourceDirect GrantSource = "direct"
+	// GrantSourceBase is a base entitlement (see WithBaseEntitlements).
+	GrantSourceBase GrantSource = "base"
+	// GrantSourceAnonymous is an anonymous entitlement, applied because the
+	// caller presented none.
+	GrantSourceAnonymous GrantSource = "anonymous"
+)
+
+// ResourceVerb is one resource/verb combination of an AccessReport catalog.
+// An empty ResourceName asks about the whole resource class.
+type ResourceVerb struct {
+	Resource     string `json:"resource"`
+	ResourceName string `json:"resourceName,omitempty"`
+	Verb         string `json:"verb"`
+}
+
+// String returns the combination in long form, e.g. "pages:/foo:read".
+func (rv ResourceVerb) String() string {
+	return rv.Resource + ":" + rv.ResourceName + ":" + rv.Verb
+}
+
+// Report is the outcome of AccessReport, grouped by resource type.
+type Report struct {
+	// Resources holds one group per resource type, in the order each type
+	// first appears in the catalog.
+	Resources []ResourceReport `json:"resources"`
+}
+
+// ResourceReport holds the catalog entries of one resource type.
+type ResourceReport struct {
+	Resource string        `json:"resource"`
+	Entries  []ReportEntry `json:"entries"`
+}
+
+// ReportEntry is the decision for one catalog entry. When Granted, Scheme,
+// Entitlement and Source identify the first entitlement found to grant it.
+type ReportEntry struct {
+	ResourceVerb ResourceVerb `json:"resourceVerb"`
+	Granted      bool         `json:"granted"`
+	Scheme       string       `json:"scheme,omitempty"`
+	Entitlement  string       `json:"entitlement,omitempty"`
+	Source       GrantSource  `json:"source,omitempty"`
+}
+
+// AccessReport evaluates every catalog entry against entitlements and reports
+// which are granted, grouped by resource type, for support tickets and
+// audits. Each entry is checked as the requirement "<resource>:<name>:<verb>"
+// under every scheme the caller holds, in sorted order, and finally under the
+// default scheme, so base and anonymous entitlements are attributed as such.
+// The first grant found is reported; a direct grant is preferred over a base
+// or anonymous one under the same scheme.
+//
+// Matching is exactly that of verification, including the checker's options
+// (disabled schemes, strict requirements, and so on).
+func (ec *EntitlementsChecker) AccessReport(entitlements Entitlements, catalog []ResourceVerb) Report {
+	held := ec.enabledSchemes(ec.ParseEntitlements(entitlements).patterns)
+	fb := callerFallback(held)
+
+	schemes := sortedKeys(held)
+	if _, ok := held[ec.defaultScheme]; !ok {
+		schemes = append(schemes, ec.defaultScheme)
+	}
+
+	var report Report
+	index := make(map[string]int)
+	for _, rv := range catalog {
+		entry := ReportEntry{ResourceVerb: rv}
+		requirement := ec.parsePattern(rv.String())
+		for _, scheme := range schemes {
+			if grant, source, ok := ec.findGrant(held[scheme], scheme, requirement, fb); ok {
+				entry.Granted = true
+				entry.Scheme = scheme
+				entry.Entitlement = grant.raw
+				entry.Source = source
+				break
+			}
+		}
+
+		i, ok := index[rv.Resource]
+		if !ok {
+			i = len(report.Resources)
+			index[rv.Resource] = i
+			report.Resources = append(report.Resources, ResourceReport{Resource: rv.Resource})
+		}
+		report.Resources[i].Entries = append(report.Resources[i].Entries, entry)
+	}
+	return report
+}
+
+// String renders the report for humans, one resource type per heading:
+//
+//	pages
+//	  pages:/foo:read    granted by bearer "pages:read" (direct)
+//	  pages:/foo:delete  denied
+func (r Report) String() string {
+	width := 0
+	for _, group := range r.Resources {
+		for _, e := range group.Entries {
+			width = max(width, len(e.ResourceVerb.String()))
+		}
+	}
+
+	var b strings.Builder
+	for _, group := range r.Resources {
+		b.WriteString(group.Resource)
+		b.WriteByte('\n')
+		for _, e := range group.Entries {
+			fmt.Fprintf(&b, "  %-*s  ", width, e.ResourceVerb.String())
+			if e.Granted {
+				fmt.Fprintf(&b, "granted by %s %q (%s)\n", e.Scheme, e.Entitlement, e.Source)
+			} else {
+				b.WriteString("denied\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+// EntitlementUsage reports, for each requirement in named that entitlements
+// satisfy, which of the caller's own entitlement strings satisfied it — for a
+// permissions-audit matrix. A requirement is attributed to the first of its
+// branches that is satisfied, and the grants are listed in scheme order, then
+// token order, each once. One entitlement may appear under several names.
+//
+// An "@name" reference contributes the grants satisfying the registered named
+// requirement; an except condition (see ExceptPrefix) contributes none. A
+// requirement satisfied only by base or anonymous entitlements, or an empty
+// one, maps to an empty list. A requirement that is not satisfied is absent.
+func (ec *EntitlementsChecker) EntitlementUsage(entitlements Entitlements, named map[string]Requirements) map[string][]string {
+	held := ec.enabledSchemes(ec.ParseEntitlements(entitlements).patterns)
+	fb := callerFallback(held)
+
+	usage := make(map[string][]string, len(named))
+	for name, reqs := range named {
+		_, grants, ok := ec.satisfyingGrants(held, ec.ParseRequirements(reqs).patterns, fb)
+		if !ok {
+			continue
+		}
+		used := []string{}
+		for _, g := range grants {
+			if g.Source == GrantSourceDirect {
+				used = appendUnique(used, g.Entitlement)
+			}
+		}
+		usage[name] = used
+	}
+	return usage
+}
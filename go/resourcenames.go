@@ -0,0 +1,130 @@
+package entitlements
+
+import (
+	"slices"
+	"strings"
+)
+
+// ResourceNameIndex is a set of known resource names that GrantedResourceNames
+// enumerates. Callers with a large, hierarchical set of names can supply an
+// optimised implementation, e.g. a radix tree; ResourceNameList is the simple
+// default.
+type ResourceNameIndex interface {
+	// Match returns the known names that the held resourceName pattern may
+	// cover: every name for "*", and otherwise the names pattern matches when
+	// read as a glob (see WithResourceNameGlob), a literal pattern matching
+	// only itself. Returning extra names is allowed, since every name is
+	// confirmed against the caller's entitlements; omitting one is not.
+	Match(pattern string) []string
+}
+
+// ResourceNameList is a ResourceNameIndex that scans its names on every Match.
+type ResourceNameList []string
+
+// Match implements ResourceNameIndex.
+func (l ResourceNameList) Match(pattern string) []string {
+	if isWildcardName(pattern) {
+		return l
+	}
+	var matched []string
+	for _, name := range l {
+		if name == pattern || (hasGlobMeta(pattern) && globsIntersect(pattern, escapeGlob(name))) {
+			matched = append(matched, name)
+		}
+	}
+	return matched
+}
+
+// GrantedResourceNames returns, sorted and without duplicates, the names in
+// names on which entitlements grant verb for resource under scheme (the
+// default scheme when empty), e.g. to list the pages a caller may read.
+//
+// Candidates come from names.Match for the resourceName (or each listed name,
+// see WithResourceNameSeparator) of each grant the caller could be using —
+// their own under scheme, plus the base and anonymous entitlements under the
+// default scheme — and each is then confirmed as the
+// requirement "<resource>:<name>:<verb>" would be (with the name's glob
+// metacharacters quoted in glob mode, names being concrete), so every option
+// of the checker, denies included, applies exactly as in verification.
+func (ec *EntitlementsChecker) GrantedResourceNames(entitlements Entitlements, scheme, resource, verb string, names ResourceNameIndex) []string {
+	if scheme == "" {
+		scheme = ec.defaultScheme
+	}
+	scheme = ec.schemeKey(scheme)
+	held := ec.enabledSchemes(ec.ParseEntitlements(entitlements).patterns)
+	fb := callerFallback(held)
+
+	grants := held[scheme]
+	if scheme == ec.defaultScheme {
+		if fb.base {
+			grants = append(slices.Clip(grants), ec.basePatterns...)
+		}
+		if fb.anonymous {
+			grants = append(slices.Clip(grants), ec.anonymousPatterns...)
+		}
+	}
+
+	m := ec.matcherFor(scheme)
+	seen := make(map[string]struct{})
+	var granted []string
+	if m.foldCase {
+		resource = strings.ToLower(resource)
+	}
+	for _, grant := range grants {
+		grant = m.expandBare(grant)
+		if m.foldCase {
+			grant = foldCase(grant)
+		}
+		if !grant.isPattern || grant.except ||
+			(grant.resource != resource && grant.resource != ResourceTypeWildcard && !resourceCovers(grant.resource, resource)) {
+			continue
+		}
+		for _, pattern := range m.heldNames(grant.resourceName) {
+			pattern = m.canonicalName(pattern)
+			if pattern == "" {
+				pattern = "*"
+			} else if prefix, ok := m.namePrefix(pattern); ok {
+				// A prefix crosses '/', as the glob "/docs/**" does.
+				pattern = escapeGlob(prefix) + "**"
+			} else if m.regexes != nil && strings.HasPrefix(pattern, RegexNamePrefix) {
+				// An index cannot evaluate an expression; every name is a
+				// candidate.
+				pattern = "*"
+			}
+			for _, name := range names.Match(pattern) {
+				// A wildcard is not a name, and would be confirmed by any grant.
+				if _, ok := seen[name]; ok || isWildcardName(name) {
+					continue
+				}
+				seen[name] = struct{}{}
+				// A name is concrete, so in glob mode its metacharacters are
+				// quoted.
+				literal := name
+				if m.glob {
+					literal = escapeGlob(name)
+				}
+				requirement := ec.parsePattern(resource + ":" + literal + ":" + verb)
+				if _, _, ok := ec.findGrant(held[scheme], scheme, requirement, fb); ok {
+					granted = append(granted, name)
+				}
+			}
+		}
+	}
+	slices.Sort(granted)
+	return granted
+}
+
+// escapeGlob quotes the glob metacharacters in name, so it reads as a literal.
+func escapeGlob(name string) string {
+	if !hasGlobMeta(name) {
+		return name
+	}
+	var b strings.Builder
+	for _, r := range name {
+		if strings.ContainsRune(globMeta, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
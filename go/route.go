@@ -0,0 +1,82 @@
+package entitlements
+
+import (
+	"net/http"
+	"strings"
+)
+
+// httpMethodVerbs maps the standard HTTP methods to the verb vocabulary used
+// throughout kdex entitlements (read/create/update/delete).
+var httpMethodVerbs = map[string]string{
+	http.MethodGet:     "read",
+	http.MethodHead:    "read",
+	http.MethodOptions: "read",
+	http.MethodPost:    "create",
+	http.MethodPut:     "update",
+	http.MethodPatch:   "update",
+	http.MethodDelete:  "delete",
+}
+
+// httpMethodVerb returns the verb for an HTTP method, compared
+// case-insensitively. A non-standard method maps to its own lowercased name,
+// so e.g. "PURGE" requires the verb "purge".
+func httpMethodVerb(method string) string {
+	method = strings.ToUpper(method)
+	if verb, ok := httpMethodVerbs[method]; ok {
+		return verb
+	}
+	return strings.ToLower(method)
+}
+
+// aliasHTTPVerb returns the verb an HTTP-method spelling stands for under
+// WithHTTPVerbAliases: a standard method in any case maps to its verb, and an
+// upper-case non-standard method to its lower-case name. Any other verb is
+// returned unchanged.
+func aliasHTTPVerb(verb string) string {
+	if mapped, ok := httpMethodVerbs[strings.ToUpper(verb)]; ok {
+		return mapped
+	}
+	if verb != "" && strings.ToUpper(verb) == verb && strings.ToLower(verb) != verb {
+		return strings.ToLower(verb)
+	}
+	return verb
+}
+
+// DefaultRequirementForRoute derives the default requirement for a route
+// pattern such as "GET /pages/{id}". The method maps to a verb (GET, HEAD and
+// OPTIONS to "read", POST to "create", PUT and PATCH to "update", DELETE to
+// "delete", anything else to its lowercased name) and the first path segment
+// becomes the resource type. The result has a single branch with a single
+// token under scheme ("bearer" when empty):
+//
+//   - GET /pages/{id}  -> pages:{id}:read  (a placeholder for BindRequirements)
+//   - POST /pages      -> pages:create     (class-wide short form)
+//   - GET /pages/foo   -> pages:read       (static segments are not names)
+//
+// Only a placeholder second segment becomes the resourceName; a route naming
+// a literal instance is still gated class-wide, since the route, not the
+// instance, is what the default describes. Teams override per route where that
+// is too coarse.
+//
+// A path with no segment (e.g. "/") has no resource type to derive, and nil is
+// returned. Treat nil as "no default" — passed to verification, an empty
+// Requirements admits every caller.
+func DefaultRequirementForRoute(scheme, httpMethod, resourceFromPath string) Requirements {
+	if scheme == "" {
+		scheme = "bearer"
+	}
+
+	segments := strings.FieldsFunc(resourceFromPath, func(r rune) bool { return r == '/' })
+	if len(segments) == 0 {
+		return nil
+	}
+
+	resource := segments[0]
+	verb := httpMethodVerb(httpMethod)
+	token := resource + ":" + verb
+	if len(segments) > 1 && placeholderKey(segments[1]) != "" {
+		token = resource + ":" + segments[1] + ":" + verb
+	}
+
+	return Requirements{{scheme: {token}}}
+}
@@ -0,0 +1,111 @@
+package entitlements
+
+import (
+	"maps"
+	"strings"
+)
+
+// WithCaseInsensitiveSchemes compares scheme names without regard to case, for
+// identity providers that spell the same scheme inconsistently: "Bearer"
+// entitlements then satisfy "bearer" requirements. It applies to the schemes
+// of entitlements and requirements alike, to the default scheme the base and
+// anonymous entitlements merge into, and to the schemes named by
+// WithDisabledSchemes, WithWildcardVerbByScheme and the methods taking a
+// scheme. Entitlements presented under two spellings of one scheme are merged
+// into one list.
+//
+// Defaults to false, in which "Bearer" and "bearer" are distinct schemes.
+// Intended for use during checker construction; not safe for concurrent
+// mutation with verify calls in flight. Set it before PrepareFor, which
+// indexes requirements by scheme.
+func (ec *EntitlementsChecker) WithCaseInsensitiveSchemes(enabled bool) *EntitlementsChecker {
+	ec.caseInsensitiveSchemes = enabled
+	return ec
+}
+
+// WithSchemeAliases maps alias scheme names to the canonical scheme they stand
+// for, e.g. {"jwt": "bearer"}, so entitlements presented under "jwt" satisfy
+// "bearer" requirements and the other way round. Like case folding (see
+// WithCaseInsensitiveSchemes), with which it combines — both alias and target
+// then compare without regard to case — it applies to the schemes of
+// entitlements and requirements alike and to every scheme the checker's
+// options and methods name; entitlements presented under an alias and its
+// target are merged into one list. Aliases are resolved once, not chained,
+// and the default scheme, which the base and anonymous entitlements merge
+// into, is never an alias: alias other spellings to it instead.
+//
+// Schemes are re-keyed on the checker's parsed copies, never in the caller's
+// maps. Replaces any previously set table; nil or empty disables aliasing, the
+// default. Intended for use during checker construction; not safe for
+// concurrent mutation with verify calls in flight. Set it before PrepareFor,
+// which indexes requirements by scheme.
+func (ec *EntitlementsChecker) WithSchemeAliases(aliases map[string]string) *EntitlementsChecker {
+	ec.schemeAliases = maps.Clone(aliases)
+	return ec
+}
+
+// rekeysSchemes reports whether schemeKey may map a scheme to another
+// spelling.
+func (ec *EntitlementsChecker) rekeysSchemes() bool {
+	return ec.caseInsensitiveSchemes || len(ec.schemeAliases) > 0
+}
+
+// schemeKey returns the canonical spelling of scheme: its alias target, if
+// any (see WithSchemeAliases), case-folded as foldScheme does.
+func (ec *EntitlementsChecker) schemeKey(scheme string) string {
+	scheme = ec.foldScheme(scheme)
+	if len(ec.schemeAliases) == 0 || scheme == ec.defaultScheme {
+		return scheme
+	}
+	if target, ok := ec.schemeAliases[scheme]; ok {
+		return ec.foldScheme(target)
+	}
+	if ec.caseInsensitiveSchemes {
+		for _, alias := range sortedKeys(ec.schemeAliases) {
+			if ec.foldScheme(alias) == scheme {
+				return ec.foldScheme(ec.schemeAliases[alias])
+			}
+		}
+	}
+	return scheme
+}
+
+// foldScheme returns scheme itself, unless schemes are case-insensitive, when
+// it is the default scheme if the two fold together and its lower case
+// otherwise. Comparisons against ec.defaultScheme therefore need no folding of
+// their own.
+func (ec *EntitlementsChecker) foldScheme(scheme string) string {
+	if !ec.caseInsensitiveSchemes || scheme == ec.defaultScheme {
+		return scheme
+	}
+	if strings.EqualFold(scheme, ec.defaultScheme) {
+		return ec.defaultScheme
+	}
+	return strings.ToLower(scheme)
+}
+
+// schemeOption looks scheme up in a map of per-scheme configuration, keyed
+// by any spelling schemeKey maps to scheme.
+func schemeOption[V any](ec *EntitlementsChecker, options map[string]V, scheme string) (V, bool) {
+	if v, ok := options[scheme]; ok || !ec.rekeysSchemes() {
+		return v, ok
+	}
+	for _, key := range sortedKeys(options) {
+		if ec.schemeKey(key) == scheme {
+			return options[key], true
+		}
+	}
+	var zero V
+	return zero, false
+}
+
+// foldSchemes re-keys parsed by schemeKey, merging the lists of schemes that
+// share a key in sorted order of their original spellings.
+func (ec *EntitlementsChecker) foldSchemes(parsed map[string][]entitlementPattern) map[string][]entitlementPattern {
+	folded := make(map[string][]entitlementPattern, len(parsed))
+	for _, scheme := range sortedKeys(parsed) {
+		key := ec.schemeKey(scheme)
+		folded[key] = append(folded[key], parsed[scheme]...)
+	}
+	return folded
+}
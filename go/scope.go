@@ -0,0 +1,52 @@
+package entitlements
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// EntitlementsFromScopeClaim maps an OAuth2 "scope" claim (RFC 6749 §3.3), a
+// space-delimited list such as "pages:read files:/x:write", to entitlements
+// under scheme ("bearer" when empty), one per scope token, in claim order and
+// without duplicates. Any run of whitespace separates tokens, and leading and
+// trailing whitespace is ignored.
+//
+// Malformed tokens — those ValidateEntitlements reports, such as ":read" or
+// "pages::" — are skipped, since they could never match anything; use
+// EntitlementsFromScopeClaimStrict to reject the claim instead. As with
+// EntitlementsFromCognito, the scheme is always present in the result, with
+// an empty list for an empty claim.
+func EntitlementsFromScopeClaim(scheme, scope string) Entitlements {
+	e, _ := entitlementsFromScopeClaim(scheme, scope)
+	return e
+}
+
+// EntitlementsFromScopeClaimStrict is EntitlementsFromScopeClaim, except that
+// malformed tokens are reported rather than skipped: it returns an error
+// wrapping ErrInvalidClaim that names each of them, and no entitlements.
+func EntitlementsFromScopeClaimStrict(scheme, scope string) (Entitlements, error) {
+	e, errs := entitlementsFromScopeClaim(scheme, scope)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return e, nil
+}
+
+// entitlementsFromScopeClaim maps scope, skipping and reporting malformed
+// tokens.
+func entitlementsFromScopeClaim(scheme, scope string) (Entitlements, []error) {
+	if scheme == "" {
+		scheme = "bearer"
+	}
+	list := []string{}
+	var errs []error
+	for _, token := range strings.Fields(scope) {
+		if problem := entitlementProblem(token); problem != "" {
+			errs = append(errs, fmt.Errorf("%w: scope %q %s", ErrInvalidClaim, token, problem))
+			continue
+		}
+		list = appendUnique(list, token)
+	}
+	return Entitlements{scheme: list}, errs
+}
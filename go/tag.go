@@ -0,0 +1,73 @@
+package entitlements
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidTag is returned by RequirementsFromTag for a malformed tag value.
+var ErrInvalidTag = errors.New("entitlements: invalid requirements tag")
+
+// RequirementsFromTag parses the value of a struct tag declaring a handler's
+// requirements, so authorization can be declared next to the handler:
+//
+//	DeletePage http.HandlerFunc `entitlements:"bearer=pages:{id}:delete | bearer=admin:all"`
+//
+// The grammar is:
+//
+//	tag    = branch { "|" branch }      OR'd alternatives
+//	branch = clause { " " clause }      AND'd, whitespace separated
+//	clause = scheme "=" [ token { "," token } ]
+//
+// A scheme listed twice in a branch accumulates its tokens, and a clause with
+// no tokens ("oauth2=") requires only that the scheme be present, like an
+// empty list in Requirements. Tokens are requirement strings in any form,
+// including placeholders for BindRequirements and "@name" references; they are
+// not validated beyond being non-empty.
+//
+// The tag value is the caller's to obtain — reflect.StructTag.Get or a code
+// generator — and no reflection happens here. A blank tag yields nil, which,
+// like any empty Requirements, admits every caller. Any malformed part returns
+// an error wrapping ErrInvalidTag that names the offending branch and clause.
+func RequirementsFromTag(tag string) (Requirements, error) {
+	if strings.TrimSpace(tag) == "" {
+		return nil, nil
+	}
+
+	branches := strings.Split(tag, "|")
+	reqs := make(Requirements, 0, len(branches))
+	for i, branch := range branches {
+		clauses := strings.Fields(branch)
+		if len(clauses) == 0 {
+			return nil, fmt.Errorf("%w: branch %d is empty", ErrInvalidTag, i+1)
+		}
+
+		set := make(map[string][]string, len(clauses))
+		for _, clause := range clauses {
+			scheme, list, ok := strings.Cut(clause, "=")
+			if !ok {
+				return nil, fmt.Errorf("%w: branch %d: clause %q has no scheme (want scheme=token,...)", ErrInvalidTag, i+1, clause)
+			}
+			if scheme == "" || strings.Contains(scheme, ":") {
+				return nil, fmt.Errorf("%w: branch %d: clause %q has an invalid scheme %q", ErrInvalidTag, i+1, clause, scheme)
+			}
+
+			tokens := set[scheme]
+			if tokens == nil {
+				tokens = []string{}
+			}
+			if list != "" {
+				for _, token := range strings.Split(list, ",") {
+					if token == "" {
+						return nil, fmt.Errorf("%w: branch %d: clause %q has an empty token", ErrInvalidTag, i+1, clause)
+					}
+					tokens = append(tokens, token)
+				}
+			}
+			set[scheme] = tokens
+		}
+		reqs = append(reqs, set)
+	}
+	return reqs, nil
+}
@@ -0,0 +1,247 @@
+package entitlements
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidEntitlement is wrapped by every problem ValidateEntitlements
+// reports.
+var ErrInvalidEntitlement = errors.New("entitlements: invalid entitlement")
+
+// ValidateEntitlements reports obviously malformed entitlement sets before a
+// token carrying them is trusted: an empty scheme name, an empty entitlement
+// string, a structured entitlement with an empty resource type or verb (":read",
+// "pages::"), a resource indicator with no URI ("resource="), and a scheme
+// whose entries are all denies (see ExceptPrefix), which grants nothing. Each
+// problem is an error wrapping ErrInvalidEntitlement that names its scheme and
+// string, or for a deny-only scheme the scheme alone; they are joined
+// (errors.Join) in scheme order, then list order. A valid set returns nil.
+//
+// Validation is syntactic. It does not reject entitlements that verification
+// accepts as opaque, such as one with too many colons, since opaque claims are
+// legitimately free-form.
+func ValidateEntitlements(entitlements Entitlements) error {
+	var errs []error
+	for _, scheme := range sortedKeys(entitlements) {
+		if scheme == "" {
+			errs = append(errs, fmt.Errorf("%w: empty scheme name", ErrInvalidEntitlement))
+		}
+		denies := 0
+		for _, s := range entitlements[scheme] {
+			if problem := entitlementProblem(s); problem != "" {
+				errs = append(errs, fmt.Errorf("%w: scheme %q: %q %s", ErrInvalidEntitlement, scheme, s, problem))
+			}
+			if _, ok := exceptCondition(s); ok {
+				denies++
+			}
+		}
+		if list := entitlements[scheme]; len(list) > 0 && denies == len(list) {
+			errs = append(errs, fmt.Errorf("%w: scheme %q: holds only denies, which grant nothing", ErrInvalidEntitlement, scheme))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// entitlementProblem describes what is malformed about s, or returns "".
+func entitlementProblem(s string) string {
+	if s == "" {
+		return "is empty"
+	}
+	body, _ := cutRegion(s)
+	p := parseForm(body)
+	switch {
+	case p.raw == ResourceIndicatorPrefix:
+		return "has an empty resource indicator"
+	case p.isPattern && p.resource == "":
+		return "has an empty resource type"
+	case p.isPattern && p.verb == "":
+		return "has an empty verb"
+	}
+	return ""
+}
+
+// ErrInvalidRequirement is wrapped by every problem ValidateRequirements
+// reports.
+var ErrInvalidRequirement = errors.New("entitlements: invalid requirement")
+
+// WithKnownVerbs sets the verbs ValidateEntitlement and ValidateRequirements
+// accept, so a typo such as "pages:raed" is reported rather than silently
+// never matching. The checker's wildcard verbs (see WithWildcardVerbs and
+// WithWildcardVerbByScheme) are always known; list verb group names, implied
+// verbs and HTTP method aliases you use too. Verification itself ignores the
+// list. With no verbs, the default, every verb is known.
+//
+// Replaces any previously set list. Intended for use during checker
+// construction; not safe for concurrent mutation with verify calls in flight.
+func (ec *EntitlementsChecker) WithKnownVerbs(verbs ...string) *EntitlementsChecker {
+	ec.knownVerbs = slices.Clone(verbs)
+	return ec
+}
+
+// ValidateEntitlement reports a malformed entitlement string as
+// ParseEntitlement does — an empty string, an empty resource type or verb, a
+// stray colon — and, under WithKnownVerbs, an unknown verb and, under
+// WithResourceNameRegex, an expression that does not compile. The error wraps
+// ErrInvalidEntitlement and names s.
+func (ec *EntitlementsChecker) ValidateEntitlement(s string) error {
+	e, err := ParseEntitlement(s)
+	if err != nil {
+		return err
+	}
+	if e.Opaque {
+		return nil
+	}
+	if !ec.knownVerb(e.Verb) {
+		return fmt.Errorf("%w: %q has unknown verb %q", ErrInvalidEntitlement, s, e.Verb)
+	}
+	if problem := ec.regexProblem(e.ResourceName); problem != "" {
+		return fmt.Errorf("%w: %q %s", ErrInvalidEntitlement, s, problem)
+	}
+	return nil
+}
+
+// ValidateRequirements reports malformed requirement tokens before they are
+// used: an empty token, an empty resource type or verb, a resource indicator
+// with no URI, more than two colons outside a resource indicator, and, under
+// WithKnownVerbs, an unknown verb (each verb of a set and each alternative,
+// under WithRequirementVerbSetSeparator and WithRequirementVerbSeparator)
+// and, under WithResourceNameRegex, an expression that does not compile. The
+// conditions of except and distinct-schemes tokens are checked as tokens of
+// their own. Each problem is an error wrapping ErrInvalidRequirement that
+// names the token and its position — branch index, scheme and index in the
+// scheme's list — joined (errors.Join) in branch, then scheme, then list
+// order. Valid requirements return nil.
+//
+// Whether an "@name" reference resolves is left to WithNamedRequirements.
+func (ec *EntitlementsChecker) ValidateRequirements(requirements Requirements) error {
+	return validateRequirements(ec, requirements)
+}
+
+// validateRequirements is ValidateRequirements; a nil ec checks syntax only.
+func validateRequirements(ec *EntitlementsChecker, requirements Requirements) error {
+	var errs []error
+	for i, set := range requirements {
+		for _, scheme := range sortedKeys(set) {
+			for j, s := range set[scheme] {
+				if problem := ec.requirementProblem(s); problem != "" {
+					errs = append(errs, fmt.Errorf("%w: branch %d, scheme %q, token %d: %q %s",
+						ErrInvalidRequirement, i, scheme, j, s, problem))
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WithStrictValidation makes VerifyEntitlementsCtx validate every entitlement
+// (ValidateEntitlement) and the requirements (ValidateRequirements) first,
+// and return false with the joined problems if any, without verifying, for
+// callers that would rather fail than let a malformed token silently never
+// match. VerifyEntitlements, which goes through it, denies instead. Opaque
+// entitlements are free-form and pass.
+//
+// Defaults to false. Intended for use during checker construction; not safe
+// for concurrent mutation with verify calls in flight.
+func (ec *EntitlementsChecker) WithStrictValidation(strict bool) *EntitlementsChecker {
+	ec.strictValidation = strict
+	return ec
+}
+
+// VerifyEntitlementsValidated is VerifyEntitlements validated as under
+// WithStrictValidation, whether or not the checker enables it.
+func (ec *EntitlementsChecker) VerifyEntitlementsValidated(entitlements Entitlements, requirements Requirements) (bool, error) {
+	if err := ec.validateVerify(entitlements, requirements); err != nil {
+		return false, err
+	}
+	return ec.VerifyEntitlements(entitlements, requirements), nil
+}
+
+// validateVerify joins the problems WithStrictValidation reports for a
+// verification.
+func (ec *EntitlementsChecker) validateVerify(entitlements Entitlements, requirements Requirements) error {
+	var errs []error
+	for _, scheme := range sortedKeys(entitlements) {
+		for _, s := range entitlements[scheme] {
+			if err := ec.ValidateEntitlement(s); err != nil {
+				errs = append(errs, fmt.Errorf("scheme %q: %w", scheme, err))
+			}
+		}
+	}
+	if err := ec.ValidateRequirements(requirements); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// requirementProblem describes what is malformed about the requirement token
+// s, or returns "". A nil ec checks syntax only, ignoring the checker's
+// options.
+func (ec *EntitlementsChecker) requirementProblem(s string) string {
+	if s == "" {
+		return "is empty"
+	}
+	p := parseForm(s)
+	switch {
+	case p.cond != nil:
+		if problem := ec.requirementProblem(p.cond.raw); problem != "" {
+			return "has a condition that " + problem
+		}
+		return ""
+	case p.ref != "":
+		return ""
+	case p.raw == ResourceIndicatorPrefix:
+		return "has an empty resource indicator"
+	case !p.isPattern:
+		if p.indicator == "" && strings.Count(s, ":") > 2 {
+			return "has too many colons"
+		}
+		return ""
+	case p.resource == "":
+		return "has an empty resource type"
+	case p.verb == "":
+		return "has an empty verb"
+	}
+	if ec == nil {
+		return ""
+	}
+	verbs := []string{p.verb}
+	if ec.requirementVerbSetSeparator != "" {
+		verbs = strings.Split(p.verb, ec.requirementVerbSetSeparator)
+	}
+	if ec.requirementVerbSeparator != "" {
+		var alternatives []string
+		for _, verb := range verbs {
+			alternatives = append(alternatives, strings.Split(verb, ec.requirementVerbSeparator)...)
+		}
+		verbs = alternatives
+	}
+	for _, verb := range verbs {
+		if verb != "" && !ec.knownVerb(verb) {
+			return fmt.Sprintf("has unknown verb %q", verb)
+		}
+	}
+	return ec.regexProblem(p.resourceName)
+}
+
+// knownVerb reports whether verb passes WithKnownVerbs.
+func (ec *EntitlementsChecker) knownVerb(verb string) bool {
+	if len(ec.knownVerbs) == 0 || slices.Contains(ec.knownVerbs, verb) {
+		return true
+	}
+	wildcards := ec.wildcardVerbSet
+	if len(wildcards) == 0 {
+		wildcards = defaultWildcardVerbs
+	}
+	if slices.Contains(wildcards, verb) {
+		return true
+	}
+	for _, v := range ec.wildcardVerbs {
+		if v == verb {
+			return true
+		}
+	}
+	return false
+}
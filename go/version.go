@@ -0,0 +1,55 @@
+package entitlements
+
+import "strings"
+
+// VersionWildcardSuffix, appended to a resource type in an entitlement,
+// grants every version of that type: "pages.*:read" satisfies "pages.v1:read"
+// and "pages.v2:read". A version is a ".v<digits>" suffix on the resource
+// type, and versioned types are otherwise compared exactly, so a grant on
+// "pages.v2" does not satisfy a "pages.v1" requirement. "pages.*" does not
+// cover the unversioned "pages", nor "pages.beta".
+//
+// Like a wildcard resourceName, the version wildcard is honoured only on the
+// held side; as a requirement "pages.*" is an ordinary resource type that only
+// "pages.*" satisfies. WithLegacySemantics turns it off.
+const VersionWildcardSuffix = ".*"
+
+// ResourceTypeWildcard, as the resource type of an entitlement, grants the
+// name and verb on every structured resource type: "*:*:read" satisfies
+// "pages:/foo:read" and "secrets::read", though no opaque requirement. Carve
+// types out of it with a held deny (see ExceptPrefix), e.g. "!secrets:read".
+//
+// It is honoured only on the held side, and only in matching: as a
+// requirement "*" is an ordinary resource type, and Dominates does not
+// treat it as covering other types, so attenuation from a type-wildcard grant
+// fails closed. WithLegacySemantics turns it off.
+const ResourceTypeWildcard = "*"
+
+// resourceCovers reports whether the held resource type covers the required
+// one: they are equal, or held is "<base>.*" and required is "<base>.v<N>".
+func resourceCovers(held, required string) bool {
+	if held == required {
+		return true
+	}
+	base, ok := strings.CutSuffix(held, VersionWildcardSuffix)
+	if !ok {
+		return false
+	}
+	reqBase, ok := versionBase(required)
+	return ok && reqBase == base
+}
+
+// versionBase returns the base of a versioned resource type, "pages" for
+// "pages.v2", and whether resource is versioned at all.
+func versionBase(resource string) (string, bool) {
+	i := strings.LastIndex(resource, ".v")
+	if i <= 0 || i+2 == len(resource) {
+		return "", false
+	}
+	for _, r := range resource[i+2:] {
+		if r < '0' || r > '9' {
+			return "", false
+		}
+	}
+	return resource[:i], true
+}
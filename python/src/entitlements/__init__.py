@@ -1,5 +1,6 @@
 from typing import Dict, List, Optional
 import dataclasses
+import re
 
 # Types
 SecurityScheme = str
@@ -7,6 +8,19 @@ Entitlements = Dict[SecurityScheme, List[str]]
 RequirementSet = Dict[SecurityScheme, List[str]]
 Requirements = List[RequirementSet]
 
+# Marks a requirement as an except condition ("!suspended" is satisfied when
+# the caller holds no matching entitlement) and an entitlement as a deny.
+EXCEPT_PREFIX = "!"
+
+# As the resource type of an entitlement, grants every structured type.
+RESOURCE_TYPE_WILDCARD = "*"
+
+# Appended to a resource type in an entitlement, grants every version.
+VERSION_WILDCARD_SUFFIX = ".*"
+
+# The held verbs that grant every verb.
+_WILDCARD_VERBS = ("all", "*")
+
 
 class BindError(Exception):
     """Base class for bind_requirements failures."""
@@ -37,6 +51,49 @@ class InvalidBoundValueError(BindError):
     ports."""
 
 
+def _except_condition(s: str) -> Optional[str]:
+    """The condition of an except token, or None. A "!" alone, or followed by
+    another "!", is not an except token."""
+    if not s.startswith(EXCEPT_PREFIX):
+        return None
+    cond = s[len(EXCEPT_PREFIX):]
+    if cond == "" or cond.startswith(EXCEPT_PREFIX):
+        return None
+    return cond
+
+
+def _is_go_only_requirement(s: str) -> bool:
+    """Whether s is a requirement form only the Go implementation supports: an
+    "@name" reference or a "#<n>:<token>" distinct-schemes token. Such a
+    requirement is unsatisfiable here, failing closed."""
+    if s.startswith("@"):
+        return len(s) > 1 and ":" not in s
+    m = re.fullmatch(r"#(\d+):(.+)", s, flags=re.S)
+    return m is not None and int(m.group(1)) >= 1
+
+
+def _version_base(resource: str) -> Optional[str]:
+    """The base of a versioned resource type, "pages" for "pages.v2", or None
+    when the type is not versioned."""
+    i = resource.rfind(".v")
+    if i <= 0 or i + 2 == len(resource):
+        return None
+    digits = resource[i + 2:]
+    if not all("0" <= c <= "9" for c in digits):
+        return None
+    return resource[:i]
+
+
+def _resource_covers(held: Optional[str], required: Optional[str]) -> bool:
+    """Whether the held resource type covers the required one: they are equal,
+    or held is "<base>.*" and required is "<base>.v<N>"."""
+    if held == required:
+        return True
+    if held is None or required is None or not held.endswith(VERSION_WILDCARD_SUFFIX):
+        return False
+    return _version_base(required) == held[:-len(VERSION_WILDCARD_SUFFIX)]
+
+
 @dataclasses.dataclass(frozen=True)
 class Pattern:
     """Represents a parsed entitlement or requirement pattern."""
@@ -44,9 +101,16 @@ class Pattern:
     name: Optional[str] = None
     verb: Optional[str] = None
     opaque: Optional[str] = None
+    # The parsed condition of a "!" token, else None: an except condition on
+    # the requirement side, a deny on the held side. The token itself is
+    # opaque, so it dominates only its exact spelling.
+    cond: Optional["Pattern"] = None
 
     @classmethod
     def parse(cls, s: str) -> "Pattern":
+        cond = _except_condition(s)
+        if cond is not None:
+            return cls(opaque=s, cond=cls.parse(cond))
         parts = s.split(":")
         if len(parts) == 3:
             return cls(resource=parts[0], name=parts[1], verb=parts[2])
@@ -73,6 +137,10 @@ class Pattern:
         return self.opaque is None and self.name in ("*", "")
 
     def satisfies(self, required: "Pattern") -> bool:
+        # A deny is never a grant.
+        if self.cond is not None:
+            return False
+
         # Both opaque: must match exactly
         if self.opaque is not None and required.opaque is not None:
             return self.opaque == required.opaque
@@ -82,12 +150,13 @@ class Pattern:
             return False
 
         # Structured:
-        # Resource must match
-        if self.resource != required.resource:
+        # Resource must match (or the entitlement covers every version, or
+        # every type)
+        if self.resource != RESOURCE_TYPE_WILDCARD and not _resource_covers(self.resource, required.resource):
             return False
         
-        # Verb must match exactly or entitlement is "all"
-        if self.verb != required.verb and self.verb != "all":
+        # Verb must match exactly or entitlement is a wildcard verb
+        if self.verb != required.verb and self.verb not in _WILDCARD_VERBS:
             return False
         
         # Name must match exactly, or either is a wildcard
@@ -114,8 +183,8 @@ class Pattern:
         if (self.opaque is not None) != (requested.opaque is not None):
             return False
 
-        # Resource type must match.
-        if self.resource != requested.resource:
+        # Resource type must match, or the held side covers every version.
+        if not _resource_covers(self.resource, requested.resource):
             return False
 
         # Verb: held "all" dominates any; otherwise verbs must match. A
@@ -142,27 +211,44 @@ def verify_attenuation(held: List[str], requested: List[str]) -> Optional[str]:
     return None
 
 
+def _subsumes(held: Pattern, requested: Pattern) -> bool:
+    """Whether held grants everything requested grants, for `compact`:
+    `Pattern.dominates`, with a "*" verb also covering every verb and a "*"
+    resource type every structured type, as in `Pattern.satisfies`."""
+    if held.dominates(requested):
+        return True
+    if held.opaque is not None or requested.opaque is not None:
+        return False
+    if held.resource != RESOURCE_TYPE_WILDCARD and not _resource_covers(held.resource, requested.resource):
+        return False
+    if held.verb != requested.verb and held.verb not in _WILDCARD_VERBS:
+        return False
+    return held.is_wildcard_name or held.name == requested.name
+
+
 def compact(entitlements: List[str]) -> List[str]:
     """Returns the subset of `entitlements` with every entry removed that is
-    strictly dominated by another entry, or that is an exact / equivalent-form
+    strictly subsumed by another entry, or that is an exact / equivalent-form
     duplicate (e.g. "pages:read", "pages::read", "pages:*:read" collapse to the
     first-seen one). The result grants exactly the same authority as the input;
     survivors keep their original strings and their first-seen order.
 
-    Built purely on `Pattern.dominates`, so it can never drift from attenuation.
-    Opaque and malformed scopes collapse only by exact equality.
+    "Broader than" is `Pattern.dominates` widened by the wildcards request-time
+    matching honours and attenuation does not (see `_subsumes`), so "*:*:all"
+    absorbs "pages:read". Opaque and malformed scopes collapse only by exact
+    equality.
     """
     patterns = [Pattern.parse(e) for e in entitlements]
     survivors: List[str] = []
     survivor_patterns: List[Pattern] = []
     for i, ep in enumerate(patterns):
         strictly_dominated = any(
-            j != i and op.dominates(ep) and not ep.dominates(op)
+            j != i and _subsumes(op, ep) and not _subsumes(ep, op)
             for j, op in enumerate(patterns)
         )
         if strictly_dominated:
             continue
-        if any(sp.dominates(ep) and ep.dominates(sp) for sp in survivor_patterns):
+        if any(_subsumes(sp, ep) and _subsumes(ep, sp) for sp in survivor_patterns):
             continue
         survivors.append(entitlements[i])
         survivor_patterns.append(ep)
@@ -241,6 +327,12 @@ class EntitlementsChecker:
                 new_entries: List[str] = []
                 for s in entries:
                     p = Pattern.parse(s)
+                    if p.cond is not None and p.cond.placeholder is not None:
+                        # Keep the token's prefix, rebinding only its
+                        # condition.
+                        p, prefix = p.cond, EXCEPT_PREFIX
+                    else:
+                        prefix = ""
                     key = p.placeholder
                     if key is None:
                         new_entries.append(s)
@@ -267,7 +359,7 @@ class EntitlementsChecker:
                             f"contain ':': {key!r} bound to {v!r} in "
                             f"requirement {s!r}"
                         )
-                    new_entries.append(f"{p.resource}:{v}:{p.verb}")
+                    new_entries.append(f"{prefix}{p.resource}:{v}:{p.verb}")
                 new_set[scheme] = new_entries
             out.append(new_set)
         return out
@@ -323,12 +415,30 @@ class EntitlementsChecker:
                 bool(self._base_patterns)
                 or (is_anonymous and bool(self._anonymous_patterns))
             )
-            if not user_list_present and not has_fallback:
+            # A scheme holding only except conditions is satisfied by its
+            # absence.
+            except_only = bool(required_patterns) and all(
+                _except_condition(r) is not None for r in required_patterns
+            )
+            if not user_list_present and not has_fallback and not except_only:
                 return False
 
             user_list = user_patterns.get(scheme, [])
             for req_str in required_patterns:
+                if _is_go_only_requirement(req_str):
+                    return False
                 req_p = Pattern.parse(req_str)
+                if req_p.cond is not None:
+                    # An except condition holds when the caller has no grant
+                    # matching it; an unbound placeholder or Go-only condition
+                    # cannot be decided.
+                    if (
+                        req_p.cond.placeholder is not None
+                        or _is_go_only_requirement(req_p.cond.opaque or "")
+                        or self._has_grant(user_list, scheme, req_p.cond, is_anonymous)
+                    ):
+                        return False
+                    continue
                 # Strict backstop for callers that skip bind_requirements: a
                 # wildcard requirement is an illegal spelling and an unbound
                 # placeholder was never resolved. Both are unsatisfiable rather
@@ -337,22 +447,33 @@ class EntitlementsChecker:
                     req_p.placeholder is not None or req_p.is_wildcard_name
                 ):
                     return False
-                satisfied = (
-                    any(p.satisfies(req_p) for p in user_list)
-                    or (
-                        scheme == self.default_scheme
-                        and any(p.satisfies(req_p) for p in self._base_patterns)
-                    )
-                    or (
-                        scheme == self.default_scheme
-                        and is_anonymous
-                        and any(p.satisfies(req_p) for p in self._anonymous_patterns)
-                    )
-                )
-                if not satisfied:
+                if not self._has_grant(user_list, scheme, req_p, is_anonymous):
                     return False
         return True
 
+    def _has_grant(
+        self,
+        user_list: List[Pattern],
+        scheme: SecurityScheme,
+        req_p: Pattern,
+        is_anonymous: bool,
+    ) -> bool:
+        # A deny the caller holds wins over every grant.
+        if any(p.cond is not None and p.cond.satisfies(req_p) for p in user_list):
+            return False
+        return (
+            any(p.satisfies(req_p) for p in user_list)
+            or (
+                scheme == self.default_scheme
+                and any(p.satisfies(req_p) for p in self._base_patterns)
+            )
+            or (
+                scheme == self.default_scheme
+                and is_anonymous
+                and any(p.satisfies(req_p) for p in self._anonymous_patterns)
+            )
+        )
+
     def verify_resource(
         self,
         user_entitlements: Entitlements,
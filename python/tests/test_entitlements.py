@@ -277,7 +277,10 @@ def test_compact_cases():
         (["functions", "functions::read"], ["functions", "functions::read"]),
         (["functions::read", "vector_stores:system:read"], ["functions::read", "vector_stores:system:read"]),
         (["functions::read", "functions:/a:create"], ["functions::read", "functions:/a:create"]),
-        (["x:/a:read", "x:/b:create"], ["x:/a:read", "x:/b:create"]),
+        (["x:/a:read", "x:/b:create"], ["x:/a:read", "x:/b:create"]),        (["*:*:all", "pages:read", "books:/x:write"], ["*:*:all"]),
+        (["pages:/a:read", "pages:*"], ["pages:*"]),
+        (["pages:all", "pages:*"], ["pages:all"]),
+        (["*:*:all", "admin", "!secrets:read"], ["*:*:all", "admin", "!secrets:read"]),
     ]
     for given, want in cases:
         assert compact(given) == want, f"input: {given}"
@@ -446,3 +449,70 @@ def test_wildcard_requirements_inventory():
     ]
     assert ec.wildcard_requirements(reqs) == ["vector_stores:*:write", "apitokens:mint"]
     assert ec.wildcard_requirements([{"bearer": ["users:me:read"]}]) == []
+
+
+def test_except_conditions():
+    ec = EntitlementsChecker()
+    reqs = [{"bearer": ["pages:read", "!suspended"]}]
+    assert ec.verify({"bearer": ["pages:read"]}, reqs)
+    assert not ec.verify({"bearer": ["pages:read", "suspended"]}, reqs)
+
+    # A scheme holding only except conditions is satisfied by its absence.
+    assert ec.verify({}, [{"bearer": ["!suspended"]}])
+
+    bound = ec.bind_requirements([{"bearer": ["pages:read", "!pages:{id}:lock"]}], {"id": "foo"})
+    assert bound == [{"bearer": ["pages:read", "!pages:foo:lock"]}]
+    assert not ec.verify({"bearer": ["pages:read", "pages:foo:lock"]}, bound)
+
+
+def test_version_wildcard():
+    ec = EntitlementsChecker()
+    assert ec.verify({"bearer": ["pages.*:read"]}, [{"bearer": ["pages.v1:read"]}])
+    assert not ec.verify({"bearer": ["pages.*:read"]}, [{"bearer": ["pages:read"]}])
+    assert not ec.verify({"bearer": ["pages.*:read"]}, [{"bearer": ["files.v1:read"]}])
+    assert verify_attenuation(["pages.*:read"], ["pages.v2:read"]) is None
+
+
+def test_type_wildcard():
+    ec = EntitlementsChecker()
+    assert ec.verify({"bearer": ["*:*:read"]}, [{"bearer": ["pages:/foo:read"]}])
+    assert not ec.verify({"bearer": ["*:*:read"]}, [{"bearer": ["pages:/foo:write"]}])
+    assert not ec.verify({"bearer": ["*:*:read"]}, [{"bearer": ["secrets"]}])
+    # Attenuation does not expand the type wildcard.
+    assert verify_attenuation(["*:*:read"], ["pages:read"]) == "pages:read"
+
+
+def test_held_deny_wins():
+    ec = EntitlementsChecker()
+    held = {"bearer": ["*:all", "!secrets:read"]}
+    assert not ec.verify(held, [{"bearer": ["secrets:read"]}])
+    assert ec.verify(held, [{"bearer": ["pages:read"]}])
+
+
+def test_type_wildcard_edge_cases():
+    ec = EntitlementsChecker()
+    assert ec.verify({"bearer": ["*:*:read"]}, [{"bearer": ["pages:read"]}])
+    assert ec.verify({"bearer": ["*:*:all"]}, [{"bearer": ["secrets:/db:delete"]}])
+    assert not ec.verify({"bearer": ["*"]}, [{"bearer": ["pages"]}])
+    assert not ec.verify({"bearer": ["*"]}, [{"bearer": ["pages:read"]}])
+    assert ec.verify({"bearer": ["*"]}, [{"bearer": ["*"]}])
+
+
+def test_held_deny_wins_over_identity():
+    ec = EntitlementsChecker()
+    held = {"bearer": ["pages:all", "!pages:foo:read"]}
+    assert not ec.verify_resource(held, "pages", "foo", "read")
+    assert ec.verify_resource(held, "pages", "bar", "read")
+
+
+def test_star_wildcard_verb():
+    ec = EntitlementsChecker()
+    assert ec.verify({"bearer": ["pages:*"]}, [{"bearer": ["pages:read"]}])
+    assert ec.verify({"bearer": ["*:*"]}, [{"bearer": ["pages:/foo:delete"]}])
+
+
+def test_go_only_requirements_fail_closed():
+    ec = EntitlementsChecker()
+    assert not ec.verify({"bearer": ["*:all"]}, [{"bearer": ["@admin"]}])
+    assert not ec.verify({"bearer": ["*:all"]}, [{"bearer": ["#2:payments:approve"]}])
+    assert not ec.verify({}, [{"bearer": ["!@admin"]}])
@@ -55,6 +55,68 @@ impl std::fmt::Display for BindError {
 
 impl std::error::Error for BindError {}
 
+/// Marks a requirement as an except condition ("!suspended" is satisfied when
+/// the caller holds no matching entitlement) and an entitlement as a deny.
+pub const EXCEPT_PREFIX: &str = "!";
+
+/// As the resource type of an entitlement, grants every structured type.
+pub const RESOURCE_TYPE_WILDCARD: &str = "*";
+
+/// Appended to a resource type in an entitlement, grants every version.
+pub const VERSION_WILDCARD_SUFFIX: &str = ".*";
+
+/// The held verbs that grant every verb.
+const WILDCARD_VERBS: [&str; 2] = ["all", "*"];
+
+/// Returns the condition of an except token, or None. A "!" alone, or
+/// followed by another "!", is not an except token.
+fn except_condition(s: &str) -> Option<&str> {
+    let cond = s.strip_prefix(EXCEPT_PREFIX)?;
+    if cond.is_empty() || cond.starts_with(EXCEPT_PREFIX) {
+        return None;
+    }
+    Some(cond)
+}
+
+/// Reports whether `s` is a requirement form only the Go implementation
+/// supports: an "@name" reference or a "#<n>:<token>" distinct-schemes token.
+/// Such a requirement is unsatisfiable here, failing closed.
+fn is_go_only_requirement(s: &str) -> bool {
+    if let Some(name) = s.strip_prefix('@') {
+        return !name.is_empty() && !s.contains(':');
+    }
+    let Some((n, token)) = s.strip_prefix('#').and_then(|rest| rest.split_once(':')) else {
+        return false;
+    };
+    !token.is_empty()
+        && !n.is_empty()
+        && n.bytes().all(|c| c.is_ascii_digit())
+        && n.bytes().any(|c| c != b'0')
+}
+
+/// Returns the base of a versioned resource type, "pages" for "pages.v2", or
+/// None when the type is not versioned.
+fn version_base(resource: &str) -> Option<&str> {
+    let i = resource.rfind(".v")?;
+    let digits = &resource[i + 2..];
+    if i == 0 || digits.is_empty() || !digits.bytes().all(|c| c.is_ascii_digit()) {
+        return None;
+    }
+    Some(&resource[..i])
+}
+
+/// Reports whether the held resource type covers the required one: they are
+/// equal, or held is "<base>.*" and required is "<base>.v<N>".
+fn resource_covers(held: &str, required: &str) -> bool {
+    if held == required {
+        return true;
+    }
+    match held.strip_suffix(VERSION_WILDCARD_SUFFIX) {
+        Some(base) => version_base(required) == Some(base),
+        None => false,
+    }
+}
+
 /// A parsed representation of an entitlement or requirement pattern.
 #[derive(Debug, Clone, PartialEq, Eq)]
 pub enum Pattern {
@@ -66,11 +128,17 @@ pub enum Pattern {
     },
     /// Opaque form: <string>
     Opaque(String),
+    /// Except form: !<token>, an except condition as a requirement and a deny
+    /// as an entitlement.
+    Except(Box<Pattern>),
 }
 
 impl Pattern {
     /// Parses a pattern string into a Pattern enum.
     pub fn parse(s: &str) -> Self {
+        if let Some(cond) = except_condition(s) {
+            return Self::Except(Box::new(Self::parse(cond)));
+        }
         let parts: Vec<&str> = s.split(':').collect();
         match parts.len() {
             3 => Self::Structured {
@@ -124,13 +192,14 @@ impl Pattern {
                     verb: rv,
                 },
             ) => {
-                // Resource types must match
-                if er != rr {
+                // Resource types must match (or the entitlement covers every
+                // version, or every type)
+                if er != RESOURCE_TYPE_WILDCARD && !resource_covers(er, rr) {
                     return false;
                 }
 
-                // Verb must match exactly or entitlement verb is "all"
-                if ev != rv && ev != "all" {
+                // Verb must match exactly or entitlement verb is a wildcard
+                if ev != rv && !WILDCARD_VERBS.contains(&ev.as_str()) {
                     return false;
                 }
 
@@ -141,7 +210,8 @@ impl Pattern {
 
                 true
             }
-            // Mixed forms only match exactly if they are identical strings (unlikely given parse logic)
+            // A deny is never a grant. Mixed forms only match exactly if they
+            // are identical strings (unlikely given parse logic)
             _ => false,
         }
     }
@@ -157,6 +227,8 @@ impl Pattern {
     pub fn dominates(&self, requested: &Pattern) -> bool {
         match (self, requested) {
             (Self::Opaque(h), Self::Opaque(r)) => h == r,
+            // "!" tokens dominate only their own spelling.
+            (Self::Except(h), Self::Except(r)) => h == r,
             (
                 Self::Structured {
                     resource: hr,
@@ -169,8 +241,9 @@ impl Pattern {
                     verb: rv,
                 },
             ) => {
-                // Resource type must match.
-                if hr != rr {
+                // Resource type must match, or the held side covers every
+                // version.
+                if !resource_covers(hr, rr) {
                     return false;
                 }
 
@@ -207,32 +280,63 @@ impl Pattern {
         None
     }
 
+    /// Reports whether this pattern (as a HELD entitlement) grants everything
+    /// `requested` grants, for `compact`: `dominates`, with a "*" verb also
+    /// covering every verb and a "*" resource type every structured type, as
+    /// in `satisfies`.
+    fn subsumes(&self, requested: &Pattern) -> bool {
+        if self.dominates(requested) {
+            return true;
+        }
+        match (self, requested) {
+            (
+                Self::Structured {
+                    resource: hr,
+                    name: hn,
+                    verb: hv,
+                },
+                Self::Structured {
+                    resource: rr,
+                    name: rn,
+                    verb: rv,
+                },
+            ) => {
+                (hr == RESOURCE_TYPE_WILDCARD || resource_covers(hr, rr))
+                    && (hv == rv || WILDCARD_VERBS.contains(&hv.as_str()))
+                    && (hn.is_empty() || hn == "*" || hn == rn)
+            }
+            _ => false,
+        }
+    }
+
     /// Returns the subset of `entitlements` with every entry removed that is
-    /// strictly dominated by another entry, or that is an exact /
+    /// strictly subsumed by another entry, or that is an exact /
     /// equivalent-form duplicate (e.g. "pages:read", "pages::read",
     /// "pages:*:read" collapse to the first-seen one). The result grants
     /// exactly the same authority as the input; survivors keep their original
     /// strings and their first-seen order.
     ///
-    /// Built purely on `dominates`, so it can never drift from attenuation.
-    /// Opaque and malformed scopes collapse only by exact equality.
+    /// "Broader than" is `dominates` widened by the wildcards request-time
+    /// matching honours and attenuation does not (see `subsumes`), so
+    /// "*:*:all" absorbs "pages:read". Opaque and malformed scopes collapse
+    /// only by exact equality.
     pub fn compact(entitlements: &[String]) -> Vec<String> {
         let patterns: Vec<Pattern> = entitlements.iter().map(|s| Pattern::parse(s)).collect();
         let mut survivors: Vec<String> = Vec::new();
         let mut survivor_patterns: Vec<Pattern> = Vec::new();
         for (i, ep) in patterns.iter().enumerate() {
-            // (1) Drop if some OTHER entry strictly dominates it.
+            // (1) Drop if some OTHER entry strictly subsumes it.
             let strictly_dominated = patterns
                 .iter()
                 .enumerate()
-                .any(|(j, op)| i != j && op.dominates(ep) && !ep.dominates(op));
+                .any(|(j, op)| i != j && op.subsumes(ep) && !ep.subsumes(op));
             if strictly_dominated {
                 continue;
             }
             // (2) Maximal; keep unless an equivalent survivor already present.
             let dup = survivor_patterns
                 .iter()
-                .any(|sp| sp.dominates(ep) && ep.dominates(sp));
+                .any(|sp| sp.subsumes(ep) && ep.subsumes(sp));
             if !dup {
                 survivors.push(entitlements[i].clone());
                 survivor_patterns.push(ep.clone());
@@ -334,7 +438,14 @@ impl EntitlementsChecker {
             for (scheme, list) in set {
                 let mut new_list = Vec::with_capacity(list.len());
                 for s in list {
-                    let p = Pattern::parse(s);
+                    // Keep an except token's prefix, rebinding only its
+                    // condition.
+                    let (p, prefix) = match Pattern::parse(s) {
+                        Pattern::Except(cond) if cond.placeholder().is_some() => {
+                            (*cond, EXCEPT_PREFIX)
+                        }
+                        p => (p, ""),
+                    };
                     match p.placeholder() {
                         None => new_list.push(s.clone()),
                         Some(key) => {
@@ -358,9 +469,9 @@ impl EntitlementsChecker {
                             }
                             match &p {
                                 Pattern::Structured { resource, verb, .. } => {
-                                    new_list.push(format!("{resource}:{v}:{verb}"))
+                                    new_list.push(format!("{prefix}{resource}:{v}:{verb}"))
                                 }
-                                Pattern::Opaque(_) => unreachable!("placeholder implies Structured"),
+                                _ => unreachable!("placeholder implies Structured"),
                             }
                         }
                     }
@@ -437,7 +548,11 @@ impl EntitlementsChecker {
             let has_fallback = scheme == &self.default_scheme
                 && (!self.base_entitlements.is_empty()
                     || (is_anonymous && !self.anonymous_entitlements.is_empty()));
-            if !user_list_present && !has_fallback {
+            // A scheme holding only except conditions is satisfied by its
+            // absence.
+            let except_only = !required_patterns.is_empty()
+                && required_patterns.iter().all(|r| except_condition(r).is_some());
+            if !user_list_present && !has_fallback && !except_only {
                 return false;
             }
 
@@ -445,8 +560,24 @@ impl EntitlementsChecker {
             let user_list = user_patterns.get(scheme).unwrap_or(&empty);
 
             for req_str in required_patterns {
+                if is_go_only_requirement(req_str) {
+                    return false;
+                }
                 let req_p = Pattern::parse(req_str);
 
+                // An except condition holds when the caller has no grant
+                // matching it; an unbound placeholder or Go-only condition
+                // cannot be decided.
+                if let Pattern::Except(cond) = &req_p {
+                    if cond.placeholder().is_some()
+                        || except_condition(req_str).is_some_and(is_go_only_requirement)
+                        || self.has_grant(user_list, scheme, cond, is_anonymous)
+                    {
+                        return false;
+                    }
+                    continue;
+                }
+
                 // Strict backstop for callers that skip bind_requirements: a
                 // wildcard requirement is an illegal spelling and an unbound
                 // placeholder was never resolved. Both are unsatisfiable rather
@@ -457,13 +588,7 @@ impl EntitlementsChecker {
                     return false;
                 }
 
-                let satisfied_by_user = user_list.iter().any(|p| p.satisfies(&req_p));
-                let satisfied_by_base = scheme == &self.default_scheme
-                    && self.base_entitlements.iter().any(|p| p.satisfies(&req_p));
-                let satisfied_by_anon = scheme == &self.default_scheme
-                    && is_anonymous
-                    && self.anonymous_entitlements.iter().any(|p| p.satisfies(&req_p));
-                if !satisfied_by_user && !satisfied_by_base && !satisfied_by_anon {
+                if !self.has_grant(user_list, scheme, &req_p, is_anonymous) {
                     return false;
                 }
             }
@@ -471,6 +596,31 @@ impl EntitlementsChecker {
         true
     }
 
+    /// Reports whether `req_p` is granted by the caller's own entitlements,
+    /// the base bag, or (when `is_anonymous`) the anonymous bag. A deny the
+    /// caller holds wins over every grant.
+    fn has_grant(
+        &self,
+        user_list: &[Pattern],
+        scheme: &str,
+        req_p: &Pattern,
+        is_anonymous: bool,
+    ) -> bool {
+        let denied = user_list
+            .iter()
+            .any(|p| matches!(p, Pattern::Except(cond) if cond.satisfies(req_p)));
+        if denied {
+            return false;
+        }
+        let satisfied_by_user = user_list.iter().any(|p| p.satisfies(req_p));
+        let satisfied_by_base = scheme == self.default_scheme
+            && self.base_entitlements.iter().any(|p| p.satisfies(req_p));
+        let satisfied_by_anon = scheme == self.default_scheme
+            && is_anonymous
+            && self.anonymous_entitlements.iter().any(|p| p.satisfies(req_p));
+        satisfied_by_user || satisfied_by_base || satisfied_by_anon
+    }
+
     /// Verifies access for a specific resource instance.
     pub fn verify_resource(
         &self,
@@ -861,6 +1011,10 @@ mod tests {
             (strs(&["functions::read", "vector_stores:system:read"]), strs(&["functions::read", "vector_stores:system:read"])),
             (strs(&["functions::read", "functions:/a:create"]), strs(&["functions::read", "functions:/a:create"])),
             (strs(&["x:/a:read", "x:/b:create"]), strs(&["x:/a:read", "x:/b:create"])),
+            (strs(&["*:*:all", "pages:read", "books:/x:write"]), strs(&["*:*:all"])),
+            (strs(&["pages:/a:read", "pages:*"]), strs(&["pages:*"])),
+            (strs(&["pages:all", "pages:*"]), strs(&["pages:all"])),
+            (strs(&["*:*:all", "admin", "!secrets:read"]), strs(&["*:*:all", "admin", "!secrets:read"])),
         ];
         for (input, want) in cases {
             assert_eq!(Pattern::compact(&input), want, "input: {:?}", input);
@@ -1103,4 +1257,80 @@ mod tests {
         );
         assert!(ec.wildcard_requirements(&reqs("bearer", &["users:me:read"])).is_empty());
     }
+
+    #[test]
+    fn except_conditions() {
+        let ec = EntitlementsChecker::new(vec![], "bearer".to_string());
+        let r = reqs("bearer", &["pages:read", "!suspended"]);
+        assert!(ec.verify(&ents("bearer", &["pages:read"]), &r));
+        assert!(!ec.verify(&ents("bearer", &["pages:read", "suspended"]), &r));
+
+        // A scheme holding only except conditions is satisfied by its absence.
+        assert!(ec.verify(&Entitlements::new(), &reqs("bearer", &["!suspended"])));
+
+        let mut b = Binding::new();
+        b.insert("id".to_string(), "foo".to_string());
+        let bound = ec
+            .bind_requirements(&reqs("bearer", &["pages:read", "!pages:{id}:lock"]), &b)
+            .unwrap();
+        assert_eq!(bound, reqs("bearer", &["pages:read", "!pages:foo:lock"]));
+        assert!(!ec.verify(&ents("bearer", &["pages:read", "pages:foo:lock"]), &bound));
+    }
+
+    #[test]
+    fn version_wildcard() {
+        let ec = EntitlementsChecker::new(vec![], "bearer".to_string());
+        assert!(!ec.verify(&ents("bearer", &["pages.*:read"]), &reqs("bearer", &["pages:read"])));
+        assert!(ec.verify(&ents("bearer", &["pages.*:read"]), &reqs("bearer", &["pages.v1:read"])));
+        assert!(!ec.verify(&ents("bearer", &["pages.*:read"]), &reqs("bearer", &["files.v1:read"])));
+    }
+
+    #[test]
+    fn type_wildcard() {
+        let ec = EntitlementsChecker::new(vec![], "bearer".to_string());
+        assert!(ec.verify(&ents("bearer", &["*:all"]), &reqs("bearer", &["pages:read"])));
+        assert!(ec.verify(&ents("bearer", &["*:*:read"]), &reqs("bearer", &["pages:/foo:read"])));
+        assert!(!ec.verify(&ents("bearer", &["*:*:read"]), &reqs("bearer", &["pages:/foo:write"])));
+    }
+
+    #[test]
+    fn held_deny_wins() {
+        let ec = EntitlementsChecker::new(vec![], "bearer".to_string());
+        let held = ents("bearer", &["*:all", "!secrets:read"]);
+        assert!(!ec.verify(&held, &reqs("bearer", &["secrets:read"])));
+        assert!(ec.verify(&held, &reqs("bearer", &["pages:read"])));
+    }
+
+    #[test]
+    fn type_wildcard_edge_cases() {
+        let ec = EntitlementsChecker::new(vec![], "bearer".to_string());
+        assert!(ec.verify(&ents("bearer", &["*:*:all"]), &reqs("bearer", &["secrets:/db:delete"])));
+        assert!(!ec.verify(&ents("bearer", &["*"]), &reqs("bearer", &["pages"])));
+        assert!(!ec.verify(&ents("bearer", &["*"]), &reqs("bearer", &["pages:read"])));
+        assert!(ec.verify(&ents("bearer", &["*"]), &reqs("bearer", &["*"])));
+    }
+
+    #[test]
+    fn held_deny_wins_over_identity() {
+        let ec = EntitlementsChecker::new(vec![], "bearer".to_string());
+        let held = ents("bearer", &["pages:all", "!pages:foo:read"]);
+        assert!(!ec.verify_resource(&held, "pages", "foo", "read", &vec![]));
+        assert!(ec.verify_resource(&held, "pages", "bar", "read", &vec![]));
+    }
+
+    #[test]
+    fn star_wildcard_verb() {
+        let ec = EntitlementsChecker::new(vec![], "bearer".to_string());
+        assert!(ec.verify(&ents("bearer", &["pages:*"]), &reqs("bearer", &["pages:read"])));
+        assert!(ec.verify(&ents("bearer", &["*:*"]), &reqs("bearer", &["pages:/foo:delete"])));
+    }
+
+    #[test]
+    fn go_only_requirements_fail_closed() {
+        let ec = EntitlementsChecker::new(vec![], "bearer".to_string());
+        let held = ents("bearer", &["*:all"]);
+        assert!(!ec.verify(&held, &reqs("bearer", &["@admin"])));
+        assert!(!ec.verify(&held, &reqs("bearer", &["#2:payments:approve"])));
+        assert!(!ec.verify(&Entitlements::new(), &reqs("bearer", &["!@admin"])));
+    }
 }
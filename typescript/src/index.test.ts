@@ -917,6 +917,10 @@ describe("compact", () => {
     { name: "cross-resource kept", in: ["functions::read", "vector_stores:system:read"], want: ["functions::read", "vector_stores:system:read"] },
     { name: "verb non-interference", in: ["functions::read", "functions:/a:create"], want: ["functions::read", "functions:/a:create"] },
     { name: "no redundancy preserves order", in: ["x:/a:read", "x:/b:create"], want: ["x:/a:read", "x:/b:create"] },
+    { name: "type and verb wildcards prune", in: ["*:*:all", "pages:read", "books:/x:write"], want: ["*:*:all"] },
+    { name: "star verb prunes", in: ["pages:/a:read", "pages:*"], want: ["pages:*"] },
+    { name: "star verb equals all", in: ["pages:all", "pages:*"], want: ["pages:all"] },
+    { name: "type wildcard keeps opaque and denies", in: ["*:*:all", "admin", "!secrets:read"], want: ["*:*:all", "admin", "!secrets:read"] },
   ];
   for (const tc of cases) {
     it(tc.name, () => {
@@ -1113,3 +1117,67 @@ describe("wildcardRequirements", () => {
     expect(ec.wildcardRequirements([{ bearer: ["users:me:read"] }])).toEqual([]);
   });
 });
+
+describe("token grammar", () => {
+  const ec = new EntitlementsChecker([], "bearer", false);
+
+  it("treats ! requirement tokens as except conditions", () => {
+    const reqs: Requirements = [{ bearer: ["pages:read", "!suspended"] }];
+    expect(ec.verifyEntitlements({ bearer: ["pages:read"] }, reqs)).toBe(true);
+    expect(ec.verifyEntitlements({ bearer: ["pages:read", "suspended"] }, reqs)).toBe(false);
+
+    // A scheme holding only except conditions is satisfied by its absence.
+    expect(ec.verifyEntitlements({}, [{ bearer: ["!suspended"] }])).toBe(true);
+  });
+
+  it("rebinds the placeholder of an except condition", () => {
+    const reqs = ec.parseRequirements([{ bearer: ["pages:read", "!pages:{id}:lock"] }]);
+    const bound = ec.bindRequirements(reqs, { id: "foo" });
+    expect(ec.verifyParsedEntitlements(ec.parseEntitlements({ bearer: ["pages:read"] }), bound)).toBe(true);
+    expect(
+      ec.verifyParsedEntitlements(ec.parseEntitlements({ bearer: ["pages:read", "pages:foo:lock"] }), bound),
+    ).toBe(false);
+  });
+
+  it("matches the .* version wildcard", () => {
+    expect(ec.verifyEntitlements({ bearer: ["pages.*:read"] }, [{ bearer: ["pages.v1:read"] }])).toBe(true);
+    expect(ec.verifyEntitlements({ bearer: ["pages.*:read"] }, [{ bearer: ["pages:read"] }])).toBe(false);
+    expect(ec.verifyEntitlements({ bearer: ["pages.*:read"] }, [{ bearer: ["files.v1:read"] }])).toBe(false);
+  });
+
+  it("matches the * resource type", () => {
+    expect(ec.verifyEntitlements({ bearer: ["*:*:read"] }, [{ bearer: ["pages:/foo:read"] }])).toBe(true);
+    expect(ec.verifyEntitlements({ bearer: ["*:*:read"] }, [{ bearer: ["pages:/foo:write"] }])).toBe(false);
+    expect(ec.verifyEntitlements({ bearer: ["*:*:read"] }, [{ bearer: ["secrets"] }])).toBe(false);
+  });
+
+  it("lets a held deny win over every grant", () => {
+    expect(ec.verifyEntitlements({ bearer: ["*:all", "!secrets:read"] }, [{ bearer: ["secrets:read"] }])).toBe(false);
+    expect(ec.verifyEntitlements({ bearer: ["*:all", "!secrets:read"] }, [{ bearer: ["pages:read"] }])).toBe(true);
+  });
+
+  it("covers type wildcard edge cases", () => {
+    expect(ec.verifyEntitlements({ bearer: ["*:*:read"] }, [{ bearer: ["pages:read"] }])).toBe(true);
+    expect(ec.verifyEntitlements({ bearer: ["*:*:all"] }, [{ bearer: ["secrets:/db:delete"] }])).toBe(true);
+    expect(ec.verifyEntitlements({ bearer: ["*"] }, [{ bearer: ["pages"] }])).toBe(false);
+    expect(ec.verifyEntitlements({ bearer: ["*"] }, [{ bearer: ["pages:read"] }])).toBe(false);
+    expect(ec.verifyEntitlements({ bearer: ["*"] }, [{ bearer: ["*"] }])).toBe(true);
+  });
+
+  it("lets a held deny win over grantReadyByDefault", () => {
+    const ready = new EntitlementsChecker([], "bearer", true);
+    expect(ready.verifyResourceEntitlements("pages", "foo", { bearer: ["!pages:foo:read"] }, [])).toBe(false);
+    expect(ready.verifyResourceEntitlements("pages", "bar", { bearer: ["!pages:foo:read"] }, [])).toBe(true);
+  });
+
+  it("matches the * wildcard verb", () => {
+    expect(ec.verifyEntitlements({ bearer: ["pages:*"] }, [{ bearer: ["pages:read"] }])).toBe(true);
+    expect(ec.verifyEntitlements({ bearer: ["*:*"] }, [{ bearer: ["pages:/foo:delete"] }])).toBe(true);
+  });
+
+  it("fails closed on Go-only requirement tokens", () => {
+    expect(ec.verifyEntitlements({ bearer: ["*:all"] }, [{ bearer: ["@admin"] }])).toBe(false);
+    expect(ec.verifyEntitlements({ bearer: ["*:all"] }, [{ bearer: ["#2:payments:approve"] }])).toBe(false);
+    expect(ec.verifyEntitlements({}, [{ bearer: ["!@admin"] }])).toBe(false);
+  });
+});
@@ -9,6 +9,10 @@
  *
  * Opaque form is intended to support JWT claims and HTTP-header-style requirements.
  *
+ * A "!" prefix makes a requirement an except condition and an entitlement a
+ * deny; a held "*" resource type, "<resource>.*" version and "all" or "*"
+ * verb are wildcards. See SPEC.md, Token Grammar.
+ *
  * Encoding: resourceName must not contain colons ':' since they would be
  * misinterpreted by the pattern splitting logic. The library does not encode
  * resourceNames - the same string is used on both sides of every match
@@ -88,6 +92,65 @@ function placeholderKey(resourceName: string): string {
   return "";
 }
 
+/**
+ * Marks a requirement as an except condition ("!suspended" is satisfied when
+ * the caller holds no matching entitlement) and an entitlement as a deny.
+ */
+export const EXCEPT_PREFIX = "!";
+
+/** As the resource type of an entitlement, grants every structured type. */
+export const RESOURCE_TYPE_WILDCARD = "*";
+
+/** Appended to a resource type in an entitlement, grants every version. */
+export const VERSION_WILDCARD_SUFFIX = ".*";
+
+/** The held verbs that grant every verb. */
+const WILDCARD_VERBS = ["all", "*"];
+
+/**
+ * The condition of an except token, or null. A "!" alone, or followed by
+ * another "!", is not an except token.
+ */
+function exceptCondition(s: string): string | null {
+  if (!s.startsWith(EXCEPT_PREFIX)) return null;
+  const cond = s.slice(EXCEPT_PREFIX.length);
+  if (cond === "" || cond.startsWith(EXCEPT_PREFIX)) return null;
+  return cond;
+}
+
+/**
+ * Whether s is a requirement form only the Go implementation supports: an
+ * "@name" reference or a "#<n>:<token>" distinct-schemes token. Such a
+ * requirement is unsatisfiable here, failing closed.
+ */
+function isGoOnlyRequirement(s: string): boolean {
+  if (s.startsWith("@")) {
+    return s.length > 1 && !s.includes(":");
+  }
+  const m = /^#(\d+):(.+)$/.exec(s);
+  return m !== null && Number(m[1]) >= 1;
+}
+
+/**
+ * The base of a versioned resource type, "pages" for "pages.v2", or null
+ * when the type is not versioned.
+ */
+function versionBase(resource: string): string | null {
+  const i = resource.lastIndexOf(".v");
+  if (i <= 0 || i + 2 === resource.length) return null;
+  return /^\d+$/.test(resource.slice(i + 2)) ? resource.slice(0, i) : null;
+}
+
+/**
+ * Whether the held resource type covers the required one: they are equal, or
+ * held is "<base>.*" and required is "<base>.v<N>".
+ */
+function resourceCovers(held: string, required: string): boolean {
+  if (held === required) return true;
+  if (!held.endsWith(VERSION_WILDCARD_SUFFIX)) return false;
+  return versionBase(required) === held.slice(0, -VERSION_WILDCARD_SUFFIX.length);
+}
+
 /**
  * Whether a resourceName is a wildcard. Empty is the parsed form of both the
  * short (<resource>:<verb>) and medium (<resource>::<verb>) syntaxes.
@@ -104,6 +167,11 @@ interface EntitlementPattern {
   isPattern: boolean;
   /** Binding key when resourceName is "{key}", else "". Requirement-side only. */
   placeholder: string;
+  /**
+   * The parsed condition of a "!" token, else null: an except condition on
+   * the requirement side, a deny on the held side.
+   */
+  cond: EntitlementPattern | null;
 }
 
 /** Parsed entitlements held for reuse across multiple verifications. */
@@ -121,6 +189,11 @@ export interface ParsedRequirements {
 const MAX_CACHE_SIZE = 10_000;
 
 function matches(ep: EntitlementPattern, req: EntitlementPattern): boolean {
+  // A deny is never a grant.
+  if (ep.cond !== null) {
+    return false;
+  }
+
   // Exact match is always the fastest path.
   if (ep.raw === req.raw) {
     return true;
@@ -131,13 +204,14 @@ function matches(ep: EntitlementPattern, req: EntitlementPattern): boolean {
     return false;
   }
 
-  // Resource type must match.
-  if (ep.resource !== req.resource) {
+  // Resource type must match (or the entitlement covers every version, or
+  // every type).
+  if (ep.resource !== RESOURCE_TYPE_WILDCARD && !resourceCovers(ep.resource, req.resource)) {
     return false;
   }
 
-  // Verb must match (or entitlement provides "all").
-  if (ep.verb !== "all" && ep.verb !== req.verb) {
+  // Verb must match (or entitlement provides a wildcard verb).
+  if (!WILDCARD_VERBS.includes(ep.verb) && ep.verb !== req.verb) {
     return false;
   }
 
@@ -155,9 +229,27 @@ function matches(ep: EntitlementPattern, req: EntitlementPattern): boolean {
   return ep.resourceName === req.resourceName;
 }
 
+/** Whether a deny in entitlementList matches requirement. */
+function denies(entitlementList: EntitlementPattern[], requirement: EntitlementPattern): boolean {
+  return entitlementList.some((ep) => ep.cond !== null && matches(ep.cond, requirement));
+}
+
 function parsePattern(s: string): EntitlementPattern {
+  const cond = exceptCondition(s);
+  if (cond !== null) {
+    return {
+      raw: s,
+      resource: "",
+      resourceName: "",
+      verb: "",
+      isPattern: false,
+      placeholder: "",
+      cond: parsePattern(cond),
+    };
+  }
+
   if (!s.includes(":")) {
-    return { raw: s, resource: "", resourceName: "", verb: "", isPattern: false, placeholder: "" };
+    return { raw: s, resource: "", resourceName: "", verb: "", isPattern: false, placeholder: "", cond: null };
   }
 
   const parts = s.split(":");
@@ -170,6 +262,7 @@ function parsePattern(s: string): EntitlementPattern {
       verb: parts[1]!,
       isPattern: true,
       placeholder: "",
+      cond: null,
     };
   } else if (parts.length === 3) {
     return {
@@ -179,11 +272,12 @@ function parsePattern(s: string): EntitlementPattern {
       verb: parts[2]!,
       isPattern: true,
       placeholder: placeholderKey(parts[1]!),
+      cond: null,
     };
   }
 
   // Too many colons → treat as opaque (matches Go behavior).
-  return { raw: s, resource: "", resourceName: "", verb: "", isPattern: false, placeholder: "" };
+  return { raw: s, resource: "", resourceName: "", verb: "", isPattern: false, placeholder: "", cond: null };
 }
 
 /**
@@ -206,8 +300,8 @@ function dominates(ep: EntitlementPattern, req: EntitlementPattern): boolean {
     return false;
   }
 
-  // Resource type must match.
-  if (ep.resource !== req.resource) {
+  // Resource type must match, or the held side covers every version.
+  if (!resourceCovers(ep.resource, req.resource)) {
     return false;
   }
 
@@ -224,6 +318,28 @@ function dominates(ep: EntitlementPattern, req: EntitlementPattern): boolean {
   return ep.resourceName === req.resourceName;
 }
 
+/**
+ * Reports whether the held entitlement (`ep`) grants everything the requested
+ * one (`req`) grants, for `compact`: `dominates`, with a "*" verb also
+ * covering every verb and a "*" resource type every structured type, as in
+ * `matches`.
+ */
+function subsumes(ep: EntitlementPattern, req: EntitlementPattern): boolean {
+  if (dominates(ep, req)) {
+    return true;
+  }
+  if (!ep.isPattern || !req.isPattern) {
+    return false;
+  }
+  if (ep.resource !== RESOURCE_TYPE_WILDCARD && !resourceCovers(ep.resource, req.resource)) {
+    return false;
+  }
+  if (!WILDCARD_VERBS.includes(ep.verb) && ep.verb !== req.verb) {
+    return false;
+  }
+  return isWildcardName(ep.resourceName) || ep.resourceName === req.resourceName;
+}
+
 /**
  * Returns `null` when every requested entitlement is dominated by at least
  * one held entitlement. Otherwise returns the first requested entitlement
@@ -243,13 +359,14 @@ export function verifyAttenuation(held: string[], requested: string[]): string |
 
 /**
  * Returns the subset of `entitlements` with every entry removed that is
- * strictly dominated by another entry, or that is an exact / equivalent-form
+ * strictly subsumed by another entry, or that is an exact / equivalent-form
  * duplicate (e.g. "pages:read", "pages::read", "pages:*:read" collapse to the
  * first-seen one). The result grants exactly the same authority as the input;
  * survivors keep their original strings and their first-seen order.
  *
- * Built purely on `dominates`, so it can never drift from attenuation. Opaque
- * and malformed scopes collapse only by exact equality.
+ * "Broader than" is `dominates` widened by the wildcards request-time matching
+ * honours and attenuation does not (see `subsumes`), so "*:*:all" absorbs
+ * "pages:read". Opaque and malformed scopes collapse only by exact equality.
  */
 export function compact(entitlements: string[]): string[] {
   const patterns = entitlements.map((s) => parsePattern(s));
@@ -257,19 +374,19 @@ export function compact(entitlements: string[]): string[] {
   const survivorPatterns: EntitlementPattern[] = [];
   for (let i = 0; i < patterns.length; i++) {
     const ep = patterns[i]!;
-    // (1) Drop if some OTHER entry strictly dominates it.
+    // (1) Drop if some OTHER entry strictly subsumes it.
     let strictlyDominated = false;
     for (let j = 0; j < patterns.length; j++) {
       if (i === j) continue;
       const op = patterns[j]!;
-      if (dominates(op, ep) && !dominates(ep, op)) {
+      if (subsumes(op, ep) && !subsumes(ep, op)) {
         strictlyDominated = true;
         break;
       }
     }
     if (strictlyDominated) continue;
     // (2) Maximal; keep unless an equivalent survivor already present.
-    const dup = survivorPatterns.some((sp) => dominates(sp, ep) && dominates(ep, sp));
+    const dup = survivorPatterns.some((sp) => subsumes(sp, ep) && subsumes(ep, sp));
     if (!dup) {
       survivors.push(entitlements[i]!);
       survivorPatterns.push(ep);
@@ -278,6 +395,56 @@ export function compact(entitlements: string[]): string[] {
   return survivors;
 }
 
+/**
+ * Binds the placeholder of p from binding.
+ *
+ * @throws {UnboundPlaceholderError} the placeholder has no entry in `binding`.
+ * @throws {InvalidBoundValueError} it is bound to "", "*", or a value
+ *   containing ':'.
+ */
+function bindPattern(p: EntitlementPattern, binding: Binding): EntitlementPattern {
+  const v = binding[p.placeholder];
+  if (v === undefined) {
+    throw new UnboundPlaceholderError(
+      `unbound placeholder "${p.placeholder}" in requirement "${p.raw}"`,
+    );
+  }
+  // "" and "*" are the wildcard spelling, not concrete names: binding
+  // one would widen the requirement to the whole class. A ':' is
+  // rejected too: although this port constructs the pattern directly
+  // below (see the comment there) rather than re-parsing it, Rust and
+  // Python have no pre-parsed type and must re-emit the bound pattern
+  // as a string that gets re-parsed — a value containing ':' would
+  // re-split into the wrong shape there and become opaque. Rejecting
+  // the colon here as well is what keeps all four ports identical
+  // instead of only the two that build the pattern directly. Fail
+  // like an unbound placeholder in every case.
+  if (isWildcardName(v) || v.includes(":")) {
+    throw new InvalidBoundValueError(
+      `bound value must not be empty, a wildcard, or contain ':': "${p.placeholder}" bound to "${v}" in requirement "${p.raw}"`,
+    );
+  }
+  // Construct directly rather than re-parsing: a bound value containing
+  // ':' would otherwise be re-split into the wrong shape.
+  return {
+    raw: `${p.resource}:${v}:${p.verb}`,
+    resource: p.resource,
+    resourceName: v,
+    verb: p.verb,
+    isPattern: true,
+    placeholder: "",
+    cond: null,
+  };
+}
+
+/**
+ * Whether a non-empty requirement list consists solely of except conditions,
+ * which a caller not presenting the scheme satisfies.
+ */
+function exceptOnly(list: EntitlementPattern[]): boolean {
+  return list.length > 0 && list.every((p) => p.cond !== null);
+}
+
 function isAnonymousCallerPatterns(
   patterns: Record<string, EntitlementPattern[]>,
 ): boolean {
@@ -405,38 +572,12 @@ export class EntitlementsChecker {
       const newSet: Record<string, EntitlementPattern[]> = {};
       for (const [scheme, list] of Object.entries(set)) {
         newSet[scheme] = list.map((p) => {
-          if (p.placeholder === "") return p;
-          const v = binding[p.placeholder];
-          if (v === undefined) {
-            throw new UnboundPlaceholderError(
-              `unbound placeholder "${p.placeholder}" in requirement "${p.raw}"`,
-            );
+          if (p.cond !== null && p.cond.placeholder !== "") {
+            // Keep the token's prefix, rebinding only its condition.
+            const cond = bindPattern(p.cond, binding);
+            return { ...p, raw: EXCEPT_PREFIX + cond.raw, cond };
           }
-          // "" and "*" are the wildcard spelling, not concrete names: binding
-          // one would widen the requirement to the whole class. A ':' is
-          // rejected too: although this port constructs the pattern directly
-          // below (see the comment there) rather than re-parsing it, Rust and
-          // Python have no pre-parsed type and must re-emit the bound pattern
-          // as a string that gets re-parsed — a value containing ':' would
-          // re-split into the wrong shape there and become opaque. Rejecting
-          // the colon here as well is what keeps all four ports identical
-          // instead of only the two that build the pattern directly. Fail
-          // like an unbound placeholder in every case.
-          if (isWildcardName(v) || v.includes(":")) {
-            throw new InvalidBoundValueError(
-              `bound value must not be empty, a wildcard, or contain ':': "${p.placeholder}" bound to "${v}" in requirement "${p.raw}"`,
-            );
-          }
-          // Construct directly rather than re-parsing: a bound value containing
-          // ':' would otherwise be re-split into the wrong shape.
-          return {
-            raw: `${p.resource}:${v}:${p.verb}`,
-            resource: p.resource,
-            resourceName: v,
-            verb: p.verb,
-            isPattern: true,
-            placeholder: "",
-          };
+          return p.placeholder === "" ? p : bindPattern(p, binding);
         });
       }
       return newSet;
@@ -493,7 +634,7 @@ export class EntitlementsChecker {
       for (const [scheme, list] of Object.entries(req)) {
         next[scheme] = list.map((s) => {
           const p = this.parsePattern(s);
-          if (p.placeholder !== "") {
+          if (p.placeholder !== "" || (p.cond !== null && p.cond.placeholder !== "")) {
             hasPlaceholder = true;
           }
           return p;
@@ -579,8 +720,9 @@ export class EntitlementsChecker {
 
     const list = entitlements.patterns[this.defaultScheme] ?? [];
     const isAnonymous = isAnonymousCallerPatterns(entitlements.patterns);
+    // A deny on the instance wins over grantReadyByDefault too.
     const hasIdentity =
-      this.grantReadyByDefault ||
+      (this.grantReadyByDefault && !denies(list, parsedIdentity)) ||
       this.hasParsedEntitlement(list, this.defaultScheme, parsedIdentity, isAnonymous);
     if (!hasIdentity) {
       return false;
@@ -609,6 +751,20 @@ export class EntitlementsChecker {
     ) {
       return false;
     }
+    return this.lookupGrant(entitlementList, scheme, requirement, isAnonymousCaller);
+  }
+
+  /** hasParsedEntitlement without the strict backstop. */
+  private lookupGrant(
+    entitlementList: EntitlementPattern[],
+    scheme: string,
+    requirement: EntitlementPattern,
+    isAnonymousCaller: boolean,
+  ): boolean {
+    // A deny the caller holds wins over every grant.
+    if (denies(entitlementList, requirement)) {
+      return false;
+    }
 
     for (const e of entitlementList) {
       if (matches(e, requirement)) return true;
@@ -653,7 +809,7 @@ export class EntitlementsChecker {
         scheme === this.defaultScheme &&
         (this.basePatterns.length > 0 ||
           (isAnonymousCaller && this.anonymousPatterns.length > 0));
-      if (!userHas && !hasFallback) return false;
+      if (!userHas && !hasFallback && !exceptOnly(requirementList)) return false;
 
       if (!this.satisfiesRequirement(entitlements, scheme, requirementList, isAnonymousCaller)) {
         return false;
@@ -670,6 +826,21 @@ export class EntitlementsChecker {
   ): boolean {
     const list = entitlements[scheme] ?? [];
     for (const r of requirement) {
+      if (isGoOnlyRequirement(r.raw)) {
+        return false;
+      }
+      if (r.cond !== null) {
+        // An except condition holds when the caller has no grant matching
+        // it; an unbound placeholder or Go-only condition cannot be decided.
+        if (
+          r.cond.placeholder !== "" ||
+          isGoOnlyRequirement(r.cond.raw) ||
+          this.lookupGrant(list, scheme, r.cond, isAnonymousCaller)
+        ) {
+          return false;
+        }
+        continue;
+      }
       if (!this.hasParsedEntitlement(list, scheme, r, isAnonymousCaller)) {
         return false;
       }